@@ -2,10 +2,13 @@ package main
 
 import (
 	"context"
+	"crypto/ed25519"
 	"fmt"
 	"net/http"
+	"net/url"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
 
@@ -13,10 +16,18 @@ import (
 	"github.com/ylh990835774/blockchain-shop-demo/configs"
 	"github.com/ylh990835774/blockchain-shop-demo/internal/api"
 	"github.com/ylh990835774/blockchain-shop-demo/internal/api/middleware"
+	"github.com/ylh990835774/blockchain-shop-demo/internal/blockchain"
+	"github.com/ylh990835774/blockchain-shop-demo/internal/exchange"
 	"github.com/ylh990835774/blockchain-shop-demo/internal/handlers"
+	"github.com/ylh990835774/blockchain-shop-demo/internal/invoice"
+	"github.com/ylh990835774/blockchain-shop-demo/internal/payment"
 	"github.com/ylh990835774/blockchain-shop-demo/internal/repository/mysql"
 	"github.com/ylh990835774/blockchain-shop-demo/internal/service"
+	"github.com/ylh990835774/blockchain-shop-demo/internal/storage"
+	"github.com/ylh990835774/blockchain-shop-demo/internal/tax"
+	"github.com/ylh990835774/blockchain-shop-demo/internal/webhook"
 	"github.com/ylh990835774/blockchain-shop-demo/pkg/logger"
+	"github.com/ylh990835774/blockchain-shop-demo/pkg/money"
 )
 
 func main() {
@@ -59,6 +70,12 @@ func main() {
 	}
 	defer sqlDB.Close()
 
+	// 初始化区块链
+	chain, err := blockchain.NewBlockchainService()
+	if err != nil {
+		logger.Fatal("初始化区块链失败", logger.Err(err))
+	}
+
 	// 设置 Gin 模式
 	gin.SetMode(cfg.Server.Mode)
 
@@ -76,19 +93,113 @@ func main() {
 	productRepo := mysql.NewProductRepository(db)
 	orderRepo := mysql.NewOrderRepository(db)
 	blockchainRepo := mysql.NewBlockchainRepository(db)
+	idempotencyRepo := mysql.NewIdempotencyRepository(db)
+	reservationRepo := mysql.NewReservationRepository(db)
+	paymentRepo := mysql.NewPaymentRepository(db)
+	shipmentRepo := mysql.NewShipmentRepository(db)
+	returnRepo := mysql.NewReturnRepository(db)
+	invoiceRepo := mysql.NewInvoiceRepository(db)
+	couponRepo := mysql.NewCouponRepository(db)
+	categoryRepo := mysql.NewCategoryRepository(db)
+	priceCommitmentRepo := mysql.NewPriceCommitmentRepository(db)
+	inventoryRepo := mysql.NewInventoryRepository(db)
 
 	// 初始化服务层
 	jwtService := service.NewJWTService(cfg.JWT.SecretKey,
 		cfg.JWT.Issuer, time.Hour*time.Duration(cfg.JWT.ExpireDurationHours))
 	userService := service.NewUserService(userRepo, jwtService)
-	productService := service.NewProductService(productRepo)
-	orderService := service.NewOrderService(orderRepo, productRepo, blockchainRepo, db)
+	rates, err := loadRateProvider(cfg.Currency.RatesFile)
+	if err != nil {
+		logger.Fatal("加载汇率失败", logger.Err(err))
+	}
+	taxes, err := loadTaxCalculator(cfg.Tax)
+	if err != nil {
+		logger.Fatal("加载税率配置失败", logger.Err(err))
+	}
+	productService := service.NewProductService(productRepo, categoryRepo, rates, taxes, db)
+	orderService := service.NewOrderService(orderRepo, productRepo, blockchainRepo, reservationRepo, couponRepo, userRepo,
+		productService, taxes, db, time.Minute*time.Duration(cfg.Order.ReservationMinutes))
+	var gateways []payment.PaymentGateway
+	if cfg.Payment.Mock.Enabled {
+		gateways = append(gateways, payment.NewMockGateway(cfg.Payment.Mock.WebhookSecret, cfg.Payment.Mock.BaseURL))
+	}
+	paymentService := service.NewPaymentService(paymentRepo, orderService, chain, gateways...)
+	merchantKey, err := loadMerchantKey(cfg.Crypto.MerchantPrivateKey)
+	if err != nil {
+		logger.Fatal("加载商户钱包私钥失败", logger.Err(err))
+	}
+	cryptoPaymentService, err := service.NewCryptoPaymentService(blockchainRepo, orderService, chain, merchantKey,
+		cfg.Crypto.Confirmations, cfg.Crypto.FaucetLimit)
+	if err != nil {
+		logger.Fatal("初始化链上支付服务失败", logger.Err(err))
+	}
+	idempotencyService := service.NewIdempotencyService(idempotencyRepo,
+		time.Hour*time.Duration(cfg.Idempotency.TTLHours), time.Second*time.Duration(cfg.Idempotency.LockTimeoutSeconds))
+	shipmentService := service.NewShipmentService(shipmentRepo, orderRepo, chain, db)
+	returnService := service.NewReturnService(returnRepo, orderRepo, productRepo, paymentService, cryptoPaymentService,
+		chain, db)
+	invoiceService := service.NewInvoiceService(invoiceRepo, orderRepo, productRepo, userRepo,
+		invoice.NewRenderer(cfg.Invoice.FontFile, cfg.Invoice.VerifyBaseURL), loadInvoiceIssuer(cfg.Invoice), db)
+	couponService := service.NewCouponService(couponRepo, productRepo, db)
+	categoryService := service.NewCategoryService(categoryRepo, db)
+	blobStore, err := loadBlobStore(router, cfg.Storage)
+	if err != nil {
+		logger.Fatal("初始化图片存储失败", logger.Err(err))
+	}
+	imageService := service.NewProductImageService(productRepo, blobStore, int64(cfg.Storage.MaxImageMB)<<20)
+	priceCommitmentService := service.NewPriceCommitmentService(priceCommitmentRepo, orderRepo, productRepo, chain, db)
+	notifier, err := loadNotifier(cfg.Inventory.Webhook)
+	if err != nil {
+		logger.Fatal("初始化低库存告警推送失败", logger.Err(err))
+	}
+	inventoryService := service.NewInventoryService(inventoryRepo, productRepo, notifier,
+		cfg.Inventory.LowStockThreshold, db)
 
 	// 初始化处理器
-	h := handlers.NewHandlers(userService, jwtService, productService, orderService)
+	h := handlers.NewHandlers(userService, jwtService, productService, orderService,
+		handlers.WithPaymentService(paymentService),
+		handlers.WithCryptoPaymentService(cryptoPaymentService),
+		handlers.WithShipmentService(shipmentService),
+		handlers.WithReturnService(returnService),
+		handlers.WithInvoiceService(invoiceService),
+		handlers.WithCouponService(couponService),
+		handlers.WithCategoryService(categoryService),
+		handlers.WithImageService(imageService),
+		handlers.WithPriceCommitmentService(priceCommitmentService),
+		handlers.WithInventoryService(inventoryService))
 
 	// 设置路由
-	api.SetupRouter(router, h, middleware.NewJWTMiddleware(jwtService))
+	api.SetupRouter(router, h, middleware.NewJWTMiddleware(jwtService),
+		middleware.NewIdempotencyMiddleware(idempotencyService), middleware.NewRoleMiddleware(userService))
+
+	// 后台任务
+	bgCtx, stopBackground := context.WithCancel(context.Background())
+	defer stopBackground()
+
+	// 定期取消超时未支付的订单并释放库存
+	reservationSweeper := service.NewReservationSweeper(orderService, reservationRepo,
+		time.Second*time.Duration(cfg.Order.SweepIntervalSeconds))
+	go reservationSweeper.Run(bgCtx)
+
+	// 定期确认链上转账并完成订单支付
+	confirmationWatcher := service.NewCryptoConfirmationWatcher(cryptoPaymentService,
+		time.Second*time.Duration(cfg.Crypto.ConfirmIntervalSeconds))
+	go confirmationWatcher.Run(bgCtx)
+
+	// 定期发布商品价格表的默克尔根并上链
+	priceCommitter := service.NewPriceCommitter(priceCommitmentService,
+		time.Minute*time.Duration(cfg.Pricing.CommitIntervalMinutes))
+	go priceCommitter.Run(bgCtx)
+
+	// 定期检查低库存并推送告警
+	lowStockMonitor := service.NewLowStockMonitor(inventoryService,
+		time.Second*time.Duration(cfg.Inventory.AlertIntervalSeconds))
+	go lowStockMonitor.Run(bgCtx)
+
+	// 定期清理过期的幂等键
+	idempotencySweeper := service.NewIdempotencySweeper(idempotencyService,
+		time.Minute*time.Duration(cfg.Idempotency.PurgeIntervalMinutes))
+	go idempotencySweeper.Run(bgCtx)
 
 	// 创建HTTP服务器
 	srv := &http.Server{
@@ -118,3 +229,127 @@ func main() {
 
 	logger.Info("服务器已关闭")
 }
+
+// loadMerchantKey 加载商户钱包私钥，未配置时生成临时私钥
+func loadMerchantKey(seed string) (ed25519.PrivateKey, error) {
+	if seed != "" {
+		return blockchain.PrivateKeyFromHex(seed)
+	}
+
+	key, err := blockchain.GenerateKey()
+	if err != nil {
+		return nil, err
+	}
+	logger.Warn("未配置商户钱包私钥，已生成临时私钥，重启后无法使用此前收到的款项",
+		logger.String("address", blockchain.AddressFromPublicKey(key.Public().(ed25519.PublicKey))))
+	return key, nil
+}
+
+// loadRateProvider 加载汇率文件，未配置时仅支持默认币种
+func loadRateProvider(path string) (exchange.RateProvider, error) {
+	if path == "" {
+		logger.Warn("未配置汇率文件，仅支持默认币种", logger.String("currency", money.DefaultCurrency))
+		return exchange.NewStaticRateProvider(money.DefaultCurrency, nil)
+	}
+	return exchange.LoadStaticRateFile(path)
+}
+
+// loadBlobStore 创建商品图片存储，使用本地存储时在访问地址的路径上提供静态文件访问
+func loadBlobStore(router *gin.Engine, cfg configs.StorageConfig) (storage.BlobStore, error) {
+	switch cfg.Driver {
+	case "s3":
+		return storage.NewS3Store(storage.S3Config{
+			Endpoint:        cfg.S3.Endpoint,
+			Region:          cfg.S3.Region,
+			Bucket:          cfg.S3.Bucket,
+			AccessKeyID:     cfg.S3.AccessKeyID,
+			SecretAccessKey: cfg.S3.SecretAccessKey,
+			PublicURL:       cfg.S3.PublicURL,
+		})
+	case "", "local":
+		dir := cfg.Local.Dir
+		if dir == "" {
+			dir = "./storage/media"
+		}
+		baseURL := cfg.Local.BaseURL
+		if baseURL == "" {
+			baseURL = "/media"
+		}
+		parsed, err := url.Parse(baseURL)
+		if err != nil {
+			return nil, fmt.Errorf("invalid storage base url %q: %w", baseURL, err)
+		}
+		store, err := storage.NewLocalStore(dir, baseURL)
+		if err != nil {
+			return nil, err
+		}
+		path := strings.TrimRight(parsed.Path, "/")
+		if path == "" {
+			return nil, fmt.Errorf("storage base url %q must have a path", baseURL)
+		}
+		router.Static(path, dir)
+		return store, nil
+	}
+	return nil, fmt.Errorf("unknown storage driver %q", cfg.Driver)
+}
+
+// loadNotifier 按配置创建低库存告警的推送方式，未配置地址时只写入日志
+func loadNotifier(cfg configs.InventoryWebhookConfig) (webhook.Notifier, error) {
+	if cfg.URL == "" {
+		logger.Warn("未配置低库存告警推送地址，告警只写入日志")
+		return webhook.LogNotifier{}, nil
+	}
+	return webhook.NewHTTPNotifier(cfg.URL, cfg.Secret, time.Second*time.Duration(cfg.TimeoutSeconds))
+}
+
+// loadInvoiceIssuer 加载开票方信息，商户编码和发票号前缀未配置时使用默认值
+func loadInvoiceIssuer(cfg configs.InvoiceConfig) service.InvoiceIssuer {
+	issuer := service.InvoiceIssuer{
+		MerchantCode: cfg.MerchantCode,
+		NumberPrefix: cfg.NumberPrefix,
+		Name:         cfg.SellerName,
+		TaxID:        cfg.SellerTaxID,
+		Address:      cfg.SellerAddress,
+	}
+	if issuer.MerchantCode == "" {
+		issuer.MerchantCode = "SHOP"
+	}
+	if issuer.NumberPrefix == "" {
+		issuer.NumberPrefix = "INV"
+	}
+	return issuer
+}
+
+// loadTaxCalculator 加载各税区税率，未配置时不计税
+func loadTaxCalculator(cfg configs.TaxConfig) (*tax.Calculator, error) {
+	if len(cfg.Regions) == 0 {
+		logger.Warn("未配置税率，订单不计税")
+		region := cfg.DefaultRegion
+		if region == "" {
+			region = "CN"
+		}
+		return tax.NoTax(region), nil
+	}
+
+	regions := make(map[string]tax.Region, len(cfg.Regions))
+	for code, region := range cfg.Regions {
+		rates := make(map[string]money.Rate, len(region.Rates))
+		for category, value := range region.Rates {
+			rate, err := parseTaxRate(value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid tax rate %q for %s/%s: %w", value, code, category, err)
+			}
+			rates[category] = rate
+		}
+		regions[code] = tax.Region{Inclusive: region.PriceIncludesTax, Rates: rates}
+	}
+	return tax.NewCalculator(cfg.DefaultRegion, regions)
+}
+
+// parseTaxRate 解析税率，允许零税率
+func parseTaxRate(value string) (money.Rate, error) {
+	if strings.Trim(value, "0.") == "" {
+		return 0, nil
+	}
+	return money.ParseRate(value)
+}
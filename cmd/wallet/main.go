@@ -0,0 +1,87 @@
+// wallet 是用于本地联调的钱包工具，可以生成钱包私钥并签名转账。
+//
+//	go run ./cmd/wallet new
+//	go run ./cmd/wallet sign -key <私钥种子> -to <收款地址> -value 19.98 -nonce 0 -order 1
+package main
+
+import (
+	"crypto/ed25519"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/ylh990835774/blockchain-shop-demo/internal/blockchain"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+	}
+
+	switch os.Args[1] {
+	case "new":
+		newWallet()
+	case "sign":
+		sign(os.Args[2:])
+	default:
+		usage()
+	}
+}
+
+func newWallet() {
+	key, err := blockchain.GenerateKey()
+	if err != nil {
+		fail(err)
+	}
+
+	printJSON(map[string]string{
+		"private_key": hex.EncodeToString(key.Seed()),
+		"address":     blockchain.AddressFromPublicKey(key.Public().(ed25519.PublicKey)),
+	})
+}
+
+func sign(args []string) {
+	fs := flag.NewFlagSet("sign", flag.ExitOnError)
+	seed := fs.String("key", "", "钱包私钥种子（十六进制）")
+	to := fs.String("to", "", "收款地址")
+	value := fs.String("value", "", "转账金额，最多两位小数")
+	nonce := fs.Uint64("nonce", 0, "发送方已发出的转账数量")
+	orderID := fs.Int64("order", 0, "支付的订单ID")
+	_ = fs.Parse(args)
+
+	key, err := blockchain.PrivateKeyFromHex(*seed)
+	if err != nil {
+		fail(err)
+	}
+
+	transfer := &blockchain.Transfer{
+		From:    blockchain.AddressFromPublicKey(key.Public().(ed25519.PublicKey)),
+		To:      *to,
+		Value:   *value,
+		Nonce:   *nonce,
+		OrderID: *orderID,
+	}
+	transfer.Sign(key)
+
+	printJSON(transfer)
+}
+
+func printJSON(v interface{}) {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		fail(err)
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: wallet new | wallet sign -key <seed> -to <address> -value <value> -nonce <n> -order <id>")
+	os.Exit(2)
+}
+
+func fail(err error) {
+	fmt.Fprintln(os.Stderr, err)
+	os.Exit(1)
+}
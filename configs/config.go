@@ -10,6 +10,17 @@ type Config struct {
 	MySQL  MySQLConfig  `yaml:"mysql"`
 	JWT    JWTConfig    `yaml:"jwt"`
 	Log    LogConfig    `yaml:"log"`
+
+	Idempotency IdempotencyConfig `yaml:"idempotency"`
+	Order       OrderConfig       `yaml:"order"`
+	Currency    CurrencyConfig    `yaml:"currency"`
+	Pricing     PricingConfig     `yaml:"pricing"`
+	Inventory   InventoryConfig   `yaml:"inventory"`
+	Tax         TaxConfig         `yaml:"tax"`
+	Payment     PaymentConfig     `yaml:"payment"`
+	Crypto      CryptoConfig      `yaml:"crypto"`
+	Invoice     InvoiceConfig     `yaml:"invoice"`
+	Storage     StorageConfig     `yaml:"storage"`
 }
 
 // ServerConfig 是服务器配置
@@ -34,6 +45,110 @@ type JWTConfig struct {
 	ExpireDurationHours int    `yaml:"expireDurationHours"`
 }
 
+// IdempotencyConfig 是幂等键配置
+type IdempotencyConfig struct {
+	TTLHours             int `yaml:"ttlHours"`             // 幂等键有效期（小时），默认24
+	LockTimeoutSeconds   int `yaml:"lockTimeoutSeconds"`   // 首次请求未完成时幂等键的占用时长（秒），默认60
+	PurgeIntervalMinutes int `yaml:"purgeIntervalMinutes"` // 清理过期幂等键的间隔（分钟），默认60
+}
+
+// OrderConfig 是订单配置
+type OrderConfig struct {
+	ReservationMinutes   int `yaml:"reservationMinutes"`   // 待支付订单的库存预留时长（分钟）
+	SweepIntervalSeconds int `yaml:"sweepIntervalSeconds"` // 超时订单清理任务的执行间隔（秒）
+}
+
+// CurrencyConfig 是币种和汇率配置
+type CurrencyConfig struct {
+	RatesFile string `yaml:"ratesFile"` // 固定汇率文件路径，为空时仅支持默认币种
+}
+
+// PricingConfig 是价格表承诺配置
+type PricingConfig struct {
+	CommitIntervalMinutes int `yaml:"commitIntervalMinutes"` // 发布价格表默克尔根并上链的间隔（分钟），默认60
+}
+
+// InventoryConfig 是库存告警配置
+type InventoryConfig struct {
+	LowStockThreshold    int                    `yaml:"lowStockThreshold"`    // 未单独设置阈值的SKU的低库存告警阈值，默认0即售罄时告警
+	AlertIntervalSeconds int                    `yaml:"alertIntervalSeconds"` // 检查低库存并发送告警的间隔（秒），默认60
+	Webhook              InventoryWebhookConfig `yaml:"webhook"`
+}
+
+// InventoryWebhookConfig 是低库存告警的webhook配置
+type InventoryWebhookConfig struct {
+	URL            string `yaml:"url"`            // 告警推送地址，为空时只写入日志
+	Secret         string `yaml:"secret"`         // 请求体签名密钥，为空时不签名
+	TimeoutSeconds int    `yaml:"timeoutSeconds"` // 单次推送的超时时间（秒），默认10
+}
+
+// TaxConfig 是税率配置
+type TaxConfig struct {
+	DefaultRegion string                     `yaml:"defaultRegion"` // 下单未指定税区时使用的税区
+	Regions       map[string]TaxRegionConfig `yaml:"regions"`       // 各税区的税率，为空时不计税
+}
+
+// TaxRegionConfig 是单个税区的税率配置
+type TaxRegionConfig struct {
+	PriceIncludesTax bool              `yaml:"priceIncludesTax"` // 商品价格是否为含税价
+	Rates            map[string]string `yaml:"rates"`            // 税目到税率的映射，必须包含standard
+}
+
+// PaymentConfig 是支付配置
+type PaymentConfig struct {
+	Mock MockPaymentConfig `yaml:"mock"`
+}
+
+// MockPaymentConfig 是本地模拟支付渠道配置
+type MockPaymentConfig struct {
+	Enabled       bool   `yaml:"enabled"`       // 是否启用模拟支付渠道，仅用于开发和测试环境
+	WebhookSecret string `yaml:"webhookSecret"` // 回调签名密钥
+	BaseURL       string `yaml:"baseURL"`       // 模拟支付页面的地址前缀
+}
+
+// CryptoConfig 是链上转账支付配置
+type CryptoConfig struct {
+	MerchantPrivateKey     string `yaml:"merchantPrivateKey"`     // 商户钱包私钥种子（十六进制），用于收款和退款
+	Confirmations          int    `yaml:"confirmations"`          // 订单转为已支付所需的确认数
+	ConfirmIntervalSeconds int    `yaml:"confirmIntervalSeconds"` // 检查转账确认数的间隔（秒）
+	FaucetLimit            string `yaml:"faucetLimit"`            // 单次测试币发放上限，为空时禁用发放接口
+}
+
+// InvoiceConfig 是发票配置
+type InvoiceConfig struct {
+	MerchantCode  string `yaml:"merchantCode"`  // 商城的开票编码，全店发票共用一个连续编号序列
+	NumberPrefix  string `yaml:"numberPrefix"`  // 发票号前缀
+	SellerName    string `yaml:"sellerName"`    // 销售方名称
+	SellerTaxID   string `yaml:"sellerTaxID"`   // 销售方纳税人识别号
+	SellerAddress string `yaml:"sellerAddress"` // 销售方地址
+	FontFile      string `yaml:"fontFile"`      // PDF使用的TrueType字体，需支持中文；为空时中文无法显示
+	VerifyBaseURL string `yaml:"verifyBaseURL"` // 发票二维码中核验地址的前缀
+}
+
+// StorageConfig 是商品图片存储配置
+type StorageConfig struct {
+	Driver     string             `yaml:"driver"`     // 存储后端: local, s3，默认local
+	MaxImageMB int                `yaml:"maxImageMB"` // 单张图片的大小上限（MB），默认5
+	Local      LocalStorageConfig `yaml:"local"`
+	S3         S3StorageConfig    `yaml:"s3"`
+}
+
+// LocalStorageConfig 是本地文件存储配置
+type LocalStorageConfig struct {
+	Dir     string `yaml:"dir"`     // 文件保存目录
+	BaseURL string `yaml:"baseURL"` // 文件访问地址前缀，其路径部分由服务以静态文件方式提供
+}
+
+// S3StorageConfig 是S3兼容对象存储配置
+type S3StorageConfig struct {
+	Endpoint        string `yaml:"endpoint"` // 服务地址，如 http://localhost:9000
+	Region          string `yaml:"region"`
+	Bucket          string `yaml:"bucket"`
+	AccessKeyID     string `yaml:"accessKeyID"`
+	SecretAccessKey string `yaml:"secretAccessKey"`
+	PublicURL       string `yaml:"publicURL"` // 对象公开访问地址前缀，如CDN地址；为空时使用 endpoint/bucket
+}
+
 // LogConfig 是日志配置
 type LogConfig struct {
 	Level      string `yaml:"level"`      // 日志级别: debug, info, warn, error, fatal
@@ -76,6 +76,15 @@ func ErrorHandler() gin.HandlerFunc {
 			case errors.ErrNoFieldsToUpdate:
 				statusCode = http.StatusBadRequest
 				message = err.Error()
+			case errors.ErrInsufficientStock, errors.ErrInvalidOrderStatus, errors.ErrReservationExpired:
+				statusCode = http.StatusConflict
+				message = err.Error()
+			case errors.ErrInvalidSignature:
+				statusCode = http.StatusUnauthorized
+				message = err.Error()
+			case errors.ErrPaymentAmountMismatch, errors.ErrInsufficientBalance, errors.ErrUnsupportedCurrency:
+				statusCode = http.StatusBadRequest
+				message = err.Error()
 			default:
 				statusCode = http.StatusInternalServerError
 				message = "服务器内部错误"
@@ -0,0 +1,131 @@
+package middleware
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ylh990835774/blockchain-shop-demo/internal/service"
+	"github.com/ylh990835774/blockchain-shop-demo/pkg/errors"
+	"github.com/ylh990835774/blockchain-shop-demo/pkg/logger"
+	"github.com/ylh990835774/blockchain-shop-demo/pkg/response"
+)
+
+const (
+	// IdempotencyKeyHeader 客户端传入幂等键的请求头
+	IdempotencyKeyHeader = "Idempotency-Key"
+	// IdempotentReplayedHeader 标记响应为重放结果的响应头
+	IdempotentReplayedHeader = "Idempotent-Replayed"
+
+	maxIdempotencyKeyLength = 255
+)
+
+// IdempotencyMiddleware 是幂等键中间件的结构体
+type IdempotencyMiddleware struct {
+	idempotencyService service.IIdempotencyService
+}
+
+// NewIdempotencyMiddleware 创建一个新的幂等键中间件
+func NewIdempotencyMiddleware(idempotencyService service.IIdempotencyService) *IdempotencyMiddleware {
+	if idempotencyService == nil {
+		panic("idempotency service cannot be nil")
+	}
+
+	return &IdempotencyMiddleware{
+		idempotencyService: idempotencyService,
+	}
+}
+
+// MiddlewareFunc 返回幂等键中间件的处理函数，需在JWT中间件之后使用
+func (m *IdempotencyMiddleware) MiddlewareFunc() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader(IdempotencyKeyHeader)
+		if key == "" {
+			c.Next()
+			return
+		}
+
+		if len(key) > maxIdempotencyKeyLength {
+			c.AbortWithStatusJSON(http.StatusBadRequest, response.Error(-1, errors.ErrInvalidInput.Error()))
+			return
+		}
+
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, response.Error(-1, errors.ErrInvalidInput.Error()))
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		userID := c.GetInt64("user_id")
+		record, err := m.idempotencyService.Acquire(userID, key, fingerprint(c.Request, body))
+		if err != nil {
+			switch err {
+			case errors.ErrIdempotencyKeyReused:
+				c.AbortWithStatusJSON(http.StatusUnprocessableEntity, response.Error(-1, err.Error()))
+			case errors.ErrIdempotencyKeyInProgress:
+				c.AbortWithStatusJSON(http.StatusConflict, response.Error(-1, err.Error()))
+			default:
+				logger.Error("占用幂等键失败", logger.String("key", key), logger.Err(err))
+				c.AbortWithStatusJSON(http.StatusInternalServerError, response.Error(-1, "服务器内部错误"))
+			}
+			return
+		}
+
+		// 重放首次请求的响应
+		if record.Completed() {
+			c.Header(IdempotentReplayedHeader, "true")
+			c.Data(record.StatusCode, "application/json; charset=utf-8", record.ResponseBody)
+			c.Abort()
+			return
+		}
+
+		writer := &bodyCaptureWriter{ResponseWriter: c.Writer}
+		c.Writer = writer
+
+		c.Next()
+
+		// 服务端错误不保存结果，允许客户端使用同一幂等键重试
+		if status := writer.Status(); status >= http.StatusInternalServerError {
+			if err := m.idempotencyService.Release(record.ID); err != nil {
+				logger.Error("释放幂等键失败", logger.String("key", key), logger.Err(err))
+			}
+			return
+		}
+
+		// 只保存到本请求占用的记录，超时后被重试接管的幂等键不受影响
+		if err := m.idempotencyService.Complete(record.ID, writer.Status(), writer.body.Bytes()); err != nil {
+			logger.Error("保存幂等键响应失败", logger.String("key", key), logger.Err(err))
+		}
+	}
+}
+
+// fingerprint 计算请求指纹，相同幂等键的请求必须具有相同的方法、路径和请求体
+func fingerprint(r *http.Request, body []byte) string {
+	h := sha256.New()
+	h.Write([]byte(r.Method))
+	h.Write([]byte(" "))
+	h.Write([]byte(r.URL.Path))
+	h.Write([]byte("\n"))
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// bodyCaptureWriter 在写出响应的同时保存响应体
+type bodyCaptureWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *bodyCaptureWriter) Write(data []byte) (int, error) {
+	w.body.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *bodyCaptureWriter) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
@@ -0,0 +1,182 @@
+package middleware
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/ylh990835774/blockchain-shop-demo/internal/model"
+	"github.com/ylh990835774/blockchain-shop-demo/pkg/errors"
+	"github.com/ylh990835774/blockchain-shop-demo/pkg/logger"
+)
+
+// MockIdempotencyService 是 IdempotencyService 的 mock 实现
+type MockIdempotencyService struct {
+	mock.Mock
+}
+
+func (m *MockIdempotencyService) Acquire(userID int64, key, fingerprint string) (*model.IdempotencyKey, error) {
+	args := m.Called(userID, key, fingerprint)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.IdempotencyKey), args.Error(1)
+}
+
+func (m *MockIdempotencyService) Complete(id int64, statusCode int, body []byte) error {
+	args := m.Called(id, statusCode, body)
+	return args.Error(0)
+}
+
+func (m *MockIdempotencyService) Release(id int64) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
+
+func (m *MockIdempotencyService) PurgeExpired() (int64, error) {
+	args := m.Called()
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func TestIdempotencyMiddleware(t *testing.T) {
+	// 初始化日志配置
+	err := logger.Setup(&logger.Config{
+		Level:    "info",
+		Format:   "console",
+		Console:  true,
+		Filename: "", // 测试时不写入文件
+	})
+	assert.NoError(t, err)
+
+	gin.SetMode(gin.TestMode)
+
+	const requestBody = `{"product_id":1,"quantity":2}`
+
+	tests := []struct {
+		name           string
+		key            string
+		setupMock      func(*MockIdempotencyService)
+		handlerStatus  int
+		expectedStatus int
+		expectedBody   string
+		expectedCalls  int
+		expectReplayed bool
+	}{
+		{
+			name:           "no idempotency key",
+			key:            "",
+			setupMock:      func(m *MockIdempotencyService) {},
+			handlerStatus:  http.StatusOK,
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"id":1}`,
+			expectedCalls:  1,
+		},
+		{
+			name: "first request stores response",
+			key:  "key-1",
+			setupMock: func(m *MockIdempotencyService) {
+				m.On("Acquire", int64(7), "key-1", mock.AnythingOfType("string")).Return(&model.IdempotencyKey{ID: 3}, nil)
+				m.On("Complete", int64(3), http.StatusOK, []byte(`{"id":1}`)).Return(nil)
+			},
+			handlerStatus:  http.StatusOK,
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"id":1}`,
+			expectedCalls:  1,
+		},
+		{
+			name: "duplicate request replays stored response",
+			key:  "key-1",
+			setupMock: func(m *MockIdempotencyService) {
+				m.On("Acquire", int64(7), "key-1", mock.AnythingOfType("string")).Return(&model.IdempotencyKey{
+					StatusCode:   http.StatusOK,
+					ResponseBody: []byte(`{"id":1}`),
+				}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"id":1}`,
+			expectedCalls:  0,
+			expectReplayed: true,
+		},
+		{
+			name: "same key with different body",
+			key:  "key-1",
+			setupMock: func(m *MockIdempotencyService) {
+				m.On("Acquire", int64(7), "key-1", mock.AnythingOfType("string")).Return(nil, errors.ErrIdempotencyKeyReused)
+			},
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedBody:   `{"code":-1,"message":"幂等键已被用于不同的请求","data":null}`,
+			expectedCalls:  0,
+		},
+		{
+			name: "concurrent request in progress",
+			key:  "key-1",
+			setupMock: func(m *MockIdempotencyService) {
+				m.On("Acquire", int64(7), "key-1", mock.AnythingOfType("string")).Return(nil, errors.ErrIdempotencyKeyInProgress)
+			},
+			expectedStatus: http.StatusConflict,
+			expectedBody:   `{"code":-1,"message":"相同幂等键的请求正在处理中","data":null}`,
+			expectedCalls:  0,
+		},
+		{
+			name: "server error releases key",
+			key:  "key-1",
+			setupMock: func(m *MockIdempotencyService) {
+				m.On("Acquire", int64(7), "key-1", mock.AnythingOfType("string")).Return(&model.IdempotencyKey{ID: 3}, nil)
+				m.On("Release", int64(3)).Return(nil)
+			},
+			handlerStatus:  http.StatusInternalServerError,
+			expectedStatus: http.StatusInternalServerError,
+			expectedBody:   `{"id":1}`,
+			expectedCalls:  1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(MockIdempotencyService)
+			tt.setupMock(mockService)
+
+			calls := 0
+			middleware := NewIdempotencyMiddleware(mockService)
+			router := gin.New()
+			router.Use(func(c *gin.Context) {
+				c.Set("user_id", int64(7))
+			})
+			router.POST("/orders", middleware.MiddlewareFunc(), func(c *gin.Context) {
+				calls++
+				body := new(bytes.Buffer)
+				_, _ = body.ReadFrom(c.Request.Body)
+				assert.Equal(t, requestBody, body.String())
+				c.Data(tt.handlerStatus, "application/json", []byte(`{"id":1}`))
+			})
+
+			req := httptest.NewRequest(http.MethodPost, "/orders", bytes.NewBufferString(requestBody))
+			if tt.key != "" {
+				req.Header.Set(IdempotencyKeyHeader, tt.key)
+			}
+			resp := httptest.NewRecorder()
+
+			router.ServeHTTP(resp, req)
+
+			assert.Equal(t, tt.expectedStatus, resp.Code)
+			assert.Equal(t, tt.expectedBody, resp.Body.String())
+			assert.Equal(t, tt.expectedCalls, calls)
+			if tt.expectReplayed {
+				assert.Equal(t, "true", resp.Header().Get(IdempotentReplayedHeader))
+			}
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestIdempotencyMiddleware_Fingerprint(t *testing.T) {
+	req1 := httptest.NewRequest(http.MethodPost, "/orders", nil)
+	req2 := httptest.NewRequest(http.MethodPost, "/orders", nil)
+
+	assert.Equal(t, fingerprint(req1, []byte(`{"quantity":1}`)), fingerprint(req2, []byte(`{"quantity":1}`)))
+	assert.NotEqual(t, fingerprint(req1, []byte(`{"quantity":1}`)), fingerprint(req2, []byte(`{"quantity":2}`)))
+}
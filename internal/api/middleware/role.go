@@ -0,0 +1,55 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ylh990835774/blockchain-shop-demo/internal/model"
+	"github.com/ylh990835774/blockchain-shop-demo/internal/service"
+	"github.com/ylh990835774/blockchain-shop-demo/pkg/errors"
+	"github.com/ylh990835774/blockchain-shop-demo/pkg/logger"
+	"github.com/ylh990835774/blockchain-shop-demo/pkg/response"
+)
+
+// RoleMiddleware 是角色校验中间件的结构体
+type RoleMiddleware struct {
+	userService service.IUserService
+}
+
+// NewRoleMiddleware 创建一个新的角色校验中间件
+func NewRoleMiddleware(userService service.IUserService) *RoleMiddleware {
+	if userService == nil {
+		panic("user service cannot be nil")
+	}
+
+	return &RoleMiddleware{
+		userService: userService,
+	}
+}
+
+// Require 返回要求当前用户具有指定角色之一的处理函数，需在JWT中间件之后使用。
+// 每次请求都读取用户的最新角色，角色变更立即生效。
+func (m *RoleMiddleware) Require(roles ...model.UserRole) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user, err := m.userService.GetByID(c.GetInt64("user_id"))
+		if err != nil {
+			if err == errors.ErrNotFound {
+				c.AbortWithStatusJSON(http.StatusUnauthorized, response.Error(-1, errors.ErrUnauthorized.Error()))
+				return
+			}
+			logger.Error("获取用户角色失败", logger.Err(err))
+			c.AbortWithStatusJSON(http.StatusInternalServerError, response.Error(-1, "服务器内部错误"))
+			return
+		}
+
+		for _, role := range roles {
+			if user.Role == role {
+				c.Set("user_role", user.Role)
+				c.Next()
+				return
+			}
+		}
+
+		c.AbortWithStatusJSON(http.StatusForbidden, response.Error(-1, errors.ErrForbidden.Error()))
+	}
+}
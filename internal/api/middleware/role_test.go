@@ -0,0 +1,120 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/ylh990835774/blockchain-shop-demo/internal/model"
+	"github.com/ylh990835774/blockchain-shop-demo/pkg/errors"
+)
+
+// MockUserService 是 UserService 的 mock 实现
+type MockUserService struct {
+	mock.Mock
+}
+
+func (m *MockUserService) Register(username, password string) (*model.User, error) {
+	args := m.Called(username, password)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.User), args.Error(1)
+}
+
+func (m *MockUserService) Login(username, password string) (*model.User, string, error) {
+	args := m.Called(username, password)
+	if args.Get(0) == nil {
+		return nil, "", args.Error(2)
+	}
+	return args.Get(0).(*model.User), args.String(1), args.Error(2)
+}
+
+func (m *MockUserService) GetByID(id int64) (*model.User, error) {
+	args := m.Called(id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.User), args.Error(1)
+}
+
+func (m *MockUserService) Update(id int64, patch model.UserPatch) error {
+	args := m.Called(id, patch)
+	return args.Error(0)
+}
+
+func (m *MockUserService) GetByUsername(username string) (*model.User, error) {
+	args := m.Called(username)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.User), args.Error(1)
+}
+
+func TestRoleMiddleware_Require(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name           string
+		setupMock      func(*MockUserService)
+		expectedStatus int
+	}{
+		{
+			name: "merchant allowed",
+			setupMock: func(m *MockUserService) {
+				m.On("GetByID", int64(7)).Return(&model.User{ID: 7, Role: model.UserRoleMerchant}, nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name: "admin allowed",
+			setupMock: func(m *MockUserService) {
+				m.On("GetByID", int64(7)).Return(&model.User{ID: 7, Role: model.UserRoleAdmin}, nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name: "customer forbidden",
+			setupMock: func(m *MockUserService) {
+				m.On("GetByID", int64(7)).Return(&model.User{ID: 7, Role: model.UserRoleCustomer}, nil)
+			},
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name: "deleted user",
+			setupMock: func(m *MockUserService) {
+				m.On("GetByID", int64(7)).Return(nil, errors.ErrNotFound)
+			},
+			expectedStatus: http.StatusUnauthorized,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(MockUserService)
+			tt.setupMock(mockService)
+
+			middleware := NewRoleMiddleware(mockService)
+			router := gin.New()
+			router.Use(func(c *gin.Context) {
+				c.Set("user_id", int64(7))
+			})
+			router.POST("/orders/:id/shipments",
+				middleware.Require(model.UserRoleMerchant, model.UserRoleAdmin),
+				func(c *gin.Context) {
+					c.Status(http.StatusOK)
+				})
+
+			req := httptest.NewRequest(http.MethodPost, "/orders/1/shipments", nil)
+			resp := httptest.NewRecorder()
+
+			router.ServeHTTP(resp, req)
+
+			assert.Equal(t, tt.expectedStatus, resp.Code)
+			mockService.AssertExpectations(t)
+		})
+	}
+}
@@ -4,10 +4,12 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/ylh990835774/blockchain-shop-demo/internal/api/middleware"
 	"github.com/ylh990835774/blockchain-shop-demo/internal/handlers"
+	"github.com/ylh990835774/blockchain-shop-demo/internal/model"
 )
 
 // SetupRouter 设置路由
-func SetupRouter(r *gin.Engine, h *handlers.Handlers, jwtMiddleware *middleware.JWTMiddleware) {
+func SetupRouter(r *gin.Engine, h *handlers.Handlers, jwtMiddleware *middleware.JWTMiddleware,
+	idempotencyMiddleware *middleware.IdempotencyMiddleware, roleMiddleware *middleware.RoleMiddleware) {
 	// 添加全局中间件
 	r.Use(middleware.Logger())
 	r.Use(middleware.ErrorHandler())
@@ -26,6 +28,26 @@ func SetupRouter(r *gin.Engine, h *handlers.Handlers, jwtMiddleware *middleware.
 		// 公开的商品列表和详情接口
 		v1.GET("/products", h.ListProducts)
 		v1.GET("/products/:id", h.GetProduct)
+		v1.GET("/products/:id/price-history", h.ListProductPriceHistory)
+
+		// 公开的商品分类接口
+		v1.GET("/categories", h.ListCategories)
+		v1.GET("/categories/:id", h.GetCategory)
+		v1.GET("/categories/:id/products", h.ListCategoryProducts)
+
+		// 支付渠道回调，通过签名校验来源
+		v1.POST("/payments/webhook/:provider", h.PaymentWebhook)
+
+		// 公开的链上账户查询接口
+		v1.GET("/chain/merchant", h.GetMerchantAccount)
+		v1.GET("/chain/accounts/:address", h.GetChainAccount)
+		v1.GET("/chain/blocks", h.ListBlocks)
+
+		// 公开的价格表承诺
+		v1.GET("/price-commitments", h.ListPriceCommitments)
+
+		// 公开的发票核验接口，发票PDF的二维码指向该地址
+		v1.GET("/invoices/:number/verify", h.VerifyInvoice)
 
 		// 需要认证的接口
 		auth := v1.Group("")
@@ -49,11 +71,73 @@ func SetupRouter(r *gin.Engine, h *handlers.Handlers, jwtMiddleware *middleware.
 			// 订单相关接口
 			orders := auth.Group("/orders")
 			{
-				orders.POST("", h.CreateOrder)
+				orders.POST("", idempotencyMiddleware.MiddlewareFunc(), h.CreateOrder)
 				orders.GET("", h.ListOrders)
 				orders.GET("/:id", h.GetOrder)
 				orders.GET("/:id/transaction", h.GetOrderTransaction)
+				orders.POST("/:id/payments", h.CreatePayment)
+				orders.GET("/:id/payments", h.ListOrderPayments)
+				orders.POST("/:id/crypto-payments", h.PayOrderWithCrypto)
+				orders.GET("/:id/shipment", h.GetOrderShipment)
+				orders.POST("/:id/confirm-delivery", h.ConfirmDelivery)
+				orders.POST("/:id/returns", h.CreateReturn)
+				orders.GET("/:id/returns", h.ListOrderReturns)
+				orders.GET("/:id/invoice", h.GetOrderInvoice)
+				orders.GET("/:id/price-proof", h.GetOrderPriceProof)
+			}
+
+			// 商户接口，维护商品和履约订单
+			merchant := auth.Group("")
+			merchant.Use(roleMiddleware.Require(model.UserRoleMerchant, model.UserRoleAdmin))
+			{
+				merchant.POST("/products/import", h.ImportProducts)
+				merchant.GET("/products/export", h.ExportProducts)
+				merchant.POST("/products/:id/archive", h.ArchiveProduct)
+				merchant.POST("/products/:id/unarchive", h.UnarchiveProduct)
+				merchant.PUT("/products/:id/prices/:currency", h.SetProductPrice)
+				merchant.DELETE("/products/:id/prices/:currency", h.DeleteProductPrice)
+				merchant.POST("/products/:id/skus", h.CreateProductSKU)
+				merchant.PUT("/products/:id/skus/:sku_id", h.UpdateProductSKU)
+				merchant.DELETE("/products/:id/skus/:sku_id", h.DeleteProductSKU)
+				merchant.POST("/products/:id/images", h.UploadProductImage)
+				merchant.DELETE("/products/:id/images/:image_id", h.DeleteProductImage)
+				merchant.PUT("/products/:id/categories", h.SetProductCategories)
+
+				merchant.POST("/orders/:id/shipments", h.CreateShipment)
+				merchant.POST("/shipments/:id/events", h.AddShipmentEvent)
+				merchant.GET("/returns/:id", h.GetReturn)
+				merchant.POST("/returns/:id/approve", h.ApproveReturn)
+				merchant.POST("/returns/:id/reject", h.RejectReturn)
+				merchant.POST("/returns/:id/receive", h.ReceiveReturn)
+
+				merchant.POST("/products/:id/skus/:sku_id/restock", h.RestockProductSKU)
+				merchant.POST("/products/:id/skus/:sku_id/adjustments", h.AdjustProductSKUStock)
+				merchant.GET("/inventory/movements", h.ListInventoryMovements)
+				merchant.GET("/inventory/alerts", h.ListLowStockAlerts)
+			}
+
+			// 管理员接口
+			admin := auth.Group("/admin")
+			admin.Use(roleMiddleware.Require(model.UserRoleAdmin))
+			{
+				admin.POST("/coupons", h.CreateCoupon)
+				admin.GET("/coupons", h.ListCoupons)
+				admin.GET("/coupons/:id", h.GetCoupon)
+				admin.PUT("/coupons/:id", h.UpdateCoupon)
+				admin.DELETE("/coupons/:id", h.DeleteCoupon)
+
+				admin.GET("/orders", h.ListAllOrders)
+				admin.GET("/products", h.ListAllProducts)
+				admin.POST("/price-commitments", h.CommitPrices)
+				admin.GET("/inventory/discrepancies", h.ListStockDiscrepancies)
+
+				admin.POST("/categories", h.CreateCategory)
+				admin.PUT("/categories/:id", h.UpdateCategory)
+				admin.DELETE("/categories/:id", h.DeleteCategory)
 			}
+
+			// 测试币发放接口，仅在配置了发放上限时可用
+			auth.POST("/chain/faucet", h.Faucet)
 		}
 	}
 }
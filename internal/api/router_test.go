@@ -0,0 +1,219 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/ylh990835774/blockchain-shop-demo/internal/api/middleware"
+	"github.com/ylh990835774/blockchain-shop-demo/internal/handlers"
+	"github.com/ylh990835774/blockchain-shop-demo/internal/model"
+	"github.com/ylh990835774/blockchain-shop-demo/pkg/logger"
+)
+
+// MockUserService 是 UserService 的 mock 实现
+type MockUserService struct {
+	mock.Mock
+}
+
+func (m *MockUserService) Register(username, password string) (*model.User, error) {
+	args := m.Called(username, password)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.User), args.Error(1)
+}
+
+func (m *MockUserService) Login(username, password string) (*model.User, string, error) {
+	args := m.Called(username, password)
+	if args.Get(0) == nil {
+		return nil, "", args.Error(2)
+	}
+	return args.Get(0).(*model.User), args.String(1), args.Error(2)
+}
+
+func (m *MockUserService) GetByID(id int64) (*model.User, error) {
+	args := m.Called(id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.User), args.Error(1)
+}
+
+func (m *MockUserService) Update(id int64, patch model.UserPatch) error {
+	args := m.Called(id, patch)
+	return args.Error(0)
+}
+
+func (m *MockUserService) GetByUsername(username string) (*model.User, error) {
+	args := m.Called(username)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.User), args.Error(1)
+}
+
+// MockJWTService 是 JWTService 的 mock 实现
+type MockJWTService struct {
+	mock.Mock
+}
+
+func (m *MockJWTService) GenerateToken(userID int64) (string, error) {
+	args := m.Called(userID)
+	return args.String(0), args.Error(1)
+}
+
+func (m *MockJWTService) ParseToken(token string) (int64, error) {
+	args := m.Called(token)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+// MockIdempotencyService 是 IdempotencyService 的 mock 实现
+type MockIdempotencyService struct {
+	mock.Mock
+}
+
+func (m *MockIdempotencyService) Acquire(userID int64, key, fingerprint string) (*model.IdempotencyKey, error) {
+	args := m.Called(userID, key, fingerprint)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.IdempotencyKey), args.Error(1)
+}
+
+func (m *MockIdempotencyService) Complete(id int64, statusCode int, body []byte) error {
+	args := m.Called(id, statusCode, body)
+	return args.Error(0)
+}
+
+func (m *MockIdempotencyService) Release(id int64) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
+
+func (m *MockIdempotencyService) PurgeExpired() (int64, error) {
+	args := m.Called()
+	return args.Get(0).(int64), args.Error(1)
+}
+
+// MockInvoiceService 是 InvoiceService 的 mock 实现
+type MockInvoiceService struct {
+	mock.Mock
+}
+
+func (m *MockInvoiceService) GetOrIssue(userID, orderID int64) (*model.Invoice, error) {
+	args := m.Called(userID, orderID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.Invoice), args.Error(1)
+}
+
+func (m *MockInvoiceService) Verify(number, token string) (*model.Invoice, error) {
+	args := m.Called(number, token)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.Invoice), args.Error(1)
+}
+
+func (m *MockInvoiceService) RenderPDF(invoice *model.Invoice) ([]byte, error) {
+	args := m.Called(invoice)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]byte), args.Error(1)
+}
+
+func (m *MockInvoiceService) VerifyURL(invoice *model.Invoice) string {
+	args := m.Called(invoice)
+	return args.String(0)
+}
+
+// TestSetupRouter_MerchantRoutes 普通用户调用维护商品的接口时应被拒绝，处理函数不会执行
+func TestSetupRouter_MerchantRoutes(t *testing.T) {
+	err := logger.Setup(&logger.Config{
+		Level:    "info",
+		Format:   "console",
+		Console:  true,
+		Filename: "",
+	})
+	assert.NoError(t, err)
+
+	gin.SetMode(gin.TestMode)
+
+	routes := []struct {
+		method string
+		path   string
+	}{
+		{method: http.MethodPost, path: "/api/v1/products/import"},
+		{method: http.MethodGet, path: "/api/v1/products/export"},
+		{method: http.MethodPost, path: "/api/v1/products/1/archive"},
+		{method: http.MethodPost, path: "/api/v1/products/1/unarchive"},
+		{method: http.MethodPut, path: "/api/v1/products/1/prices/USD"},
+		{method: http.MethodDelete, path: "/api/v1/products/1/prices/USD"},
+		{method: http.MethodPost, path: "/api/v1/products/1/skus"},
+		{method: http.MethodPut, path: "/api/v1/products/1/skus/2"},
+		{method: http.MethodDelete, path: "/api/v1/products/1/skus/2"},
+		{method: http.MethodPost, path: "/api/v1/products/1/images"},
+		{method: http.MethodDelete, path: "/api/v1/products/1/images/3"},
+		{method: http.MethodPut, path: "/api/v1/products/1/categories"},
+	}
+
+	for _, route := range routes {
+		t.Run(route.method+" "+route.path, func(t *testing.T) {
+			userService := new(MockUserService)
+			userService.On("GetByID", int64(7)).Return(&model.User{ID: 7, Role: model.UserRoleCustomer}, nil)
+			jwtService := new(MockJWTService)
+			jwtService.On("ParseToken", "customer.jwt.token").Return(int64(7), nil)
+
+			// 商品和订单服务为nil，处理函数被执行时会panic
+			h := handlers.NewHandlers(userService, jwtService, nil, nil)
+			router := gin.New()
+			SetupRouter(router, h, middleware.NewJWTMiddleware(jwtService),
+				middleware.NewIdempotencyMiddleware(new(MockIdempotencyService)), middleware.NewRoleMiddleware(userService))
+
+			req := httptest.NewRequest(route.method, route.path, nil)
+			req.Header.Set("Authorization", "Bearer customer.jwt.token")
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, http.StatusForbidden, w.Code)
+			userService.AssertExpectations(t)
+			jwtService.AssertExpectations(t)
+		})
+	}
+}
+
+// TestSetupRouter_VerifyInvoice 发票核验地址无需登录即可访问
+func TestSetupRouter_VerifyInvoice(t *testing.T) {
+	err := logger.Setup(&logger.Config{
+		Level:    "info",
+		Format:   "console",
+		Console:  true,
+		Filename: "",
+	})
+	assert.NoError(t, err)
+
+	gin.SetMode(gin.TestMode)
+
+	invoiceService := new(MockInvoiceService)
+	invoiceService.On("Verify", "INV-SHOP-00000001", "0f1e").Return(&model.Invoice{Number: "INV-SHOP-00000001", OrderID: 3}, nil)
+
+	userService, jwtService := new(MockUserService), new(MockJWTService)
+	h := handlers.NewHandlers(userService, jwtService, nil, nil, handlers.WithInvoiceService(invoiceService))
+	router := gin.New()
+	SetupRouter(router, h, middleware.NewJWTMiddleware(jwtService),
+		middleware.NewIdempotencyMiddleware(new(MockIdempotencyService)), middleware.NewRoleMiddleware(userService))
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/invoices/INV-SHOP-00000001/verify?token=0f1e", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"number":"INV-SHOP-00000001"`)
+	invoiceService.AssertExpectations(t)
+}
@@ -128,7 +128,8 @@ func (bc *Blockchain) Validate() error {
 }
 
 func calculateHash(b *Block) []byte {
-	record := strconv.Itoa(b.Index) + b.Timestamp.String() + string(b.PrevHash) + string(b.Data) + strconv.Itoa(b.Nonce)
+	// 时间戳使用固定格式，避免单调时钟读数和时区影响区块重新加载后的哈希校验
+	record := strconv.Itoa(b.Index) + b.Timestamp.UTC().Format(time.RFC3339Nano) + string(b.PrevHash) + string(b.Data) + strconv.Itoa(b.Nonce)
 	h := sha256.New()
 	h.Write([]byte(record))
 	return h.Sum(nil)
@@ -142,3 +143,18 @@ func (bc *Blockchain) FindBlockByHash(hash []byte) *Block {
 	}
 	return nil
 }
+
+// BlocksBefore 按高度从高到低返回高度小于before的区块，最多limit个；before为负数时从最新区块开始
+func (bc *Blockchain) BlocksBefore(before, limit int) []*Block {
+	// 区块高度与其在链中的位置一致
+	end := len(bc.Blocks)
+	if before >= 0 && before < end {
+		end = before
+	}
+
+	blocks := make([]*Block, 0, limit)
+	for i := end - 1; i >= 0 && len(blocks) < limit; i-- {
+		blocks = append(blocks, bc.Blocks[i])
+	}
+	return blocks
+}
@@ -0,0 +1,41 @@
+package blockchain
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBlockchain_BlocksBefore(t *testing.T) {
+	bc := &Blockchain{}
+	for i := 0; i < 5; i++ {
+		bc.Blocks = append(bc.Blocks, &Block{Index: i})
+	}
+
+	heights := func(blocks []*Block) []int {
+		result := make([]int, 0, len(blocks))
+		for _, block := range blocks {
+			result = append(result, block.Index)
+		}
+		return result
+	}
+
+	tests := []struct {
+		name     string
+		before   int
+		limit    int
+		expected []int
+	}{
+		{name: "from tip", before: -1, limit: 2, expected: []int{4, 3}},
+		{name: "before height", before: 3, limit: 2, expected: []int{2, 1}},
+		{name: "reaches genesis", before: 2, limit: 5, expected: []int{1, 0}},
+		{name: "before genesis", before: 0, limit: 2, expected: []int{}},
+		{name: "beyond tip", before: 10, limit: 10, expected: []int{4, 3, 2, 1, 0}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, heights(bc.BlocksBefore(tt.before, tt.limit)))
+		})
+	}
+}
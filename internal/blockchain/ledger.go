@@ -0,0 +1,101 @@
+package blockchain
+
+import (
+	"encoding/json"
+
+	"github.com/ylh990835774/blockchain-shop-demo/pkg/money"
+)
+
+// LedgerCurrency 链上账户余额和转账金额的计价币种
+const LedgerCurrency = money.DefaultCurrency
+
+// 账本记录类型
+const (
+	EntryTransfer = "transfer"
+	EntryMint     = "mint"
+)
+
+// LedgerEntry 上链的账本记录
+type LedgerEntry struct {
+	Type string `json:"type"`
+	Transfer
+}
+
+// Account 账户状态
+type Account struct {
+	Address string `json:"address"`
+	Balance string `json:"balance"`
+	Nonce   uint64 `json:"nonce"`
+}
+
+// ledger 由区块中的账本记录推导出的账户状态
+type ledger struct {
+	balances map[string]money.Amount
+	nonces   map[string]uint64
+}
+
+func newLedger() *ledger {
+	return &ledger{
+		balances: make(map[string]money.Amount),
+		nonces:   make(map[string]uint64),
+	}
+}
+
+// parseLedgerEntry 解析区块数据，非账本记录返回nil
+func parseLedgerEntry(data []byte) *LedgerEntry {
+	var entry LedgerEntry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return nil
+	}
+	if entry.Type != EntryTransfer && entry.Type != EntryMint {
+		return nil
+	}
+	return &entry
+}
+
+// check 检查账本记录能否应用到当前状态
+func (l *ledger) check(entry *LedgerEntry) (money.Amount, error) {
+	value, err := ParseValue(entry.Value)
+	if err != nil {
+		return 0, err
+	}
+	if !value.IsPositive() {
+		return 0, ErrInvalidValue
+	}
+	if !ValidAddress(entry.To) {
+		return 0, ErrInvalidAddress
+	}
+
+	if entry.Type == EntryTransfer {
+		if entry.Nonce != l.nonces[entry.From] {
+			return 0, ErrInvalidNonce
+		}
+		if l.balances[entry.From] < value {
+			return 0, ErrInsufficientBalance
+		}
+	}
+	return value, nil
+}
+
+// apply 将账本记录应用到当前状态
+func (l *ledger) apply(entry *LedgerEntry) error {
+	value, err := l.check(entry)
+	if err != nil {
+		return err
+	}
+
+	if entry.Type == EntryTransfer {
+		l.balances[entry.From] -= value
+		l.nonces[entry.From]++
+	}
+	l.balances[entry.To] += value
+	return nil
+}
+
+func (l *ledger) account(address string) *Account {
+	return &Account{
+		Address: address,
+		Balance: FormatValue(l.balances[address]),
+		Nonce:   l.nonces[address],
+	}
+}
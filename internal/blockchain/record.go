@@ -0,0 +1,82 @@
+package blockchain
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// 订单事件类型
+const (
+	EventPaymentSucceeded = "payment_succeeded"
+	EventOrderShipped     = "order_shipped"
+	EventOrderDelivered   = "order_delivered"
+	EventReturnRequested  = "return_requested"
+	EventReturnApproved   = "return_approved"
+	EventReturnRejected   = "return_rejected"
+	EventReturnReceived   = "return_received"
+	EventReturnRefunded   = "return_refunded"
+)
+
+// OrderEvent 订单相关的上链记录
+type OrderEvent struct {
+	Type      string          `json:"type"`
+	OrderID   int64           `json:"order_id"`
+	Payload   json.RawMessage `json:"payload"`
+	Timestamp time.Time       `json:"timestamp"`
+}
+
+// RecordOrderEvent 将订单事件写入区块链，返回记录所在区块的哈希
+func RecordOrderEvent(s Service, orderID int64, eventType string, payload interface{}) (string, error) {
+	raw, err := json.Marshal(payload)
+	if err != nil {
+		return "", fmt.Errorf("marshal event payload: %w", err)
+	}
+
+	data, err := json.Marshal(&OrderEvent{
+		Type:      eventType,
+		OrderID:   orderID,
+		Payload:   raw,
+		Timestamp: time.Now(),
+	})
+	if err != nil {
+		return "", fmt.Errorf("marshal order event: %w", err)
+	}
+
+	return s.RecordTransaction(data)
+}
+
+// RecordTypePriceCommitment 商品价格表承诺的上链记录类型
+const RecordTypePriceCommitment = "price_commitment"
+
+// PriceCommitmentRecord 商品价格表默克尔根的上链记录
+type PriceCommitmentRecord struct {
+	Type       string    `json:"type"`
+	MerkleRoot string    `json:"merkle_root"`
+	EntryCount int       `json:"entry_count"`
+	Timestamp  time.Time `json:"timestamp"` // 生成价格表的时间
+}
+
+// RecordPriceCommitment 将商品价格表的默克尔根写入区块链，返回记录所在区块的哈希
+func RecordPriceCommitment(s Service, merkleRoot string, entryCount int, timestamp time.Time) (string, error) {
+	data, err := json.Marshal(&PriceCommitmentRecord{
+		Type:       RecordTypePriceCommitment,
+		MerkleRoot: merkleRoot,
+		EntryCount: entryCount,
+		Timestamp:  timestamp,
+	})
+	if err != nil {
+		return "", fmt.Errorf("marshal price commitment: %w", err)
+	}
+
+	return s.RecordTransaction(data)
+}
+
+// ParsePriceCommitment 解析区块数据中的价格表承诺，不是价格表承诺时返回false
+func ParsePriceCommitment(data []byte) (*PriceCommitmentRecord, bool) {
+	var record PriceCommitmentRecord
+	if err := json.Unmarshal(data, &record); err != nil || record.Type != RecordTypePriceCommitment {
+		return nil, false
+	}
+	return &record, true
+}
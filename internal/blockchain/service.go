@@ -1,17 +1,32 @@
 package blockchain
 
 import (
+	"bytes"
 	"encoding/hex"
+	"encoding/json"
 	"fmt"
+	"sync"
 )
 
 type Service interface {
 	RecordTransaction(data []byte) (string, error)
 	GetTransaction(txHash string) ([]byte, error)
+	// SubmitTransfer 校验签名、nonce和余额后将转账上链
+	SubmitTransfer(transfer *Transfer) (string, error)
+	// Mint 向地址发放测试币
+	Mint(to, value string) (string, error)
+	// GetAccount 获取账户余额和nonce
+	GetAccount(address string) *Account
+	// Confirmations 获取交易所在区块的确认数，交易所在区块本身计为一次确认
+	Confirmations(txHash string) (int, error)
+	// ListBlocks 按高度从高到低列出高度小于before的区块，before为负数时从最新区块开始
+	ListBlocks(before, limit int) []*Block
 }
 
 type service struct {
-	chain *Blockchain
+	mu     sync.RWMutex
+	chain  *Blockchain
+	ledger *ledger
 }
 
 func NewBlockchainService() (Service, error) {
@@ -19,18 +34,32 @@ func NewBlockchainService() (Service, error) {
 	if err != nil {
 		return nil, fmt.Errorf("create blockchain: %w", err)
 	}
-	return &service{chain: chain}, nil
+
+	// 重放区块中的账本记录，恢复账户状态
+	l := newLedger()
+	for _, block := range chain.Blocks {
+		entry := parseLedgerEntry(block.Data)
+		if entry == nil {
+			continue
+		}
+		if err := l.apply(entry); err != nil {
+			return nil, fmt.Errorf("replay block %d: %w", block.Index, err)
+		}
+	}
+
+	return &service{chain: chain, ledger: l}, nil
 }
 
 func (s *service) RecordTransaction(data []byte) (string, error) {
-	block, err := s.chain.AddBlock(data)
-	if err != nil {
-		return "", err
+	// 账本记录必须经过签名和余额校验，不能直接写入
+	if parseLedgerEntry(data) != nil {
+		return "", ErrReservedEntryType
 	}
-	if err := s.ValidateBlockchain(); err != nil {
-		return "", err
-	}
-	return fmt.Sprintf("%x", block.Hash), nil
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.addBlock(data)
 }
 
 func (s *service) GetTransaction(txHash string) ([]byte, error) {
@@ -39,14 +68,104 @@ func (s *service) GetTransaction(txHash string) ([]byte, error) {
 		return nil, fmt.Errorf("invalid transaction hash: %w", err)
 	}
 
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
 	block := s.chain.FindBlockByHash(hash)
 	if block == nil {
-		return nil, fmt.Errorf("transaction not found")
+		return nil, ErrTransactionNotFound
 	}
 
 	return block.Data, nil
 }
 
+func (s *service) SubmitTransfer(transfer *Transfer) (string, error) {
+	if err := transfer.Verify(); err != nil {
+		return "", err
+	}
+
+	return s.recordEntry(&LedgerEntry{Type: EntryTransfer, Transfer: *transfer})
+}
+
+func (s *service) Mint(to, value string) (string, error) {
+	return s.recordEntry(&LedgerEntry{
+		Type: EntryMint,
+		Transfer: Transfer{
+			From:  MintAddress,
+			To:    to,
+			Value: value,
+		},
+	})
+}
+
+func (s *service) GetAccount(address string) *Account {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.ledger.account(address)
+}
+
+func (s *service) ListBlocks(before, limit int) []*Block {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.chain.BlocksBefore(before, limit)
+}
+
+func (s *service) Confirmations(txHash string) (int, error) {
+	hash, err := hex.DecodeString(txHash)
+	if err != nil {
+		return 0, fmt.Errorf("invalid transaction hash: %w", err)
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for i := len(s.chain.Blocks) - 1; i >= 0; i-- {
+		if bytes.Equal(s.chain.Blocks[i].Hash, hash) {
+			return s.chain.GetLatestBlock().Index - s.chain.Blocks[i].Index + 1, nil
+		}
+	}
+	return 0, ErrTransactionNotFound
+}
+
 func (s *service) ValidateBlockchain() error {
 	return s.chain.Validate()
 }
+
+// recordEntry 校验账本记录并上链，成功后更新账户状态
+func (s *service) recordEntry(entry *LedgerEntry) (string, error) {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return "", fmt.Errorf("marshal ledger entry: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, err := s.ledger.check(entry); err != nil {
+		return "", err
+	}
+
+	txHash, err := s.addBlock(data)
+	if err != nil {
+		return "", err
+	}
+
+	if err := s.ledger.apply(entry); err != nil {
+		return "", err
+	}
+	return txHash, nil
+}
+
+// addBlock 追加区块并校验整条链，调用方需持有写锁
+func (s *service) addBlock(data []byte) (string, error) {
+	block, err := s.chain.AddBlock(data)
+	if err != nil {
+		return "", err
+	}
+	if err := s.ValidateBlockchain(); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%x", block.Hash), nil
+}
@@ -0,0 +1,119 @@
+package blockchain
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/ylh990835774/blockchain-shop-demo/pkg/money"
+)
+
+// MintAddress 测试币发放的来源地址
+const MintAddress = "0x0000000000000000000000000000000000000000"
+
+var (
+	ErrInvalidSignature    = errors.New("invalid transfer signature")
+	ErrInvalidNonce        = errors.New("invalid transfer nonce")
+	ErrInvalidValue        = errors.New("invalid transfer value")
+	ErrInvalidAddress      = errors.New("invalid address")
+	ErrInsufficientBalance = errors.New("insufficient balance")
+	ErrTransactionNotFound = errors.New("transaction not found")
+	ErrReservedEntryType   = errors.New("reserved ledger entry type")
+)
+
+var valuePattern = regexp.MustCompile(`^(0|[1-9][0-9]*)(\.[0-9]{1,2})?$`)
+
+// Transfer 钱包地址之间的签名转账
+type Transfer struct {
+	From      string `json:"from"`
+	To        string `json:"to"`
+	Value     string `json:"value"` // 十进制金额，最多两位小数
+	Nonce     uint64 `json:"nonce"` // 发送方已发出的转账数量，防止重放
+	OrderID   int64  `json:"order_id,omitempty"`
+	PublicKey string `json:"public_key"` // 发送方ed25519公钥的十六进制编码
+	Signature string `json:"signature"`  // 对SigningBytes的ed25519签名的十六进制编码
+}
+
+// SigningBytes 返回需要签名的内容
+func (t *Transfer) SigningBytes() []byte {
+	return []byte(fmt.Sprintf("%s|%s|%s|%d|%d", t.From, t.To, t.Value, t.Nonce, t.OrderID))
+}
+
+// Sign 使用私钥签名转账，并填充公钥
+func (t *Transfer) Sign(key ed25519.PrivateKey) {
+	t.PublicKey = hex.EncodeToString(key.Public().(ed25519.PublicKey))
+	t.Signature = hex.EncodeToString(ed25519.Sign(key, t.SigningBytes()))
+}
+
+// Verify 校验公钥与发送地址匹配且签名有效
+func (t *Transfer) Verify() error {
+	pub, err := hex.DecodeString(t.PublicKey)
+	if err != nil || len(pub) != ed25519.PublicKeySize {
+		return ErrInvalidSignature
+	}
+	if AddressFromPublicKey(pub) != t.From {
+		return ErrInvalidSignature
+	}
+
+	sig, err := hex.DecodeString(t.Signature)
+	if err != nil || !ed25519.Verify(pub, t.SigningBytes(), sig) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+// GenerateKey 生成新的钱包私钥
+func GenerateKey() (ed25519.PrivateKey, error) {
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("generate wallet key: %w", err)
+	}
+	return key, nil
+}
+
+// PrivateKeyFromHex 从十六进制编码的32字节种子恢复私钥
+func PrivateKeyFromHex(seed string) (ed25519.PrivateKey, error) {
+	b, err := hex.DecodeString(seed)
+	if err != nil || len(b) != ed25519.SeedSize {
+		return nil, fmt.Errorf("invalid wallet key seed")
+	}
+	return ed25519.NewKeyFromSeed(b), nil
+}
+
+// AddressFromPublicKey 由公钥计算钱包地址：公钥SHA256的前20字节
+func AddressFromPublicKey(pub ed25519.PublicKey) string {
+	sum := sha256.Sum256(pub)
+	return "0x" + hex.EncodeToString(sum[:20])
+}
+
+// ValidAddress 检查地址格式
+func ValidAddress(address string) bool {
+	if len(address) != 42 || !strings.HasPrefix(address, "0x") {
+		return false
+	}
+	_, err := hex.DecodeString(address[2:])
+	return err == nil
+}
+
+// ParseValue 解析转账金额。金额是签名内容的一部分，只接受非负且没有多余前导零的写法
+func ParseValue(value string) (money.Amount, error) {
+	if !valuePattern.MatchString(value) {
+		return 0, ErrInvalidValue
+	}
+
+	amount, err := money.ParseAmount(value)
+	if err != nil {
+		return 0, ErrInvalidValue
+	}
+	return amount, nil
+}
+
+// FormatValue 将金额格式化为保留两位小数的十进制字符串
+func FormatValue(amount money.Amount) string {
+	return amount.String()
+}
@@ -0,0 +1,122 @@
+package blockchain
+
+import (
+	"crypto/ed25519"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/ylh990835774/blockchain-shop-demo/pkg/money"
+)
+
+func newSignedTransfer(t *testing.T, key ed25519.PrivateKey, to, value string, nonce uint64) *Transfer {
+	t.Helper()
+
+	transfer := &Transfer{
+		From:  AddressFromPublicKey(key.Public().(ed25519.PublicKey)),
+		To:    to,
+		Value: value,
+		Nonce: nonce,
+	}
+	transfer.Sign(key)
+	return transfer
+}
+
+func TestTransfer_Verify(t *testing.T) {
+	key, err := GenerateKey()
+	require.NoError(t, err)
+	other, err := GenerateKey()
+	require.NoError(t, err)
+	to := AddressFromPublicKey(other.Public().(ed25519.PublicKey))
+
+	t.Run("valid signature", func(t *testing.T) {
+		transfer := newSignedTransfer(t, key, to, "10.00", 0)
+		assert.NoError(t, transfer.Verify())
+	})
+
+	t.Run("tampered value", func(t *testing.T) {
+		transfer := newSignedTransfer(t, key, to, "10.00", 0)
+		transfer.Value = "1000.00"
+		assert.Equal(t, ErrInvalidSignature, transfer.Verify())
+	})
+
+	t.Run("public key does not match sender", func(t *testing.T) {
+		transfer := newSignedTransfer(t, key, to, "10.00", 0)
+		transfer.From = to
+		assert.Equal(t, ErrInvalidSignature, transfer.Verify())
+	})
+
+	t.Run("signed by another key", func(t *testing.T) {
+		transfer := newSignedTransfer(t, key, to, "10.00", 0)
+		forged := newSignedTransfer(t, other, to, "10.00", 0)
+		transfer.Signature = forged.Signature
+		assert.Equal(t, ErrInvalidSignature, transfer.Verify())
+	})
+}
+
+func TestParseValue(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected money.Amount
+		valid    bool
+	}{
+		{"0", 0, true},
+		{"19.98", 1998, true},
+		{"19.9", 1990, true},
+		{"100", 10000, true},
+		{"0.01", 1, true},
+		{"1.999", 0, false},
+		{"-1.00", 0, false},
+		{"01.00", 0, false},
+		{"", 0, false},
+		{"abc", 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			amount, err := ParseValue(tt.value)
+			if !tt.valid {
+				assert.Equal(t, ErrInvalidValue, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, amount)
+			assert.Equal(t, tt.expected, mustParse(t, FormatValue(amount)))
+		})
+	}
+}
+
+func mustParse(t *testing.T, value string) money.Amount {
+	t.Helper()
+	amount, err := ParseValue(value)
+	require.NoError(t, err)
+	return amount
+}
+
+func TestLedger_Apply(t *testing.T) {
+	key, err := GenerateKey()
+	require.NoError(t, err)
+	from := AddressFromPublicKey(key.Public().(ed25519.PublicKey))
+	merchant := "0x1111111111111111111111111111111111111111"
+
+	l := newLedger()
+	require.NoError(t, l.apply(&LedgerEntry{Type: EntryMint, Transfer: Transfer{From: MintAddress, To: from, Value: "50.00"}}))
+
+	transfer := newSignedTransfer(t, key, merchant, "19.98", 0)
+	require.NoError(t, l.apply(&LedgerEntry{Type: EntryTransfer, Transfer: *transfer}))
+	assert.Equal(t, &Account{Address: from, Balance: "30.02", Nonce: 1}, l.account(from))
+	assert.Equal(t, &Account{Address: merchant, Balance: "19.98", Nonce: 0}, l.account(merchant))
+
+	// 重放同一笔转账会因nonce不匹配被拒绝
+	assert.Equal(t, ErrInvalidNonce, l.apply(&LedgerEntry{Type: EntryTransfer, Transfer: *transfer}))
+
+	overdraft := newSignedTransfer(t, key, merchant, "30.03", 1)
+	assert.Equal(t, ErrInsufficientBalance, l.apply(&LedgerEntry{Type: EntryTransfer, Transfer: *overdraft}))
+	assert.Equal(t, "30.02", l.account(from).Balance)
+}
+
+func TestParseLedgerEntry(t *testing.T) {
+	assert.Nil(t, parseLedgerEntry([]byte("Genesis Block")))
+	assert.Nil(t, parseLedgerEntry([]byte(`{"type":"payment_succeeded","order_id":1}`)))
+	assert.NotNil(t, parseLedgerEntry([]byte(`{"type":"mint","to":"0x00","value":"1"}`)))
+}
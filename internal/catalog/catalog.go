@@ -0,0 +1,95 @@
+// Package catalog 商品目录文件的CSV和JSON Lines编解码，用于批量导入和导出商品。
+// 文件的每一行对应一个SKU，同一商品的各SKU重复商品字段
+package catalog
+
+import (
+	"mime"
+	"strings"
+
+	"github.com/ylh990835774/blockchain-shop-demo/internal/model"
+	"github.com/ylh990835774/blockchain-shop-demo/pkg/money"
+)
+
+// Format 目录文件格式
+type Format string
+
+const (
+	FormatCSV    Format = "csv"
+	FormatNDJSON Format = "ndjson" // JSON Lines，每行一个JSON对象
+)
+
+// Columns 文件的列，CSV文件首行为列名，JSON Lines每行是以列名为键的对象
+var Columns = []string{
+	"sku", "product_id", "name", "description", "price", "currency", "tax_category", "sku_price", "stock", "options",
+}
+
+// ParseFormat 按格式名或Content-Type识别文件格式
+func ParseFormat(value string) (Format, bool) {
+	mediaType, _, err := mime.ParseMediaType(value)
+	if err != nil {
+		mediaType = value
+	}
+	switch strings.ToLower(strings.TrimSpace(mediaType)) {
+	case "csv", "text/csv":
+		return FormatCSV, true
+	case "ndjson", "jsonl", "application/x-ndjson", "application/jsonl", "application/x-jsonlines":
+		return FormatNDJSON, true
+	}
+	return "", false
+}
+
+// ContentType 格式对应的MIME类型
+func (f Format) ContentType() string {
+	if f == FormatNDJSON {
+		return "application/x-ndjson"
+	}
+	return "text/csv; charset=utf-8"
+}
+
+// Row 目录文件中的一行。导入时为空的字段表示保持不变，新建商品时name和price必填
+type Row struct {
+	Line        int              `json:"-"`                    // 在文件中的行号，从1开始，CSV包含首行
+	SKU         string           `json:"sku"`                  // SKU编码，按编码新增或更新SKU
+	ProductID   int64            `json:"product_id,omitempty"` // 新SKU所属的已有商品，为空时按名称归入本次导入新建的商品
+	Name        string           `json:"name,omitempty"`
+	Description string           `json:"description,omitempty"`
+	Price       *money.Amount    `json:"price,omitempty"` // 商品基础币种的价格
+	Currency    string           `json:"currency,omitempty"`
+	TaxCategory string           `json:"tax_category,omitempty"`
+	SKUPrice    *money.Amount    `json:"sku_price,omitempty"` // SKU的价格，覆盖商品价格
+	Stock       *int             `json:"stock,omitempty"`
+	Options     model.SKUOptions `json:"options"` // 规格属性，为空对象时清空
+}
+
+// RowError 行的校验或导入错误
+type RowError struct {
+	Line    int    `json:"line"`
+	SKU     string `json:"sku,omitempty"`
+	Message string `json:"message"`
+}
+
+// ProductRows 将商品转换为目录文件的行，每个SKU一行
+func ProductRows(product *model.Product) []Row {
+	rows := make([]Row, 0, len(product.SKUs))
+	for i := range product.SKUs {
+		sku := &product.SKUs[i]
+		price, stock := product.Price, sku.Stock
+		options := sku.Options
+		if options == nil {
+			options = model.SKUOptions{}
+		}
+		rows = append(rows, Row{
+			SKU:         sku.Code,
+			ProductID:   product.ID,
+			Name:        product.Name,
+			Description: product.Description,
+			Price:       &price,
+			Currency:    product.Currency,
+			TaxCategory: product.TaxCategory,
+			SKUPrice:    sku.Price,
+			Stock:       &stock,
+			Options:     options,
+		})
+	}
+	return rows
+}
@@ -0,0 +1,164 @@
+package catalog
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/ylh990835774/blockchain-shop-demo/internal/model"
+	"github.com/ylh990835774/blockchain-shop-demo/pkg/money"
+)
+
+func TestParseFormat(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected Format
+		ok       bool
+	}{
+		{value: "csv", expected: FormatCSV, ok: true},
+		{value: "text/csv; charset=utf-8", expected: FormatCSV, ok: true},
+		{value: "NDJSON", expected: FormatNDJSON, ok: true},
+		{value: "application/x-ndjson", expected: FormatNDJSON, ok: true},
+		{value: "application/json"},
+		{value: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			format, ok := ParseFormat(tt.value)
+			assert.Equal(t, tt.ok, ok)
+			assert.Equal(t, tt.expected, format)
+		})
+	}
+}
+
+func TestDecodeCSV(t *testing.T) {
+	price := money.MustParseAmount("25.00")
+	stock := 5
+
+	tests := []struct {
+		name           string
+		input          string
+		expectedRows   []Row
+		expectedErrors []RowError
+	}{
+		{
+			name:  "valid rows",
+			input: "\ufeffSKU,name,price,stock,options\nTEA-S,绿茶,25.00,5,\"{\"\"规格\"\":\"\"小\"\"}\"\nTEA-L,,,,\n",
+			expectedRows: []Row{
+				{Line: 2, SKU: "TEA-S", Name: "绿茶", Price: &price, Stock: &stock, Options: model.SKUOptions{"规格": "小"}},
+				{Line: 3, SKU: "TEA-L"},
+			},
+		},
+		{
+			name:           "invalid values",
+			input:          "sku,price,stock,options\nA,abc,,\nB,,1.5,\nC,,,[1]\n",
+			expectedErrors: []RowError{{Line: 2, SKU: "A", Message: "price的值无效"}, {Line: 3, SKU: "B", Message: "stock的值无效"}, {Line: 4, SKU: "C", Message: "options的值无效"}},
+		},
+		{
+			name:           "wrong column count",
+			input:          "sku,name\nA,绿茶,多余\nB,红茶\n",
+			expectedRows:   []Row{{Line: 3, SKU: "B", Name: "红茶"}},
+			expectedErrors: []RowError{{Line: 2, Message: "CSV格式错误，列数与首行不一致或引号不匹配"}},
+		},
+		{name: "unknown column", input: "sku,colour\n", expectedErrors: []RowError{{Line: 1, Message: "未知的列: colour"}}},
+		{name: "duplicate column", input: "sku,name,name\n", expectedErrors: []RowError{{Line: 1, Message: "重复的列: name"}}},
+		{name: "missing sku column", input: "name,price\n", expectedErrors: []RowError{{Line: 1, Message: "缺少sku列"}}},
+		{name: "empty file", input: "", expectedErrors: []RowError{{Line: 1, Message: "文件为空"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rows, rowErrors, err := Decode(strings.NewReader(tt.input), FormatCSV)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectedRows, rows)
+			assert.Equal(t, tt.expectedErrors, rowErrors)
+		})
+	}
+}
+
+func TestDecodeNDJSON(t *testing.T) {
+	price := money.MustParseAmount("25")
+
+	tests := []struct {
+		name           string
+		input          string
+		expectedRows   []Row
+		expectedErrors []RowError
+	}{
+		{
+			name:  "valid rows with blank lines",
+			input: "{\"sku\":\"TEA-S\",\"product_id\":3,\"price\":\"25\"}\n\n{\"sku\":\"TEA-L\",\"options\":{}}",
+			expectedRows: []Row{
+				{Line: 1, SKU: "TEA-S", ProductID: 3, Price: &price},
+				{Line: 3, SKU: "TEA-L", Options: model.SKUOptions{}},
+			},
+		},
+		{
+			name:  "invalid lines",
+			input: "{\"sku\":\"A\",\"colour\":\"红\"}\n{\"sku\":\"B\",\"stock\":\"many\"}\n{\"sku\":\"C\"} {\"sku\":\"D\"}\nnot json\n",
+			expectedErrors: []RowError{
+				{Line: 1, Message: "未知的字段: colour"},
+				{Line: 2, Message: "stock的值无效"},
+				{Line: 3, Message: "每行只能包含一个JSON对象"},
+				{Line: 4, Message: "JSON格式错误或字段的值无效"},
+			},
+		},
+		{name: "empty file", input: "\n\n", expectedErrors: []RowError{{Line: 1, Message: "文件为空"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rows, rowErrors, err := Decode(strings.NewReader(tt.input), FormatNDJSON)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectedRows, rows)
+			assert.Equal(t, tt.expectedErrors, rowErrors)
+		})
+	}
+}
+
+func TestWriter_RoundTrip(t *testing.T) {
+	skuPrice := money.MustParseAmount("40.00")
+	product := &model.Product{
+		ID:          7,
+		Name:        "绿茶, 特级",
+		Description: "明前\"龙井\"",
+		Price:       money.MustParseAmount("25.00"),
+		Currency:    "CNY",
+		TaxCategory: "standard",
+		SKUs: []model.ProductSKU{
+			{Code: "TEA-S", Options: model.SKUOptions{"规格": "小"}, Stock: 5},
+			{Code: "TEA-L", Options: model.SKUOptions{"规格": "大"}, Price: &skuPrice, Stock: 3},
+		},
+	}
+	expected := ProductRows(product)
+	require.Len(t, expected, 2)
+
+	for _, format := range []Format{FormatCSV, FormatNDJSON} {
+		t.Run(string(format), func(t *testing.T) {
+			var buf strings.Builder
+			writer := NewWriter(&buf, format)
+			for _, row := range expected {
+				require.NoError(t, writer.Write(row))
+			}
+			require.NoError(t, writer.Flush())
+
+			rows, rowErrors, err := Decode(strings.NewReader(buf.String()), format)
+			require.NoError(t, err)
+			assert.Empty(t, rowErrors)
+			require.Len(t, rows, len(expected))
+			for i := range rows {
+				rows[i].Line = 0
+			}
+			assert.Equal(t, expected, rows)
+		})
+	}
+}
+
+func TestWriter_EmptyCSV(t *testing.T) {
+	var buf strings.Builder
+	writer := NewWriter(&buf, FormatCSV)
+	require.NoError(t, writer.Flush())
+	assert.Equal(t, strings.Join(Columns, ",")+"\n", buf.String())
+}
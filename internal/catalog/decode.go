@@ -0,0 +1,206 @@
+package catalog
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/ylh990835774/blockchain-shop-demo/internal/model"
+	"github.com/ylh990835774/blockchain-shop-demo/pkg/money"
+)
+
+// Decode 解析目录文件，返回解析成功的行和无法解析的行的错误。
+// 文件结构错误（如CSV首行缺少sku列）作为第1行的错误返回，读取失败时返回error
+func Decode(r io.Reader, format Format) ([]Row, []RowError, error) {
+	switch format {
+	case FormatCSV:
+		return decodeCSV(r)
+	case FormatNDJSON:
+		return decodeNDJSON(r)
+	}
+	return nil, nil, fmt.Errorf("unsupported catalog format %q", format)
+}
+
+func decodeCSV(r io.Reader) ([]Row, []RowError, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, []RowError{{Line: 1, Message: "文件为空"}}, nil
+	}
+	if err != nil {
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			return nil, []RowError{{Line: 1, Message: "首行格式错误"}}, nil
+		}
+		return nil, nil, fmt.Errorf("read csv header: %w", err)
+	}
+	columns, headerErr := parseHeader(header)
+	if headerErr != "" {
+		return nil, []RowError{{Line: 1, Message: headerErr}}, nil
+	}
+
+	var rows []Row
+	var rowErrors []RowError
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			var parseErr *csv.ParseError
+			if errors.As(err, &parseErr) {
+				rowErrors = append(rowErrors, RowError{Line: parseErr.StartLine, Message: "CSV格式错误，列数与首行不一致或引号不匹配"})
+				continue
+			}
+			return nil, nil, fmt.Errorf("read csv: %w", err)
+		}
+
+		line, _ := reader.FieldPos(0)
+		row := Row{Line: line}
+		if message := row.setFields(columns, record); message != "" {
+			rowErrors = append(rowErrors, RowError{Line: line, SKU: row.SKU, Message: message})
+			continue
+		}
+		rows = append(rows, row)
+	}
+	return rows, rowErrors, nil
+}
+
+// parseHeader 校验CSV首行的列名，返回各列对应的字段名
+func parseHeader(header []string) ([]string, string) {
+	known := make(map[string]bool, len(Columns))
+	for _, column := range Columns {
+		known[column] = true
+	}
+
+	columns := make([]string, len(header))
+	seen := make(map[string]bool, len(header))
+	for i, name := range header {
+		// Excel导出的UTF-8文件以BOM开头
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		if !known[name] {
+			return nil, fmt.Sprintf("未知的列: %s", name)
+		}
+		if seen[name] {
+			return nil, fmt.Sprintf("重复的列: %s", name)
+		}
+		seen[name] = true
+		columns[i] = name
+	}
+	if !seen["sku"] {
+		return nil, "缺少sku列"
+	}
+	return columns, ""
+}
+
+// setFields 按列名设置字段，空值表示未提供，返回第一个无法解析的值的错误
+func (row *Row) setFields(columns, values []string) string {
+	for i, column := range columns {
+		value := strings.TrimSpace(values[i])
+		if column == "sku" {
+			row.SKU = value
+		}
+		if value == "" {
+			continue
+		}
+
+		switch column {
+		case "product_id":
+			id, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				return invalidValue(column)
+			}
+			row.ProductID = id
+		case "name":
+			row.Name = value
+		case "description":
+			row.Description = value
+		case "price", "sku_price":
+			amount, err := money.ParseAmount(value)
+			if err != nil {
+				return invalidValue(column)
+			}
+			if column == "price" {
+				row.Price = &amount
+			} else {
+				row.SKUPrice = &amount
+			}
+		case "currency":
+			row.Currency = value
+		case "tax_category":
+			row.TaxCategory = value
+		case "stock":
+			stock, err := strconv.Atoi(value)
+			if err != nil {
+				return invalidValue(column)
+			}
+			row.Stock = &stock
+		case "options":
+			var options model.SKUOptions
+			if err := json.Unmarshal([]byte(value), &options); err != nil || options == nil {
+				return invalidValue(column)
+			}
+			row.Options = options
+		}
+	}
+	return ""
+}
+
+func invalidValue(column string) string {
+	return fmt.Sprintf("%s的值无效", column)
+}
+
+func decodeNDJSON(r io.Reader) ([]Row, []RowError, error) {
+	reader := bufio.NewReader(r)
+
+	var rows []Row
+	var rowErrors []RowError
+	for line := 1; ; line++ {
+		data, err := reader.ReadBytes('\n')
+		if err != nil && err != io.EOF {
+			return nil, nil, fmt.Errorf("read ndjson: %w", err)
+		}
+
+		if data = bytes.TrimSpace(data); len(data) > 0 {
+			row := Row{Line: line}
+			decoder := json.NewDecoder(bytes.NewReader(data))
+			decoder.DisallowUnknownFields()
+			if decodeErr := decoder.Decode(&row); decodeErr != nil || decoder.More() {
+				rowErrors = append(rowErrors, RowError{Line: line, Message: ndjsonError(decodeErr)})
+			} else {
+				rows = append(rows, row)
+			}
+		}
+
+		if err == io.EOF {
+			break
+		}
+	}
+	if len(rows) == 0 && len(rowErrors) == 0 {
+		rowErrors = append(rowErrors, RowError{Line: 1, Message: "文件为空"})
+	}
+	return rows, rowErrors, nil
+}
+
+// ndjsonError 将JSON解析错误转换为行错误信息
+func ndjsonError(err error) string {
+	if err == nil {
+		return "每行只能包含一个JSON对象"
+	}
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) && typeErr.Field != "" {
+		return invalidValue(typeErr.Field)
+	}
+	if field, ok := strings.CutPrefix(err.Error(), "json: unknown field "); ok {
+		return fmt.Sprintf("未知的字段: %s", strings.Trim(field, `"`))
+	}
+	return "JSON格式错误或字段的值无效"
+}
@@ -0,0 +1,109 @@
+package catalog
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"strconv"
+)
+
+// Writer 按指定格式写出目录文件，CSV格式在第一行写出列名
+type Writer struct {
+	format  Format
+	buf     *bufio.Writer
+	csv     *csv.Writer
+	json    *json.Encoder
+	started bool
+}
+
+func NewWriter(w io.Writer, format Format) *Writer {
+	buf := bufio.NewWriter(w)
+	writer := &Writer{format: format, buf: buf}
+	if format == FormatNDJSON {
+		writer.json = json.NewEncoder(buf)
+		writer.json.SetEscapeHTML(false)
+	} else {
+		writer.csv = csv.NewWriter(buf)
+	}
+	return writer
+}
+
+// Write 写出一行
+func (w *Writer) Write(row Row) error {
+	if err := w.start(); err != nil {
+		return err
+	}
+	if w.json != nil {
+		return w.json.Encode(row)
+	}
+	return w.csv.Write(row.record())
+}
+
+// Flush 将缓冲的内容写入底层Writer，没有任何行时CSV格式仍写出列名
+func (w *Writer) Flush() error {
+	if err := w.start(); err != nil {
+		return err
+	}
+	if w.csv != nil {
+		w.csv.Flush()
+		if err := w.csv.Error(); err != nil {
+			return err
+		}
+	}
+	return w.buf.Flush()
+}
+
+func (w *Writer) start() error {
+	if w.started {
+		return nil
+	}
+	w.started = true
+	if w.csv != nil {
+		return w.csv.Write(Columns)
+	}
+	return nil
+}
+
+// record 按Columns的顺序输出CSV记录
+func (row *Row) record() []string {
+	record := make([]string, 0, len(Columns))
+	for _, column := range Columns {
+		var value string
+		switch column {
+		case "sku":
+			value = row.SKU
+		case "product_id":
+			if row.ProductID > 0 {
+				value = strconv.FormatInt(row.ProductID, 10)
+			}
+		case "name":
+			value = row.Name
+		case "description":
+			value = row.Description
+		case "price":
+			if row.Price != nil {
+				value = row.Price.String()
+			}
+		case "currency":
+			value = row.Currency
+		case "tax_category":
+			value = row.TaxCategory
+		case "sku_price":
+			if row.SKUPrice != nil {
+				value = row.SKUPrice.String()
+			}
+		case "stock":
+			if row.Stock != nil {
+				value = strconv.Itoa(*row.Stock)
+			}
+		case "options":
+			if row.Options != nil {
+				data, _ := json.Marshal(row.Options)
+				value = string(data)
+			}
+		}
+		record = append(record, value)
+	}
+	return record
+}
@@ -0,0 +1,19 @@
+// Package exchange 提供币种之间的汇率
+package exchange
+
+import (
+	"errors"
+
+	"github.com/ylh990835774/blockchain-shop-demo/pkg/money"
+)
+
+// ErrUnsupportedCurrency 汇率来源不支持该币种
+var ErrUnsupportedCurrency = errors.New("unsupported currency")
+
+// RateProvider 汇率来源接口
+type RateProvider interface {
+	// Rate 返回1单位from币种可兑换的to币种数量
+	Rate(from, to string) (money.Rate, error)
+	// Currencies 返回支持的币种
+	Currencies() []string
+}
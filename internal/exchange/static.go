@@ -0,0 +1,110 @@
+package exchange
+
+import (
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/ylh990835774/blockchain-shop-demo/pkg/money"
+	"gopkg.in/yaml.v3"
+)
+
+// StaticRateProvider 使用固定汇率表的汇率来源，汇率表以基准币种表示
+type StaticRateProvider struct {
+	base  string
+	rates map[string]money.Rate
+}
+
+// 确保StaticRateProvider实现了RateProvider接口
+var _ RateProvider = (*StaticRateProvider)(nil)
+
+// staticRateFile 汇率文件格式
+//
+//	base: CNY
+//	rates:
+//	  USD: "0.13800000"
+type staticRateFile struct {
+	Base  string            `yaml:"base"`
+	Rates map[string]string `yaml:"rates"`
+}
+
+// NewStaticRateProvider 创建固定汇率来源，rates为1单位基准币种可兑换的各币种数量
+func NewStaticRateProvider(base string, rates map[string]money.Rate) (*StaticRateProvider, error) {
+	if !money.ValidCurrency(base) {
+		return nil, fmt.Errorf("invalid base currency %q", base)
+	}
+
+	table := map[string]money.Rate{base: money.RateOne}
+	for currency, rate := range rates {
+		if !money.ValidCurrency(currency) {
+			return nil, fmt.Errorf("invalid currency %q", currency)
+		}
+		if rate <= 0 {
+			return nil, fmt.Errorf("invalid rate for %s: %w", currency, money.ErrInvalidRate)
+		}
+		if currency == base && rate != money.RateOne {
+			return nil, fmt.Errorf("rate of base currency %s must be 1", base)
+		}
+		table[currency] = rate
+	}
+
+	return &StaticRateProvider{base: base, rates: table}, nil
+}
+
+// LoadStaticRateFile 从YAML文件加载固定汇率表
+func LoadStaticRateFile(path string) (*StaticRateProvider, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read rate file: %w", err)
+	}
+
+	var file staticRateFile
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("parse rate file: %w", err)
+	}
+
+	rates := make(map[string]money.Rate, len(file.Rates))
+	for currency, value := range file.Rates {
+		rate, err := money.ParseRate(value)
+		if err != nil {
+			return nil, fmt.Errorf("rate for %s: %w", currency, err)
+		}
+		rates[currency] = rate
+	}
+
+	return NewStaticRateProvider(file.Base, rates)
+}
+
+func (p *StaticRateProvider) Rate(from, to string) (money.Rate, error) {
+	if from == to {
+		if _, ok := p.rates[from]; !ok {
+			return 0, ErrUnsupportedCurrency
+		}
+		return money.RateOne, nil
+	}
+
+	fromRate, ok := p.rates[from]
+	if !ok {
+		return 0, ErrUnsupportedCurrency
+	}
+	toRate, ok := p.rates[to]
+	if !ok {
+		return 0, ErrUnsupportedCurrency
+	}
+
+	// 通过基准币种计算交叉汇率
+	rate := toRate.Div(fromRate)
+	if rate <= 0 {
+		return 0, money.ErrInvalidRate
+	}
+	return rate, nil
+}
+
+func (p *StaticRateProvider) Currencies() []string {
+	currencies := make([]string, 0, len(p.rates))
+	for currency := range p.rates {
+		currencies = append(currencies, currency)
+	}
+	sort.Strings(currencies)
+	return currencies
+}
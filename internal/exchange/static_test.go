@@ -0,0 +1,68 @@
+package exchange
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/ylh990835774/blockchain-shop-demo/pkg/money"
+)
+
+func TestStaticRateProvider_Rate(t *testing.T) {
+	provider, err := NewStaticRateProvider("CNY", map[string]money.Rate{
+		"USD": money.MustParseRate("0.14"),
+		"EUR": money.MustParseRate("0.125"),
+	})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name        string
+		from        string
+		to          string
+		expected    string
+		expectedErr error
+	}{
+		{name: "same currency", from: "USD", to: "USD", expected: "1.00000000"},
+		{name: "from base", from: "CNY", to: "USD", expected: "0.14000000"},
+		{name: "to base", from: "USD", to: "CNY", expected: "7.14285714"},
+		{name: "cross rate", from: "USD", to: "EUR", expected: "0.89285714"},
+		{name: "unsupported target", from: "CNY", to: "JPY", expectedErr: ErrUnsupportedCurrency},
+		{name: "unsupported source", from: "JPY", to: "JPY", expectedErr: ErrUnsupportedCurrency},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rate, err := provider.Rate(tt.from, tt.to)
+			if tt.expectedErr != nil {
+				assert.Equal(t, tt.expectedErr, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, rate.String())
+		})
+	}
+
+	assert.Equal(t, []string{"CNY", "EUR", "USD"}, provider.Currencies())
+}
+
+func TestLoadStaticRateFile(t *testing.T) {
+	dir := t.TempDir()
+
+	valid := filepath.Join(dir, "rates.yaml")
+	require.NoError(t, os.WriteFile(valid, []byte("base: CNY\nrates:\n  USD: \"0.138\"\n"), 0o600))
+	provider, err := LoadStaticRateFile(valid)
+	require.NoError(t, err)
+	rate, err := provider.Rate("CNY", "USD")
+	require.NoError(t, err)
+	assert.Equal(t, money.MustParseRate("0.138"), rate)
+
+	invalid := filepath.Join(dir, "invalid.yaml")
+	require.NoError(t, os.WriteFile(invalid, []byte("base: CNY\nrates:\n  USD: \"-1\"\n"), 0o600))
+	_, err = LoadStaticRateFile(invalid)
+	assert.ErrorIs(t, err, money.ErrInvalidRate)
+
+	_, err = LoadStaticRateFile(filepath.Join(dir, "missing.yaml"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,115 @@
+package handlers
+
+import (
+	goerrors "errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ylh990835774/blockchain-shop-demo/internal/catalog"
+	"github.com/ylh990835774/blockchain-shop-demo/internal/model"
+	"github.com/ylh990835774/blockchain-shop-demo/internal/service"
+	"github.com/ylh990835774/blockchain-shop-demo/pkg/errors"
+	"github.com/ylh990835774/blockchain-shop-demo/pkg/logger"
+)
+
+// maxImportRequestBytes 商品导入文件的大小上限
+const maxImportRequestBytes = 16 << 20
+
+// ImportProducts 从CSV或JSON Lines文件按SKU编码批量导入商品，文件作为请求体提交。
+// 部分行导入失败时仍返回200，由结果中的errors列出各行的错误
+func (h *Handlers) ImportProducts(c *gin.Context) {
+	var query ImportProductsQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		handleError(c, errors.ErrInvalidInput, "导入商品-参数验证")
+		return
+	}
+
+	value := query.Format
+	if value == "" {
+		value = c.ContentType()
+	}
+	format, ok := catalog.ParseFormat(value)
+	if !ok {
+		handleError(c, errors.ErrUnsupportedFormat, "导入商品-参数验证")
+		return
+	}
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxImportRequestBytes)
+	result, err := h.productService.Import(c.Request.Body, format, service.ProductImportOptions{
+		DryRun:    query.DryRun,
+		ChunkSize: query.ChunkSize,
+		ActorID:   c.GetInt64("user_id"),
+	})
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if goerrors.As(err, &tooLarge) {
+			err = errors.ErrPayloadTooLarge
+		}
+		handleError(c, err, "导入商品")
+		return
+	}
+
+	handleSuccess(c, result, "导入商品")
+}
+
+// ExportProducts 以CSV或JSON Lines格式流式导出商品，每个SKU一行，导出的文件可以直接导入
+func (h *Handlers) ExportProducts(c *gin.Context) {
+	var query ExportProductsQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		handleError(c, errors.ErrInvalidInput, "导出商品-参数验证")
+		return
+	}
+	filter, err := query.toFilter()
+	if err != nil {
+		handleError(c, err, "导出商品-参数验证")
+		return
+	}
+	format := catalog.FormatCSV
+	if query.Format != "" {
+		format = catalog.Format(query.Format)
+	}
+
+	// 第一批商品查询成功后才开始发送响应，之前的错误仍按普通错误响应返回
+	var writer *catalog.Writer
+	start := func() {
+		c.Header("Content-Type", format.ContentType())
+		c.Header("Content-Disposition", `attachment; filename="products.`+string(format)+`"`)
+		c.Status(http.StatusOK)
+		writer = catalog.NewWriter(c.Writer, format)
+	}
+
+	err = h.productService.Export(filter, func(products []*model.Product) error {
+		if writer == nil {
+			start()
+		}
+		for _, product := range products {
+			for _, row := range catalog.ProductRows(product) {
+				if err := writer.Write(row); err != nil {
+					return err
+				}
+			}
+		}
+		if err := writer.Flush(); err != nil {
+			return err
+		}
+		c.Writer.Flush()
+		return nil
+	})
+	if err != nil {
+		if writer == nil {
+			handleError(c, err, "导出商品")
+			return
+		}
+		// 响应已经开始发送，无法再返回错误响应，客户端会收到不完整的文件
+		logger.Error("导出商品中断", logger.String("path", c.Request.URL.Path), logger.Err(err))
+		c.Abort()
+		return
+	}
+
+	if writer == nil {
+		start()
+	}
+	if err := writer.Flush(); err != nil {
+		logger.Error("导出商品中断", logger.String("path", c.Request.URL.Path), logger.Err(err))
+	}
+}
@@ -0,0 +1,193 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/ylh990835774/blockchain-shop-demo/internal/catalog"
+	"github.com/ylh990835774/blockchain-shop-demo/internal/model"
+	"github.com/ylh990835774/blockchain-shop-demo/internal/service"
+	customerrors "github.com/ylh990835774/blockchain-shop-demo/pkg/errors"
+	"github.com/ylh990835774/blockchain-shop-demo/pkg/logger"
+	"github.com/ylh990835774/blockchain-shop-demo/pkg/money"
+)
+
+func TestHandlers_ImportProducts(t *testing.T) {
+	err := logger.Setup(&logger.Config{
+		Level:    "info",
+		Format:   "console",
+		Console:  true,
+		Filename: "",
+	})
+	assert.NoError(t, err)
+
+	gin.SetMode(gin.TestMode)
+
+	result := &service.ProductImportResult{
+		Total:   2,
+		Created: 1,
+		Failed:  1,
+		Errors:  []catalog.RowError{{Line: 3, SKU: "TEA-L", Message: "price必须大于零"}},
+	}
+
+	tests := []struct {
+		name           string
+		query          string
+		contentType    string
+		expectedFormat catalog.Format
+		expectedOpts   service.ProductImportOptions
+		serviceErr     error
+		expectedStatus int
+	}{
+		{
+			name:           "csv by content type",
+			contentType:    "text/csv; charset=utf-8",
+			expectedFormat: catalog.FormatCSV,
+			expectedOpts:   service.ProductImportOptions{ActorID: 9},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "ndjson by query with options",
+			query:          "?format=ndjson&dry_run=true&chunk_size=100",
+			contentType:    "application/octet-stream",
+			expectedFormat: catalog.FormatNDJSON,
+			expectedOpts:   service.ProductImportOptions{DryRun: true, ChunkSize: 100, ActorID: 9},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "service error",
+			contentType:    "application/x-ndjson",
+			expectedFormat: catalog.FormatNDJSON,
+			expectedOpts:   service.ProductImportOptions{ActorID: 9},
+			serviceErr:     customerrors.ErrInvalidInput,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{name: "unsupported content type", contentType: "application/json", expectedStatus: http.StatusUnsupportedMediaType},
+		{name: "unsupported format", query: "?format=xlsx", contentType: "text/csv", expectedStatus: http.StatusBadRequest},
+		{name: "chunk size too large", query: "?chunk_size=5000", contentType: "text/csv", expectedStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockProductService := new(MockProductService)
+			if tt.expectedFormat != "" {
+				if tt.serviceErr != nil {
+					mockProductService.On("Import", mock.Anything, tt.expectedFormat, tt.expectedOpts).Return(nil, tt.serviceErr)
+				} else {
+					mockProductService.On("Import", mock.Anything, tt.expectedFormat, tt.expectedOpts).Return(result, nil)
+				}
+			}
+
+			handlers := NewHandlers(new(MockUserService), new(MockJWTService), mockProductService, new(MockOrderService))
+			router := gin.New()
+			router.POST("/products/import", func(c *gin.Context) {
+				c.Set("user_id", int64(9))
+				handlers.ImportProducts(c)
+			})
+
+			req := httptest.NewRequest(http.MethodPost, "/products/import"+tt.query, strings.NewReader("sku,name\nTEA-S,绿茶\n"))
+			req.Header.Set("Content-Type", tt.contentType)
+			resp := httptest.NewRecorder()
+
+			router.ServeHTTP(resp, req)
+
+			assert.Equal(t, tt.expectedStatus, resp.Code)
+			if tt.expectedStatus == http.StatusOK {
+				assert.Contains(t, resp.Body.String(), `"message":"price必须大于零"`)
+			}
+			mockProductService.AssertExpectations(t)
+			if tt.expectedFormat == "" {
+				mockProductService.AssertNotCalled(t, "Import", mock.Anything, mock.Anything, mock.Anything)
+			}
+		})
+	}
+}
+
+func TestHandlers_ExportProducts(t *testing.T) {
+	err := logger.Setup(&logger.Config{
+		Level:    "info",
+		Format:   "console",
+		Console:  true,
+		Filename: "",
+	})
+	assert.NoError(t, err)
+
+	gin.SetMode(gin.TestMode)
+
+	products := []*model.Product{
+		{
+			ID:          1,
+			Name:        "绿茶",
+			Price:       money.MustParseAmount("25.00"),
+			Currency:    "CNY",
+			TaxCategory: "standard",
+			SKUs:        []model.ProductSKU{{Code: "TEA-S", Stock: 5, Options: model.SKUOptions{"规格": "小"}}},
+		},
+	}
+
+	tests := []struct {
+		name                string
+		query               string
+		products            []*model.Product
+		serviceErr          error
+		expectedStatus      int
+		expectedContentType string
+		expectedBody        string
+	}{
+		{
+			name:                "csv",
+			products:            products,
+			expectedStatus:      http.StatusOK,
+			expectedContentType: "text/csv; charset=utf-8",
+			expectedBody: "sku,product_id,name,description,price,currency,tax_category,sku_price,stock,options\n" +
+				"TEA-S,1,绿茶,,25.00,CNY,standard,,5,\"{\"\"规格\"\":\"\"小\"\"}\"\n",
+		},
+		{
+			name:                "ndjson",
+			query:               "?format=ndjson",
+			products:            products,
+			expectedStatus:      http.StatusOK,
+			expectedContentType: "application/x-ndjson",
+			expectedBody: `{"sku":"TEA-S","product_id":1,"name":"绿茶","price":"25.00","currency":"CNY",` +
+				`"tax_category":"standard","stock":5,"options":{"规格":"小"}}` + "\n",
+		},
+		{
+			name:                "no products",
+			expectedStatus:      http.StatusOK,
+			expectedContentType: "text/csv; charset=utf-8",
+			expectedBody:        "sku,product_id,name,description,price,currency,tax_category,sku_price,stock,options\n",
+		},
+		{name: "error before streaming", serviceErr: customerrors.ErrInvalidInput, expectedStatus: http.StatusBadRequest},
+		{name: "unsupported format", query: "?format=xlsx", expectedStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockProductService := new(MockProductService)
+			if tt.expectedContentType != "" || tt.serviceErr != nil {
+				mockProductService.On("Export", mock.Anything, mock.Anything).Return(tt.products, tt.serviceErr)
+			}
+
+			handlers := NewHandlers(new(MockUserService), new(MockJWTService), mockProductService, new(MockOrderService))
+			router := gin.New()
+			router.GET("/products/export", handlers.ExportProducts)
+
+			req := httptest.NewRequest(http.MethodGet, "/products/export"+tt.query, nil)
+			resp := httptest.NewRecorder()
+
+			router.ServeHTTP(resp, req)
+
+			assert.Equal(t, tt.expectedStatus, resp.Code)
+			if tt.expectedContentType != "" {
+				assert.Equal(t, tt.expectedContentType, resp.Header().Get("Content-Type"))
+				assert.Equal(t, tt.expectedBody, resp.Body.String())
+			}
+			mockProductService.AssertExpectations(t)
+		})
+	}
+}
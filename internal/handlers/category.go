@@ -0,0 +1,142 @@
+package handlers
+
+import (
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ylh990835774/blockchain-shop-demo/internal/model"
+	"github.com/ylh990835774/blockchain-shop-demo/pkg/errors"
+)
+
+// ListCategories 获取完整的分类树
+func (h *Handlers) ListCategories(c *gin.Context) {
+	categories, err := h.categoryService.Tree()
+	if err != nil {
+		handleError(c, err, "获取分类树")
+		return
+	}
+
+	handleSuccess(c, categories, "")
+}
+
+// GetCategory 获取分类及其子分类
+func (h *Handlers) GetCategory(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		handleError(c, errors.ErrInvalidInput, "获取分类详情-参数验证")
+		return
+	}
+
+	category, err := h.categoryService.GetByID(id)
+	if err != nil {
+		handleError(c, err, "获取分类详情")
+		return
+	}
+
+	handleSuccess(c, category, "")
+}
+
+// ListCategoryProducts 获取分类及其所有子分类下的商品，支持与商品列表相同的查询参数
+func (h *Handlers) ListCategoryProducts(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil || id <= 0 {
+		handleError(c, errors.ErrInvalidInput, "获取分类商品-参数验证")
+		return
+	}
+
+	var query ListProductsQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		handleError(c, errors.ErrInvalidInput, "获取分类商品-参数验证")
+		return
+	}
+	query.CategoryID = id
+
+	h.listProducts(c, &query, "获取分类商品")
+}
+
+// CreateCategory 管理员创建分类
+func (h *Handlers) CreateCategory(c *gin.Context) {
+	var req CategoryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		handleError(c, errors.ErrInvalidInput, "创建分类-参数验证")
+		return
+	}
+
+	category := req.toCategory()
+	if err := h.categoryService.Create(category); err != nil {
+		handleError(c, err, "创建分类")
+		return
+	}
+
+	handleSuccess(c, category, "创建分类")
+}
+
+// UpdateCategory 管理员更新分类，可以移动到其他父分类下
+func (h *Handlers) UpdateCategory(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		handleError(c, errors.ErrInvalidInput, "更新分类-参数验证")
+		return
+	}
+
+	var req CategoryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		handleError(c, errors.ErrInvalidInput, "更新分类-参数验证")
+		return
+	}
+
+	category, err := h.categoryService.Update(id, req.toCategory())
+	if err != nil {
+		handleError(c, err, "更新分类")
+		return
+	}
+
+	handleSuccess(c, category, "更新分类")
+}
+
+// DeleteCategory 管理员删除分类
+func (h *Handlers) DeleteCategory(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		handleError(c, errors.ErrInvalidInput, "删除分类-参数验证")
+		return
+	}
+
+	if err := h.categoryService.Delete(id); err != nil {
+		handleError(c, err, "删除分类")
+		return
+	}
+
+	handleSuccess(c, nil, "删除分类")
+}
+
+// SetProductCategories 设置商品所属的分类
+func (h *Handlers) SetProductCategories(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		handleError(c, errors.ErrInvalidInput, "设置商品分类-参数验证")
+		return
+	}
+
+	var req SetProductCategoriesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		handleError(c, errors.ErrInvalidInput, "设置商品分类-参数验证")
+		return
+	}
+
+	if err := h.productService.SetCategories(id, req.CategoryIDs); err != nil {
+		handleError(c, err, "设置商品分类")
+		return
+	}
+
+	handleSuccess(c, nil, "设置商品分类")
+}
+
+func (r *CategoryRequest) toCategory() *model.Category {
+	return &model.Category{
+		Name:     r.Name,
+		Slug:     r.Slug,
+		ParentID: r.ParentID,
+		Position: r.Position,
+	}
+}
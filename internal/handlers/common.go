@@ -42,6 +42,23 @@ func handleError(c *gin.Context, err error, operation string) {
 		c.JSON(http.StatusConflict, response.Error(-1, err.Error()))
 	case errors.ErrNoFieldsToUpdate:
 		c.JSON(http.StatusBadRequest, response.Error(-1, err.Error()))
+	case errors.ErrInsufficientStock, errors.ErrInvalidOrderStatus, errors.ErrReservationExpired, errors.ErrCategoryNotEmpty,
+		errors.ErrProductArchived:
+		c.JSON(http.StatusConflict, response.Error(-1, err.Error()))
+	case errors.ErrInvalidSignature:
+		c.JSON(http.StatusUnauthorized, response.Error(-1, err.Error()))
+	case errors.ErrPaymentAmountMismatch, errors.ErrInsufficientBalance, errors.ErrUnsupportedCurrency:
+		c.JSON(http.StatusBadRequest, response.Error(-1, err.Error()))
+	case errors.ErrCouponInvalid, errors.ErrCouponNotApplicable, errors.ErrCouponUsageExceeded:
+		c.JSON(http.StatusUnprocessableEntity, response.Error(-1, err.Error()))
+	case errors.ErrUnsupportedMediaType, errors.ErrUnsupportedFormat:
+		c.JSON(http.StatusUnsupportedMediaType, response.Error(-1, err.Error()))
+	case errors.ErrPayloadTooLarge:
+		c.JSON(http.StatusRequestEntityTooLarge, response.Error(-1, err.Error()))
+	case errors.ErrPreconditionFailed:
+		c.JSON(http.StatusPreconditionFailed, response.Error(-1, err.Error()))
+	case errors.ErrForbidden:
+		c.JSON(http.StatusForbidden, response.Error(-1, err.Error()))
 	default:
 		// 对于未知错误，返回500但记录详细日志
 		logger.Error("未处理的错误",
@@ -0,0 +1,116 @@
+package handlers
+
+import (
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ylh990835774/blockchain-shop-demo/internal/model"
+	"github.com/ylh990835774/blockchain-shop-demo/pkg/errors"
+)
+
+// CreateCoupon 管理员创建优惠券
+func (h *Handlers) CreateCoupon(c *gin.Context) {
+	var req CouponRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		handleError(c, errors.ErrInvalidInput, "创建优惠券-参数验证")
+		return
+	}
+
+	coupon := req.toCoupon()
+	if err := h.couponService.Create(coupon); err != nil {
+		handleError(c, err, "创建优惠券")
+		return
+	}
+
+	handleSuccess(c, coupon, "创建优惠券")
+}
+
+// ListCoupons 管理员获取优惠券列表
+func (h *Handlers) ListCoupons(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "10"))
+
+	coupons, total, err := h.couponService.List(page, pageSize)
+	if err != nil {
+		handleError(c, err, "获取优惠券列表")
+		return
+	}
+
+	handleSuccess(c, gin.H{
+		"total":   total,
+		"coupons": coupons,
+	}, "获取优惠券列表")
+}
+
+// GetCoupon 管理员获取优惠券详情
+func (h *Handlers) GetCoupon(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		handleError(c, errors.ErrInvalidInput, "获取优惠券详情-参数验证")
+		return
+	}
+
+	coupon, err := h.couponService.GetByID(id)
+	if err != nil {
+		handleError(c, err, "获取优惠券详情")
+		return
+	}
+
+	handleSuccess(c, coupon, "获取优惠券详情")
+}
+
+// UpdateCoupon 管理员更新优惠券
+func (h *Handlers) UpdateCoupon(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		handleError(c, errors.ErrInvalidInput, "更新优惠券-参数验证")
+		return
+	}
+
+	var req CouponRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		handleError(c, errors.ErrInvalidInput, "更新优惠券-参数验证")
+		return
+	}
+
+	coupon, err := h.couponService.Update(id, req.toCoupon())
+	if err != nil {
+		handleError(c, err, "更新优惠券")
+		return
+	}
+
+	handleSuccess(c, coupon, "更新优惠券")
+}
+
+// DeleteCoupon 管理员删除优惠券
+func (h *Handlers) DeleteCoupon(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		handleError(c, errors.ErrInvalidInput, "删除优惠券-参数验证")
+		return
+	}
+
+	if err := h.couponService.Delete(id); err != nil {
+		handleError(c, err, "删除优惠券")
+		return
+	}
+
+	handleSuccess(c, nil, "删除优惠券")
+}
+
+func (r *CouponRequest) toCoupon() *model.Coupon {
+	return &model.Coupon{
+		Code:         r.Code,
+		Type:         r.Type,
+		PercentOff:   r.PercentOff,
+		AmountOff:    r.AmountOff,
+		Currency:     r.Currency,
+		MinAmount:    r.MinAmount,
+		UsageLimit:   r.UsageLimit,
+		PerUserLimit: r.PerUserLimit,
+		StartsAt:     r.StartsAt,
+		EndsAt:       r.EndsAt,
+		Disabled:     r.Disabled,
+		ProductIDs:   r.ProductIDs,
+	}
+}
@@ -0,0 +1,111 @@
+package handlers
+
+import (
+	"encoding/hex"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ylh990835774/blockchain-shop-demo/internal/blockchain"
+	"github.com/ylh990835774/blockchain-shop-demo/pkg/errors"
+)
+
+// PayOrderWithCrypto 提交签名转账支付订单
+func (h *Handlers) PayOrderWithCrypto(c *gin.Context) {
+	orderID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		handleError(c, errors.ErrInvalidInput, "链上支付-参数验证")
+		return
+	}
+
+	var req CryptoPaymentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		handleError(c, errors.ErrInvalidInput, "链上支付-参数验证")
+		return
+	}
+
+	userID := c.GetInt64("user_id")
+	tx, err := h.cryptoPaymentService.Pay(userID, orderID, &blockchain.Transfer{
+		From:      req.From,
+		To:        req.To,
+		Value:     req.Value,
+		Nonce:     req.Nonce,
+		OrderID:   orderID,
+		PublicKey: req.PublicKey,
+		Signature: req.Signature,
+	})
+	if err != nil {
+		handleError(c, err, "链上支付")
+		return
+	}
+
+	handleSuccess(c, tx, "链上支付")
+}
+
+// GetChainAccount 获取链上账户余额
+func (h *Handlers) GetChainAccount(c *gin.Context) {
+	account, err := h.cryptoPaymentService.GetAccount(c.Param("address"))
+	if err != nil {
+		handleError(c, err, "获取链上账户")
+		return
+	}
+
+	handleSuccess(c, account, "")
+}
+
+// GetMerchantAccount 获取商户收款地址及余额
+func (h *Handlers) GetMerchantAccount(c *gin.Context) {
+	account, err := h.cryptoPaymentService.GetAccount(h.cryptoPaymentService.MerchantAddress())
+	if err != nil {
+		handleError(c, err, "获取商户账户")
+		return
+	}
+
+	handleSuccess(c, account, "")
+}
+
+// ListBlocks 按高度从高到低分页获取区块
+func (h *Handlers) ListBlocks(c *gin.Context) {
+	limit, _ := strconv.Atoi(c.DefaultQuery("page_size", "10"))
+
+	blocks, next, err := h.cryptoPaymentService.ListBlocks(c.Query("cursor"), limit)
+	if err != nil {
+		handleError(c, err, "获取区块列表")
+		return
+	}
+
+	items := make([]BlockResponse, 0, len(blocks))
+	for _, block := range blocks {
+		items = append(items, BlockResponse{
+			Height:    block.Index,
+			Timestamp: block.Timestamp,
+			Hash:      hex.EncodeToString(block.Hash),
+			PrevHash:  hex.EncodeToString(block.PrevHash),
+			Nonce:     block.Nonce,
+			Data:      string(block.Data),
+		})
+	}
+
+	handleSuccess(c, gin.H{
+		"blocks":      items,
+		"next_cursor": next,
+	}, "")
+}
+
+// Faucet 向地址发放测试币
+func (h *Handlers) Faucet(c *gin.Context) {
+	var req FaucetRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		handleError(c, errors.ErrInvalidInput, "发放测试币-参数验证")
+		return
+	}
+
+	txHash, err := h.cryptoPaymentService.Faucet(req.Address, req.Value)
+	if err != nil {
+		handleError(c, err, "发放测试币")
+		return
+	}
+
+	handleSuccess(c, gin.H{
+		"tx_hash": txHash,
+	}, "发放测试币")
+}
@@ -2,11 +2,15 @@ package handlers
 
 import (
 	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
 
 	"github.com/gin-gonic/gin"
 	"github.com/ylh990835774/blockchain-shop-demo/internal/model"
 	"github.com/ylh990835774/blockchain-shop-demo/internal/service"
 	"github.com/ylh990835774/blockchain-shop-demo/pkg/errors"
+	"github.com/ylh990835774/blockchain-shop-demo/pkg/money"
 )
 
 // Handlers 包含所有HTTP处理器
@@ -15,6 +19,91 @@ type Handlers struct {
 	jwtService     service.IJWTService
 	productService service.IProductService
 	orderService   service.IOrderService
+	paymentService service.IPaymentService
+
+	cryptoPaymentService service.ICryptoPaymentService
+	shipmentService      service.IShipmentService
+	returnService        service.IReturnService
+	invoiceService       service.IInvoiceService
+	couponService        service.ICouponService
+	categoryService      service.ICategoryService
+	imageService         service.IProductImageService
+
+	priceCommitmentService service.IPriceCommitmentService
+	inventoryService       service.IInventoryService
+}
+
+// Option 配置Handlers的可选依赖
+type Option func(*Handlers)
+
+// WithPaymentService 设置支付服务
+func WithPaymentService(paymentService service.IPaymentService) Option {
+	return func(h *Handlers) {
+		h.paymentService = paymentService
+	}
+}
+
+// WithCryptoPaymentService 设置链上转账支付服务
+func WithCryptoPaymentService(cryptoPaymentService service.ICryptoPaymentService) Option {
+	return func(h *Handlers) {
+		h.cryptoPaymentService = cryptoPaymentService
+	}
+}
+
+// WithShipmentService 设置发货服务
+func WithShipmentService(shipmentService service.IShipmentService) Option {
+	return func(h *Handlers) {
+		h.shipmentService = shipmentService
+	}
+}
+
+// WithReturnService 设置退货服务
+func WithReturnService(returnService service.IReturnService) Option {
+	return func(h *Handlers) {
+		h.returnService = returnService
+	}
+}
+
+// WithInvoiceService 设置发票服务
+func WithInvoiceService(invoiceService service.IInvoiceService) Option {
+	return func(h *Handlers) {
+		h.invoiceService = invoiceService
+	}
+}
+
+// WithCouponService 设置优惠券管理服务
+func WithCouponService(couponService service.ICouponService) Option {
+	return func(h *Handlers) {
+		h.couponService = couponService
+	}
+}
+
+// WithCategoryService 设置商品分类服务
+func WithCategoryService(categoryService service.ICategoryService) Option {
+	return func(h *Handlers) {
+		h.categoryService = categoryService
+	}
+}
+
+// WithImageService 设置商品图片服务
+func WithImageService(imageService service.IProductImageService) Option {
+	return func(h *Handlers) {
+		h.imageService = imageService
+	}
+}
+
+// WithPriceCommitmentService 设置价格表承诺服务
+func WithPriceCommitmentService(priceCommitmentService service.IPriceCommitmentService) Option {
+	return func(h *Handlers) {
+		h.priceCommitmentService = priceCommitmentService
+	}
+}
+
+// WithInventoryService 设置库存服务
+func WithInventoryService(inventoryService service.IInventoryService) Option {
+	return func(h *Handlers) {
+		h.inventoryService = inventoryService
+	}
 }
 
 // NewHandlers 创建一个新的Handlers实例
@@ -23,30 +112,105 @@ func NewHandlers(
 	jwtService service.IJWTService,
 	productService service.IProductService,
 	orderService service.IOrderService,
+	opts ...Option,
 ) *Handlers {
-	return &Handlers{
+	h := &Handlers{
 		userService:    userService,
 		jwtService:     jwtService,
 		productService: productService,
 		orderService:   orderService,
 	}
+	for _, opt := range opts {
+		opt(h)
+	}
+	return h
 }
 
 // ListProducts 获取商品列表
 func (h *Handlers) ListProducts(c *gin.Context) {
-	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
-	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "10"))
+	var query ListProductsQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		handleError(c, errors.ErrInvalidInput, "获取商品列表-参数验证")
+		return
+	}
+
+	h.listProducts(c, &query, "获取商品列表")
+}
+
+// ListAllProducts 管理员获取商品列表，包含已下架的商品
+func (h *Handlers) ListAllProducts(c *gin.Context) {
+	var query ListProductsQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		handleError(c, errors.ErrInvalidInput, "获取全部商品-参数验证")
+		return
+	}
+	query.withArchived = true
+
+	h.listProducts(c, &query, "获取全部商品")
+}
+
+func (h *Handlers) listProducts(c *gin.Context, query *ListProductsQuery, operation string) {
+	filter, err := query.toFilter()
+	if err != nil {
+		handleError(c, err, operation+"-参数验证")
+		return
+	}
+
+	// 携带cursor参数时使用游标分页，不返回总数和分面统计
+	if after, ok := c.GetQuery("cursor"); ok {
+		products, next, err := h.productService.ListByCursor(filter, after, query.PageSize)
+		if err != nil {
+			handleError(c, err, operation)
+			return
+		}
+
+		handleSuccess(c, gin.H{
+			"products":    products,
+			"next_cursor": next,
+		}, operation)
+		return
+	}
+
+	products, total, err := h.productService.List(filter, query.Page, query.PageSize)
+	if err != nil {
+		handleError(c, err, operation)
+		return
+	}
 
-	products, total, err := h.productService.List(page, pageSize)
+	facets, err := h.productService.Facets(filter)
 	if err != nil {
-		handleError(c, err, "获取商品列表")
+		handleError(c, err, operation)
 		return
 	}
 
 	handleSuccess(c, gin.H{
 		"total":    total,
 		"products": products,
-	}, "获取商品列表")
+		"facets":   facets,
+	}, operation)
+}
+
+func (q *ListProductsQuery) toFilter() (model.ProductFilter, error) {
+	filter := model.ProductFilter{
+		Query:        q.Query,
+		Currency:     q.Currency,
+		InStock:      q.InStock,
+		SortBy:       q.Sort,
+		Ascending:    q.Order == "asc",
+		WithArchived: q.withArchived,
+	}
+	if q.CategoryID > 0 {
+		filter.CategoryIDs = []int64{q.CategoryID}
+	}
+
+	var err error
+	if filter.MinPrice, err = parseAmountParam(q.MinPrice); err != nil {
+		return filter, err
+	}
+	if filter.MaxPrice, err = parseAmountParam(q.MaxPrice); err != nil {
+		return filter, err
+	}
+	return filter, nil
 }
 
 // GetProduct 获取商品详情
@@ -63,18 +227,20 @@ func (h *Handlers) GetProduct(c *gin.Context) {
 		return
 	}
 
+	c.Header("ETag", productETag(product))
 	handleSuccess(c, product, "获取商品详情")
 }
 
 // CreateProduct 创建商品
 func (h *Handlers) CreateProduct(c *gin.Context) {
-	var product model.Product
-	if err := c.ShouldBindJSON(&product); err != nil {
+	var req CreateProductRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
 		handleError(c, errors.ErrInvalidInput, "创建商品-参数验证")
 		return
 	}
 
-	if err := h.productService.Create(&product); err != nil {
+	product := req.toProduct()
+	if err := h.productService.Create(c.GetInt64("user_id"), product); err != nil {
 		handleError(c, err, "创建商品")
 		return
 	}
@@ -82,7 +248,27 @@ func (h *Handlers) CreateProduct(c *gin.Context) {
 	handleSuccess(c, product, "创建商品")
 }
 
-// UpdateProduct 更新商品
+func (r *CreateProductRequest) toProduct() *model.Product {
+	product := &model.Product{
+		Name:        r.Name,
+		Description: r.Description,
+		Price:       r.Price,
+		Currency:    r.Currency,
+		Stock:       r.Stock,
+		TaxCategory: r.TaxCategory,
+		CategoryIDs: r.CategoryIDs,
+	}
+	for _, price := range r.Prices {
+		product.Prices = append(product.Prices, model.ProductPrice{Currency: price.Currency, Price: price.Price})
+	}
+	for i := range r.SKUs {
+		product.SKUs = append(product.SKUs, *r.SKUs[i].toSKU())
+	}
+	return product
+}
+
+// UpdateProduct 更新商品。携带If-Match请求头时仅在商品未被修改时更新，
+// 商品已被其他请求修改时返回412，响应的ETag为更新后的版本
 func (h *Handlers) UpdateProduct(c *gin.Context) {
 	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
 	if err != nil {
@@ -90,18 +276,87 @@ func (h *Handlers) UpdateProduct(c *gin.Context) {
 		return
 	}
 
-	var updates map[string]interface{}
-	if err := c.ShouldBindJSON(&updates); err != nil {
-		handleError(c, errors.ErrInvalidInput, "更新商品-参数验证")
+	version, err := ifMatchVersion(c.GetHeader("If-Match"))
+	if err != nil {
+		handleError(c, err, "更新商品-版本校验")
+		return
+	}
+
+	var req UpdateProductRequest
+	if err := bindPatch(c, &req); err != nil {
+		handleError(c, err, "更新商品-参数验证")
+		return
+	}
+	patch, err := req.toPatch()
+	if err != nil {
+		handleError(c, err, "更新商品-参数验证")
+		return
+	}
+	if patch.IsEmpty() {
+		handleError(c, errors.ErrNoFieldsToUpdate, "更新商品-无更新字段")
 		return
 	}
 
-	if err := h.productService.Update(id, updates); err != nil {
+	product, err := h.productService.Update(id, version, patch)
+	if err != nil {
 		handleError(c, err, "更新商品")
 		return
 	}
 
-	handleSuccess(c, nil, "更新商品")
+	c.Header("ETag", productETag(product))
+	handleSuccess(c, product, "更新商品")
+}
+
+// toPatch 校验并转换为商品更新，校验规则与创建商品一致
+func (r *UpdateProductRequest) toPatch() (model.ProductPatch, error) {
+	var patch model.ProductPatch
+	if r.Name.Set {
+		if r.Name.Null || strings.TrimSpace(r.Name.Value) == "" || utf8.RuneCountInString(r.Name.Value) > model.MaxProductNameLength {
+			return patch, errors.ErrInvalidInput
+		}
+		patch.Name = r.Name.optional()
+	}
+	if r.Description.Set {
+		if utf8.RuneCountInString(r.Description.Value) > model.MaxProductDescriptionLength {
+			return patch, errors.ErrInvalidInput
+		}
+		patch.Description = r.Description.optional()
+	}
+	if r.Price.Set {
+		if r.Price.Null || !r.Price.Value.IsPositive() {
+			return patch, errors.ErrInvalidInput
+		}
+		patch.Price = r.Price.optional()
+	}
+	if r.TaxCategory.Set {
+		if r.TaxCategory.Null || r.TaxCategory.Value == "" || len(r.TaxCategory.Value) > maxTaxCategoryLength {
+			return patch, errors.ErrInvalidInput
+		}
+		patch.TaxCategory = r.TaxCategory.optional()
+	}
+	return patch, nil
+}
+
+// productETag 以商品版本号作为实体标签
+func productETag(product *model.Product) string {
+	return `"` + strconv.FormatInt(product.Version, 10) + `"`
+}
+
+// ifMatchVersion 解析If-Match请求头中的商品版本号。未携带或为*时返回0，表示不校验版本；
+// If-Match要求强比较，弱实体标签和格式不正确的值均视为不匹配
+func ifMatchVersion(header string) (int64, error) {
+	header = strings.TrimSpace(header)
+	if header == "" || header == "*" {
+		return 0, nil
+	}
+	if len(header) < 2 || header[0] != '"' || header[len(header)-1] != '"' {
+		return 0, errors.ErrPreconditionFailed
+	}
+	version, err := strconv.ParseInt(header[1:len(header)-1], 10, 64)
+	if err != nil || version <= 0 {
+		return 0, errors.ErrPreconditionFailed
+	}
+	return version, nil
 }
 
 // DeleteProduct 删除商品
@@ -120,31 +375,179 @@ func (h *Handlers) DeleteProduct(c *gin.Context) {
 	handleSuccess(c, nil, "删除商品")
 }
 
-// CreateOrder 创建订单
-func (h *Handlers) CreateOrder(c *gin.Context) {
-	var req CreateOrderRequest
+// ArchiveProduct 下架商品，已下架的商品不在商品列表中展示且不能下单，历史订单不受影响
+func (h *Handlers) ArchiveProduct(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		handleError(c, errors.ErrInvalidInput, "下架商品-参数验证")
+		return
+	}
+
+	if err := h.productService.Archive(id); err != nil {
+		handleError(c, err, "下架商品")
+		return
+	}
+
+	handleSuccess(c, nil, "下架商品")
+}
+
+// UnarchiveProduct 重新上架商品
+func (h *Handlers) UnarchiveProduct(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		handleError(c, errors.ErrInvalidInput, "上架商品-参数验证")
+		return
+	}
+
+	if err := h.productService.Unarchive(id); err != nil {
+		handleError(c, err, "上架商品")
+		return
+	}
+
+	handleSuccess(c, nil, "上架商品")
+}
+
+// SetProductPrice 设置商品在指定币种下的定价
+func (h *Handlers) SetProductPrice(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		handleError(c, errors.ErrInvalidInput, "设置商品定价-参数验证")
+		return
+	}
+
+	var req SetProductPriceRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		handleError(c, errors.ErrInvalidInput, "创建订单-参数验证")
+		handleError(c, errors.ErrInvalidInput, "设置商品定价-参数验证")
 		return
 	}
 
-	userID := c.GetInt64("user_id")
+	if err := h.productService.SetPrice(id, c.Param("currency"), req.Price); err != nil {
+		handleError(c, err, "设置商品定价")
+		return
+	}
+
+	handleSuccess(c, nil, "设置商品定价")
+}
 
-	product, err := h.productService.GetByID(req.ProductID)
+// DeleteProductPrice 删除商品在指定币种下的定价
+func (h *Handlers) DeleteProductPrice(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
 	if err != nil {
-		handleError(c, err, "创建订单-获取商品信息")
+		handleError(c, errors.ErrInvalidInput, "删除商品定价-参数验证")
 		return
 	}
 
-	order := &model.Order{
-		UserID:     userID,
-		ProductID:  req.ProductID,
-		Quantity:   req.Quantity,
-		TotalPrice: product.Price * float64(req.Quantity),
-		Status:     model.OrderStatusPending,
+	if err := h.productService.DeletePrice(id, c.Param("currency")); err != nil {
+		handleError(c, err, "删除商品定价")
+		return
+	}
+
+	handleSuccess(c, nil, "删除商品定价")
+}
+
+// CreateProductSKU 为商品添加SKU
+func (h *Handlers) CreateProductSKU(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		handleError(c, errors.ErrInvalidInput, "创建商品SKU-参数验证")
+		return
+	}
+
+	var req SKURequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		handleError(c, errors.ErrInvalidInput, "创建商品SKU-参数验证")
+		return
+	}
+
+	sku := req.toSKU()
+	if err := h.productService.CreateSKU(c.GetInt64("user_id"), id, sku); err != nil {
+		handleError(c, err, "创建商品SKU")
+		return
+	}
+
+	handleSuccess(c, sku, "创建商品SKU")
+}
+
+// UpdateProductSKU 更新商品的SKU
+func (h *Handlers) UpdateProductSKU(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		handleError(c, errors.ErrInvalidInput, "更新商品SKU-参数验证")
+		return
+	}
+	skuID, err := strconv.ParseInt(c.Param("sku_id"), 10, 64)
+	if err != nil {
+		handleError(c, errors.ErrInvalidInput, "更新商品SKU-参数验证")
+		return
+	}
+
+	var req SKURequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		handleError(c, errors.ErrInvalidInput, "更新商品SKU-参数验证")
+		return
+	}
+
+	sku := req.toSKU()
+	sku.ID = skuID
+	if err := h.productService.UpdateSKU(c.GetInt64("user_id"), id, sku); err != nil {
+		handleError(c, err, "更新商品SKU")
+		return
+	}
+
+	handleSuccess(c, sku, "更新商品SKU")
+}
+
+// DeleteProductSKU 删除商品的SKU
+func (h *Handlers) DeleteProductSKU(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		handleError(c, errors.ErrInvalidInput, "删除商品SKU-参数验证")
+		return
+	}
+	skuID, err := strconv.ParseInt(c.Param("sku_id"), 10, 64)
+	if err != nil {
+		handleError(c, errors.ErrInvalidInput, "删除商品SKU-参数验证")
+		return
+	}
+
+	if err := h.productService.DeleteSKU(c.GetInt64("user_id"), id, skuID); err != nil {
+		handleError(c, err, "删除商品SKU")
+		return
+	}
+
+	handleSuccess(c, nil, "删除商品SKU")
+}
+
+func (r *SKURequest) toSKU() *model.ProductSKU {
+	return &model.ProductSKU{
+		Code:              r.Code,
+		Options:           model.SKUOptions(r.Options),
+		Price:             r.Price,
+		Stock:             r.Stock,
+		IsDefault:         r.IsDefault,
+		LowStockThreshold: r.LowStockThreshold,
+	}
+}
+
+// CreateOrder 创建订单
+func (h *Handlers) CreateOrder(c *gin.Context) {
+	var req CreateOrderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		handleError(c, errors.ErrInvalidInput, "创建订单-参数验证")
+		return
 	}
 
-	if err := h.orderService.Create(order); err != nil {
+	// 价格、税额和优惠由订单服务在扣减库存的事务内计算
+	order, err := h.orderService.Create(&service.CreateOrderInput{
+		UserID:     c.GetInt64("user_id"),
+		ProductID:  req.ProductID,
+		SKUID:      req.SKUID,
+		Quantity:   req.Quantity,
+		Currency:   req.Currency,
+		TaxRegion:  req.Region,
+		CouponCode: req.CouponCode,
+	})
+	if err != nil {
 		handleError(c, err, "创建订单")
 		return
 	}
@@ -152,22 +555,120 @@ func (h *Handlers) CreateOrder(c *gin.Context) {
 	handleSuccess(c, order, "创建订单")
 }
 
-// ListOrders 获取订单列表
+// ListOrders 获取当前用户的订单列表，支持按状态、商品、下单时间和金额过滤
 func (h *Handlers) ListOrders(c *gin.Context) {
-	userID := c.GetInt64("user_id")
-	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
-	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "10"))
+	var query ListOrdersQuery
+	if err := c.ShouldBindQuery(&query); err != nil || query.UserID != 0 {
+		handleError(c, errors.ErrInvalidInput, "获取订单列表-参数验证")
+		return
+	}
+	query.UserID = c.GetInt64("user_id")
+
+	h.listOrders(c, &query, "获取订单列表")
+}
+
+// ListAllOrders 管理员获取所有用户的订单列表，可按用户过滤
+func (h *Handlers) ListAllOrders(c *gin.Context) {
+	var query ListOrdersQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		handleError(c, errors.ErrInvalidInput, "获取全部订单-参数验证")
+		return
+	}
+
+	h.listOrders(c, &query, "获取全部订单")
+}
 
-	orders, total, err := h.orderService.ListByUserID(userID, page, pageSize)
+func (h *Handlers) listOrders(c *gin.Context, query *ListOrdersQuery, operation string) {
+	filter, err := query.toFilter()
 	if err != nil {
-		handleError(c, err, "获取订单列表")
+		handleError(c, err, operation+"-参数验证")
+		return
+	}
+
+	// 携带cursor参数时使用游标分页，不返回总数
+	if after, ok := c.GetQuery("cursor"); ok {
+		orders, next, err := h.orderService.ListByCursor(filter, after, query.PageSize)
+		if err != nil {
+			handleError(c, err, operation)
+			return
+		}
+
+		handleSuccess(c, gin.H{
+			"orders":      orders,
+			"next_cursor": next,
+		}, operation)
+		return
+	}
+
+	orders, total, err := h.orderService.List(filter, query.Page, query.PageSize)
+	if err != nil {
+		handleError(c, err, operation)
 		return
 	}
 
 	handleSuccess(c, gin.H{
 		"total":  total,
 		"orders": orders,
-	}, "获取订单列表")
+	}, operation)
+}
+
+func (q *ListOrdersQuery) toFilter() (model.OrderFilter, error) {
+	filter := model.OrderFilter{
+		UserID:    q.UserID,
+		ProductID: q.ProductID,
+		SortBy:    q.Sort,
+		Ascending: q.Order == "asc",
+	}
+
+	if q.Status != "" {
+		for _, status := range strings.Split(q.Status, ",") {
+			filter.Statuses = append(filter.Statuses, model.OrderStatus(strings.TrimSpace(status)))
+		}
+	}
+
+	var err error
+	if filter.CreatedFrom, err = parseTimeParam(q.CreatedFrom, false); err != nil {
+		return filter, err
+	}
+	if filter.CreatedTo, err = parseTimeParam(q.CreatedTo, true); err != nil {
+		return filter, err
+	}
+	if filter.MinTotal, err = parseAmountParam(q.MinTotal); err != nil {
+		return filter, err
+	}
+	if filter.MaxTotal, err = parseAmountParam(q.MaxTotal); err != nil {
+		return filter, err
+	}
+	return filter, nil
+}
+
+// parseTimeParam 解析日期或RFC3339时间参数。endOfDay为true时，日期参数解析为次日零点，用作不包含的上限
+func parseTimeParam(value string, endOfDay bool) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return &t, nil
+	}
+	t, err := time.ParseInLocation("2006-01-02", value, time.Local)
+	if err != nil {
+		return nil, errors.ErrInvalidInput
+	}
+	if endOfDay {
+		t = t.AddDate(0, 0, 1)
+	}
+	return &t, nil
+}
+
+func parseAmountParam(value string) (*money.Amount, error) {
+	if value == "" {
+		return nil, nil
+	}
+	amount, err := money.ParseAmount(value)
+	if err != nil || amount < 0 {
+		return nil, errors.ErrInvalidInput
+	}
+	return &amount, nil
 }
 
 // GetOrder 获取订单详情
@@ -0,0 +1,70 @@
+package handlers
+
+import (
+	goerrors "errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ylh990835774/blockchain-shop-demo/pkg/errors"
+)
+
+// maxImageRequestBytes 图片上传请求体的大小上限，单张图片的大小由图片服务校验
+const maxImageRequestBytes = 32 << 20
+
+// UploadProductImage 上传商品图片，图片以multipart表单的image字段提交
+func (h *Handlers) UploadProductImage(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		handleError(c, errors.ErrInvalidInput, "上传商品图片-参数验证")
+		return
+	}
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxImageRequestBytes)
+	header, err := c.FormFile("image")
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if goerrors.As(err, &tooLarge) {
+			handleError(c, errors.ErrPayloadTooLarge, "上传商品图片-参数验证")
+			return
+		}
+		handleError(c, errors.ErrInvalidInput, "上传商品图片-参数验证")
+		return
+	}
+	file, err := header.Open()
+	if err != nil {
+		handleError(c, err, "上传商品图片")
+		return
+	}
+	defer file.Close()
+
+	// 图片格式按内容识别，不信任客户端提交的Content-Type和文件名
+	image, err := h.imageService.Upload(id, file)
+	if err != nil {
+		handleError(c, err, "上传商品图片")
+		return
+	}
+
+	handleSuccess(c, image, "上传商品图片")
+}
+
+// DeleteProductImage 删除商品图片
+func (h *Handlers) DeleteProductImage(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		handleError(c, errors.ErrInvalidInput, "删除商品图片-参数验证")
+		return
+	}
+	imageID, err := strconv.ParseInt(c.Param("image_id"), 10, 64)
+	if err != nil {
+		handleError(c, errors.ErrInvalidInput, "删除商品图片-参数验证")
+		return
+	}
+
+	if err := h.imageService.Delete(id, imageID); err != nil {
+		handleError(c, err, "删除商品图片")
+		return
+	}
+
+	handleSuccess(c, nil, "删除商品图片")
+}
@@ -0,0 +1,130 @@
+package handlers
+
+import (
+	"bytes"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/ylh990835774/blockchain-shop-demo/internal/model"
+	customerrors "github.com/ylh990835774/blockchain-shop-demo/pkg/errors"
+	"github.com/ylh990835774/blockchain-shop-demo/pkg/logger"
+)
+
+// MockImageService 是商品图片服务的mock实现
+type MockImageService struct {
+	mock.Mock
+}
+
+func (m *MockImageService) Upload(productID int64, r io.Reader) (*model.ProductImage, error) {
+	data, _ := io.ReadAll(r)
+	args := m.Called(productID, data)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.ProductImage), args.Error(1)
+}
+
+func (m *MockImageService) Delete(productID, imageID int64) error {
+	args := m.Called(productID, imageID)
+	return args.Error(0)
+}
+
+func multipartBody(t *testing.T, field string, data []byte) (*bytes.Buffer, string) {
+	t.Helper()
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	part, err := writer.CreateFormFile(field, "photo.png")
+	require.NoError(t, err)
+	_, err = part.Write(data)
+	require.NoError(t, err)
+	require.NoError(t, writer.Close())
+	return &body, writer.FormDataContentType()
+}
+
+func TestHandlers_UploadProductImage(t *testing.T) {
+	err := logger.Setup(&logger.Config{
+		Level:    "info",
+		Format:   "console",
+		Console:  true,
+		Filename: "",
+	})
+	assert.NoError(t, err)
+
+	gin.SetMode(gin.TestMode)
+
+	data := []byte("\x89PNG\r\n\x1a\nimage")
+
+	tests := []struct {
+		name           string
+		path           string
+		field          string
+		serviceErr     error
+		expectedStatus int
+	}{
+		{name: "uploaded", path: "/products/1/images", field: "image", expectedStatus: http.StatusOK},
+		{name: "missing file", path: "/products/1/images", field: "file", expectedStatus: http.StatusBadRequest},
+		{name: "invalid product id", path: "/products/abc/images", field: "image", expectedStatus: http.StatusBadRequest},
+		{
+			name:           "unsupported type",
+			path:           "/products/1/images",
+			field:          "image",
+			serviceErr:     customerrors.ErrUnsupportedMediaType,
+			expectedStatus: http.StatusUnsupportedMediaType,
+		},
+		{
+			name:           "too large",
+			path:           "/products/1/images",
+			field:          "image",
+			serviceErr:     customerrors.ErrPayloadTooLarge,
+			expectedStatus: http.StatusRequestEntityTooLarge,
+		},
+		{
+			name:           "duplicate",
+			path:           "/products/1/images",
+			field:          "image",
+			serviceErr:     customerrors.ErrDuplicateEntry,
+			expectedStatus: http.StatusConflict,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			imageService := new(MockImageService)
+			if tt.serviceErr != nil {
+				imageService.On("Upload", int64(1), data).Return(nil, tt.serviceErr)
+			} else {
+				imageService.On("Upload", int64(1), data).Return(&model.ProductImage{
+					ID:        1,
+					ProductID: 1,
+					URL:       "http://localhost:38080/media/products/1/abc.png",
+				}, nil)
+			}
+
+			handlers := NewHandlers(new(MockUserService), new(MockJWTService), new(MockProductService), new(MockOrderService),
+				WithImageService(imageService))
+			router := gin.New()
+			router.POST("/products/:id/images", handlers.UploadProductImage)
+
+			body, contentType := multipartBody(t, tt.field, data)
+			req := httptest.NewRequest(http.MethodPost, tt.path, body)
+			req.Header.Set("Content-Type", contentType)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			if tt.expectedStatus == http.StatusOK {
+				assert.Contains(t, w.Body.String(), `"url":"http://localhost:38080/media/products/1/abc.png"`)
+			}
+			if tt.field != "image" || tt.path != "/products/1/images" {
+				imageService.AssertNotCalled(t, "Upload", mock.Anything, mock.Anything)
+			}
+		})
+	}
+}
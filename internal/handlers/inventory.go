@@ -0,0 +1,133 @@
+package handlers
+
+import (
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ylh990835774/blockchain-shop-demo/internal/model"
+	"github.com/ylh990835774/blockchain-shop-demo/pkg/errors"
+)
+
+// RestockProductSKU 商户为SKU补货入库
+func (h *Handlers) RestockProductSKU(c *gin.Context) {
+	productID, skuID, ok := skuParams(c)
+	if !ok {
+		handleError(c, errors.ErrInvalidInput, "补货入库-参数验证")
+		return
+	}
+
+	var req RestockRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		handleError(c, errors.ErrInvalidInput, "补货入库-参数验证")
+		return
+	}
+
+	userID := c.GetInt64("user_id")
+	movement, err := h.inventoryService.Restock(userID, productID, skuID, req.Quantity, req.Reason)
+	if err != nil {
+		handleError(c, err, "补货入库")
+		return
+	}
+
+	handleSuccess(c, movement, "补货入库")
+}
+
+// AdjustProductSKUStock 按盘点结果等调整SKU库存
+func (h *Handlers) AdjustProductSKUStock(c *gin.Context) {
+	productID, skuID, ok := skuParams(c)
+	if !ok {
+		handleError(c, errors.ErrInvalidInput, "调整库存-参数验证")
+		return
+	}
+
+	var req AdjustStockRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		handleError(c, errors.ErrInvalidInput, "调整库存-参数验证")
+		return
+	}
+
+	userID := c.GetInt64("user_id")
+	movement, err := h.inventoryService.Adjust(userID, productID, skuID, req.Quantity, req.Reason)
+	if err != nil {
+		handleError(c, err, "调整库存")
+		return
+	}
+
+	handleSuccess(c, movement, "调整库存")
+}
+
+// ListInventoryMovements 查询库存流水，支持按商品、SKU和变动类型过滤
+func (h *Handlers) ListInventoryMovements(c *gin.Context) {
+	var filter model.InventoryMovementFilter
+	var err error
+	if v := c.Query("product_id"); v != "" {
+		if filter.ProductID, err = strconv.ParseInt(v, 10, 64); err != nil {
+			handleError(c, errors.ErrInvalidInput, "获取库存流水-参数验证")
+			return
+		}
+	}
+	if v := c.Query("sku_id"); v != "" {
+		if filter.SKUID, err = strconv.ParseInt(v, 10, 64); err != nil {
+			handleError(c, errors.ErrInvalidInput, "获取库存流水-参数验证")
+			return
+		}
+	}
+	filter.Type = model.MovementType(c.Query("type"))
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "10"))
+
+	movements, total, err := h.inventoryService.ListMovements(filter, page, pageSize)
+	if err != nil {
+		handleError(c, err, "获取库存流水")
+		return
+	}
+
+	handleSuccess(c, gin.H{
+		"total":     total,
+		"movements": movements,
+	}, "获取库存流水")
+}
+
+// ListLowStockAlerts 查询低库存告警，open=true时只返回未关闭的告警
+func (h *Handlers) ListLowStockAlerts(c *gin.Context) {
+	openOnly := c.Query("open") == "true"
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "10"))
+
+	alerts, total, err := h.inventoryService.ListAlerts(openOnly, page, pageSize)
+	if err != nil {
+		handleError(c, err, "获取低库存告警")
+		return
+	}
+
+	handleSuccess(c, gin.H{
+		"total":  total,
+		"alerts": alerts,
+	}, "获取低库存告警")
+}
+
+// ListStockDiscrepancies 管理员核对库存，返回库存合计与库存流水之和不一致的商品
+func (h *Handlers) ListStockDiscrepancies(c *gin.Context) {
+	discrepancies, err := h.inventoryService.Discrepancies()
+	if err != nil {
+		handleError(c, err, "核对库存")
+		return
+	}
+
+	handleSuccess(c, gin.H{
+		"discrepancies": discrepancies,
+	}, "核对库存")
+}
+
+// skuParams 解析路径中的商品ID和SKU ID
+func skuParams(c *gin.Context) (int64, int64, bool) {
+	productID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return 0, 0, false
+	}
+	skuID, err := strconv.ParseInt(c.Param("sku_id"), 10, 64)
+	if err != nil {
+		return 0, 0, false
+	}
+	return productID, skuID, true
+}
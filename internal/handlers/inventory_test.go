@@ -0,0 +1,247 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/ylh990835774/blockchain-shop-demo/internal/model"
+	customerrors "github.com/ylh990835774/blockchain-shop-demo/pkg/errors"
+	"github.com/ylh990835774/blockchain-shop-demo/pkg/logger"
+)
+
+// MockInventoryService 是库存服务的mock实现
+type MockInventoryService struct {
+	mock.Mock
+}
+
+func (m *MockInventoryService) Restock(actorID, productID, skuID int64, quantity int, reason string) (*model.InventoryMovement, error) {
+	args := m.Called(actorID, productID, skuID, quantity, reason)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.InventoryMovement), args.Error(1)
+}
+
+func (m *MockInventoryService) Adjust(actorID, productID, skuID int64, quantity int, reason string) (*model.InventoryMovement, error) {
+	args := m.Called(actorID, productID, skuID, quantity, reason)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.InventoryMovement), args.Error(1)
+}
+
+func (m *MockInventoryService) ListMovements(filter model.InventoryMovementFilter, page, pageSize int) ([]*model.InventoryMovement, int64, error) {
+	args := m.Called(filter, page, pageSize)
+	if args.Get(0) == nil {
+		return nil, args.Get(1).(int64), args.Error(2)
+	}
+	return args.Get(0).([]*model.InventoryMovement), args.Get(1).(int64), args.Error(2)
+}
+
+func (m *MockInventoryService) ListAlerts(openOnly bool, page, pageSize int) ([]*model.LowStockAlert, int64, error) {
+	args := m.Called(openOnly, page, pageSize)
+	if args.Get(0) == nil {
+		return nil, args.Get(1).(int64), args.Error(2)
+	}
+	return args.Get(0).([]*model.LowStockAlert), args.Get(1).(int64), args.Error(2)
+}
+
+func (m *MockInventoryService) Discrepancies() ([]*model.StockDiscrepancy, error) {
+	args := m.Called()
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*model.StockDiscrepancy), args.Error(1)
+}
+
+func (m *MockInventoryService) CheckLowStock(ctx context.Context) (int, error) {
+	args := m.Called(ctx)
+	return args.Int(0), args.Error(1)
+}
+
+func TestHandlers_RestockProductSKU(t *testing.T) {
+	err := logger.Setup(&logger.Config{
+		Level:    "info",
+		Format:   "console",
+		Console:  true,
+		Filename: "",
+	})
+	assert.NoError(t, err)
+
+	gin.SetMode(gin.TestMode)
+
+	actorID := int64(7)
+	tests := []struct {
+		name           string
+		path           string
+		body           string
+		setupMock      func(*MockInventoryService)
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name: "restocked",
+			path: "/products/1/skus/2/restock",
+			body: `{"quantity": 20, "reason": "PO-1001"}`,
+			setupMock: func(m *MockInventoryService) {
+				m.On("Restock", int64(7), int64(1), int64(2), 20, "PO-1001").Return(&model.InventoryMovement{
+					ID: 9, ProductID: 1, SKUID: 2, Type: model.MovementTypeRestock, Quantity: 20, ActorID: &actorID, Reason: "PO-1001",
+				}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `"type":"restock","quantity":20,"actor_id":7`,
+		},
+		{
+			name:           "quantity must be positive",
+			path:           "/products/1/skus/2/restock",
+			body:           `{"quantity": -1}`,
+			setupMock:      func(m *MockInventoryService) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "invalid sku id",
+			path:           "/products/1/skus/abc/restock",
+			body:           `{"quantity": 1}`,
+			setupMock:      func(m *MockInventoryService) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name: "sku of another product",
+			path: "/products/1/skus/3/restock",
+			body: `{"quantity": 1}`,
+			setupMock: func(m *MockInventoryService) {
+				m.On("Restock", int64(7), int64(1), int64(3), 1, "").Return(nil, customerrors.ErrNotFound)
+			},
+			expectedStatus: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inventoryService := new(MockInventoryService)
+			tt.setupMock(inventoryService)
+
+			handlers := NewHandlers(new(MockUserService), new(MockJWTService), new(MockProductService), new(MockOrderService),
+				WithInventoryService(inventoryService))
+			router := gin.New()
+			router.POST("/products/:id/skus/:sku_id/restock", func(c *gin.Context) {
+				c.Set("user_id", actorID)
+				handlers.RestockProductSKU(c)
+			})
+
+			req := httptest.NewRequest(http.MethodPost, tt.path, strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			if tt.expectedBody != "" {
+				assert.Contains(t, w.Body.String(), tt.expectedBody)
+			}
+			inventoryService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestHandlers_AdjustProductSKUStock(t *testing.T) {
+	err := logger.Setup(&logger.Config{
+		Level:    "info",
+		Format:   "console",
+		Console:  true,
+		Filename: "",
+	})
+	assert.NoError(t, err)
+
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name           string
+		body           string
+		serviceErr     error
+		callsService   bool
+		expectedStatus int
+	}{
+		{name: "write off damaged stock", body: `{"quantity": -2, "reason": "盘点破损"}`, callsService: true, expectedStatus: http.StatusOK},
+		{name: "reason required", body: `{"quantity": -2}`, expectedStatus: http.StatusBadRequest},
+		{name: "zero quantity", body: `{"quantity": 0, "reason": "盘点"}`, expectedStatus: http.StatusBadRequest},
+		{
+			name:           "stock would become negative",
+			body:           `{"quantity": -2, "reason": "盘点破损"}`,
+			serviceErr:     customerrors.ErrInsufficientStock,
+			callsService:   true,
+			expectedStatus: http.StatusConflict,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inventoryService := new(MockInventoryService)
+			if tt.callsService {
+				if tt.serviceErr != nil {
+					inventoryService.On("Adjust", int64(7), int64(1), int64(2), -2, "盘点破损").Return(nil, tt.serviceErr)
+				} else {
+					inventoryService.On("Adjust", int64(7), int64(1), int64(2), -2, "盘点破损").Return(&model.InventoryMovement{
+						ID: 10, ProductID: 1, SKUID: 2, Type: model.MovementTypeAdjustment, Quantity: -2, Reason: "盘点破损",
+					}, nil)
+				}
+			}
+
+			handlers := NewHandlers(new(MockUserService), new(MockJWTService), new(MockProductService), new(MockOrderService),
+				WithInventoryService(inventoryService))
+			router := gin.New()
+			router.POST("/products/:id/skus/:sku_id/adjustments", func(c *gin.Context) {
+				c.Set("user_id", int64(7))
+				handlers.AdjustProductSKUStock(c)
+			})
+
+			req := httptest.NewRequest(http.MethodPost, "/products/1/skus/2/adjustments", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			inventoryService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestHandlers_ListInventoryMovements(t *testing.T) {
+	err := logger.Setup(&logger.Config{
+		Level:    "info",
+		Format:   "console",
+		Console:  true,
+		Filename: "",
+	})
+	assert.NoError(t, err)
+
+	gin.SetMode(gin.TestMode)
+
+	inventoryService := new(MockInventoryService)
+	inventoryService.On("ListMovements", model.InventoryMovementFilter{ProductID: 1, Type: model.MovementTypeSale}, 2, 5).
+		Return([]*model.InventoryMovement{{ID: 3, ProductID: 1, SKUID: 2, Type: model.MovementTypeSale, Quantity: -1}}, int64(6), nil)
+
+	handlers := NewHandlers(new(MockUserService), new(MockJWTService), new(MockProductService), new(MockOrderService),
+		WithInventoryService(inventoryService))
+	router := gin.New()
+	router.GET("/inventory/movements", handlers.ListInventoryMovements)
+
+	req := httptest.NewRequest(http.MethodGet, "/inventory/movements?product_id=1&type=sale&page=2&page_size=5", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"total":6`)
+	assert.Contains(t, w.Body.String(), `"type":"sale","quantity":-1`)
+	inventoryService.AssertExpectations(t)
+
+	req = httptest.NewRequest(http.MethodGet, "/inventory/movements?sku_id=abc", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
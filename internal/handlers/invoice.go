@@ -0,0 +1,63 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ylh990835774/blockchain-shop-demo/pkg/errors"
+)
+
+// GetOrderInvoice 获取订单发票，默认返回JSON，format=pdf或Accept为application/pdf时返回PDF
+func (h *Handlers) GetOrderInvoice(c *gin.Context) {
+	orderID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		handleError(c, errors.ErrInvalidInput, "获取订单发票-参数验证")
+		return
+	}
+
+	userID := c.GetInt64("user_id")
+	invoice, err := h.invoiceService.GetOrIssue(userID, orderID)
+	if err != nil {
+		handleError(c, err, "获取订单发票")
+		return
+	}
+
+	if c.Query("format") != "pdf" && !strings.Contains(c.GetHeader("Accept"), "application/pdf") {
+		handleSuccess(c, gin.H{
+			"invoice":    invoice,
+			"verify_url": h.invoiceService.VerifyURL(invoice),
+		}, "获取订单发票")
+		return
+	}
+
+	pdf, err := h.invoiceService.RenderPDF(invoice)
+	if err != nil {
+		handleError(c, err, "获取订单发票-生成PDF")
+		return
+	}
+
+	c.Header("Content-Disposition", `attachment; filename="`+invoice.Number+`.pdf"`)
+	c.Data(http.StatusOK, "application/pdf", pdf)
+}
+
+// VerifyInvoice 公开核验发票，需携带发票核验地址中的令牌，只返回核验所需的信息，不包含购买方信息
+func (h *Handlers) VerifyInvoice(c *gin.Context) {
+	invoice, err := h.invoiceService.Verify(c.Param("number"), c.Query("token"))
+	if err != nil {
+		handleError(c, err, "核验发票")
+		return
+	}
+
+	handleSuccess(c, gin.H{
+		"number":      invoice.Number,
+		"order_id":    invoice.OrderID,
+		"seller_name": invoice.SellerName,
+		"currency":    invoice.Currency,
+		"total":       invoice.Total,
+		"tax_total":   invoice.TaxTotal,
+		"tx_hash":     invoice.TxHash,
+		"issued_at":   invoice.IssuedAt,
+	}, "核验发票")
+}
@@ -0,0 +1,118 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/ylh990835774/blockchain-shop-demo/internal/model"
+	customerrors "github.com/ylh990835774/blockchain-shop-demo/pkg/errors"
+	"github.com/ylh990835774/blockchain-shop-demo/pkg/logger"
+	"github.com/ylh990835774/blockchain-shop-demo/pkg/money"
+)
+
+// MockInvoiceService 是发票服务的mock实现
+type MockInvoiceService struct {
+	mock.Mock
+}
+
+func (m *MockInvoiceService) GetOrIssue(userID, orderID int64) (*model.Invoice, error) {
+	args := m.Called(userID, orderID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.Invoice), args.Error(1)
+}
+
+func (m *MockInvoiceService) Verify(number, token string) (*model.Invoice, error) {
+	args := m.Called(number, token)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.Invoice), args.Error(1)
+}
+
+func (m *MockInvoiceService) RenderPDF(invoice *model.Invoice) ([]byte, error) {
+	args := m.Called(invoice)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]byte), args.Error(1)
+}
+
+func (m *MockInvoiceService) VerifyURL(invoice *model.Invoice) string {
+	args := m.Called(invoice)
+	return args.String(0)
+}
+
+func TestHandlers_VerifyInvoice(t *testing.T) {
+	err := logger.Setup(&logger.Config{
+		Level:    "info",
+		Format:   "console",
+		Console:  true,
+		Filename: "",
+	})
+	assert.NoError(t, err)
+
+	gin.SetMode(gin.TestMode)
+
+	invoice := &model.Invoice{
+		ID:         1,
+		Number:     "INV-SHOP-00000001",
+		OrderID:    3,
+		UserID:     7,
+		SellerName: "区块链商城",
+		BuyerName:  "张三",
+		Currency:   "CNY",
+		TaxTotal:   money.MustParseAmount("2.30"),
+		Total:      money.MustParseAmount("19.98"),
+		TxHash:     "ab",
+		IssuedAt:   time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+	}
+
+	tests := []struct {
+		name           string
+		number         string
+		token          string
+		serviceErr     error
+		expectedStatus int
+	}{
+		{name: "issued invoice", number: "INV-SHOP-00000001", token: "0f1e", expectedStatus: http.StatusOK},
+		{name: "wrong token", number: "INV-SHOP-00000001", token: "ffff", serviceErr: customerrors.ErrNotFound, expectedStatus: http.StatusNotFound},
+		{name: "missing token", number: "INV-SHOP-00000001", serviceErr: customerrors.ErrInvalidInput, expectedStatus: http.StatusBadRequest},
+		{name: "unknown number", number: "INV-SHOP-00000002", token: "0f1e", serviceErr: customerrors.ErrNotFound, expectedStatus: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			invoiceService := new(MockInvoiceService)
+			if tt.serviceErr != nil {
+				invoiceService.On("Verify", tt.number, tt.token).Return(nil, tt.serviceErr)
+			} else {
+				invoiceService.On("Verify", tt.number, tt.token).Return(invoice, nil)
+			}
+
+			handlers := NewHandlers(new(MockUserService), new(MockJWTService), new(MockProductService), new(MockOrderService),
+				WithInvoiceService(invoiceService))
+			router := gin.New()
+			router.GET("/invoices/:number/verify", handlers.VerifyInvoice)
+
+			req := httptest.NewRequest(http.MethodGet, "/invoices/"+tt.number+"/verify?token="+tt.token, nil)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			if tt.expectedStatus == http.StatusOK {
+				assert.Contains(t, w.Body.String(), `"number":"INV-SHOP-00000001"`)
+				assert.Contains(t, w.Body.String(), `"total":"19.98"`)
+				assert.NotContains(t, w.Body.String(), "张三")
+				assert.NotContains(t, w.Body.String(), "user_id")
+			}
+			invoiceService.AssertExpectations(t)
+		})
+	}
+}
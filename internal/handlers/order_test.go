@@ -0,0 +1,321 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/ylh990835774/blockchain-shop-demo/internal/model"
+	"github.com/ylh990835774/blockchain-shop-demo/internal/service"
+	customerrors "github.com/ylh990835774/blockchain-shop-demo/pkg/errors"
+	"github.com/ylh990835774/blockchain-shop-demo/pkg/logger"
+	"github.com/ylh990835774/blockchain-shop-demo/pkg/money"
+)
+
+func TestHandlers_CreateOrder(t *testing.T) {
+	// 初始化日志配置
+	err := logger.Setup(&logger.Config{
+		Level:    "info",
+		Format:   "console",
+		Console:  true,
+		Filename: "", // 测试时不写入文件
+	})
+	assert.NoError(t, err)
+
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name           string
+		requestBody    map[string]interface{}
+		createErr      error
+		expectedStatus int
+		expectedInput  *service.CreateOrderInput
+	}{
+		{
+			name:           "currency, region and coupon",
+			requestBody:    map[string]interface{}{"product_id": 3, "quantity": 2, "currency": "USD", "region": "US", "coupon_code": "SPRING20"},
+			expectedStatus: http.StatusOK,
+			expectedInput: &service.CreateOrderInput{
+				UserID: 1, ProductID: 3, Quantity: 2, Currency: "USD", TaxRegion: "US", CouponCode: "SPRING20",
+			},
+		},
+		{
+			name:           "defaults left to order service",
+			requestBody:    map[string]interface{}{"product_id": 3, "quantity": 1},
+			expectedStatus: http.StatusOK,
+			expectedInput:  &service.CreateOrderInput{UserID: 1, ProductID: 3, Quantity: 1},
+		},
+		{
+			name:           "unsupported currency",
+			requestBody:    map[string]interface{}{"product_id": 3, "quantity": 1, "currency": "XYZ"},
+			createErr:      customerrors.ErrUnsupportedCurrency,
+			expectedStatus: http.StatusBadRequest,
+			expectedInput:  &service.CreateOrderInput{UserID: 1, ProductID: 3, Quantity: 1, Currency: "XYZ"},
+		},
+		{
+			name:           "coupon usage exceeded",
+			requestBody:    map[string]interface{}{"product_id": 3, "quantity": 1, "coupon_code": "SPRING20"},
+			createErr:      customerrors.ErrCouponUsageExceeded,
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedInput:  &service.CreateOrderInput{UserID: 1, ProductID: 3, Quantity: 1, CouponCode: "SPRING20"},
+		},
+		{
+			name:           "insufficient stock",
+			requestBody:    map[string]interface{}{"product_id": 3, "quantity": 5},
+			createErr:      customerrors.ErrInsufficientStock,
+			expectedStatus: http.StatusConflict,
+			expectedInput:  &service.CreateOrderInput{UserID: 1, ProductID: 3, Quantity: 5},
+		},
+		{
+			name:           "malformed currency",
+			requestBody:    map[string]interface{}{"product_id": 3, "quantity": 1, "currency": "usd"},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "malformed region",
+			requestBody:    map[string]interface{}{"product_id": 3, "quantity": 1, "region": "usa"},
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockUserService := new(MockUserService)
+			mockProductService := new(MockProductService)
+			mockOrderService := new(MockOrderService)
+
+			if tt.expectedInput != nil {
+				if tt.createErr != nil {
+					mockOrderService.On("Create", tt.expectedInput).Return(nil, tt.createErr)
+				} else {
+					mockOrderService.On("Create", tt.expectedInput).Return(&model.Order{
+						ID: 9, UserID: 1, ProductID: 3, Quantity: tt.expectedInput.Quantity, Status: model.OrderStatusPending,
+					}, nil)
+				}
+			}
+
+			handlers := NewHandlers(mockUserService, new(MockJWTService), mockProductService, mockOrderService)
+			router := gin.New()
+			router.Use(func(c *gin.Context) {
+				c.Set("user_id", int64(1))
+			})
+			router.POST("/orders", handlers.CreateOrder)
+
+			requestJSON, _ := json.Marshal(tt.requestBody)
+			req := httptest.NewRequest(http.MethodPost, "/orders", bytes.NewBuffer(requestJSON))
+			req.Header.Set("Content-Type", "application/json")
+			resp := httptest.NewRecorder()
+
+			router.ServeHTTP(resp, req)
+
+			assert.Equal(t, tt.expectedStatus, resp.Code)
+			mockUserService.AssertExpectations(t)
+			mockProductService.AssertExpectations(t)
+			mockOrderService.AssertExpectations(t)
+			if tt.expectedInput == nil {
+				mockOrderService.AssertNotCalled(t, "Create", mock.Anything)
+			}
+			if tt.expectedStatus == http.StatusOK {
+				var body struct {
+					Data model.Order `json:"data"`
+				}
+				assert.NoError(t, json.Unmarshal(resp.Body.Bytes(), &body))
+				assert.Equal(t, int64(9), body.Data.ID)
+			}
+		})
+	}
+}
+
+func TestHandlers_ListOrders(t *testing.T) {
+	err := logger.Setup(&logger.Config{
+		Level:    "info",
+		Format:   "console",
+		Console:  true,
+		Filename: "",
+	})
+	assert.NoError(t, err)
+
+	gin.SetMode(gin.TestMode)
+
+	from, _ := time.ParseInLocation("2006-01-02", "2024-03-01", time.Local)
+	to, _ := time.ParseInLocation("2006-01-02", "2024-04-01", time.Local)
+	exactTo, _ := time.Parse(time.RFC3339, "2024-03-31T12:00:00Z")
+	minTotal, maxTotal := money.MustParseAmount("10"), money.MustParseAmount("99.99")
+
+	tests := []struct {
+		name           string
+		path           string
+		query          string
+		expectedStatus int
+		expectedFilter *model.OrderFilter
+		expectedPage   int
+		expectedSize   int
+	}{
+		{
+			name:           "defaults to current user",
+			path:           "/orders",
+			expectedStatus: http.StatusOK,
+			expectedFilter: &model.OrderFilter{UserID: 1},
+		},
+		{
+			name:           "all filters",
+			path:           "/orders",
+			query:          "status=paid,%20complete&product_id=3&created_from=2024-03-01&created_to=2024-03-31&min_total=10&max_total=99.99&sort=total&order=asc&page=2&page_size=20",
+			expectedStatus: http.StatusOK,
+			expectedFilter: &model.OrderFilter{
+				UserID:      1,
+				Statuses:    []model.OrderStatus{model.OrderStatusPaid, model.OrderStatusComplete},
+				ProductID:   3,
+				CreatedFrom: &from,
+				CreatedTo:   &to,
+				MinTotal:    &minTotal,
+				MaxTotal:    &maxTotal,
+				SortBy:      model.OrderSortTotal,
+				Ascending:   true,
+			},
+			expectedPage: 2,
+			expectedSize: 20,
+		},
+		{
+			name:           "exact upper bound",
+			path:           "/orders",
+			query:          "created_to=2024-03-31T12:00:00Z",
+			expectedStatus: http.StatusOK,
+			expectedFilter: &model.OrderFilter{UserID: 1, CreatedTo: &exactTo},
+		},
+		{
+			name:           "customer cannot query other users",
+			path:           "/orders",
+			query:          "user_id=2",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "admin filters by user",
+			path:           "/admin/orders",
+			query:          "user_id=2",
+			expectedStatus: http.StatusOK,
+			expectedFilter: &model.OrderFilter{UserID: 2},
+		},
+		{
+			name:           "admin lists all users",
+			path:           "/admin/orders",
+			expectedStatus: http.StatusOK,
+			expectedFilter: &model.OrderFilter{},
+		},
+		{
+			name:           "unknown sort field",
+			path:           "/orders",
+			query:          "sort=price",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "malformed date",
+			path:           "/orders",
+			query:          "created_from=03/01/2024",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "negative amount",
+			path:           "/orders",
+			query:          "min_total=-1",
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockOrderService := new(MockOrderService)
+			if tt.expectedFilter != nil {
+				mockOrderService.On("List", *tt.expectedFilter, tt.expectedPage, tt.expectedSize).
+					Return([]*model.Order{{ID: 9}}, int64(1), nil)
+			}
+
+			handlers := NewHandlers(new(MockUserService), new(MockJWTService), new(MockProductService), mockOrderService)
+			router := gin.New()
+			router.Use(func(c *gin.Context) {
+				c.Set("user_id", int64(1))
+			})
+			router.GET("/orders", handlers.ListOrders)
+			router.GET("/admin/orders", handlers.ListAllOrders)
+
+			req := httptest.NewRequest(http.MethodGet, tt.path+"?"+tt.query, nil)
+			resp := httptest.NewRecorder()
+
+			router.ServeHTTP(resp, req)
+
+			assert.Equal(t, tt.expectedStatus, resp.Code)
+			mockOrderService.AssertExpectations(t)
+			if tt.expectedFilter == nil {
+				mockOrderService.AssertNotCalled(t, "List", mock.Anything, mock.Anything, mock.Anything)
+			}
+		})
+	}
+}
+
+func TestHandlers_ListByCursor(t *testing.T) {
+	err := logger.Setup(&logger.Config{
+		Level:    "info",
+		Format:   "console",
+		Console:  true,
+		Filename: "",
+	})
+	assert.NoError(t, err)
+
+	gin.SetMode(gin.TestMode)
+
+	mockProductService := new(MockProductService)
+	mockOrderService := new(MockOrderService)
+	mockProductService.On("ListByCursor", model.ProductFilter{}, "", 10).Return([]*model.Product{{ID: 1}}, "next", nil)
+	mockOrderService.On("ListByCursor", model.OrderFilter{UserID: 1, SortBy: model.OrderSortTotal}, "abc", 20).
+		Return([]*model.Order{{ID: 9}}, "", nil)
+	mockOrderService.On("ListByCursor", model.OrderFilter{UserID: 1}, "bad", 0).
+		Return([]*model.Order(nil), "", customerrors.ErrInvalidInput)
+
+	handlers := NewHandlers(new(MockUserService), new(MockJWTService), mockProductService, mockOrderService)
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Set("user_id", int64(1))
+	})
+	router.GET("/products", handlers.ListProducts)
+	router.GET("/orders", handlers.ListOrders)
+
+	tests := []struct {
+		name           string
+		url            string
+		expectedStatus int
+		expectedCursor string
+	}{
+		{name: "first page of products", url: "/products?cursor=", expectedStatus: http.StatusOK, expectedCursor: "next"},
+		{name: "last page of orders", url: "/orders?cursor=abc&sort=total&page_size=20", expectedStatus: http.StatusOK},
+		{name: "invalid cursor", url: "/orders?cursor=bad", expectedStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.url, nil)
+			resp := httptest.NewRecorder()
+
+			router.ServeHTTP(resp, req)
+
+			assert.Equal(t, tt.expectedStatus, resp.Code)
+			if tt.expectedStatus == http.StatusOK {
+				var body struct {
+					Data map[string]interface{} `json:"data"`
+				}
+				assert.NoError(t, json.Unmarshal(resp.Body.Bytes(), &body))
+				assert.Equal(t, tt.expectedCursor, body.Data["next_cursor"])
+				assert.NotContains(t, body.Data, "total")
+			}
+		})
+	}
+
+	mockProductService.AssertExpectations(t)
+	mockOrderService.AssertExpectations(t)
+	mockProductService.AssertNotCalled(t, "List", mock.Anything, mock.Anything, mock.Anything)
+}
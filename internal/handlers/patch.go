@@ -0,0 +1,61 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ylh990835774/blockchain-shop-demo/pkg/errors"
+)
+
+// 税目和用户资料的长度限制，与CreateProductRequest等请求的binding规则一致；商品名称和描述的长度限制定义在model中
+const (
+	maxTaxCategoryLength = 32
+	phoneLength          = 11
+	maxAddressLength     = 512
+)
+
+// patchField JSON Merge Patch（RFC 7396）请求中的字段，区分未出现、值为null和有值三种情况
+type patchField[T any] struct {
+	Set   bool // 字段出现在请求中
+	Null  bool // 值为null，表示清空该字段
+	Value T
+}
+
+func (f *patchField[T]) UnmarshalJSON(data []byte) error {
+	f.Set = true
+	if bytes.Equal(bytes.TrimSpace(data), []byte("null")) {
+		f.Null = true
+		return nil
+	}
+	return json.Unmarshal(data, &f.Value)
+}
+
+// optional 返回字段的新值，未出现时返回nil，值为null时返回零值
+func (f patchField[T]) optional() *T {
+	if !f.Set {
+		return nil
+	}
+	value := f.Value
+	return &value
+}
+
+// bindPatch 将请求体解析为合并补丁，请求体必须是JSON对象，且只能包含req中定义的字段
+func bindPatch(c *gin.Context, req interface{}) error {
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		return errors.ErrInvalidInput
+	}
+	body = bytes.TrimSpace(body)
+	if len(body) == 0 || body[0] != '{' {
+		return errors.ErrInvalidInput
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(req); err != nil {
+		return errors.ErrInvalidInput
+	}
+	return nil
+}
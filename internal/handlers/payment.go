@@ -0,0 +1,83 @@
+package handlers
+
+import (
+	"io"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ylh990835774/blockchain-shop-demo/pkg/errors"
+)
+
+// PaymentSignatureHeader 支付回调签名请求头
+const PaymentSignatureHeader = "X-Signature"
+
+// CreatePayment 为订单发起支付
+func (h *Handlers) CreatePayment(c *gin.Context) {
+	orderID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		handleError(c, errors.ErrInvalidInput, "发起支付-参数验证")
+		return
+	}
+
+	var req CreatePaymentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		handleError(c, errors.ErrInvalidInput, "发起支付-参数验证")
+		return
+	}
+
+	userID := c.GetInt64("user_id")
+	payment, err := h.paymentService.Create(userID, orderID, req.Provider)
+	if err != nil {
+		handleError(c, err, "发起支付")
+		return
+	}
+
+	handleSuccess(c, payment, "发起支付")
+}
+
+// ListOrderPayments 获取订单的支付记录
+func (h *Handlers) ListOrderPayments(c *gin.Context) {
+	userID := c.GetInt64("user_id")
+	orderID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		handleError(c, errors.ErrInvalidInput, "获取支付记录-参数验证")
+		return
+	}
+
+	order, err := h.orderService.GetByID(orderID)
+	if err != nil {
+		handleError(c, err, "获取支付记录-订单验证")
+		return
+	}
+
+	// 验证订单所属用户
+	if order.UserID != userID {
+		handleError(c, errors.ErrUnauthorized, "获取支付记录-权限验证")
+		return
+	}
+
+	payments, err := h.paymentService.ListByOrderID(orderID)
+	if err != nil {
+		handleError(c, err, "获取支付记录")
+		return
+	}
+
+	handleSuccess(c, payments, "获取支付记录")
+}
+
+// PaymentWebhook 处理支付渠道回调
+func (h *Handlers) PaymentWebhook(c *gin.Context) {
+	payload, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		handleError(c, errors.ErrInvalidInput, "支付回调-读取请求体")
+		return
+	}
+
+	provider := c.Param("provider")
+	if err := h.paymentService.HandleWebhook(provider, payload, c.GetHeader(PaymentSignatureHeader)); err != nil {
+		handleError(c, err, "支付回调")
+		return
+	}
+
+	handleSuccess(c, nil, "支付回调")
+}
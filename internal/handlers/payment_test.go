@@ -0,0 +1,197 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/ylh990835774/blockchain-shop-demo/internal/model"
+	customerrors "github.com/ylh990835774/blockchain-shop-demo/pkg/errors"
+	"github.com/ylh990835774/blockchain-shop-demo/pkg/logger"
+	"github.com/ylh990835774/blockchain-shop-demo/pkg/money"
+)
+
+// MockPaymentService 是支付服务的mock实现
+type MockPaymentService struct {
+	mock.Mock
+}
+
+func (m *MockPaymentService) Create(userID, orderID int64, provider string) (*model.Payment, error) {
+	args := m.Called(userID, orderID, provider)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.Payment), args.Error(1)
+}
+
+func (m *MockPaymentService) HandleWebhook(provider string, payload []byte, signature string) error {
+	args := m.Called(provider, payload, signature)
+	return args.Error(0)
+}
+
+func (m *MockPaymentService) ListByOrderID(orderID int64) ([]*model.Payment, error) {
+	args := m.Called(orderID)
+	return args.Get(0).([]*model.Payment), args.Error(1)
+}
+
+func (m *MockPaymentService) Refund(orderID int64, amount money.Amount) (string, error) {
+	args := m.Called(orderID, amount)
+	return args.String(0), args.Error(1)
+}
+
+func TestHandlers_CreatePayment(t *testing.T) {
+	// 初始化日志配置
+	err := logger.Setup(&logger.Config{
+		Level:    "info",
+		Format:   "console",
+		Console:  true,
+		Filename: "", // 测试时不写入文件
+	})
+	assert.NoError(t, err)
+
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name           string
+		setupMock      func(*MockPaymentService)
+		requestBody    map[string]interface{}
+		expectedStatus int
+		expectedBody   map[string]interface{}
+	}{
+		{
+			name: "successful_create",
+			setupMock: func(m *MockPaymentService) {
+				m.On("Create", int64(1), int64(5), "mock").Return(&model.Payment{
+					ID:          9,
+					OrderID:     5,
+					ProviderRef: "mock_ch_1",
+					Status:      model.PaymentStatusPending,
+				}, nil)
+			},
+			requestBody:    map[string]interface{}{"provider": "mock"},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "missing_provider",
+			requestBody:    map[string]interface{}{},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody: map[string]interface{}{
+				"code":    float64(-1),
+				"message": "无效的输入",
+				"data":    nil,
+			},
+		},
+		{
+			name: "order_not_pending",
+			setupMock: func(m *MockPaymentService) {
+				m.On("Create", int64(1), int64(5), "mock").Return(nil, customerrors.ErrInvalidOrderStatus)
+			},
+			requestBody:    map[string]interface{}{"provider": "mock"},
+			expectedStatus: http.StatusConflict,
+			expectedBody: map[string]interface{}{
+				"code":    float64(-1),
+				"message": "当前订单状态不允许该操作",
+				"data":    nil,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockPaymentService := new(MockPaymentService)
+			if tt.setupMock != nil {
+				tt.setupMock(mockPaymentService)
+			}
+
+			handlers := NewHandlers(new(MockUserService), new(MockJWTService), new(MockProductService), new(MockOrderService),
+				WithPaymentService(mockPaymentService))
+			router := gin.New()
+			router.Use(func(c *gin.Context) {
+				c.Set("user_id", int64(1))
+			})
+			router.POST("/orders/:id/payments", handlers.CreatePayment)
+
+			requestJSON, _ := json.Marshal(tt.requestBody)
+			req := httptest.NewRequest(http.MethodPost, "/orders/5/payments", bytes.NewBuffer(requestJSON))
+			req.Header.Set("Content-Type", "application/json")
+			resp := httptest.NewRecorder()
+
+			router.ServeHTTP(resp, req)
+
+			assert.Equal(t, tt.expectedStatus, resp.Code)
+			if tt.expectedBody != nil {
+				var actualBody map[string]interface{}
+				err := json.Unmarshal(resp.Body.Bytes(), &actualBody)
+				assert.NoError(t, err)
+				assert.Equal(t, tt.expectedBody, actualBody)
+			}
+
+			mockPaymentService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestHandlers_PaymentWebhook(t *testing.T) {
+	// 初始化日志配置
+	err := logger.Setup(&logger.Config{
+		Level:    "info",
+		Format:   "console",
+		Console:  true,
+		Filename: "", // 测试时不写入文件
+	})
+	assert.NoError(t, err)
+
+	gin.SetMode(gin.TestMode)
+
+	payload := []byte(`{"provider_ref":"mock_ch_1","status":"succeeded","amount":19.98,"currency":"CNY"}`)
+
+	tests := []struct {
+		name           string
+		signature      string
+		setupMock      func(*MockPaymentService)
+		expectedStatus int
+	}{
+		{
+			name:      "valid_signature",
+			signature: "good",
+			setupMock: func(m *MockPaymentService) {
+				m.On("HandleWebhook", "mock", payload, "good").Return(nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:      "invalid_signature",
+			signature: "bad",
+			setupMock: func(m *MockPaymentService) {
+				m.On("HandleWebhook", "mock", payload, "bad").Return(customerrors.ErrInvalidSignature)
+			},
+			expectedStatus: http.StatusUnauthorized,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockPaymentService := new(MockPaymentService)
+			tt.setupMock(mockPaymentService)
+
+			handlers := NewHandlers(new(MockUserService), new(MockJWTService), new(MockProductService), new(MockOrderService),
+				WithPaymentService(mockPaymentService))
+			router := gin.New()
+			router.POST("/payments/webhook/:provider", handlers.PaymentWebhook)
+
+			req := httptest.NewRequest(http.MethodPost, "/payments/webhook/mock", bytes.NewBuffer(payload))
+			req.Header.Set(PaymentSignatureHeader, tt.signature)
+			resp := httptest.NewRecorder()
+
+			router.ServeHTTP(resp, req)
+
+			assert.Equal(t, tt.expectedStatus, resp.Code)
+			mockPaymentService.AssertExpectations(t)
+		})
+	}
+}
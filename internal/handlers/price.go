@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ylh990835774/blockchain-shop-demo/pkg/errors"
+)
+
+// ListProductPriceHistory 公开查询商品的价格变更记录
+func (h *Handlers) ListProductPriceHistory(c *gin.Context) {
+	productID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		handleError(c, errors.ErrInvalidInput, "获取商品价格记录-参数验证")
+		return
+	}
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "10"))
+
+	changes, total, err := h.productService.PriceHistory(productID, page, pageSize)
+	if err != nil {
+		handleError(c, err, "获取商品价格记录")
+		return
+	}
+
+	handleSuccess(c, gin.H{
+		"total":   total,
+		"changes": changes,
+	}, "获取商品价格记录")
+}
+
+// ListPriceCommitments 公开查询已发布的价格表承诺
+func (h *Handlers) ListPriceCommitments(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "10"))
+
+	commitments, total, err := h.priceCommitmentService.List(page, pageSize)
+	if err != nil {
+		handleError(c, err, "获取价格表承诺列表")
+		return
+	}
+
+	handleSuccess(c, gin.H{
+		"total":       total,
+		"commitments": commitments,
+	}, "获取价格表承诺列表")
+}
+
+// CommitPrices 管理员立即发布价格表承诺，价格表没有变化时返回上次的承诺
+func (h *Handlers) CommitPrices(c *gin.Context) {
+	commitment, created, err := h.priceCommitmentService.Commit()
+	if err != nil {
+		handleError(c, err, "发布价格表承诺")
+		return
+	}
+
+	handleSuccess(c, gin.H{
+		"commitment": commitment,
+		"created":    created,
+	}, "发布价格表承诺")
+}
+
+// GetOrderPriceProof 获取订单单价与下单时已发布价格表一致的默克尔证明
+func (h *Handlers) GetOrderPriceProof(c *gin.Context) {
+	orderID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		handleError(c, errors.ErrInvalidInput, "获取订单价格证明-参数验证")
+		return
+	}
+
+	userID := c.GetInt64("user_id")
+	proof, err := h.priceCommitmentService.ProveOrder(userID, orderID)
+	if err != nil {
+		handleError(c, err, "获取订单价格证明")
+		return
+	}
+
+	handleSuccess(c, proof, "获取订单价格证明")
+}
@@ -0,0 +1,149 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/ylh990835774/blockchain-shop-demo/internal/model"
+	customerrors "github.com/ylh990835774/blockchain-shop-demo/pkg/errors"
+	"github.com/ylh990835774/blockchain-shop-demo/pkg/logger"
+	"github.com/ylh990835774/blockchain-shop-demo/pkg/money"
+)
+
+// MockPriceCommitmentService 是价格表承诺服务的mock实现
+type MockPriceCommitmentService struct {
+	mock.Mock
+}
+
+func (m *MockPriceCommitmentService) Commit() (*model.PriceCommitment, bool, error) {
+	args := m.Called()
+	if args.Get(0) == nil {
+		return nil, args.Bool(1), args.Error(2)
+	}
+	return args.Get(0).(*model.PriceCommitment), args.Bool(1), args.Error(2)
+}
+
+func (m *MockPriceCommitmentService) List(page, pageSize int) ([]*model.PriceCommitment, int64, error) {
+	args := m.Called(page, pageSize)
+	if args.Get(0) == nil {
+		return nil, args.Get(1).(int64), args.Error(2)
+	}
+	return args.Get(0).([]*model.PriceCommitment), args.Get(1).(int64), args.Error(2)
+}
+
+func (m *MockPriceCommitmentService) ProveOrder(userID, orderID int64) (*model.OrderPriceProof, error) {
+	args := m.Called(userID, orderID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.OrderPriceProof), args.Error(1)
+}
+
+func TestHandlers_GetOrderPriceProof(t *testing.T) {
+	err := logger.Setup(&logger.Config{
+		Level:    "info",
+		Format:   "console",
+		Console:  true,
+		Filename: "",
+	})
+	assert.NoError(t, err)
+
+	gin.SetMode(gin.TestMode)
+
+	proof := &model.OrderPriceProof{
+		OrderID:    1,
+		SKUCode:    "TEA-S",
+		Currency:   "CNY",
+		UnitPrice:  money.MustParseAmount("25.00"),
+		Commitment: &model.PriceCommitment{ID: 3, MerkleRoot: "ab", TxHash: "cd"},
+		Entry:      &model.PriceCommitmentEntry{Position: 1, SKUCode: "TEA-S", Currency: "CNY", Price: money.MustParseAmount("25.00")},
+		Proof:      []model.MerkleProofStep{{Hash: "ef", Position: "left"}},
+		Matched:    true,
+		OnChain:    true,
+		Verified:   true,
+	}
+
+	tests := []struct {
+		name           string
+		path           string
+		serviceErr     error
+		expectedStatus int
+	}{
+		{name: "verified", path: "/orders/1/price-proof", expectedStatus: http.StatusOK},
+		{name: "invalid order id", path: "/orders/abc/price-proof", expectedStatus: http.StatusBadRequest},
+		{name: "other user's order", path: "/orders/1/price-proof", serviceErr: customerrors.ErrUnauthorized, expectedStatus: http.StatusUnauthorized},
+		{name: "no commitment before order", path: "/orders/1/price-proof", serviceErr: customerrors.ErrNotFound, expectedStatus: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			priceService := new(MockPriceCommitmentService)
+			if tt.serviceErr != nil {
+				priceService.On("ProveOrder", int64(7), int64(1)).Return(nil, tt.serviceErr)
+			} else {
+				priceService.On("ProveOrder", int64(7), int64(1)).Return(proof, nil)
+			}
+
+			handlers := NewHandlers(new(MockUserService), new(MockJWTService), new(MockProductService), new(MockOrderService),
+				WithPriceCommitmentService(priceService))
+			router := gin.New()
+			router.GET("/orders/:id/price-proof", func(c *gin.Context) {
+				c.Set("user_id", int64(7))
+				handlers.GetOrderPriceProof(c)
+			})
+
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			if tt.expectedStatus == http.StatusOK {
+				assert.Contains(t, w.Body.String(), `"verified":true`)
+				assert.Contains(t, w.Body.String(), `"proof":[{"hash":"ef","position":"left"}]`)
+			}
+			if tt.path != "/orders/1/price-proof" {
+				priceService.AssertNotCalled(t, "ProveOrder", mock.Anything, mock.Anything)
+			}
+		})
+	}
+}
+
+func TestHandlers_ListProductPriceHistory(t *testing.T) {
+	err := logger.Setup(&logger.Config{
+		Level:    "info",
+		Format:   "console",
+		Console:  true,
+		Filename: "",
+	})
+	assert.NoError(t, err)
+
+	gin.SetMode(gin.TestMode)
+
+	oldPrice, newPrice := money.MustParseAmount("25.00"), money.MustParseAmount("26.00")
+	productService := new(MockProductService)
+	productService.On("PriceHistory", int64(1), 2, 5).Return([]*model.ProductPriceHistory{
+		{ID: 2, ProductID: 1, Currency: "CNY", OldPrice: &oldPrice, NewPrice: &newPrice},
+	}, int64(6), nil)
+
+	handlers := NewHandlers(new(MockUserService), new(MockJWTService), productService, new(MockOrderService))
+	router := gin.New()
+	router.GET("/products/:id/price-history", handlers.ListProductPriceHistory)
+
+	req := httptest.NewRequest(http.MethodGet, "/products/1/price-history?page=2&page_size=5", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"total":6`)
+	assert.Contains(t, w.Body.String(), `"old_price":"25.00","new_price":"26.00"`)
+	productService.AssertExpectations(t)
+
+	req = httptest.NewRequest(http.MethodGet, "/products/abc/price-history", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
@@ -0,0 +1,398 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/ylh990835774/blockchain-shop-demo/internal/model"
+	customerrors "github.com/ylh990835774/blockchain-shop-demo/pkg/errors"
+	"github.com/ylh990835774/blockchain-shop-demo/pkg/logger"
+	"github.com/ylh990835774/blockchain-shop-demo/pkg/money"
+)
+
+func TestHandlers_ListProducts(t *testing.T) {
+	err := logger.Setup(&logger.Config{
+		Level:    "info",
+		Format:   "console",
+		Console:  true,
+		Filename: "",
+	})
+	assert.NoError(t, err)
+
+	gin.SetMode(gin.TestMode)
+
+	minPrice, maxPrice := money.MustParseAmount("10"), money.MustParseAmount("99.50")
+
+	tests := []struct {
+		name           string
+		query          string
+		expectedStatus int
+		expectedFilter *model.ProductFilter
+		expectedPage   int
+		expectedSize   int
+	}{
+		{
+			name:           "defaults",
+			expectedStatus: http.StatusOK,
+			expectedFilter: &model.ProductFilter{},
+			expectedPage:   1,
+			expectedSize:   10,
+		},
+		{
+			name:           "search with filters",
+			query:          "q=%E7%BB%BF%E8%8C%B6&currency=CNY&min_price=10&max_price=99.50&in_stock=true&sort=price&order=asc&page=2&page_size=20",
+			expectedStatus: http.StatusOK,
+			expectedFilter: &model.ProductFilter{
+				Query:     "绿茶",
+				Currency:  "CNY",
+				MinPrice:  &minPrice,
+				MaxPrice:  &maxPrice,
+				InStock:   true,
+				SortBy:    model.ProductSortPrice,
+				Ascending: true,
+			},
+			expectedPage: 2,
+			expectedSize: 20,
+		},
+		{name: "unknown sort", query: "sort=stock", expectedStatus: http.StatusBadRequest},
+		{name: "malformed price", query: "min_price=abc", expectedStatus: http.StatusBadRequest},
+		{name: "malformed in_stock", query: "in_stock=maybe", expectedStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockProductService := new(MockProductService)
+			if tt.expectedFilter != nil {
+				mockProductService.On("List", *tt.expectedFilter, tt.expectedPage, tt.expectedSize).
+					Return([]*model.Product{{ID: 1}}, int64(21), nil)
+				mockProductService.On("Facets", *tt.expectedFilter).
+					Return(&model.ProductFacets{InStock: 20, OutOfStock: 1}, nil)
+			}
+
+			handlers := NewHandlers(new(MockUserService), new(MockJWTService), mockProductService, new(MockOrderService))
+			router := gin.New()
+			router.GET("/products", handlers.ListProducts)
+
+			req := httptest.NewRequest(http.MethodGet, "/products?"+tt.query, nil)
+			resp := httptest.NewRecorder()
+
+			router.ServeHTTP(resp, req)
+
+			assert.Equal(t, tt.expectedStatus, resp.Code)
+			mockProductService.AssertExpectations(t)
+			if tt.expectedFilter == nil {
+				mockProductService.AssertNotCalled(t, "List", mock.Anything, mock.Anything, mock.Anything)
+				return
+			}
+
+			var body struct {
+				Data struct {
+					Total  int64               `json:"total"`
+					Facets model.ProductFacets `json:"facets"`
+				} `json:"data"`
+			}
+			assert.NoError(t, json.Unmarshal(resp.Body.Bytes(), &body))
+			assert.Equal(t, int64(21), body.Data.Total)
+			assert.Equal(t, int64(20), body.Data.Facets.InStock)
+		})
+	}
+}
+
+func TestHandlers_ListCategoryProducts(t *testing.T) {
+	err := logger.Setup(&logger.Config{
+		Level:    "info",
+		Format:   "console",
+		Console:  true,
+		Filename: "",
+	})
+	assert.NoError(t, err)
+
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name           string
+		url            string
+		listErr        error
+		expectedStatus int
+		expectedFilter *model.ProductFilter
+	}{
+		{
+			name:           "category with search",
+			url:            "/categories/3/products?q=tea&in_stock=true",
+			expectedStatus: http.StatusOK,
+			expectedFilter: &model.ProductFilter{Query: "tea", CategoryIDs: []int64{3}, InStock: true},
+		},
+		{
+			name:           "path overrides category query",
+			url:            "/categories/3/products?category_id=5",
+			expectedStatus: http.StatusOK,
+			expectedFilter: &model.ProductFilter{CategoryIDs: []int64{3}},
+		},
+		{
+			name:           "unknown category",
+			url:            "/categories/9/products",
+			listErr:        customerrors.ErrNotFound,
+			expectedStatus: http.StatusNotFound,
+			expectedFilter: &model.ProductFilter{CategoryIDs: []int64{9}},
+		},
+		{name: "invalid id", url: "/categories/abc/products", expectedStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockProductService := new(MockProductService)
+			if tt.expectedFilter != nil {
+				if tt.listErr != nil {
+					mockProductService.On("List", *tt.expectedFilter, 1, 10).Return([]*model.Product(nil), int64(0), tt.listErr)
+				} else {
+					mockProductService.On("List", *tt.expectedFilter, 1, 10).Return([]*model.Product{{ID: 1}}, int64(1), nil)
+					mockProductService.On("Facets", *tt.expectedFilter).Return(&model.ProductFacets{}, nil)
+				}
+			}
+
+			handlers := NewHandlers(new(MockUserService), new(MockJWTService), mockProductService, new(MockOrderService))
+			router := gin.New()
+			router.GET("/categories/:id/products", handlers.ListCategoryProducts)
+
+			req := httptest.NewRequest(http.MethodGet, tt.url, nil)
+			resp := httptest.NewRecorder()
+
+			router.ServeHTTP(resp, req)
+
+			assert.Equal(t, tt.expectedStatus, resp.Code)
+			mockProductService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestHandlers_UpdateProduct(t *testing.T) {
+	err := logger.Setup(&logger.Config{
+		Level:    "info",
+		Format:   "console",
+		Console:  true,
+		Filename: "",
+	})
+	assert.NoError(t, err)
+
+	gin.SetMode(gin.TestMode)
+
+	updates := map[string]interface{}{"name": "新名称"}
+	name := "新名称"
+	patch := model.ProductPatch{Name: &name}
+
+	tests := []struct {
+		name            string
+		ifMatch         string
+		expectedVersion int64
+		serviceErr      error
+		expectedStatus  int
+		expectedETag    string
+	}{
+		{
+			name:            "without if-match",
+			expectedVersion: 0,
+			expectedStatus:  http.StatusOK,
+			expectedETag:    `"4"`,
+		},
+		{
+			name:            "wildcard",
+			ifMatch:         "*",
+			expectedVersion: 0,
+			expectedStatus:  http.StatusOK,
+			expectedETag:    `"4"`,
+		},
+		{
+			name:            "matching version",
+			ifMatch:         `"3"`,
+			expectedVersion: 3,
+			expectedStatus:  http.StatusOK,
+			expectedETag:    `"4"`,
+		},
+		{
+			name:            "stale version",
+			ifMatch:         `"2"`,
+			expectedVersion: 2,
+			serviceErr:      customerrors.ErrPreconditionFailed,
+			expectedStatus:  http.StatusPreconditionFailed,
+		},
+		{name: "weak etag", ifMatch: `W/"3"`, expectedStatus: http.StatusPreconditionFailed},
+		{name: "malformed etag", ifMatch: "3", expectedStatus: http.StatusPreconditionFailed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockProductService := new(MockProductService)
+			called := tt.expectedStatus == http.StatusOK || tt.serviceErr != nil
+			if called {
+				if tt.serviceErr != nil {
+					mockProductService.On("Update", int64(1), tt.expectedVersion, patch).Return(nil, tt.serviceErr)
+				} else {
+					mockProductService.On("Update", int64(1), tt.expectedVersion, patch).
+						Return(&model.Product{ID: 1, Name: "新名称", Version: 4}, nil)
+				}
+			}
+
+			handlers := NewHandlers(new(MockUserService), new(MockJWTService), mockProductService, new(MockOrderService))
+			router := gin.New()
+			router.PUT("/products/:id", handlers.UpdateProduct)
+
+			body, _ := json.Marshal(updates)
+			req := httptest.NewRequest(http.MethodPut, "/products/1", bytes.NewReader(body))
+			req.Header.Set("Content-Type", "application/json")
+			if tt.ifMatch != "" {
+				req.Header.Set("If-Match", tt.ifMatch)
+			}
+			resp := httptest.NewRecorder()
+
+			router.ServeHTTP(resp, req)
+
+			assert.Equal(t, tt.expectedStatus, resp.Code)
+			assert.Equal(t, tt.expectedETag, resp.Header().Get("ETag"))
+			mockProductService.AssertExpectations(t)
+			if !called {
+				mockProductService.AssertNotCalled(t, "Update", mock.Anything, mock.Anything, mock.Anything)
+			}
+		})
+	}
+}
+
+func TestHandlers_UpdateProductPatch(t *testing.T) {
+	err := logger.Setup(&logger.Config{
+		Level:    "info",
+		Format:   "console",
+		Console:  true,
+		Filename: "",
+	})
+	assert.NoError(t, err)
+
+	gin.SetMode(gin.TestMode)
+
+	empty, name := "", "新名称"
+	price := money.MustParseAmount("19.90")
+
+	tests := []struct {
+		name           string
+		body           string
+		expectedPatch  *model.ProductPatch
+		expectedStatus int
+	}{
+		{
+			name:           "set fields",
+			body:           `{"name": "新名称", "price": "19.90"}`,
+			expectedPatch:  &model.ProductPatch{Name: &name, Price: &price},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "clear description",
+			body:           `{"description": null}`,
+			expectedPatch:  &model.ProductPatch{Description: &empty},
+			expectedStatus: http.StatusOK,
+		},
+		{name: "empty patch", body: `{}`, expectedStatus: http.StatusBadRequest},
+		{name: "not an object", body: `[{"name": "x"}]`, expectedStatus: http.StatusBadRequest},
+		{name: "null name", body: `{"name": null}`, expectedStatus: http.StatusBadRequest},
+		{name: "blank name", body: `{"name": "  "}`, expectedStatus: http.StatusBadRequest},
+		{name: "zero price", body: `{"price": "0"}`, expectedStatus: http.StatusBadRequest},
+		{name: "stock not allowed", body: `{"stock": 10}`, expectedStatus: http.StatusBadRequest},
+		{name: "id not allowed", body: `{"id": 2, "name": "新名称"}`, expectedStatus: http.StatusBadRequest},
+		{name: "created_at not allowed", body: `{"created_at": "2024-01-01T00:00:00Z"}`, expectedStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockProductService := new(MockProductService)
+			if tt.expectedPatch != nil {
+				mockProductService.On("Update", int64(1), int64(0), *tt.expectedPatch).
+					Return(&model.Product{ID: 1, Version: 2}, nil)
+			}
+
+			handlers := NewHandlers(new(MockUserService), new(MockJWTService), mockProductService, new(MockOrderService))
+			router := gin.New()
+			router.PUT("/products/:id", handlers.UpdateProduct)
+
+			req := httptest.NewRequest(http.MethodPut, "/products/1", bytes.NewBufferString(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			resp := httptest.NewRecorder()
+
+			router.ServeHTTP(resp, req)
+
+			assert.Equal(t, tt.expectedStatus, resp.Code)
+			mockProductService.AssertExpectations(t)
+			if tt.expectedPatch == nil {
+				mockProductService.AssertNotCalled(t, "Update", mock.Anything, mock.Anything, mock.Anything)
+			}
+		})
+	}
+}
+
+func TestHandlers_CreateProduct(t *testing.T) {
+	err := logger.Setup(&logger.Config{
+		Level:    "info",
+		Format:   "console",
+		Console:  true,
+		Filename: "",
+	})
+	assert.NoError(t, err)
+
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name           string
+		body           string
+		expected       *model.Product
+		expectedStatus int
+	}{
+		{
+			name: "valid",
+			body: `{"name": "绿茶", "price": "25.00", "stock": 0, "prices": [{"currency": "USD", "price": "3.50"}],
+				"skus": [{"code": "TEA-S", "stock": 5, "is_default": true}], "category_ids": [3]}`,
+			expected: &model.Product{
+				Name:        "绿茶",
+				Price:       money.MustParseAmount("25.00"),
+				Prices:      []model.ProductPrice{{Currency: "USD", Price: money.MustParseAmount("3.50")}},
+				SKUs:        []model.ProductSKU{{Code: "TEA-S", Stock: 5, IsDefault: true}},
+				CategoryIDs: []int64{3},
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{name: "missing name", body: `{"price": "25.00"}`, expectedStatus: http.StatusBadRequest},
+		{name: "zero price", body: `{"name": "绿茶", "price": "0"}`, expectedStatus: http.StatusBadRequest},
+		{name: "negative stock", body: `{"name": "绿茶", "price": "25.00", "stock": -1}`, expectedStatus: http.StatusBadRequest},
+		{name: "invalid sku", body: `{"name": "绿茶", "price": "25.00", "skus": [{"stock": 1}]}`, expectedStatus: http.StatusBadRequest},
+		{name: "invalid category", body: `{"name": "绿茶", "price": "25.00", "category_ids": [0]}`, expectedStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockProductService := new(MockProductService)
+			if tt.expected != nil {
+				mockProductService.On("Create", int64(9), tt.expected).Return(nil)
+			}
+
+			handlers := NewHandlers(new(MockUserService), new(MockJWTService), mockProductService, new(MockOrderService))
+			router := gin.New()
+			router.POST("/products", func(c *gin.Context) {
+				c.Set("user_id", int64(9))
+				handlers.CreateProduct(c)
+			})
+
+			req := httptest.NewRequest(http.MethodPost, "/products", bytes.NewBufferString(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			resp := httptest.NewRecorder()
+
+			router.ServeHTTP(resp, req)
+
+			assert.Equal(t, tt.expectedStatus, resp.Code)
+			mockProductService.AssertExpectations(t)
+			if tt.expected == nil {
+				mockProductService.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+			}
+		})
+	}
+}
@@ -0,0 +1,146 @@
+package handlers
+
+import (
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ylh990835774/blockchain-shop-demo/pkg/errors"
+)
+
+// CreateReturn 买家为已完成订单申请退货
+func (h *Handlers) CreateReturn(c *gin.Context) {
+	orderID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		handleError(c, errors.ErrInvalidInput, "申请退货-参数验证")
+		return
+	}
+
+	var req CreateReturnRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		handleError(c, errors.ErrInvalidInput, "申请退货-参数验证")
+		return
+	}
+
+	userID := c.GetInt64("user_id")
+	ret, err := h.returnService.Request(userID, orderID, req.Quantity, req.Reason)
+	if err != nil {
+		handleError(c, err, "申请退货")
+		return
+	}
+
+	handleSuccess(c, ret, "申请退货")
+}
+
+// ListOrderReturns 获取订单的退货申请
+func (h *Handlers) ListOrderReturns(c *gin.Context) {
+	userID := c.GetInt64("user_id")
+	orderID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		handleError(c, errors.ErrInvalidInput, "获取退货申请-参数验证")
+		return
+	}
+
+	order, err := h.orderService.GetByID(orderID)
+	if err != nil {
+		handleError(c, err, "获取退货申请-订单验证")
+		return
+	}
+
+	// 验证订单所属用户
+	if order.UserID != userID {
+		handleError(c, errors.ErrUnauthorized, "获取退货申请-权限验证")
+		return
+	}
+
+	returns, err := h.returnService.ListByOrderID(orderID)
+	if err != nil {
+		handleError(c, err, "获取退货申请")
+		return
+	}
+
+	handleSuccess(c, returns, "获取退货申请")
+}
+
+// GetReturn 商户获取退货申请详情
+func (h *Handlers) GetReturn(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		handleError(c, errors.ErrInvalidInput, "获取退货详情-参数验证")
+		return
+	}
+
+	ret, err := h.returnService.GetByID(id)
+	if err != nil {
+		handleError(c, err, "获取退货详情")
+		return
+	}
+
+	handleSuccess(c, ret, "获取退货详情")
+}
+
+// ApproveReturn 商户同意退货
+func (h *Handlers) ApproveReturn(c *gin.Context) {
+	id, req, ok := bindReviewReturn(c, "同意退货-参数验证")
+	if !ok {
+		return
+	}
+
+	ret, err := h.returnService.Approve(id, req.Note)
+	if err != nil {
+		handleError(c, err, "同意退货")
+		return
+	}
+
+	handleSuccess(c, ret, "同意退货")
+}
+
+// RejectReturn 商户拒绝退货
+func (h *Handlers) RejectReturn(c *gin.Context) {
+	id, req, ok := bindReviewReturn(c, "拒绝退货-参数验证")
+	if !ok {
+		return
+	}
+
+	ret, err := h.returnService.Reject(id, req.Note)
+	if err != nil {
+		handleError(c, err, "拒绝退货")
+		return
+	}
+
+	handleSuccess(c, ret, "拒绝退货")
+}
+
+// ReceiveReturn 商户确认收到退回商品，恢复库存并退款
+func (h *Handlers) ReceiveReturn(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		handleError(c, errors.ErrInvalidInput, "退货入库-参数验证")
+		return
+	}
+
+	ret, err := h.returnService.Receive(id)
+	if err != nil {
+		handleError(c, err, "退货入库")
+		return
+	}
+
+	handleSuccess(c, ret, "退货入库")
+}
+
+// bindReviewReturn 解析审核退货的路径参数和请求体，审核备注可为空
+func bindReviewReturn(c *gin.Context, operation string) (int64, *ReviewReturnRequest, bool) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		handleError(c, errors.ErrInvalidInput, operation)
+		return 0, nil, false
+	}
+
+	var req ReviewReturnRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			handleError(c, errors.ErrInvalidInput, operation)
+			return 0, nil, false
+		}
+	}
+	return id, &req, true
+}
@@ -0,0 +1,108 @@
+package handlers
+
+import (
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ylh990835774/blockchain-shop-demo/internal/model"
+	"github.com/ylh990835774/blockchain-shop-demo/pkg/errors"
+)
+
+// CreateShipment 商户为已支付订单发货
+func (h *Handlers) CreateShipment(c *gin.Context) {
+	orderID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		handleError(c, errors.ErrInvalidInput, "订单发货-参数验证")
+		return
+	}
+
+	var req CreateShipmentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		handleError(c, errors.ErrInvalidInput, "订单发货-参数验证")
+		return
+	}
+
+	shipment, err := h.shipmentService.Create(orderID, req.Carrier, req.TrackingNumber)
+	if err != nil {
+		handleError(c, err, "订单发货")
+		return
+	}
+
+	handleSuccess(c, shipment, "订单发货")
+}
+
+// AddShipmentEvent 商户更新物流跟踪信息
+func (h *Handlers) AddShipmentEvent(c *gin.Context) {
+	shipmentID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		handleError(c, errors.ErrInvalidInput, "更新物流信息-参数验证")
+		return
+	}
+
+	var req ShipmentEventRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		handleError(c, errors.ErrInvalidInput, "更新物流信息-参数验证")
+		return
+	}
+
+	shipment, err := h.shipmentService.AddEvent(shipmentID, &model.ShipmentEvent{
+		Status:      req.Status,
+		Location:    req.Location,
+		Description: req.Description,
+		OccurredAt:  req.OccurredAt,
+	})
+	if err != nil {
+		handleError(c, err, "更新物流信息")
+		return
+	}
+
+	handleSuccess(c, shipment, "更新物流信息")
+}
+
+// GetOrderShipment 获取订单的发货和物流信息
+func (h *Handlers) GetOrderShipment(c *gin.Context) {
+	userID := c.GetInt64("user_id")
+	orderID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		handleError(c, errors.ErrInvalidInput, "获取物流信息-参数验证")
+		return
+	}
+
+	order, err := h.orderService.GetByID(orderID)
+	if err != nil {
+		handleError(c, err, "获取物流信息-订单验证")
+		return
+	}
+
+	// 验证订单所属用户
+	if order.UserID != userID {
+		handleError(c, errors.ErrUnauthorized, "获取物流信息-权限验证")
+		return
+	}
+
+	shipment, err := h.shipmentService.GetByOrderID(orderID)
+	if err != nil {
+		handleError(c, err, "获取物流信息")
+		return
+	}
+
+	handleSuccess(c, shipment, "获取物流信息")
+}
+
+// ConfirmDelivery 买家确认收货
+func (h *Handlers) ConfirmDelivery(c *gin.Context) {
+	orderID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		handleError(c, errors.ErrInvalidInput, "确认收货-参数验证")
+		return
+	}
+
+	userID := c.GetInt64("user_id")
+	shipment, err := h.shipmentService.ConfirmDelivery(userID, orderID)
+	if err != nil {
+		handleError(c, err, "确认收货")
+		return
+	}
+
+	handleSuccess(c, shipment, "确认收货")
+}
@@ -1,5 +1,12 @@
 package handlers
 
+import (
+	"time"
+
+	"github.com/ylh990835774/blockchain-shop-demo/internal/model"
+	"github.com/ylh990835774/blockchain-shop-demo/pkg/money"
+)
+
 // RegisterRequest 用户注册请求
 type RegisterRequest struct {
 	Username string `json:"username" binding:"required"`
@@ -12,22 +19,202 @@ type LoginRequest struct {
 	Password string `json:"password" binding:"required"`
 }
 
-// UpdateProfileRequest 更新用户资料请求
+// UpdateProfileRequest 更新用户资料请求，按JSON Merge Patch语义：未出现的字段保持不变，值为null或空字符串时清空
 type UpdateProfileRequest struct {
-	Phone   string `json:"phone"`
-	Address string `json:"address"`
+	Phone    patchField[string] `json:"phone"`
+	Address  patchField[string] `json:"address"`
+	Currency patchField[string] `json:"currency"` // 清空后使用商品基础币种
 }
 
 // 商品相关请求结构体
 type CreateProductRequest struct {
-	Name        string  `json:"name" binding:"required"`
-	Description string  `json:"description"`
-	Price       float64 `json:"price" binding:"required,gt=0"`
-	Stock       int     `json:"stock" binding:"required,gte=0"`
+	Name        string                `json:"name" binding:"required,max=255"`
+	Description string                `json:"description" binding:"max=10000"`
+	Price       money.Amount          `json:"price" binding:"required,gt=0"`
+	Currency    string                `json:"currency" binding:"omitempty,len=3,uppercase"` // 商品基础币种，为空时使用默认币种
+	Stock       int                   `json:"stock" binding:"gte=0"`                        // 未指定SKU时作为默认SKU的库存
+	TaxCategory string                `json:"tax_category" binding:"omitempty,max=32"`      // 为空时使用standard
+	Prices      []ProductPriceRequest `json:"prices" binding:"dive"`
+	SKUs        []SKURequest          `json:"skus" binding:"dive"`
+	CategoryIDs []int64               `json:"category_ids" binding:"dive,gt=0"`
+}
+
+// ProductPriceRequest 创建商品时指定的其他币种定价
+type ProductPriceRequest struct {
+	Currency string       `json:"currency" binding:"required,len=3,uppercase"`
+	Price    money.Amount `json:"price" binding:"required,gt=0"`
+}
+
+// UpdateProductRequest 更新商品请求，按JSON Merge Patch语义：未出现的字段保持不变，
+// 只有描述可以为null以清空。只能更新以下字段，库存通过SKU维护，上下架和分类通过专门的接口修改
+type UpdateProductRequest struct {
+	Name        patchField[string]       `json:"name"`
+	Description patchField[string]       `json:"description"`
+	Price       patchField[money.Amount] `json:"price"`
+	TaxCategory patchField[string]       `json:"tax_category"`
 }
 
 // 订单相关请求结构体
 type CreateOrderRequest struct {
-	ProductID int64 `json:"product_id" binding:"required"`
-	Quantity  int   `json:"quantity" binding:"required,gt=0"`
+	ProductID  int64  `json:"product_id" binding:"required"`
+	SKUID      int64  `json:"sku_id" binding:"omitempty,gt=0"` // 为空时使用商品的默认SKU
+	Quantity   int    `json:"quantity" binding:"required,gt=0"`
+	Currency   string `json:"currency" binding:"omitempty,len=3,uppercase"` // 为空时使用用户偏好币种
+	Region     string `json:"region" binding:"omitempty,len=2,uppercase"`   // 计税税区，为空时使用默认税区
+	CouponCode string `json:"coupon_code" binding:"omitempty,max=32"`
+}
+
+// CouponRequest 创建或更新优惠券请求
+type CouponRequest struct {
+	Code         string           `json:"code" binding:"required,max=32"`
+	Type         model.CouponType `json:"type" binding:"required,oneof=percent fixed"`
+	PercentOff   money.Rate       `json:"percent_off"` // 按比例折扣时必填，0.2表示减免20%
+	AmountOff    money.Amount     `json:"amount_off"`  // 固定金额减免时必填
+	Currency     string           `json:"currency" binding:"omitempty,len=3,uppercase"`
+	MinAmount    money.Amount     `json:"min_amount" binding:"gte=0"`
+	UsageLimit   int              `json:"usage_limit" binding:"gte=0"`
+	PerUserLimit int              `json:"per_user_limit" binding:"gte=0"`
+	StartsAt     *time.Time       `json:"starts_at"`
+	EndsAt       *time.Time       `json:"ends_at"`
+	Disabled     bool             `json:"disabled"`
+	ProductIDs   []int64          `json:"product_ids"`
+}
+
+// CategoryRequest 创建或更新分类请求
+type CategoryRequest struct {
+	Name     string `json:"name" binding:"required,max=64"`
+	Slug     string `json:"slug" binding:"required,max=64"`
+	ParentID *int64 `json:"parent_id" binding:"omitempty,gt=0"` // 为空时为顶级分类
+	Position int    `json:"position" binding:"gte=0"`
+}
+
+// SetProductCategoriesRequest 设置商品分类请求
+type SetProductCategoriesRequest struct {
+	CategoryIDs []int64 `json:"category_ids" binding:"omitempty,dive,gt=0"`
+}
+
+// ListProductsQuery 商品列表查询参数
+type ListProductsQuery struct {
+	Page       int    `form:"page,default=1"`
+	PageSize   int    `form:"page_size,default=10"`
+	Query      string `form:"q"`                                    // 按名称和描述搜索
+	CategoryID int64  `form:"category_id" binding:"omitempty,gt=0"` // 包含子分类下的商品
+	Currency   string `form:"currency"`                             // 商品基础币种
+	MinPrice   string `form:"min_price"`
+	MaxPrice   string `form:"max_price"`
+	InStock    bool   `form:"in_stock"`
+	Sort       string `form:"sort" binding:"omitempty,oneof=relevance created_at price name"`
+	Order      string `form:"order" binding:"omitempty,oneof=asc desc"`
+
+	withArchived bool // 包含已下架的商品，仅管理员接口设置
+}
+
+// ImportProductsQuery 商品导入参数
+type ImportProductsQuery struct {
+	Format    string `form:"format" binding:"omitempty,oneof=csv ndjson"` // 为空时按Content-Type识别
+	DryRun    bool   `form:"dry_run"`
+	ChunkSize int    `form:"chunk_size" binding:"gte=0,lte=1000"` // 每个事务导入的行数，为0时全部行在一个事务内导入
+}
+
+// ExportProductsQuery 商品导出参数，过滤条件与商品列表相同
+type ExportProductsQuery struct {
+	ListProductsQuery
+	Format string `form:"format" binding:"omitempty,oneof=csv ndjson"` // 默认为csv
+}
+
+// ListOrdersQuery 订单列表查询参数
+type ListOrdersQuery struct {
+	Page        int    `form:"page"`
+	PageSize    int    `form:"page_size"`
+	UserID      int64  `form:"user_id" binding:"omitempty,gt=0"` // 仅管理员接口可用
+	Status      string `form:"status"`                           // 多个状态以逗号分隔
+	ProductID   int64  `form:"product_id" binding:"omitempty,gt=0"`
+	CreatedFrom string `form:"created_from"` // 日期（2006-01-02）或RFC3339时间，包含
+	CreatedTo   string `form:"created_to"`   // 日期时包含当天，RFC3339时间时不包含
+	MinTotal    string `form:"min_total"`
+	MaxTotal    string `form:"max_total"`
+	Sort        string `form:"sort" binding:"omitempty,oneof=created_at total"`
+	Order       string `form:"order" binding:"omitempty,oneof=asc desc"`
+}
+
+// SetProductPriceRequest 设置商品币种定价请求
+type SetProductPriceRequest struct {
+	Price money.Amount `json:"price" binding:"required,gt=0"`
+}
+
+// SKURequest 创建或更新商品SKU请求
+type SKURequest struct {
+	Code              string            `json:"code" binding:"required,max=64"`
+	Options           map[string]string `json:"options"`                        // 规格属性，如{"颜色": "红"}
+	Price             *money.Amount     `json:"price" binding:"omitempty,gt=0"` // 为空时使用商品价格
+	Stock             int               `json:"stock" binding:"gte=0"`
+	IsDefault         bool              `json:"is_default"`
+	LowStockThreshold *int              `json:"low_stock_threshold" binding:"omitempty,gte=0"` // 低库存告警阈值，为空时使用全局配置
+}
+
+// 库存相关请求结构体
+type RestockRequest struct {
+	Quantity int    `json:"quantity" binding:"required,gt=0"`
+	Reason   string `json:"reason" binding:"max=255"` // 如采购单号，可选
+}
+
+// AdjustStockRequest 调整库存请求，quantity为负数时扣减
+type AdjustStockRequest struct {
+	Quantity int    `json:"quantity" binding:"required,ne=0"`
+	Reason   string `json:"reason" binding:"required,max=255"`
+}
+
+// 发货相关请求结构体
+type CreateShipmentRequest struct {
+	Carrier        string `json:"carrier" binding:"required,max=64"`
+	TrackingNumber string `json:"tracking_number" binding:"required,max=128"`
+}
+
+// ShipmentEventRequest 物流跟踪事件请求
+type ShipmentEventRequest struct {
+	Status      model.ShipmentStatus `json:"status" binding:"required"`
+	Location    string               `json:"location" binding:"max=255"`
+	Description string               `json:"description" binding:"max=512"`
+	OccurredAt  time.Time            `json:"occurred_at"` // 为空时使用当前时间
+}
+
+// 退货相关请求结构体
+type CreateReturnRequest struct {
+	Quantity int    `json:"quantity" binding:"required,gt=0"`
+	Reason   string `json:"reason" binding:"required,max=512"`
+}
+
+// ReviewReturnRequest 商户审核退货请求
+type ReviewReturnRequest struct {
+	Note string `json:"note" binding:"max=512"`
+}
+
+// 支付相关请求结构体
+type CreatePaymentRequest struct {
+	Provider string `json:"provider" binding:"required"`
+}
+
+// 链上支付相关请求结构体
+type CryptoPaymentRequest struct {
+	From      string `json:"from" binding:"required"`
+	To        string `json:"to" binding:"required"`
+	Value     string `json:"value" binding:"required"`
+	Nonce     uint64 `json:"nonce"`
+	PublicKey string `json:"public_key" binding:"required"`
+	Signature string `json:"signature" binding:"required"`
+}
+
+type FaucetRequest struct {
+	Address string `json:"address" binding:"required"`
+	Value   string `json:"value" binding:"required"`
+}
+
+// BlockResponse 区块信息，哈希以十六进制表示
+type BlockResponse struct {
+	Height    int       `json:"height"`
+	Timestamp time.Time `json:"timestamp"`
+	Hash      string    `json:"hash"`
+	PrevHash  string    `json:"prev_hash"`
+	Nonce     int       `json:"nonce"`
+	Data      string    `json:"data"`
 }
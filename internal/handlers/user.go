@@ -1,7 +1,11 @@
 package handlers
 
 import (
+	"strings"
+	"unicode/utf8"
+
 	"github.com/gin-gonic/gin"
+	"github.com/ylh990835774/blockchain-shop-demo/internal/model"
 	"github.com/ylh990835774/blockchain-shop-demo/pkg/errors"
 )
 
@@ -66,36 +70,54 @@ func (h *Handlers) GetProfile(c *gin.Context) {
 	}, "获取用户资料")
 }
 
+// UpdateProfile 更新用户资料，按JSON Merge Patch语义只更新请求中出现的字段
 func (h *Handlers) UpdateProfile(c *gin.Context) {
-	var req struct {
-		Phone   string `json:"phone" binding:"omitempty,len=11"`
-		Address string `json:"address" binding:"omitempty"`
-	}
-	if err := c.ShouldBindJSON(&req); err != nil {
-		handleError(c, errors.ErrInvalidInput, "更新用户资料-参数验证")
+	var req UpdateProfileRequest
+	if err := bindPatch(c, &req); err != nil {
+		handleError(c, err, "更新用户资料-参数验证")
 		return
 	}
-
-	userID := c.GetInt64("user_id")
-	updates := make(map[string]interface{})
-
-	if req.Phone != "" {
-		updates["phone"] = req.Phone
-	}
-	if req.Address != "" {
-		updates["address"] = req.Address
+	patch, err := req.toPatch()
+	if err != nil {
+		handleError(c, err, "更新用户资料-参数验证")
+		return
 	}
 
 	// 如果没有要更新的字段，返回错误
-	if len(updates) == 0 {
+	if patch.IsEmpty() {
 		handleError(c, errors.ErrNoFieldsToUpdate, "更新用户资料-无更新字段")
 		return
 	}
 
-	if err := h.userService.Update(userID, updates); err != nil {
+	userID := c.GetInt64("user_id")
+	if err := h.userService.Update(userID, patch); err != nil {
 		handleError(c, err, "更新用户资料")
 		return
 	}
 
 	handleSuccess(c, nil, "更新用户资料")
 }
+
+// toPatch 校验并转换为用户资料更新，null和空字符串均表示清空
+func (r *UpdateProfileRequest) toPatch() (model.UserPatch, error) {
+	var patch model.UserPatch
+	if r.Phone.Set {
+		if r.Phone.Value != "" && utf8.RuneCountInString(r.Phone.Value) != phoneLength {
+			return patch, errors.ErrInvalidInput
+		}
+		patch.Phone = r.Phone.optional()
+	}
+	if r.Address.Set {
+		if utf8.RuneCountInString(r.Address.Value) > maxAddressLength {
+			return patch, errors.ErrInvalidInput
+		}
+		patch.Address = r.Address.optional()
+	}
+	if r.Currency.Set {
+		if currency := r.Currency.Value; currency != "" && (len(currency) != 3 || currency != strings.ToUpper(currency)) {
+			return patch, errors.ErrInvalidInput
+		}
+		patch.Currency = r.Currency.optional()
+	}
+	return patch, nil
+}
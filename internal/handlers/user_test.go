@@ -3,6 +3,7 @@ package handlers
 import (
 	"bytes"
 	"encoding/json"
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -10,9 +11,12 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/ylh990835774/blockchain-shop-demo/internal/catalog"
 	"github.com/ylh990835774/blockchain-shop-demo/internal/model"
+	"github.com/ylh990835774/blockchain-shop-demo/internal/service"
 	customerrors "github.com/ylh990835774/blockchain-shop-demo/pkg/errors"
 	"github.com/ylh990835774/blockchain-shop-demo/pkg/logger"
+	"github.com/ylh990835774/blockchain-shop-demo/pkg/money"
 )
 
 // MockUserService 是用户服务的mock实现
@@ -44,8 +48,8 @@ func (m *MockUserService) GetByID(id int64) (*model.User, error) {
 	return args.Get(0).(*model.User), args.Error(1)
 }
 
-func (m *MockUserService) Update(id int64, updates map[string]interface{}) error {
-	args := m.Called(id, updates)
+func (m *MockUserService) Update(id int64, patch model.UserPatch) error {
+	args := m.Called(id, patch)
 	return args.Error(0)
 }
 
@@ -77,14 +81,17 @@ type MockProductService struct {
 	mock.Mock
 }
 
-func (m *MockProductService) Create(product *model.Product) error {
-	args := m.Called(product)
+func (m *MockProductService) Create(actorID int64, product *model.Product) error {
+	args := m.Called(actorID, product)
 	return args.Error(0)
 }
 
-func (m *MockProductService) Update(id int64, updates map[string]interface{}) error {
-	args := m.Called(id, updates)
-	return args.Error(0)
+func (m *MockProductService) Update(id, version int64, patch model.ProductPatch) (*model.Product, error) {
+	args := m.Called(id, version, patch)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.Product), args.Error(1)
 }
 
 func (m *MockProductService) Delete(id int64) error {
@@ -92,6 +99,42 @@ func (m *MockProductService) Delete(id int64) error {
 	return args.Error(0)
 }
 
+func (m *MockProductService) Import(r io.Reader, format catalog.Format, opts service.ProductImportOptions) (*service.ProductImportResult, error) {
+	args := m.Called(r, format, opts)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*service.ProductImportResult), args.Error(1)
+}
+
+func (m *MockProductService) Export(filter model.ProductFilter, fn func([]*model.Product) error) error {
+	args := m.Called(filter, fn)
+	if products, ok := args.Get(0).([]*model.Product); ok && products != nil {
+		if err := fn(products); err != nil {
+			return err
+		}
+	}
+	return args.Error(1)
+}
+
+func (m *MockProductService) PriceHistory(productID int64, page, pageSize int) ([]*model.ProductPriceHistory, int64, error) {
+	args := m.Called(productID, page, pageSize)
+	if args.Get(0) == nil {
+		return nil, args.Get(1).(int64), args.Error(2)
+	}
+	return args.Get(0).([]*model.ProductPriceHistory), args.Get(1).(int64), args.Error(2)
+}
+
+func (m *MockProductService) Archive(id int64) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
+
+func (m *MockProductService) Unarchive(id int64) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
+
 func (m *MockProductService) GetByID(id int64) (*model.Product, error) {
 	args := m.Called(id)
 	if args.Get(0) == nil {
@@ -100,19 +143,70 @@ func (m *MockProductService) GetByID(id int64) (*model.Product, error) {
 	return args.Get(0).(*model.Product), args.Error(1)
 }
 
-func (m *MockProductService) List(page, pageSize int) ([]*model.Product, int64, error) {
-	args := m.Called(page, pageSize)
+func (m *MockProductService) List(filter model.ProductFilter, page, pageSize int) ([]*model.Product, int64, error) {
+	args := m.Called(filter, page, pageSize)
 	return args.Get(0).([]*model.Product), args.Get(1).(int64), args.Error(2)
 }
 
+func (m *MockProductService) SetCategories(productID int64, categoryIDs []int64) error {
+	args := m.Called(productID, categoryIDs)
+	return args.Error(0)
+}
+
+func (m *MockProductService) Facets(filter model.ProductFilter) (*model.ProductFacets, error) {
+	args := m.Called(filter)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.ProductFacets), args.Error(1)
+}
+
+func (m *MockProductService) ListByCursor(filter model.ProductFilter, after string, limit int) ([]*model.Product, string, error) {
+	args := m.Called(filter, after, limit)
+	return args.Get(0).([]*model.Product), args.String(1), args.Error(2)
+}
+
+func (m *MockProductService) SetPrice(productID int64, currency string, price money.Amount) error {
+	args := m.Called(productID, currency, price)
+	return args.Error(0)
+}
+
+func (m *MockProductService) DeletePrice(productID int64, currency string) error {
+	args := m.Called(productID, currency)
+	return args.Error(0)
+}
+
+func (m *MockProductService) CreateSKU(actorID, productID int64, sku *model.ProductSKU) error {
+	args := m.Called(actorID, productID, sku)
+	return args.Error(0)
+}
+
+func (m *MockProductService) UpdateSKU(actorID, productID int64, sku *model.ProductSKU) error {
+	args := m.Called(actorID, productID, sku)
+	return args.Error(0)
+}
+
+func (m *MockProductService) DeleteSKU(actorID, productID, skuID int64) error {
+	args := m.Called(actorID, productID, skuID)
+	return args.Error(0)
+}
+
+func (m *MockProductService) Quote(product *model.Product, sku *model.ProductSKU, currency string) (money.Amount, money.Rate, error) {
+	args := m.Called(product, sku, currency)
+	return args.Get(0).(money.Amount), args.Get(1).(money.Rate), args.Error(2)
+}
+
 // MockOrderService 是订单服务的mock实现
 type MockOrderService struct {
 	mock.Mock
 }
 
-func (m *MockOrderService) Create(order *model.Order) error {
-	args := m.Called(order)
-	return args.Error(0)
+func (m *MockOrderService) Create(input *service.CreateOrderInput) (*model.Order, error) {
+	args := m.Called(input)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.Order), args.Error(1)
 }
 
 func (m *MockOrderService) GetByID(id int64) (*model.Order, error) {
@@ -123,11 +217,16 @@ func (m *MockOrderService) GetByID(id int64) (*model.Order, error) {
 	return args.Get(0).(*model.Order), args.Error(1)
 }
 
-func (m *MockOrderService) ListByUserID(userID int64, page, pageSize int) ([]*model.Order, int64, error) {
-	args := m.Called(userID, page, pageSize)
+func (m *MockOrderService) List(filter model.OrderFilter, page, pageSize int) ([]*model.Order, int64, error) {
+	args := m.Called(filter, page, pageSize)
 	return args.Get(0).([]*model.Order), args.Get(1).(int64), args.Error(2)
 }
 
+func (m *MockOrderService) ListByCursor(filter model.OrderFilter, after string, limit int) ([]*model.Order, string, error) {
+	args := m.Called(filter, after, limit)
+	return args.Get(0).([]*model.Order), args.String(1), args.Error(2)
+}
+
 func (m *MockOrderService) GetTransaction(orderID int64) (*model.Transaction, error) {
 	args := m.Called(orderID)
 	if args.Get(0) == nil {
@@ -136,6 +235,16 @@ func (m *MockOrderService) GetTransaction(orderID int64) (*model.Transaction, er
 	return args.Get(0).(*model.Transaction), args.Error(1)
 }
 
+func (m *MockOrderService) MarkPaid(id int64) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
+
+func (m *MockOrderService) Expire(id int64) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
+
 func TestHandlers_Login(t *testing.T) {
 	// 初始化日志配置
 	err := logger.Setup(&logger.Config{
@@ -344,10 +453,8 @@ func TestHandlers_UpdateProfile(t *testing.T) {
 		{
 			name: "successful_update_both_fields",
 			setupMock: func(m *MockUserService) {
-				m.On("Update", int64(1), map[string]interface{}{
-					"phone":   "12345678901",
-					"address": "test address",
-				}).Return(nil)
+				phone, address := "12345678901", "test address"
+				m.On("Update", int64(1), model.UserPatch{Phone: &phone, Address: &address}).Return(nil)
 			},
 			userID: 1,
 			requestBody: map[string]interface{}{
@@ -364,9 +471,8 @@ func TestHandlers_UpdateProfile(t *testing.T) {
 		{
 			name: "successful_update_phone_only",
 			setupMock: func(m *MockUserService) {
-				m.On("Update", int64(1), map[string]interface{}{
-					"phone": "12345678901",
-				}).Return(nil)
+				phone := "12345678901"
+				m.On("Update", int64(1), model.UserPatch{Phone: &phone}).Return(nil)
 			},
 			userID: 1,
 			requestBody: map[string]interface{}{
@@ -382,9 +488,8 @@ func TestHandlers_UpdateProfile(t *testing.T) {
 		{
 			name: "successful_update_address_only",
 			setupMock: func(m *MockUserService) {
-				m.On("Update", int64(1), map[string]interface{}{
-					"address": "test address",
-				}).Return(nil)
+				address := "test address"
+				m.On("Update", int64(1), model.UserPatch{Address: &address}).Return(nil)
 			},
 			userID: 1,
 			requestBody: map[string]interface{}{
@@ -421,12 +526,56 @@ func TestHandlers_UpdateProfile(t *testing.T) {
 				"data":    nil,
 			},
 		},
+		{
+			name: "successful_clear_fields",
+			setupMock: func(m *MockUserService) {
+				empty := ""
+				m.On("Update", int64(1), model.UserPatch{Address: &empty, Currency: &empty}).Return(nil)
+			},
+			userID: 1,
+			requestBody: map[string]interface{}{
+				"address":  nil,
+				"currency": "",
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody: map[string]interface{}{
+				"code":    float64(200),
+				"message": "success",
+				"data":    nil,
+			},
+		},
+		{
+			name:   "invalid_unknown_field",
+			userID: 1,
+			requestBody: map[string]interface{}{
+				"phone": "12345678901",
+				"role":  "admin",
+			},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody: map[string]interface{}{
+				"code":    float64(-1),
+				"message": "无效的输入",
+				"data":    nil,
+			},
+		},
+		{
+			name:   "invalid_currency",
+			userID: 1,
+			requestBody: map[string]interface{}{
+				"currency": "usd",
+			},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody: map[string]interface{}{
+				"code":    float64(-1),
+				"message": "无效的输入",
+				"data":    nil,
+			},
+		},
 		{
 			name: "user_not_found",
 			setupMock: func(m *MockUserService) {
-				m.On("Update", int64(1), map[string]interface{}{
-					"phone": "12345678901",
-				}).Return(customerrors.ErrNotFound)
+				phone := "12345678901"
+				m.On("Update", int64(1), model.UserPatch{Phone: &phone}).Return(customerrors.ErrNotFound)
 			},
 			userID: 1,
 			requestBody: map[string]interface{}{
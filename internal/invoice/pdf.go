@@ -0,0 +1,217 @@
+package invoice
+
+import (
+	"bytes"
+	"fmt"
+	"image/png"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/boombuler/barcode"
+	"github.com/boombuler/barcode/qr"
+	"github.com/go-pdf/fpdf"
+	"github.com/ylh990835774/blockchain-shop-demo/internal/model"
+	"github.com/ylh990835774/blockchain-shop-demo/pkg/money"
+)
+
+// qrSize 二维码图片的像素边长
+const qrSize = 256
+
+// Renderer 将发票渲染为PDF
+type Renderer struct {
+	fontFile      string
+	verifyBaseURL string
+}
+
+// NewRenderer 创建发票渲染器。fontFile为TrueType字体路径，用于显示中文等非拉丁字符，
+// 为空时使用PDF内置字体，无法显示的字符以"."代替
+func NewRenderer(fontFile, verifyBaseURL string) *Renderer {
+	return &Renderer{
+		fontFile:      fontFile,
+		verifyBaseURL: strings.TrimRight(verifyBaseURL, "/"),
+	}
+}
+
+// VerifyURL 发票核验地址，编码在PDF的二维码中
+func (r *Renderer) VerifyURL(number, token string) string {
+	return r.verifyBaseURL + "/api/v1/invoices/" + url.PathEscape(number) + "/verify?token=" + url.QueryEscape(token)
+}
+
+// RenderPDF 渲染发票PDF，内容包含订单的链上交易哈希和核验二维码
+func (r *Renderer) RenderPDF(inv *model.Invoice) ([]byte, error) {
+	pdf := fpdf.New("P", "mm", "A4", "")
+	pdf.SetTitle("Invoice "+inv.Number, true)
+	// 固定创建时间和对象顺序，同一张发票每次渲染的结果相同
+	pdf.SetCreationDate(inv.IssuedAt)
+	pdf.SetCatalogSort(true)
+
+	family, text := "Helvetica", pdf.UnicodeTranslatorFromDescriptor("")
+	if r.fontFile != "" {
+		family, text = "invoice", func(s string) string { return s }
+		pdf.AddUTF8Font(family, "", r.fontFile)
+	}
+	font := func(bold bool, size float64) {
+		style := ""
+		if bold && r.fontFile == "" {
+			style = "B"
+		}
+		pdf.SetFont(family, style, size)
+	}
+
+	pdf.AddPage()
+
+	font(true, 20)
+	pdf.CellFormat(0, 12, "INVOICE", "", 1, "L", false, 0, "")
+
+	font(false, 10)
+	for _, row := range [][2]string{
+		{"Invoice No.", inv.Number},
+		{"Issue Date", inv.IssuedAt.Format("2006-01-02")},
+		{"Order No.", strconv.FormatInt(inv.OrderID, 10)},
+		{"Currency", inv.Currency},
+	} {
+		pdf.CellFormat(30, 6, row[0], "", 0, "L", false, 0, "")
+		pdf.CellFormat(0, 6, text(row[1]), "", 1, "L", false, 0, "")
+	}
+	pdf.Ln(4)
+
+	// 销售方和购买方信息
+	top := pdf.GetY()
+	party := func(x float64, title string, lines ...string) {
+		pdf.SetXY(x, top)
+		font(true, 11)
+		pdf.CellFormat(90, 7, title, "", 2, "L", false, 0, "")
+		font(false, 10)
+		for _, line := range lines {
+			if line != "" {
+				pdf.MultiCell(90, 5, text(line), "", "L", false)
+				pdf.SetX(x)
+			}
+		}
+	}
+	party(10, "Seller", inv.SellerName, taxID(inv.SellerTaxID), inv.SellerAddress)
+	sellerBottom := pdf.GetY()
+	party(110, "Buyer", inv.BuyerName, inv.BuyerAddress)
+	pdf.SetY(maxFloat(sellerBottom, pdf.GetY()) + 6)
+
+	// 明细行
+	widths := []float64{62, 14, 22, 22, 16, 20, 24}
+	headers := []string{"Description", "Qty", "Unit Price", "Net", "Tax Rate", "Tax", "Amount"}
+	font(true, 9)
+	pdf.SetFillColor(235, 235, 235)
+	for i, header := range headers {
+		align := "R"
+		if i == 0 {
+			align = "L"
+		}
+		pdf.CellFormat(widths[i], 7, header, "1", 0, align, true, 0, "")
+	}
+	pdf.Ln(-1)
+
+	font(false, 9)
+	for _, line := range inv.Lines {
+		cells := []string{
+			text(line.Description),
+			strconv.Itoa(line.Quantity),
+			line.UnitPrice.String(),
+			line.NetAmount.String(),
+			percent(line.TaxRate),
+			line.TaxAmount.String(),
+			line.Amount.String(),
+		}
+		for i, cell := range cells {
+			align := "R"
+			if i == 0 {
+				align = "L"
+			}
+			pdf.CellFormat(widths[i], 7, cell, "1", 0, align, false, 0, "")
+		}
+		pdf.Ln(-1)
+	}
+	pdf.Ln(3)
+
+	// 合计，明细行金额已扣除优惠
+	var discount money.Amount
+	for _, line := range inv.Lines {
+		discount += line.Discount
+	}
+	totals := [][2]string{
+		{"Subtotal", inv.Subtotal.String()},
+		{"Tax", inv.TaxTotal.String()},
+		{"Total (" + inv.Currency + ")", inv.Total.String()},
+	}
+	if discount > 0 {
+		totals = append([][2]string{{"Discount", "-" + discount.String()}}, totals...)
+	}
+	for _, row := range totals {
+		font(row[0] == totals[len(totals)-1][0], 10)
+		pdf.CellFormat(136, 6, row[0], "", 0, "R", false, 0, "")
+		pdf.CellFormat(44, 6, row[1], "", 1, "R", false, 0, "")
+	}
+	pdf.Ln(8)
+
+	// 链上存证信息和核验二维码
+	qrPNG, err := QRCode(r.VerifyURL(inv.Number, inv.VerifyToken))
+	if err != nil {
+		return nil, err
+	}
+	pdf.RegisterImageOptionsReader("qr", fpdf.ImageOptions{ImageType: "PNG"}, bytes.NewReader(qrPNG))
+
+	y := pdf.GetY()
+	font(true, 10)
+	pdf.CellFormat(140, 6, "Blockchain Transaction", "", 2, "L", false, 0, "")
+	font(false, 8)
+	pdf.MultiCell(140, 5, inv.TxHash, "", "L", false)
+	pdf.Ln(2)
+	pdf.MultiCell(140, 5, "Scan the QR code or visit the URL below to verify this invoice:", "", "L", false)
+	pdf.MultiCell(140, 5, r.VerifyURL(inv.Number, inv.VerifyToken), "", "L", false)
+	pdf.ImageOptions("qr", 160, y, 35, 35, false, fpdf.ImageOptions{ImageType: "PNG"}, 0, "")
+
+	if err := pdf.Error(); err != nil {
+		return nil, fmt.Errorf("render invoice pdf: %w", err)
+	}
+
+	var buf bytes.Buffer
+	if err := pdf.Output(&buf); err != nil {
+		return nil, fmt.Errorf("render invoice pdf: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// QRCode 生成内容的二维码PNG图片
+func QRCode(content string) ([]byte, error) {
+	code, err := qr.Encode(content, qr.M, qr.Auto)
+	if err != nil {
+		return nil, fmt.Errorf("encode qr code: %w", err)
+	}
+	code, err = barcode.Scale(code, qrSize, qrSize)
+	if err != nil {
+		return nil, fmt.Errorf("scale qr code: %w", err)
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, code); err != nil {
+		return nil, fmt.Errorf("encode qr png: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+func taxID(id string) string {
+	if id == "" {
+		return ""
+	}
+	return "Tax ID: " + id
+}
+
+// percent 将税率格式化为百分比，如0.13格式化为13%
+func percent(r money.Rate) string {
+	return strconv.FormatFloat(float64(r)/1e6, 'f', -1, 64) + "%"
+}
+
+func maxFloat(a, b float64) float64 {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,71 @@
+package invoice
+
+import (
+	"bytes"
+	"image/png"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/ylh990835774/blockchain-shop-demo/internal/model"
+	"github.com/ylh990835774/blockchain-shop-demo/pkg/money"
+)
+
+func TestRenderer_VerifyURL(t *testing.T) {
+	r := NewRenderer("", "http://localhost:38080/")
+	assert.Equal(t, "http://localhost:38080/api/v1/invoices/INV-SHOP-00000001/verify?token=0f1e",
+		r.VerifyURL("INV-SHOP-00000001", "0f1e"))
+}
+
+func TestRenderer_RenderPDF(t *testing.T) {
+	r := NewRenderer("", "http://localhost:38080")
+	inv := &model.Invoice{
+		Number:       "INV-SHOP-00000001",
+		OrderID:      1,
+		SellerName:   "Blockchain Shop",
+		SellerTaxID:  "91310000000000000X",
+		BuyerName:    "testuser",
+		BuyerAddress: "上海市浦东新区",
+		Currency:     "CNY",
+		Subtotal:     money.MustParseAmount("17.68"),
+		TaxTotal:     money.MustParseAmount("2.30"),
+		Total:        money.MustParseAmount("19.98"),
+		TxHash:       "0xabc123",
+		Lines: []model.InvoiceLine{{
+			Description: "测试商品",
+			Quantity:    2,
+			UnitPrice:   money.MustParseAmount("9.99"),
+			NetAmount:   money.MustParseAmount("17.68"),
+			TaxRate:     money.MustParseRate("0.13"),
+			TaxAmount:   money.MustParseAmount("2.30"),
+			Amount:      money.MustParseAmount("19.98"),
+		}},
+		IssuedAt: time.Date(2024, 1, 2, 10, 0, 0, 0, time.UTC),
+	}
+
+	data, err := r.RenderPDF(inv)
+	require.NoError(t, err)
+	assert.True(t, bytes.HasPrefix(data, []byte("%PDF-")))
+
+	// 相同发票的渲染结果一致
+	again, err := r.RenderPDF(inv)
+	require.NoError(t, err)
+	assert.Equal(t, data, again)
+}
+
+func TestQRCode(t *testing.T) {
+	data, err := QRCode("http://localhost:38080/api/v1/invoices/INV-SHOP-00000001/verify")
+	require.NoError(t, err)
+
+	img, err := png.Decode(bytes.NewReader(data))
+	require.NoError(t, err)
+	assert.Equal(t, qrSize, img.Bounds().Dx())
+	assert.Equal(t, qrSize, img.Bounds().Dy())
+}
+
+func TestPercent(t *testing.T) {
+	assert.Equal(t, "13%", percent(money.MustParseRate("0.13")))
+	assert.Equal(t, "6.5%", percent(money.MustParseRate("0.065")))
+	assert.Equal(t, "0%", percent(0))
+}
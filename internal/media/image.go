@@ -0,0 +1,152 @@
+// Package media 校验上传的图片并生成缩略图，仅使用标准库
+package media
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"image"
+	_ "image/gif" // 注册GIF解码器
+	"image/jpeg"
+	"image/png"
+	"net/http"
+)
+
+const (
+	// ThumbnailSize 缩略图长边的最大像素数
+	ThumbnailSize = 320
+	// MaxPixels 允许解码的最大像素数，防止体积很小但尺寸巨大的图片耗尽内存
+	MaxPixels = 40_000_000
+
+	thumbnailQuality = 85
+)
+
+var (
+	// ErrUnsupportedType 不支持的图片格式或内容不是图片
+	ErrUnsupportedType = errors.New("unsupported image type")
+	// ErrTooManyPixels 图片尺寸超过MaxPixels
+	ErrTooManyPixels = errors.New("image dimensions too large")
+)
+
+// formats 支持的图片格式，按内容识别的MIME类型对应文件扩展名
+var formats = map[string]string{
+	"image/jpeg": "jpg",
+	"image/png":  "png",
+	"image/gif":  "gif",
+}
+
+// Image 校验通过的图片及其缩略图
+type Image struct {
+	Data        []byte
+	ContentType string
+	Ext         string
+	Width       int
+	Height      int
+	SHA256      string // 原图内容的SHA-256十六进制值
+
+	Thumbnail            []byte
+	ThumbnailContentType string
+	ThumbnailExt         string
+}
+
+// Process 按内容识别图片格式，校验尺寸并生成缩略图。JPEG图片的缩略图为JPEG，其他格式为PNG以保留透明度
+func Process(data []byte) (*Image, error) {
+	contentType := http.DetectContentType(data)
+	ext, ok := formats[contentType]
+	if !ok {
+		return nil, ErrUnsupportedType
+	}
+
+	config, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil || formats["image/"+format] != ext {
+		return nil, ErrUnsupportedType
+	}
+	if config.Width <= 0 || config.Height <= 0 {
+		return nil, ErrUnsupportedType
+	}
+	if int64(config.Width)*int64(config.Height) > MaxPixels {
+		return nil, ErrTooManyPixels
+	}
+
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrUnsupportedType
+	}
+
+	sum := sha256.Sum256(data)
+	img := &Image{
+		Data:        data,
+		ContentType: contentType,
+		Ext:         ext,
+		Width:       config.Width,
+		Height:      config.Height,
+		SHA256:      hex.EncodeToString(sum[:]),
+	}
+
+	var buf bytes.Buffer
+	thumb := Thumbnail(src, ThumbnailSize)
+	if contentType == "image/jpeg" {
+		err = jpeg.Encode(&buf, thumb, &jpeg.Options{Quality: thumbnailQuality})
+		img.ThumbnailContentType, img.ThumbnailExt = "image/jpeg", "jpg"
+	} else {
+		err = png.Encode(&buf, thumb)
+		img.ThumbnailContentType, img.ThumbnailExt = "image/png", "png"
+	}
+	if err != nil {
+		return nil, err
+	}
+	img.Thumbnail = buf.Bytes()
+	return img, nil
+}
+
+// Thumbnail 按比例缩小图片，使长边不超过size；较小的图片保持原尺寸。
+// 缩小时每个目标像素取其覆盖的源像素的平均值（区域平均），避免最近邻采样产生的锯齿
+func Thumbnail(src image.Image, size int) *image.RGBA {
+	bounds := src.Bounds()
+	w, h := bounds.Dx(), bounds.Dy()
+	tw, th := w, h
+	if w > size || h > size {
+		if w >= h {
+			tw, th = size, h*size/w
+		} else {
+			tw, th = w*size/h, size
+		}
+		if tw < 1 {
+			tw = 1
+		}
+		if th < 1 {
+			th = 1
+		}
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, tw, th))
+	for y := 0; y < th; y++ {
+		sy0, sy1 := bounds.Min.Y+y*h/th, bounds.Min.Y+(y+1)*h/th
+		if sy1 == sy0 {
+			sy1++
+		}
+		for x := 0; x < tw; x++ {
+			sx0, sx1 := bounds.Min.X+x*w/tw, bounds.Min.X+(x+1)*w/tw
+			if sx1 == sx0 {
+				sx1++
+			}
+
+			// RGBA()返回预乘透明度的16位分量，直接平均即可
+			var r, g, b, a, n uint64
+			for sy := sy0; sy < sy1; sy++ {
+				for sx := sx0; sx < sx1; sx++ {
+					cr, cg, cb, ca := src.At(sx, sy).RGBA()
+					r, g, b, a = r+uint64(cr), g+uint64(cg), b+uint64(cb), a+uint64(ca)
+					n++
+				}
+			}
+			i := dst.PixOffset(x, y)
+			dst.Pix[i+0] = uint8(r / n >> 8)
+			dst.Pix[i+1] = uint8(g / n >> 8)
+			dst.Pix[i+2] = uint8(b / n >> 8)
+			dst.Pix[i+3] = uint8(a / n >> 8)
+		}
+	}
+	return dst
+}
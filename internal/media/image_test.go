@@ -0,0 +1,131 @@
+package media
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func encode(t *testing.T, format string, img image.Image) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	var err error
+	switch format {
+	case "png":
+		err = png.Encode(&buf, img)
+	case "jpeg":
+		err = jpeg.Encode(&buf, img, nil)
+	case "gif":
+		err = gif.Encode(&buf, img, nil)
+	}
+	require.NoError(t, err)
+	return buf.Bytes()
+}
+
+func solid(w, h int, c color.Color) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.Set(x, y, c)
+		}
+	}
+	return img
+}
+
+func TestProcess(t *testing.T) {
+	tests := []struct {
+		name          string
+		data          []byte
+		contentType   string
+		width, height int
+		thumbType     string
+		thumbW        int
+		thumbH        int
+		expectedErr   error
+	}{
+		{
+			name:        "large png",
+			data:        encode(t, "png", solid(800, 400, color.RGBA{R: 255, A: 255})),
+			contentType: "image/png",
+			width:       800, height: 400,
+			thumbType: "image/png",
+			thumbW:    320, thumbH: 160,
+		},
+		{
+			name:        "portrait jpeg",
+			data:        encode(t, "jpeg", solid(300, 600, color.RGBA{G: 255, A: 255})),
+			contentType: "image/jpeg",
+			width:       300, height: 600,
+			thumbType: "image/jpeg",
+			thumbW:    160, thumbH: 320,
+		},
+		{
+			name:        "small gif keeps size",
+			data:        encode(t, "gif", solid(40, 20, color.RGBA{B: 255, A: 255})),
+			contentType: "image/gif",
+			width:       40, height: 20,
+			thumbType: "image/png",
+			thumbW:    40, thumbH: 20,
+		},
+		{name: "text", data: []byte("hello, world"), expectedErr: ErrUnsupportedType},
+		{name: "truncated png", data: encode(t, "png", solid(10, 10, color.White))[:30], expectedErr: ErrUnsupportedType},
+		{name: "svg", data: []byte(`<svg xmlns="http://www.w3.org/2000/svg"></svg>`), expectedErr: ErrUnsupportedType},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			img, err := Process(tt.data)
+			if tt.expectedErr != nil {
+				assert.Equal(t, tt.expectedErr, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.contentType, img.ContentType)
+			assert.Equal(t, tt.width, img.Width)
+			assert.Equal(t, tt.height, img.Height)
+			assert.Len(t, img.SHA256, 64)
+			assert.Equal(t, tt.thumbType, img.ThumbnailContentType)
+
+			thumb, _, err := image.DecodeConfig(bytes.NewReader(img.Thumbnail))
+			require.NoError(t, err)
+			assert.Equal(t, tt.thumbW, thumb.Width)
+			assert.Equal(t, tt.thumbH, thumb.Height)
+		})
+	}
+}
+
+func TestProcess_TooManyPixels(t *testing.T) {
+	// 改写PNG文件头中声明的尺寸，超过上限时不会解码像素数据
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, image.NewGray(image.Rect(0, 0, 1, 1))))
+	data := buf.Bytes()
+	// IHDR块的类型和数据位于第12到28字节，宽度和高度位于第16到23字节，之后是块的CRC
+	binary.BigEndian.PutUint32(data[16:20], 10000)
+	binary.BigEndian.PutUint32(data[20:24], 10000)
+	binary.BigEndian.PutUint32(data[29:33], crc32.ChecksumIEEE(data[12:29]))
+
+	_, err := Process(data)
+	assert.Equal(t, ErrTooManyPixels, err)
+}
+
+func TestThumbnail_AveragesPixels(t *testing.T) {
+	// 左半黑右半白的图片缩小为1x1时应为灰色
+	src := solid(2, 2, color.White)
+	src.Set(0, 0, color.Black)
+	src.Set(0, 1, color.Black)
+
+	dst := Thumbnail(src, 1)
+	require.Equal(t, image.Rect(0, 0, 1, 1), dst.Bounds())
+	c := dst.RGBAAt(0, 0)
+	assert.InDelta(t, 127, int(c.R), 1)
+	assert.Equal(t, uint8(255), c.A)
+}
@@ -0,0 +1,21 @@
+package model
+
+import "time"
+
+// Category 商品分类，通过ParentID组成树形结构，ParentID为空的是顶级分类
+type Category struct {
+	ID        int64       `json:"id" gorm:"primaryKey"`
+	ParentID  *int64      `json:"parent_id" gorm:"index"`
+	Name      string      `json:"name" gorm:"type:varchar(64);not null"`
+	Slug      string      `json:"slug" gorm:"type:varchar(64);not null;uniqueIndex"`
+	Position  int         `json:"position" gorm:"not null;default:0"` // 同级分类的显示顺序，从小到大
+	Children  []*Category `json:"children,omitempty" gorm:"-"`
+	CreatedAt time.Time   `json:"created_at"`
+	UpdatedAt time.Time   `json:"updated_at"`
+}
+
+// ProductCategory 商品与分类的关联
+type ProductCategory struct {
+	ProductID  int64 `gorm:"primaryKey"`
+	CategoryID int64 `gorm:"primaryKey;index"`
+}
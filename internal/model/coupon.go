@@ -0,0 +1,78 @@
+package model
+
+import (
+	"time"
+
+	"github.com/ylh990835774/blockchain-shop-demo/pkg/money"
+)
+
+type CouponType string
+
+const (
+	CouponTypePercent CouponType = "percent" // 按比例折扣
+	CouponTypeFixed   CouponType = "fixed"   // 固定金额减免
+)
+
+// Coupon 优惠券，下单时按折扣前的商品金额计算优惠
+type Coupon struct {
+	ID           int64        `json:"id" gorm:"primaryKey"`
+	Code         string       `json:"code" gorm:"type:varchar(32);not null;uniqueIndex"`
+	Type         CouponType   `json:"type" gorm:"type:varchar(16);not null"`
+	PercentOff   money.Rate   `json:"percent_off" gorm:"not null;default:0"` // 折扣比例，0.2表示减免20%
+	AmountOff    money.Amount `json:"amount_off" gorm:"not null;default:0"`  // 减免金额，不超过商品金额
+	Currency     string       `json:"currency" gorm:"type:char(3)"`          // 减免金额和最低消费的币种，订单须使用相同币种
+	MinAmount    money.Amount `json:"min_amount" gorm:"not null;default:0"`  // 最低消费金额，为零时不限
+	UsageLimit   int          `json:"usage_limit" gorm:"not null;default:0"` // 总使用次数上限，为零时不限
+	PerUserLimit int          `json:"per_user_limit" gorm:"not null;default:0"`
+	UsedCount    int          `json:"used_count" gorm:"not null;default:0"`
+	StartsAt     *time.Time   `json:"starts_at"`
+	EndsAt       *time.Time   `json:"ends_at"`
+	Disabled     bool         `json:"disabled" gorm:"not null;default:false"`
+	ProductIDs   []int64      `json:"product_ids" gorm:"-"` // 限定可用的商品，为空时不限
+	CreatedAt    time.Time    `json:"created_at"`
+	UpdatedAt    time.Time    `json:"updated_at"`
+}
+
+// Valid 优惠券在指定时间是否可用
+func (c *Coupon) Valid(now time.Time) bool {
+	if c.Disabled {
+		return false
+	}
+	if c.StartsAt != nil && now.Before(*c.StartsAt) {
+		return false
+	}
+	if c.EndsAt != nil && !now.Before(*c.EndsAt) {
+		return false
+	}
+	return true
+}
+
+// AppliesTo 优惠券是否可用于指定商品
+func (c *Coupon) AppliesTo(productID int64) bool {
+	if len(c.ProductIDs) == 0 {
+		return true
+	}
+	for _, id := range c.ProductIDs {
+		if id == productID {
+			return true
+		}
+	}
+	return false
+}
+
+// CouponProduct 优惠券限定的商品
+type CouponProduct struct {
+	CouponID  int64 `gorm:"primaryKey"`
+	ProductID int64 `gorm:"primaryKey"`
+}
+
+// CouponRedemption 优惠券使用记录，订单取消后删除
+type CouponRedemption struct {
+	ID        int64        `json:"id" gorm:"primaryKey"`
+	CouponID  int64        `json:"coupon_id" gorm:"not null;index:idx_coupon_redemptions_coupon_user"`
+	UserID    int64        `json:"user_id" gorm:"not null;index:idx_coupon_redemptions_coupon_user"`
+	OrderID   int64        `json:"order_id" gorm:"not null;uniqueIndex"`
+	Discount  money.Amount `json:"discount" gorm:"not null"`
+	Currency  string       `json:"currency" gorm:"type:char(3);not null"`
+	CreatedAt time.Time    `json:"created_at"`
+}
@@ -0,0 +1,26 @@
+package model
+
+import "time"
+
+// IdempotencyKey 幂等键记录，保存请求指纹及首次请求的响应
+type IdempotencyKey struct {
+	ID           int64     `json:"id" gorm:"primaryKey"`
+	UserID       int64     `json:"user_id" gorm:"not null;uniqueIndex:uk_idempotency_keys_user_key"`
+	Key          string    `json:"key" gorm:"column:idempotency_key;not null;uniqueIndex:uk_idempotency_keys_user_key"`
+	Fingerprint  string    `json:"fingerprint" gorm:"not null"`
+	StatusCode   int       `json:"status_code"` // 0 表示请求仍在处理中
+	ResponseBody []byte    `json:"-"`
+	ExpiresAt    time.Time `json:"expires_at" gorm:"not null"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// Completed 请求是否已处理完成并保存了响应
+func (k *IdempotencyKey) Completed() bool {
+	return k.StatusCode != 0
+}
+
+// Expired 幂等键在给定时间点是否已过期
+func (k *IdempotencyKey) Expired(now time.Time) bool {
+	return !now.Before(k.ExpiresAt)
+}
@@ -0,0 +1,19 @@
+package model
+
+import "time"
+
+// ProductImage 商品图片，原图和缩略图保存在对象存储中
+type ProductImage struct {
+	ID           int64     `json:"id" gorm:"primaryKey"`
+	ProductID    int64     `json:"product_id" gorm:"not null;uniqueIndex:uk_product_images_product_sha256"`
+	Key          string    `json:"-" gorm:"type:varchar(255);not null"`
+	ThumbnailKey string    `json:"-" gorm:"type:varchar(255);not null"`
+	URL          string    `json:"url" gorm:"type:varchar(512);not null"`
+	ThumbnailURL string    `json:"thumbnail_url" gorm:"type:varchar(512);not null"`
+	ContentType  string    `json:"content_type" gorm:"type:varchar(32);not null"`
+	Size         int64     `json:"size" gorm:"not null"`
+	Width        int       `json:"width" gorm:"not null"`
+	Height       int       `json:"height" gorm:"not null"`
+	SHA256       string    `json:"sha256" gorm:"column:sha256;type:char(64);not null;uniqueIndex:uk_product_images_product_sha256"` // 原图内容哈希，用于核验图片未被篡改
+	CreatedAt    time.Time `json:"created_at"`
+}
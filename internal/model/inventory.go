@@ -0,0 +1,70 @@
+package model
+
+import "time"
+
+type MovementType string
+
+const (
+	// MovementTypeSale 订单支付，预留的库存转为售出
+	MovementTypeSale MovementType = "sale"
+	// MovementTypeRestock 商户补货入库
+	MovementTypeRestock MovementType = "restock"
+	// MovementTypeAdjustment 盘点调整、维护SKU库存等人工调整
+	MovementTypeAdjustment MovementType = "adjustment"
+	// MovementTypeReturn 退货入库
+	MovementTypeReturn MovementType = "return"
+	// MovementTypeReservation 下单预留库存，预留释放时为正数
+	MovementTypeReservation MovementType = "reservation"
+)
+
+// Valid 是否为已知的库存变动类型
+func (t MovementType) Valid() bool {
+	switch t {
+	case MovementTypeSale, MovementTypeRestock, MovementTypeAdjustment, MovementTypeReturn, MovementTypeReservation:
+		return true
+	}
+	return false
+}
+
+// InventoryMovement 库存变动流水，与SKU库存的变更在同一事务中写入，SKU的库存始终等于其流水数量之和
+type InventoryMovement struct {
+	ID        int64        `json:"id" gorm:"primaryKey"`
+	ProductID int64        `json:"product_id" gorm:"not null;index:idx_inventory_movements_product_created,priority:1"`
+	SKUID     int64        `json:"sku_id" gorm:"column:sku_id;not null;index:idx_inventory_movements_sku_created,priority:1"`
+	Type      MovementType `json:"type" gorm:"type:varchar(20);not null"`
+	Quantity  int          `json:"quantity" gorm:"not null"` // 库存变化量，入库为正数，出库为负数
+	ActorID   *int64       `json:"actor_id,omitempty"`       // 操作人，由系统任务产生的变动为空
+	OrderID   *int64       `json:"order_id,omitempty" gorm:"index"`
+	Reason    string       `json:"reason" gorm:"type:varchar(255);not null;default:''"`
+	CreatedAt time.Time    `json:"created_at" gorm:"index:idx_inventory_movements_product_created,priority:2;index:idx_inventory_movements_sku_created,priority:2"`
+}
+
+// InventoryMovementFilter 库存流水查询条件，零值字段不参与过滤
+type InventoryMovementFilter struct {
+	ProductID int64
+	SKUID     int64
+	Type      MovementType
+}
+
+// StockDiscrepancy 商品库存与库存流水合计不一致的商品
+type StockDiscrepancy struct {
+	ProductID     int64 `json:"product_id"`
+	Stock         int   `json:"stock"`          // 商品的库存合计
+	MovementTotal int   `json:"movement_total"` // 商品所有库存流水的数量之和
+}
+
+// LowStockAlert 低库存告警。SKU库存降到阈值及以下时创建，回到阈值以上时关闭，
+// 关闭前同一SKU不会重复告警
+type LowStockAlert struct {
+	ID          int64      `json:"id" gorm:"primaryKey"`
+	ProductID   int64      `json:"product_id" gorm:"not null"`
+	SKUID       int64      `json:"sku_id" gorm:"column:sku_id;not null;index"`
+	SKUCode     string     `json:"sku_code" gorm:"column:sku_code;type:varchar(64);not null"`
+	Stock       int        `json:"stock" gorm:"not null"`     // 告警时的库存
+	Threshold   int        `json:"threshold" gorm:"not null"` // 告警时适用的阈值
+	ResolvedAt  *time.Time `json:"resolved_at"`               // 库存回到阈值以上的时间
+	DeliveredAt *time.Time `json:"delivered_at"`              // 通知送达的时间
+	Attempts    int        `json:"attempts" gorm:"not null;default:0"`
+	LastError   string     `json:"last_error,omitempty" gorm:"type:varchar(512);not null;default:''"`
+	CreatedAt   time.Time  `json:"created_at" gorm:"index"`
+}
@@ -0,0 +1,53 @@
+package model
+
+import (
+	"time"
+
+	"github.com/ylh990835774/blockchain-shop-demo/pkg/money"
+)
+
+// Invoice 订单发票，开具后内容不再变化
+type Invoice struct {
+	ID            int64         `json:"id" gorm:"primaryKey"`
+	Number        string        `json:"number" gorm:"type:varchar(64);not null;uniqueIndex"`
+	VerifyToken   string        `json:"-" gorm:"type:char(32);not null"` // 随机核验令牌，核验地址需携带，避免按连续编号遍历发票
+	MerchantCode  string        `json:"merchant_code" gorm:"type:varchar(32);not null;uniqueIndex:uk_invoices_merchant_sequence"`
+	Sequence      int64         `json:"sequence" gorm:"not null;uniqueIndex:uk_invoices_merchant_sequence"` // 开票编码内连续无间断的编号
+	OrderID       int64         `json:"order_id" gorm:"not null;uniqueIndex"`
+	UserID        int64         `json:"user_id" gorm:"not null"`
+	SellerName    string        `json:"seller_name" gorm:"not null"`
+	SellerTaxID   string        `json:"seller_tax_id"`
+	SellerAddress string        `json:"seller_address"`
+	BuyerName     string        `json:"buyer_name" gorm:"not null"`
+	BuyerAddress  string        `json:"buyer_address" gorm:"type:text"`
+	Currency      string        `json:"currency" gorm:"type:char(3);not null"`
+	Subtotal      money.Amount  `json:"subtotal" gorm:"not null"` // 不含税金额合计
+	TaxTotal      money.Amount  `json:"tax_total" gorm:"not null"`
+	Total         money.Amount  `json:"total" gorm:"not null"` // 价税合计，与订单金额一致
+	TxHash        string        `json:"tx_hash"`
+	Lines         []InvoiceLine `json:"lines" gorm:"foreignKey:InvoiceID"`
+	IssuedAt      time.Time     `json:"issued_at" gorm:"not null"`
+	CreatedAt     time.Time     `json:"created_at"`
+}
+
+// InvoiceLine 发票明细行
+type InvoiceLine struct {
+	ID          int64        `json:"-" gorm:"primaryKey"`
+	InvoiceID   int64        `json:"-" gorm:"not null;index"`
+	Description string       `json:"description" gorm:"not null"`
+	Quantity    int          `json:"quantity" gorm:"not null"`
+	UnitPrice   money.Amount `json:"unit_price" gorm:"not null"` // 下单时的标价
+	Discount    money.Amount `json:"discount" gorm:"not null;default:0"`
+	NetAmount   money.Amount `json:"net_amount" gorm:"not null"`
+	TaxRate     money.Rate   `json:"tax_rate" gorm:"not null"`
+	TaxAmount   money.Amount `json:"tax_amount" gorm:"not null"`
+	Amount      money.Amount `json:"amount" gorm:"not null"` // 含税金额
+}
+
+// InvoiceSequence 发票编号计数器。商城是唯一的销售方，商户和管理员只是运营角色，
+// 所有发票使用配置的开票编码下的同一个序列，不按处理订单的商户账号分别编号
+type InvoiceSequence struct {
+	MerchantCode string `gorm:"type:varchar(32);primaryKey"`
+	LastNumber   int64  `gorm:"not null;default:0"`
+	UpdatedAt    time.Time
+}
@@ -1,6 +1,10 @@
 package model
 
-import "time"
+import (
+	"time"
+
+	"github.com/ylh990835774/blockchain-shop-demo/pkg/money"
+)
 
 type OrderStatus string
 
@@ -10,27 +14,92 @@ const (
 	OrderStatusShipped   OrderStatus = "shipped"
 	OrderStatusComplete  OrderStatus = "complete"
 	OrderStatusCancelled OrderStatus = "cancelled"
+	OrderStatusRefunded  OrderStatus = "refunded" // 全部商品已退货退款
 )
 
 type Order struct {
-	ID         int64       `json:"id" gorm:"primaryKey"`
-	UserID     int64       `json:"user_id" gorm:"not null"`
-	ProductID  int64       `json:"product_id" gorm:"not null"`
-	Quantity   int         `json:"quantity" gorm:"not null"`
-	TotalPrice float64     `json:"total_price" gorm:"not null"`
-	Status     OrderStatus `json:"status" gorm:"not null"`
-	TxHash     string      `json:"tx_hash"`
-	CreatedAt  time.Time   `json:"created_at"`
-	UpdatedAt  time.Time   `json:"updated_at"`
+	ID              int64          `json:"id" gorm:"primaryKey"`
+	UserID          int64          `json:"user_id" gorm:"not null;index:idx_orders_user_created,priority:1"`
+	ProductID       int64          `json:"product_id" gorm:"not null;index"`
+	SKUID           int64          `json:"sku_id" gorm:"column:sku_id;not null;index"`                // 下单的SKU，库存按SKU扣减和归还
+	ProductName     string         `json:"product_name" gorm:"type:varchar(255);not null;default:''"` // 下单时的商品名称快照
+	SKUCode         string         `json:"sku_code" gorm:"column:sku_code;type:varchar(64);not null;default:''"`
+	Quantity        int            `json:"quantity" gorm:"not null"`
+	UnitPrice       money.Amount   `json:"unit_price" gorm:"not null;default:0"` // 下单时订单币种的商品单价快照
+	Discount        money.Amount   `json:"discount" gorm:"not null;default:0"`   // 优惠金额，从商品金额中扣除后再计税
+	Subtotal        money.Amount   `json:"subtotal" gorm:"not null;default:0"`   // 不含税金额
+	TaxTotal        money.Amount   `json:"tax_total" gorm:"not null;default:0"`
+	TotalPrice      money.Amount   `json:"total_price" gorm:"not null;index"` // 应付金额，含税
+	Currency        string         `json:"currency" gorm:"type:char(3);not null;default:CNY"`
+	ExchangeRate    money.Rate     `json:"exchange_rate" gorm:"not null;default:1"` // 下单时商品基础币种到订单币种的汇率
+	Status          OrderStatus    `json:"status" gorm:"not null;index:idx_orders_status_created,priority:1"`
+	ShippingAddress string         `json:"shipping_address" gorm:"type:text"` // 下单时的收货地址快照
+	TaxRegion       string         `json:"tax_region" gorm:"type:varchar(8)"`
+	CouponCode      string         `json:"coupon_code,omitempty" gorm:"type:varchar(32)"`
+	TaxLines        []OrderTaxLine `json:"tax_lines,omitempty" gorm:"foreignKey:OrderID"`
+	TxHash          string         `json:"tx_hash"`
+	Product         *Product       `json:"product,omitempty" gorm:"-"` // 订单商品的当前信息，商品已删除时仍返回
+	CreatedAt       time.Time      `json:"created_at" gorm:"index;index:idx_orders_user_created,priority:2;index:idx_orders_status_created,priority:2"`
+	UpdatedAt       time.Time      `json:"updated_at"`
+}
+
+// 订单排序字段
+const (
+	OrderSortCreatedAt = "created_at"
+	OrderSortTotal     = "total"
+)
+
+// OrderFilter 订单查询条件，零值字段不参与过滤
+type OrderFilter struct {
+	UserID      int64 // 为零时查询所有用户的订单
+	Statuses    []OrderStatus
+	ProductID   int64
+	CreatedFrom *time.Time // 下单时间下限，包含
+	CreatedTo   *time.Time // 下单时间上限，不包含
+	MinTotal    *money.Amount
+	MaxTotal    *money.Amount
+	SortBy      string // 排序字段，默认按下单时间
+	Ascending   bool   // 默认降序
+}
+
+// OrderCursor 键集分页的位置，即上一页最后一个订单的排序键
+type OrderCursor struct {
+	CreatedAt  time.Time
+	TotalPrice money.Amount
+	ID         int64
+}
+
+// OrderTaxLine 订单商品行的计税明细，记录下单时适用的税率
+type OrderTaxLine struct {
+	ID          int64        `json:"-" gorm:"primaryKey"`
+	OrderID     int64        `json:"-" gorm:"not null;index"`
+	ProductID   int64        `json:"product_id" gorm:"not null"`
+	Quantity    int          `json:"quantity" gorm:"not null"`
+	UnitPrice   money.Amount `json:"unit_price" gorm:"not null"` // 订单币种的标价，是否含税由Inclusive决定
+	Discount    money.Amount `json:"discount" gorm:"not null;default:0"`
+	Category    string       `json:"category" gorm:"type:varchar(32);not null"`
+	Region      string       `json:"region" gorm:"type:varchar(8);not null"`
+	Rate        money.Rate   `json:"rate" gorm:"not null"`
+	Inclusive   bool         `json:"inclusive" gorm:"not null"`
+	NetAmount   money.Amount `json:"net_amount" gorm:"not null"`
+	TaxAmount   money.Amount `json:"tax_amount" gorm:"not null"`
+	GrossAmount money.Amount `json:"gross_amount" gorm:"not null"`
+	CreatedAt   time.Time    `json:"-"`
 }
 
 // Transaction 区块链交易信息
 type Transaction struct {
-	TxHash    string    `json:"tx_hash"`
-	From      string    `json:"from"`
-	To        string    `json:"to"`
-	Value     string    `json:"value"`
-	Status    bool      `json:"status"`
-	Timestamp time.Time `json:"timestamp"`
-	OrderID   int64     `json:"order_id"`
+	TxHash        string       `json:"tx_hash"`
+	From          string       `json:"from"`
+	To            string       `json:"to"`
+	Value         string       `json:"value"`
+	Currency      string       `json:"currency"`
+	Discount      string       `json:"discount,omitempty"`    // 订单优惠金额
+	CouponCode    string       `json:"coupon_code,omitempty"` // 订单使用的优惠券
+	Status        bool         `json:"status"`
+	Timestamp     time.Time    `json:"timestamp"`
+	OrderID       int64        `json:"order_id"`
+	Refunded      bool         `json:"refunded"`                                 // 转账已全额退回（订单无法完成支付时整笔退回或订单退款累计达到转账金额），不再用于订单退款
+	RefundedValue money.Amount `json:"refunded_value" gorm:"not null;default:0"` // 累计退回金额
+	RefundTxHash  string       `json:"refund_tx_hash,omitempty"`                 // 整笔退回的退款转账哈希
 }
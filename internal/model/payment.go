@@ -0,0 +1,33 @@
+package model
+
+import (
+	"time"
+
+	"github.com/ylh990835774/blockchain-shop-demo/pkg/money"
+)
+
+type PaymentStatus string
+
+const (
+	PaymentStatusPending   PaymentStatus = "pending"
+	PaymentStatusSucceeded PaymentStatus = "succeeded"
+	PaymentStatusFailed    PaymentStatus = "failed"
+	PaymentStatusRefunded  PaymentStatus = "refunded"
+)
+
+// Payment 订单支付记录
+type Payment struct {
+	ID          int64         `json:"id" gorm:"primaryKey"`
+	OrderID     int64         `json:"order_id" gorm:"not null"`
+	UserID      int64         `json:"user_id" gorm:"not null"`
+	Amount      money.Amount  `json:"amount" gorm:"not null"`
+	Currency    string        `json:"currency" gorm:"not null"`
+	Provider    string        `json:"provider" gorm:"not null"`
+	ProviderRef string        `json:"provider_ref"`
+	PaymentURL  string        `json:"payment_url"`
+	Status      PaymentStatus `json:"status" gorm:"not null"`
+	Refunded    money.Amount  `json:"refunded_amount" gorm:"column:refunded_amount;not null;default:0"` // 累计退款金额，达到支付金额时状态变为已退款
+	TxHash      string        `json:"tx_hash"`
+	CreatedAt   time.Time     `json:"created_at"`
+	UpdatedAt   time.Time     `json:"updated_at"`
+}
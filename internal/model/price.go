@@ -0,0 +1,77 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/ylh990835774/blockchain-shop-demo/pkg/money"
+)
+
+// ProductPriceHistory 商品价格变更记录，商品价格、币种定价和SKU价格每次变更时在同一事务中写入
+type ProductPriceHistory struct {
+	ID        int64         `json:"id" gorm:"primaryKey"`
+	ProductID int64         `json:"product_id" gorm:"not null;index:idx_product_price_history_product_created,priority:1"`
+	SKUID     *int64        `json:"sku_id,omitempty" gorm:"column:sku_id"` // SKU价格的变更，为空时为商品价格或币种定价的变更
+	Currency  string        `json:"currency" gorm:"type:char(3);not null"`
+	OldPrice  *money.Amount `json:"old_price"` // 变更前的价格，为空表示此前没有定价
+	NewPrice  *money.Amount `json:"new_price"` // 变更后的价格，为空表示删除了币种定价或SKU改用商品价格
+	CreatedAt time.Time     `json:"created_at" gorm:"index:idx_product_price_history_product_created,priority:2"`
+}
+
+func (ProductPriceHistory) TableName() string {
+	return "product_price_history"
+}
+
+// PriceCommitment 商品价格表的默克尔根，定期发布并上链，用于证明订单价格与下单时公布的价格一致
+type PriceCommitment struct {
+	ID         int64     `json:"id" gorm:"primaryKey"`
+	MerkleRoot string    `json:"merkle_root" gorm:"type:char(64);not null"`
+	EntryCount int       `json:"entry_count" gorm:"not null"`
+	TxHash     string    `json:"tx_hash" gorm:"type:varchar(64);not null;default:''"` // 默克尔根上链记录所在区块的哈希
+	CreatedAt  time.Time `json:"created_at" gorm:"index"`                             // 生成价格表的时间
+}
+
+// PriceCommitmentEntry 价格表中的一项，即默克尔树的一个叶子。
+// 每个SKU有一项基础币种的价格，未单独定价的SKU还有商品各币种定价的项
+type PriceCommitmentEntry struct {
+	ID           int64        `json:"-" gorm:"primaryKey"`
+	CommitmentID int64        `json:"-" gorm:"not null;uniqueIndex:uk_price_commitment_entries_sku_currency,priority:1"`
+	Position     int          `json:"position" gorm:"not null"` // 叶子在树中的位置，从0开始
+	ProductID    int64        `json:"product_id" gorm:"not null"`
+	SKUCode      string       `json:"sku_code" gorm:"column:sku_code;type:varchar(64);not null;uniqueIndex:uk_price_commitment_entries_sku_currency,priority:2"`
+	Currency     string       `json:"currency" gorm:"type:char(3);not null;uniqueIndex:uk_price_commitment_entries_sku_currency,priority:3"`
+	Price        money.Amount `json:"price" gorm:"not null"`
+}
+
+// LeafData 默克尔树叶子的数据，为按product_id、sku_code、currency、price顺序排列字段的JSON对象
+func (e *PriceCommitmentEntry) LeafData() []byte {
+	data, _ := json.Marshal(struct {
+		ProductID int64        `json:"product_id"`
+		SKUCode   string       `json:"sku_code"`
+		Currency  string       `json:"currency"`
+		Price     money.Amount `json:"price"`
+	}{e.ProductID, e.SKUCode, e.Currency, e.Price})
+	return data
+}
+
+// MerkleProofStep 默克尔包含证明中的兄弟节点
+type MerkleProofStep struct {
+	Hash     string `json:"hash"`
+	Position string `json:"position"` // 兄弟节点在左侧(left)或右侧(right)
+}
+
+// OrderPriceProof 订单单价与下单前最近一次发布的价格表一致的证明
+type OrderPriceProof struct {
+	OrderID      int64                 `json:"order_id"`
+	SKUCode      string                `json:"sku_code"`
+	Currency     string                `json:"currency"`
+	UnitPrice    money.Amount          `json:"unit_price"`
+	ExchangeRate money.Rate            `json:"exchange_rate"` // 价格表中没有订单币种的价格时，由基础币种价格按此汇率换算
+	Commitment   *PriceCommitment      `json:"commitment"`    // 下单前最近一次发布的价格表
+	Entry        *PriceCommitmentEntry `json:"entry"`         // 价格表中订单SKU在订单币种或基础币种下的价格
+	Proof        []MerkleProofStep     `json:"proof"`         // 从叶子到根的兄弟节点
+	LeafData     string                `json:"leaf_data"`     // 叶子数据，其哈希为 SHA256(0x00 || leaf_data)
+	Matched      bool                  `json:"matched"`       // 订单单价与价格表中的价格一致
+	OnChain      bool                  `json:"on_chain"`      // 链上记录的默克尔根与价格表一致
+	Verified     bool                  `json:"verified"`      // 证明有效且价格一致
+}
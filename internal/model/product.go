@@ -1,13 +1,123 @@
 package model
 
-import "time"
+import (
+	"fmt"
+	"time"
+
+	"github.com/ylh990835774/blockchain-shop-demo/pkg/money"
+	"gorm.io/gorm"
+)
+
+// 商品名称和描述的最大长度（按字符计），请求校验和批量导入共用
+const (
+	MaxProductNameLength        = 255
+	MaxProductDescriptionLength = 10000
+)
 
 type Product struct {
-	ID          int64     `json:"id" gorm:"primaryKey"`
-	Name        string    `json:"name" gorm:"not null"`
-	Description string    `json:"description"`
-	Price       float64   `json:"price" gorm:"not null"`
-	Stock       int       `json:"stock" gorm:"not null"`
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
+	ID          int64          `json:"id" gorm:"primaryKey"`
+	Name        string         `json:"name" gorm:"not null"`
+	Description string         `json:"description"`
+	Price       money.Amount   `json:"price" gorm:"not null;index"`
+	Currency    string         `json:"currency" gorm:"type:char(3);not null;default:CNY"`
+	Stock       int            `json:"stock" gorm:"not null"`                                          // 所有SKU的库存合计，由SKU库存变更同步维护
+	TaxCategory string         `json:"tax_category" gorm:"type:varchar(32);not null;default:standard"` // 税目，对应各税区配置的税率
+	Prices      []ProductPrice `json:"prices,omitempty" gorm:"foreignKey:ProductID"`                   // 其他币种的定价，未定价的币种按汇率换算
+	SKUs        []ProductSKU   `json:"skus,omitempty" gorm:"foreignKey:ProductID"`                     // 可售规格，至少包含一个默认SKU
+	Images      []ProductImage `json:"images,omitempty" gorm:"foreignKey:ProductID"`                   // 商品图片，按上传顺序排列
+	CategoryIDs []int64        `json:"category_ids" gorm:"-"`                                          // 所属分类
+	ArchivedAt  *time.Time     `json:"archived_at,omitempty" gorm:"index"`                             // 下架时间，已下架的商品不在列表中展示且不能下单
+	Version     int64          `json:"version" gorm:"not null;default:1"`                              // 每次更新商品信息或上下架时递增，用于乐观锁
+	CreatedAt   time.Time      `json:"created_at"`
+	UpdatedAt   time.Time      `json:"updated_at"`
+	DeletedAt   gorm.DeletedAt `json:"-" gorm:"index"` // 软删除，已删除的商品仍可通过订单查询
+}
+
+// ProductPatch 商品的部分更新，nil字段保持不变。基础币种在创建后不能修改，库存通过SKU维护
+type ProductPatch struct {
+	Name        *string
+	Description *string
+	Price       *money.Amount
+	TaxCategory *string
+}
+
+// IsEmpty 是否没有需要更新的字段
+func (p ProductPatch) IsEmpty() bool {
+	return p.Name == nil && p.Description == nil && p.Price == nil && p.TaxCategory == nil
+}
+
+// DefaultSKUCode 创建商品时未指定SKU所生成的默认SKU编码
+func DefaultSKUCode(productID int64) string {
+	return fmt.Sprintf("P%d", productID)
+}
+
+// IsReservedSKUCode 编码是否为P加数字的形式，该形式保留给生成的默认SKU，商户不能使用
+func IsReservedSKUCode(code string) bool {
+	if len(code) < 2 || code[0] != 'P' {
+		return false
+	}
+	for _, c := range code[1:] {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// ProductPrice 商品在指定币种下的定价
+type ProductPrice struct {
+	ID        int64        `json:"-" gorm:"primaryKey"`
+	ProductID int64        `json:"-" gorm:"not null;uniqueIndex:uk_product_prices_product_currency"`
+	Currency  string       `json:"currency" gorm:"type:char(3);not null;uniqueIndex:uk_product_prices_product_currency"`
+	Price     money.Amount `json:"price" gorm:"not null"`
+	CreatedAt time.Time    `json:"-"`
+	UpdatedAt time.Time    `json:"-"`
+}
+
+// PriceIn 返回商品在指定币种下的定价，未单独定价时返回false
+func (p *Product) PriceIn(currency string) (money.Amount, bool) {
+	if currency == p.Currency {
+		return p.Price, true
+	}
+	for _, price := range p.Prices {
+		if price.Currency == currency {
+			return price.Price, true
+		}
+	}
+	return 0, false
+}
+
+// 商品列表的排序字段
+const (
+	ProductSortRelevance = "relevance" // 按搜索相关度，仅在指定关键词时可用
+	ProductSortCreatedAt = "created_at"
+	ProductSortPrice     = "price"
+	ProductSortName      = "name"
+)
+
+// ProductFilter 商品查询条件，零值字段不参与过滤
+type ProductFilter struct {
+	Query        string        // 按名称和描述全文搜索
+	CategoryIDs  []int64       // 属于其中任一分类的商品
+	Currency     string        // 商品基础币种
+	MinPrice     *money.Amount // 基础币种价格下限，包含
+	MaxPrice     *money.Amount // 基础币种价格上限，包含
+	InStock      bool          // 仅包含有库存的商品
+	WithArchived bool          // 包含已下架的商品，仅用于管理员查询
+	SortBy       string        // 排序字段，为空时按ID升序
+	Ascending    bool          // 默认降序，按相关度排序时忽略
+}
+
+// ProductFacets 商品查询结果的分面统计，统计每个分面时不应用该分面自身的过滤条件
+type ProductFacets struct {
+	InStock     int64             `json:"in_stock"`
+	OutOfStock  int64             `json:"out_of_stock"`
+	PriceRanges []PriceRangeFacet `json:"price_ranges"`
+}
+
+// PriceRangeFacet 价格区间内的商品数量，区间包含下限不包含上限
+type PriceRangeFacet struct {
+	Min   money.Amount  `json:"min"`
+	Max   *money.Amount `json:"max,omitempty"` // 为空表示不设上限
+	Count int64         `json:"count"`
 }
@@ -0,0 +1,32 @@
+package model
+
+import "time"
+
+type ReservationStatus string
+
+const (
+	// ReservationStatusActive 库存已预留，等待支付
+	ReservationStatusActive ReservationStatus = "active"
+	// ReservationStatusConverted 订单已支付，预留转为销售
+	ReservationStatusConverted ReservationStatus = "converted"
+	// ReservationStatusReleased 预留已释放，库存已归还
+	ReservationStatusReleased ReservationStatus = "released"
+)
+
+// StockReservation 待支付订单的库存预留
+type StockReservation struct {
+	ID        int64             `json:"id" gorm:"primaryKey"`
+	OrderID   int64             `json:"order_id" gorm:"not null"`
+	ProductID int64             `json:"product_id" gorm:"not null"`
+	SKUID     int64             `json:"sku_id" gorm:"column:sku_id;not null"`
+	Quantity  int               `json:"quantity" gorm:"not null"`
+	Status    ReservationStatus `json:"status" gorm:"not null"`
+	ExpiresAt time.Time         `json:"expires_at" gorm:"not null"`
+	CreatedAt time.Time         `json:"created_at"`
+	UpdatedAt time.Time         `json:"updated_at"`
+}
+
+// Expired 预留在给定时间点是否已过期
+func (r *StockReservation) Expired(now time.Time) bool {
+	return !now.Before(r.ExpiresAt)
+}
@@ -0,0 +1,38 @@
+package model
+
+import (
+	"time"
+
+	"github.com/ylh990835774/blockchain-shop-demo/pkg/money"
+)
+
+type ReturnStatus string
+
+const (
+	// ReturnStatusRequested 买家已申请，等待商户审核
+	ReturnStatusRequested ReturnStatus = "requested"
+	// ReturnStatusApproved 商户已同意，等待退回商品
+	ReturnStatusApproved ReturnStatus = "approved"
+	// ReturnStatusRejected 商户已拒绝
+	ReturnStatusRejected ReturnStatus = "rejected"
+	// ReturnStatusReceived 商户已收到退回商品并恢复库存，等待退款
+	ReturnStatusReceived ReturnStatus = "received"
+	// ReturnStatusRefunded 已退款
+	ReturnStatusRefunded ReturnStatus = "refunded"
+)
+
+// ReturnRequest 订单退货申请
+type ReturnRequest struct {
+	ID           int64        `json:"id" gorm:"primaryKey"`
+	OrderID      int64        `json:"order_id" gorm:"not null;index"`
+	UserID       int64        `json:"user_id" gorm:"not null"`
+	Quantity     int          `json:"quantity" gorm:"not null"`
+	Reason       string       `json:"reason" gorm:"type:varchar(512);not null"`
+	Status       ReturnStatus `json:"status" gorm:"not null"`
+	MerchantNote string       `json:"merchant_note" gorm:"type:varchar(512)"`
+	RefundAmount money.Amount `json:"refund_amount" gorm:"not null"` // 按退货数量占订单数量的比例计算
+	Currency     string       `json:"currency" gorm:"type:char(3);not null"`
+	RefundRef    string       `json:"refund_ref"` // 支付渠道退款流水号或链上退款交易哈希
+	CreatedAt    time.Time    `json:"created_at"`
+	UpdatedAt    time.Time    `json:"updated_at"`
+}
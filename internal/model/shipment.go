@@ -0,0 +1,53 @@
+package model
+
+import "time"
+
+type ShipmentStatus string
+
+const (
+	// ShipmentStatusShipped 已交给承运商
+	ShipmentStatusShipped ShipmentStatus = "shipped"
+	// ShipmentStatusInTransit 运输中
+	ShipmentStatusInTransit ShipmentStatus = "in_transit"
+	// ShipmentStatusOutForDelivery 派送中
+	ShipmentStatusOutForDelivery ShipmentStatus = "out_for_delivery"
+	// ShipmentStatusException 运输异常，如地址错误、拒收
+	ShipmentStatusException ShipmentStatus = "exception"
+	// ShipmentStatusDelivered 已签收
+	ShipmentStatusDelivered ShipmentStatus = "delivered"
+)
+
+// Valid 是否为已知的物流状态
+func (s ShipmentStatus) Valid() bool {
+	switch s {
+	case ShipmentStatusShipped, ShipmentStatusInTransit, ShipmentStatusOutForDelivery,
+		ShipmentStatusException, ShipmentStatusDelivered:
+		return true
+	}
+	return false
+}
+
+// Shipment 订单的发货记录
+type Shipment struct {
+	ID             int64           `json:"id" gorm:"primaryKey"`
+	OrderID        int64           `json:"order_id" gorm:"not null;uniqueIndex"`
+	Carrier        string          `json:"carrier" gorm:"not null"`
+	TrackingNumber string          `json:"tracking_number" gorm:"not null"`
+	Status         ShipmentStatus  `json:"status" gorm:"not null"`
+	ShippedAt      time.Time       `json:"shipped_at" gorm:"not null"`
+	DeliveredAt    *time.Time      `json:"delivered_at"`
+	Events         []ShipmentEvent `json:"events,omitempty" gorm:"foreignKey:ShipmentID"`
+	CreatedAt      time.Time       `json:"created_at"`
+	UpdatedAt      time.Time       `json:"updated_at"`
+}
+
+// ShipmentEvent 物流跟踪事件
+type ShipmentEvent struct {
+	ID          int64          `json:"id" gorm:"primaryKey"`
+	ShipmentID  int64          `json:"shipment_id" gorm:"not null;index"`
+	Status      ShipmentStatus `json:"status" gorm:"not null"`
+	Location    string         `json:"location"`
+	Description string         `json:"description"`
+	OccurredAt  time.Time      `json:"occurred_at" gorm:"not null"`
+	CreatedAt   time.Time      `json:"created_at"`
+}
@@ -0,0 +1,88 @@
+package model
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/ylh990835774/blockchain-shop-demo/pkg/money"
+)
+
+// ProductSKU 商品的可售规格，库存按SKU维护
+type ProductSKU struct {
+	ID                int64         `json:"id" gorm:"primaryKey"`
+	ProductID         int64         `json:"product_id" gorm:"not null;index"`
+	Code              string        `json:"code" gorm:"type:varchar(64);not null;uniqueIndex"`
+	Options           SKUOptions    `json:"options" gorm:"type:json"` // 规格属性，如颜色、尺码
+	Price             *money.Amount `json:"price,omitempty"`          // 商品基础币种下的价格，为空时使用商品价格
+	Stock             int           `json:"stock" gorm:"not null"`
+	IsDefault         bool          `json:"is_default" gorm:"not null;default:false"` // 未指定SKU下单时使用的默认规格
+	LowStockThreshold *int          `json:"low_stock_threshold,omitempty"`            // 低库存告警阈值，为空时使用全局配置
+	CreatedAt         time.Time     `json:"created_at"`
+	UpdatedAt         time.Time     `json:"updated_at"`
+}
+
+// SKUOptions SKU的规格属性，以JSON对象存储
+type SKUOptions map[string]string
+
+// Scan 实现sql.Scanner接口
+func (o *SKUOptions) Scan(value interface{}) error {
+	var data []byte
+	switch v := value.(type) {
+	case nil:
+		*o = nil
+		return nil
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		return fmt.Errorf("model: cannot scan %T into SKUOptions", value)
+	}
+	if len(data) == 0 {
+		*o = nil
+		return nil
+	}
+	return json.Unmarshal(data, o)
+}
+
+// Value 实现driver.Valuer接口
+func (o SKUOptions) Value() (driver.Value, error) {
+	if o == nil {
+		return "{}", nil
+	}
+	data, err := json.Marshal(o)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+// DefaultSKU 返回商品的默认SKU，没有默认SKU时返回false
+func (p *Product) DefaultSKU() (*ProductSKU, bool) {
+	for i := range p.SKUs {
+		if p.SKUs[i].IsDefault {
+			return &p.SKUs[i], true
+		}
+	}
+	return nil, false
+}
+
+// SKU 返回商品指定ID的SKU，不属于该商品时返回false
+func (p *Product) SKU(id int64) (*ProductSKU, bool) {
+	for i := range p.SKUs {
+		if p.SKUs[i].ID == id {
+			return &p.SKUs[i], true
+		}
+	}
+	return nil, false
+}
+
+// BasePrice SKU在商品基础币种下的价格
+func (p *Product) BasePrice(sku *ProductSKU) money.Amount {
+	if sku != nil && sku.Price != nil {
+		return *sku.Price
+	}
+	return p.Price
+}
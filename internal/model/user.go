@@ -6,16 +6,39 @@ import (
 	"golang.org/x/crypto/bcrypt"
 )
 
+// UserRole 用户角色
+type UserRole string
+
+const (
+	UserRoleCustomer UserRole = "customer"
+	UserRoleMerchant UserRole = "merchant" // 商户，负责发货等履约操作
+	UserRoleAdmin    UserRole = "admin"
+)
+
 type User struct {
 	ID        int64     `json:"id" gorm:"primaryKey"`
 	Username  string    `json:"username" gorm:"unique;not null"`
 	Password  string    `json:"-" gorm:"not null"`
 	Phone     string    `json:"phone" gorm:"size:11"`
 	Address   string    `json:"address"`
+	Currency  string    `json:"currency" gorm:"type:varchar(3);not null;default:''"` // 偏好币种，为空时使用商品基础币种
+	Role      UserRole  `json:"role" gorm:"type:varchar(16);not null;default:customer"`
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
 }
 
+// UserPatch 用户资料的部分更新，nil字段保持不变，空字符串表示清空
+type UserPatch struct {
+	Phone    *string
+	Address  *string
+	Currency *string
+}
+
+// IsEmpty 是否没有需要更新的字段
+func (p UserPatch) IsEmpty() bool {
+	return p.Phone == nil && p.Address == nil && p.Currency == nil
+}
+
 func (u *User) SetPassword(password string) error {
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
 	if err != nil {
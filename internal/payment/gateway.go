@@ -0,0 +1,56 @@
+package payment
+
+import (
+	"errors"
+
+	"github.com/ylh990835774/blockchain-shop-demo/pkg/money"
+)
+
+var (
+	// ErrInvalidSignature 回调签名校验失败
+	ErrInvalidSignature = errors.New("invalid webhook signature")
+	// ErrInvalidPayload 回调内容无法解析
+	ErrInvalidPayload = errors.New("invalid webhook payload")
+)
+
+// EventStatus 支付渠道回调的支付结果
+type EventStatus string
+
+const (
+	EventStatusSucceeded EventStatus = "succeeded"
+	EventStatusFailed    EventStatus = "failed"
+)
+
+// ChargeRequest 创建支付请求
+type ChargeRequest struct {
+	PaymentID int64
+	OrderID   int64
+	Amount    money.Amount
+	Currency  string
+}
+
+// Charge 支付渠道创建的支付
+type Charge struct {
+	ProviderRef string // 支付渠道流水号
+	PaymentURL  string // 客户端完成支付的跳转地址
+}
+
+// WebhookEvent 支付渠道回调事件
+type WebhookEvent struct {
+	ProviderRef string       `json:"provider_ref"`
+	Status      EventStatus  `json:"status"`
+	Amount      money.Amount `json:"amount"`
+	Currency    string       `json:"currency"`
+}
+
+// PaymentGateway 支付渠道接口
+type PaymentGateway interface {
+	// Name 支付渠道名称，对应回调地址中的provider
+	Name() string
+	// CreateCharge 在支付渠道创建支付
+	CreateCharge(req ChargeRequest) (*Charge, error)
+	// ParseWebhook 校验回调签名并解析回调事件
+	ParseWebhook(payload []byte, signature string) (*WebhookEvent, error)
+	// Refund 对已成功的支付退款，金额不超过支付金额，返回退款流水号
+	Refund(providerRef string, amount money.Amount) (string, error)
+}
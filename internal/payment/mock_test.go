@@ -0,0 +1,88 @@
+package payment
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/ylh990835774/blockchain-shop-demo/pkg/money"
+)
+
+func TestMockGateway_CreateCharge(t *testing.T) {
+	gateway := NewMockGateway("secret", "http://localhost:38080")
+
+	charge, err := gateway.CreateCharge(ChargeRequest{PaymentID: 1, OrderID: 2, Amount: money.MustParseAmount("19.98"), Currency: "CNY"})
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(charge.ProviderRef, "mock_ch_"))
+	assert.Equal(t, "http://localhost:38080/mock-pay/"+charge.ProviderRef, charge.PaymentURL)
+
+	other, err := gateway.CreateCharge(ChargeRequest{PaymentID: 2, OrderID: 3, Amount: money.MustParseAmount("1"), Currency: "CNY"})
+	require.NoError(t, err)
+	assert.NotEqual(t, charge.ProviderRef, other.ProviderRef)
+}
+
+func TestMockGateway_ParseWebhook(t *testing.T) {
+	gateway := NewMockGateway("secret", "")
+	valid := []byte(`{"provider_ref":"mock_ch_1","status":"succeeded","amount":19.98,"currency":"CNY"}`)
+
+	tests := []struct {
+		name        string
+		payload     []byte
+		signature   string
+		expectedErr error
+	}{
+		{
+			name:      "valid signature",
+			payload:   valid,
+			signature: gateway.Sign(valid),
+		},
+		{
+			name:        "signature from another secret",
+			payload:     valid,
+			signature:   NewMockGateway("other", "").Sign(valid),
+			expectedErr: ErrInvalidSignature,
+		},
+		{
+			name:        "malformed signature",
+			payload:     valid,
+			signature:   "not-hex",
+			expectedErr: ErrInvalidSignature,
+		},
+		{
+			name:        "tampered payload",
+			payload:     []byte(`{"provider_ref":"mock_ch_1","status":"succeeded","amount":0.01,"currency":"CNY"}`),
+			signature:   gateway.Sign(valid),
+			expectedErr: ErrInvalidSignature,
+		},
+		{
+			name:        "unknown status",
+			payload:     []byte(`{"provider_ref":"mock_ch_1","status":"maybe"}`),
+			signature:   gateway.Sign([]byte(`{"provider_ref":"mock_ch_1","status":"maybe"}`)),
+			expectedErr: ErrInvalidPayload,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			event, err := gateway.ParseWebhook(tt.payload, tt.signature)
+			if tt.expectedErr != nil {
+				assert.Equal(t, tt.expectedErr, err)
+				assert.Nil(t, event)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, "mock_ch_1", event.ProviderRef)
+			assert.Equal(t, EventStatusSucceeded, event.Status)
+			assert.Equal(t, money.MustParseAmount("19.98"), event.Amount)
+			assert.Equal(t, "CNY", event.Currency)
+		})
+	}
+}
+
+func TestNewMockGateway_EmptySecret(t *testing.T) {
+	assert.Panics(t, func() {
+		NewMockGateway("", "")
+	})
+}
@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/ylh990835774/blockchain-shop-demo/internal/model"
+	"github.com/ylh990835774/blockchain-shop-demo/pkg/money"
 	"gorm.io/gorm"
 )
 
@@ -33,24 +34,100 @@ func (r *BlockchainRepository) SaveTransaction(tx *model.Transaction) error {
 	return r.db.Create(tx).Error
 }
 
-func (r *BlockchainRepository) CreateTransaction(orderID int64, amount float64) (*model.Transaction, error) {
+// ListUnconfirmed 获取尚未确认的链上交易
+func (r *BlockchainRepository) ListUnconfirmed(limit int) ([]*model.Transaction, error) {
+	var txs []*model.Transaction
+	err := r.db.Where("status = ?", false).Order("timestamp").Limit(limit).Find(&txs).Error
+	if err != nil {
+		return nil, err
+	}
+	return txs, nil
+}
+
+func (r *BlockchainRepository) ListByOrderID(orderID int64) ([]*model.Transaction, error) {
+	var txs []*model.Transaction
+	err := r.db.Where("order_id = ?", orderID).Order("timestamp").Find(&txs).Error
+	if err != nil {
+		return nil, err
+	}
+	return txs, nil
+}
+
+func (r *BlockchainRepository) UpdateStatus(txHash string, status bool) error {
+	result := r.db.Model(&model.Transaction{}).Where("tx_hash = ?", txHash).Update("status", status)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// MarkRefunded 仅当转账尚未确认时将其标记为已确认并已整笔退回value，返回是否标记成功。
+// 标记在提交退款转账之前完成，确认流程重复执行时不会重复退款
+func (r *BlockchainRepository) MarkRefunded(txHash string, value money.Amount) (bool, error) {
+	result := r.db.Model(&model.Transaction{}).
+		Where("tx_hash = ? AND status = ? AND refunded = ?", txHash, false, false).
+		Updates(map[string]interface{}{"status": true, "refunded": true, "refunded_value": value})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// RevertRefunded 退款转账提交失败时撤销退回标记，等待下次确认时重试
+func (r *BlockchainRepository) RevertRefunded(txHash string) error {
+	return r.db.Model(&model.Transaction{}).
+		Where("tx_hash = ? AND refunded = ?", txHash, true).
+		Updates(map[string]interface{}{"status": false, "refunded": false, "refunded_value": money.Amount(0)}).Error
+}
+
+// TransitionRefund 仅当已确认的转账累计退回金额为from时将其更新为to，refunded表示是否已全额退回，返回是否更新成功
+func (r *BlockchainRepository) TransitionRefund(txHash string, from, to money.Amount, refunded bool) (bool, error) {
+	result := r.db.Model(&model.Transaction{}).
+		Where("tx_hash = ? AND status = ? AND refunded_value = ?", txHash, true, from).
+		Updates(map[string]interface{}{"refunded_value": to, "refunded": refunded})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// SetRefundTxHash 记录退款转账的哈希
+func (r *BlockchainRepository) SetRefundTxHash(txHash, refundTxHash string) error {
+	return r.db.Model(&model.Transaction{}).Where("tx_hash = ?", txHash).Update("refund_tx_hash", refundTxHash).Error
+}
+
+// CreateTransaction 为订单创建链上交易记录，记录订单金额和使用的优惠
+func (r *BlockchainRepository) CreateTransaction(order *model.Order) (*model.Transaction, error) {
+	return r.CreateTransactionWithTx(r.db, order)
+}
+
+// CreateTransactionWithTx 在事务中为订单创建链上交易记录
+func (r *BlockchainRepository) CreateTransactionWithTx(tx *gorm.DB, order *model.Order) (*model.Transaction, error) {
 	// 这里应该调用实际的区块链接口生成交易
 	// 为了演示，我们先生成一个模拟的交易
-	tx := &model.Transaction{
+	transaction := &model.Transaction{
 		TxHash:    generateTxHash(), // 这里需要实现一个生成交易哈希的函数
 		From:      "shop_address",   // 商店的区块链地址
 		To:        "user_address",   // 用户的区块链地址
-		Value:     fmt.Sprintf("%.2f", amount),
+		Value:     order.TotalPrice.String(),
+		Currency:  order.Currency,
 		Status:    true,
 		Timestamp: time.Now(),
-		OrderID:   orderID,
+		OrderID:   order.ID,
+	}
+	if order.CouponCode != "" {
+		transaction.Discount = order.Discount.String()
+		transaction.CouponCode = order.CouponCode
 	}
 
-	if err := r.SaveTransaction(tx); err != nil {
+	if err := tx.Create(transaction).Error; err != nil {
 		return nil, err
 	}
 
-	return tx, nil
+	return transaction, nil
 }
 
 func generateTxHash() string {
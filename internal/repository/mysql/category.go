@@ -0,0 +1,80 @@
+package mysql
+
+import (
+	"github.com/ylh990835774/blockchain-shop-demo/internal/model"
+	"gorm.io/gorm"
+)
+
+type CategoryRepository struct {
+	db *gorm.DB
+}
+
+func NewCategoryRepository(db *gorm.DB) *CategoryRepository {
+	return &CategoryRepository{db: db}
+}
+
+func (r *CategoryRepository) Create(category *model.Category) error {
+	if err := r.db.Create(category).Error; err != nil {
+		if err == gorm.ErrDuplicatedKey {
+			return ErrDuplicateKey
+		}
+		return err
+	}
+	return nil
+}
+
+func (r *CategoryRepository) Update(id int64, updates map[string]interface{}) error {
+	result := r.db.Model(&model.Category{}).Where("id = ?", id).Updates(updates)
+	if result.Error != nil {
+		if result.Error == gorm.ErrDuplicatedKey {
+			return ErrDuplicateKey
+		}
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// DeleteWithTx 删除分类及其与商品的关联，子分类由调用方先行检查
+func (r *CategoryRepository) DeleteWithTx(tx *gorm.DB, id int64) error {
+	if err := tx.Where("category_id = ?", id).Delete(&model.ProductCategory{}).Error; err != nil {
+		return err
+	}
+	result := tx.Delete(&model.Category{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *CategoryRepository) GetByID(id int64) (*model.Category, error) {
+	var category model.Category
+	if err := r.db.First(&category, id).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return &category, nil
+}
+
+// List 获取所有分类，同级分类按显示顺序和ID排序
+func (r *CategoryRepository) List() ([]*model.Category, error) {
+	var categories []*model.Category
+	if err := r.db.Order("position").Order("id").Find(&categories).Error; err != nil {
+		return nil, err
+	}
+	return categories, nil
+}
+
+// CountChildrenWithTx 统计分类的直接子分类数量
+func (r *CategoryRepository) CountChildrenWithTx(tx *gorm.DB, id int64) (int64, error) {
+	var count int64
+	err := tx.Model(&model.Category{}).Where("parent_id = ?", id).Count(&count).Error
+	return count, err
+}
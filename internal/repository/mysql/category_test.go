@@ -0,0 +1,74 @@
+package mysql
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/ylh990835774/blockchain-shop-demo/internal/model"
+	"github.com/ylh990835774/blockchain-shop-demo/internal/testutil"
+	"github.com/ylh990835774/blockchain-shop-demo/pkg/money"
+)
+
+func TestCategoryRepository(t *testing.T) {
+	db := testutil.NewDB(t)
+	repo := NewCategoryRepository(db)
+	productRepo := NewProductRepository(db)
+
+	drinks := &model.Category{Name: "饮品", Slug: "drinks", Position: 2}
+	require.NoError(t, repo.Create(drinks))
+	food := &model.Category{Name: "食品", Slug: "food", Position: 1}
+	require.NoError(t, repo.Create(food))
+	tea := &model.Category{Name: "茶", Slug: "tea", ParentID: &drinks.ID}
+	require.NoError(t, repo.Create(tea))
+	assert.Equal(t, ErrDuplicateKey, repo.Create(&model.Category{Name: "茶叶", Slug: "tea"}))
+
+	categories, err := repo.List()
+	require.NoError(t, err)
+	require.Len(t, categories, 3)
+	assert.Equal(t, []string{"tea", "food", "drinks"}, []string{categories[0].Slug, categories[1].Slug, categories[2].Slug})
+
+	count, err := repo.CountChildrenWithTx(db, drinks.ID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), count)
+
+	// 商品关联分类，按分类过滤商品
+	product := &model.Product{Name: "绿茶", Price: money.MustParseAmount("10"), Currency: "CNY", Stock: 1, CategoryIDs: []int64{tea.ID, food.ID}}
+	require.NoError(t, productRepo.Create(product))
+	other := &model.Product{Name: "咖啡", Price: money.MustParseAmount("20"), Currency: "CNY", Stock: 1, CategoryIDs: []int64{drinks.ID}}
+	require.NoError(t, productRepo.Create(other))
+
+	got, err := productRepo.GetByID(product.ID)
+	require.NoError(t, err)
+	assert.Equal(t, []int64{food.ID, tea.ID}, got.CategoryIDs)
+
+	products, total, err := productRepo.List(model.ProductFilter{CategoryIDs: []int64{tea.ID}}, 0, 10)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), total)
+	require.Len(t, products, 1)
+	assert.Equal(t, product.ID, products[0].ID)
+
+	products, total, err = productRepo.List(model.ProductFilter{CategoryIDs: []int64{drinks.ID, tea.ID}}, 0, 10)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), total)
+	require.Len(t, products, 2)
+
+	require.NoError(t, productRepo.SetCategoriesWithTx(db, product.ID, []int64{food.ID}))
+	products, _, err = productRepo.List(model.ProductFilter{CategoryIDs: []int64{tea.ID}}, 0, 10)
+	require.NoError(t, err)
+	assert.Empty(t, products)
+
+	// 删除分类时解除与商品的关联
+	require.NoError(t, repo.Update(tea.ID, map[string]interface{}{"parent_id": nil}))
+	require.NoError(t, repo.DeleteWithTx(db, food.ID))
+	assert.Equal(t, ErrNotFound, repo.DeleteWithTx(db, food.ID))
+	got, err = productRepo.GetByID(product.ID)
+	require.NoError(t, err)
+	assert.Empty(t, got.CategoryIDs)
+
+	_, err = repo.GetByID(food.ID)
+	assert.Equal(t, ErrNotFound, err)
+	count, err = repo.CountChildrenWithTx(db, drinks.ID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), count)
+}
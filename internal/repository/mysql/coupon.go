@@ -0,0 +1,163 @@
+package mysql
+
+import (
+	"github.com/ylh990835774/blockchain-shop-demo/internal/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type CouponRepository struct {
+	db *gorm.DB
+}
+
+func NewCouponRepository(db *gorm.DB) *CouponRepository {
+	return &CouponRepository{db: db}
+}
+
+// CreateWithTx 创建优惠券及其限定商品
+func (r *CouponRepository) CreateWithTx(tx *gorm.DB, coupon *model.Coupon) error {
+	if err := tx.Create(coupon).Error; err != nil {
+		if err == gorm.ErrDuplicatedKey {
+			return ErrDuplicateKey
+		}
+		return err
+	}
+	return r.SetProductsWithTx(tx, coupon.ID, coupon.ProductIDs)
+}
+
+func (r *CouponRepository) UpdateWithTx(tx *gorm.DB, id int64, updates map[string]interface{}) error {
+	result := tx.Model(&model.Coupon{}).Where("id = ?", id).Updates(updates)
+	if result.Error != nil {
+		if result.Error == gorm.ErrDuplicatedKey {
+			return ErrDuplicateKey
+		}
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// SetProductsWithTx 替换优惠券限定的商品
+func (r *CouponRepository) SetProductsWithTx(tx *gorm.DB, couponID int64, productIDs []int64) error {
+	if err := tx.Where("coupon_id = ?", couponID).Delete(&model.CouponProduct{}).Error; err != nil {
+		return err
+	}
+	if len(productIDs) == 0 {
+		return nil
+	}
+
+	products := make([]model.CouponProduct, 0, len(productIDs))
+	for _, id := range productIDs {
+		products = append(products, model.CouponProduct{CouponID: couponID, ProductID: id})
+	}
+	return tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&products).Error
+}
+
+// DeleteWithTx 删除优惠券，已有的使用记录保留
+func (r *CouponRepository) DeleteWithTx(tx *gorm.DB, id int64) error {
+	if err := tx.Where("coupon_id = ?", id).Delete(&model.CouponProduct{}).Error; err != nil {
+		return err
+	}
+	result := tx.Delete(&model.Coupon{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *CouponRepository) GetByID(id int64) (*model.Coupon, error) {
+	var coupon model.Coupon
+	if err := r.db.First(&coupon, id).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	if err := r.loadProducts(r.db, &coupon); err != nil {
+		return nil, err
+	}
+	return &coupon, nil
+}
+
+// GetByCodeForUpdateWithTx 按券码获取优惠券并加行锁，使用次数的检查和累加在锁内完成
+func (r *CouponRepository) GetByCodeForUpdateWithTx(tx *gorm.DB, code string) (*model.Coupon, error) {
+	var coupon model.Coupon
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("code = ?", code).First(&coupon).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	if err := r.loadProducts(tx, &coupon); err != nil {
+		return nil, err
+	}
+	return &coupon, nil
+}
+
+func (r *CouponRepository) List(offset, limit int) ([]*model.Coupon, int64, error) {
+	var coupons []*model.Coupon
+	var total int64
+
+	if err := r.db.Model(&model.Coupon{}).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	if err := r.db.Order("id DESC").Offset(offset).Limit(limit).Find(&coupons).Error; err != nil {
+		return nil, 0, err
+	}
+	for _, coupon := range coupons {
+		if err := r.loadProducts(r.db, coupon); err != nil {
+			return nil, 0, err
+		}
+	}
+	return coupons, total, nil
+}
+
+// CountRedemptionsWithTx 统计用户使用优惠券的次数
+func (r *CouponRepository) CountRedemptionsWithTx(tx *gorm.DB, couponID, userID int64) (int64, error) {
+	var count int64
+	err := tx.Model(&model.CouponRedemption{}).
+		Where("coupon_id = ? AND user_id = ?", couponID, userID).Count(&count).Error
+	return count, err
+}
+
+// RedeemWithTx 记录优惠券使用并累加使用次数
+func (r *CouponRepository) RedeemWithTx(tx *gorm.DB, redemption *model.CouponRedemption) error {
+	if err := tx.Create(redemption).Error; err != nil {
+		if err == gorm.ErrDuplicatedKey {
+			return ErrDuplicateKey
+		}
+		return err
+	}
+	return tx.Model(&model.Coupon{}).Where("id = ?", redemption.CouponID).
+		UpdateColumn("used_count", gorm.Expr("used_count + 1")).Error
+}
+
+// ReleaseWithTx 撤销订单的优惠券使用记录并退还使用次数，订单未使用优惠券时不做处理
+func (r *CouponRepository) ReleaseWithTx(tx *gorm.DB, orderID int64) error {
+	var redemption model.CouponRedemption
+	err := tx.Where("order_id = ?", orderID).First(&redemption).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil
+		}
+		return err
+	}
+
+	if err := tx.Delete(&redemption).Error; err != nil {
+		return err
+	}
+	return tx.Model(&model.Coupon{}).Where("id = ? AND used_count > 0", redemption.CouponID).
+		UpdateColumn("used_count", gorm.Expr("used_count - 1")).Error
+}
+
+func (r *CouponRepository) loadProducts(tx *gorm.DB, coupon *model.Coupon) error {
+	coupon.ProductIDs = []int64{}
+	return tx.Model(&model.CouponProduct{}).Where("coupon_id = ?", coupon.ID).
+		Order("product_id").Pluck("product_id", &coupon.ProductIDs).Error
+}
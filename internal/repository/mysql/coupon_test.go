@@ -0,0 +1,79 @@
+package mysql
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/ylh990835774/blockchain-shop-demo/internal/model"
+	"github.com/ylh990835774/blockchain-shop-demo/internal/testutil"
+	"github.com/ylh990835774/blockchain-shop-demo/pkg/money"
+)
+
+func TestCouponRepository(t *testing.T) {
+	db := testutil.NewDB(t)
+	repo := NewCouponRepository(db)
+
+	coupon := &model.Coupon{
+		Code:         "SPRING20",
+		Type:         model.CouponTypePercent,
+		PercentOff:   money.MustParseRate("0.2"),
+		UsageLimit:   10,
+		PerUserLimit: 1,
+		ProductIDs:   []int64{5, 3},
+	}
+	require.NoError(t, repo.CreateWithTx(db, coupon))
+
+	duplicate := &model.Coupon{Code: "SPRING20", Type: model.CouponTypeFixed, AmountOff: money.MustParseAmount("5")}
+	assert.Equal(t, ErrDuplicateKey, repo.CreateWithTx(db, duplicate))
+
+	got, err := repo.GetByCodeForUpdateWithTx(db, "SPRING20")
+	require.NoError(t, err)
+	assert.Equal(t, coupon.ID, got.ID)
+	assert.Equal(t, []int64{3, 5}, got.ProductIDs)
+	assert.False(t, got.Disabled)
+
+	// 替换限定商品，清空后不限商品
+	require.NoError(t, repo.SetProductsWithTx(db, coupon.ID, []int64{7}))
+	got, err = repo.GetByID(coupon.ID)
+	require.NoError(t, err)
+	assert.Equal(t, []int64{7}, got.ProductIDs)
+	require.NoError(t, repo.SetProductsWithTx(db, coupon.ID, nil))
+	got, err = repo.GetByID(coupon.ID)
+	require.NoError(t, err)
+	assert.Empty(t, got.ProductIDs)
+
+	// 使用记录累加次数，订单取消后退还
+	require.NoError(t, repo.RedeemWithTx(db, &model.CouponRedemption{
+		CouponID: coupon.ID, UserID: 1, OrderID: 100, Discount: money.MustParseAmount("20"), Currency: "CNY",
+	}))
+	assert.Equal(t, ErrDuplicateKey, repo.RedeemWithTx(db, &model.CouponRedemption{
+		CouponID: coupon.ID, UserID: 1, OrderID: 100, Discount: money.MustParseAmount("20"), Currency: "CNY",
+	}))
+	count, err := repo.CountRedemptionsWithTx(db, coupon.ID, 1)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), count)
+	got, err = repo.GetByID(coupon.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 1, got.UsedCount)
+
+	require.NoError(t, repo.ReleaseWithTx(db, 100))
+	require.NoError(t, repo.ReleaseWithTx(db, 101))
+	count, err = repo.CountRedemptionsWithTx(db, coupon.ID, 1)
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), count)
+	got, err = repo.GetByID(coupon.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 0, got.UsedCount)
+
+	coupons, total, err := repo.List(0, 10)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), total)
+	require.Len(t, coupons, 1)
+
+	require.NoError(t, repo.UpdateWithTx(db, coupon.ID, map[string]interface{}{"disabled": true}))
+	require.NoError(t, repo.DeleteWithTx(db, coupon.ID))
+	assert.Equal(t, ErrNotFound, repo.DeleteWithTx(db, coupon.ID))
+	_, err = repo.GetByCodeForUpdateWithTx(db, "SPRING20")
+	assert.Equal(t, ErrNotFound, err)
+}
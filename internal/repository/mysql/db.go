@@ -26,7 +26,10 @@ func NewDB(cfg *Config) (*gorm.DB, error) {
 		cfg.Database,
 	)
 
-	db, err := gorm.Open(mysql.Open(dsn), &gorm.Config{})
+	db, err := gorm.Open(mysql.Open(dsn), &gorm.Config{
+		// 将驱动错误转换为 gorm.ErrDuplicatedKey 等通用错误
+		TranslateError: true,
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -2,5 +2,13 @@ package mysql
 
 import "errors"
 
-// ErrNotFound 记录未找到
-var ErrNotFound = errors.New("record not found")
+var (
+	// ErrNotFound 记录未找到
+	ErrNotFound = errors.New("record not found")
+	// ErrDuplicateKey 违反唯一约束
+	ErrDuplicateKey = errors.New("duplicate key")
+	// ErrInsufficientStock 库存不足
+	ErrInsufficientStock = errors.New("insufficient stock")
+	// ErrVersionConflict 记录已被修改，版本号与预期不一致
+	ErrVersionConflict = errors.New("version conflict")
+)
@@ -0,0 +1,70 @@
+package mysql
+
+import (
+	"errors"
+	"time"
+
+	"github.com/ylh990835774/blockchain-shop-demo/internal/model"
+	"gorm.io/gorm"
+)
+
+type IdempotencyRepository struct {
+	db *gorm.DB
+}
+
+func NewIdempotencyRepository(db *gorm.DB) *IdempotencyRepository {
+	return &IdempotencyRepository{db: db}
+}
+
+func (r *IdempotencyRepository) Create(record *model.IdempotencyKey) error {
+	err := r.db.Create(record).Error
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		return ErrDuplicateKey
+	}
+	return err
+}
+
+func (r *IdempotencyRepository) Get(userID int64, key string) (*model.IdempotencyKey, error) {
+	var record model.IdempotencyKey
+	err := r.db.Where("user_id = ? AND idempotency_key = ?", userID, key).First(&record).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return &record, nil
+}
+
+// Complete 保存处理中记录的响应，记录不存在或已完成时返回ErrNotFound
+func (r *IdempotencyRepository) Complete(id int64, statusCode int, body []byte) error {
+	result := r.db.Model(&model.IdempotencyKey{}).
+		Where("id = ? AND status_code = 0", id).
+		Updates(map[string]interface{}{
+			"status_code":   statusCode,
+			"response_body": body,
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// DeleteInProgress 删除处理中的记录，已完成的记录保留
+func (r *IdempotencyRepository) DeleteInProgress(id int64) error {
+	return r.db.Where("id = ? AND status_code = 0", id).Delete(&model.IdempotencyKey{}).Error
+}
+
+// DeleteByID 删除指定的幂等键记录，记录已不存在时返回false
+func (r *IdempotencyRepository) DeleteByID(id int64) (bool, error) {
+	result := r.db.Where("id = ?", id).Delete(&model.IdempotencyKey{})
+	return result.RowsAffected > 0, result.Error
+}
+
+func (r *IdempotencyRepository) DeleteExpired(now time.Time) (int64, error) {
+	result := r.db.Where("expires_at <= ?", now).Delete(&model.IdempotencyKey{})
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,153 @@
+package mysql
+
+import (
+	"time"
+
+	"github.com/ylh990835774/blockchain-shop-demo/internal/model"
+	"gorm.io/gorm"
+)
+
+// InventoryRepository 库存流水和低库存告警。库存流水由ProductRepository在调整库存时写入
+type InventoryRepository struct {
+	db *gorm.DB
+}
+
+func NewInventoryRepository(db *gorm.DB) *InventoryRepository {
+	return &InventoryRepository{db: db}
+}
+
+// ListMovements 按时间从新到旧分页查询库存流水
+func (r *InventoryRepository) ListMovements(filter model.InventoryMovementFilter, offset, limit int) ([]*model.InventoryMovement, int64, error) {
+	var movements []*model.InventoryMovement
+	var total int64
+
+	db := r.db.Model(&model.InventoryMovement{})
+	if filter.ProductID > 0 {
+		db = db.Where("product_id = ?", filter.ProductID)
+	}
+	if filter.SKUID > 0 {
+		db = db.Where("sku_id = ?", filter.SKUID)
+	}
+	if filter.Type != "" {
+		db = db.Where("type = ?", filter.Type)
+	}
+
+	if err := db.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	if err := db.Order("created_at DESC, id DESC").Offset(offset).Limit(limit).Find(&movements).Error; err != nil {
+		return nil, 0, err
+	}
+	return movements, total, nil
+}
+
+// StockDiscrepancies 查询库存合计与库存流水数量之和不一致的商品，包括已删除的商品
+func (r *InventoryRepository) StockDiscrepancies() ([]*model.StockDiscrepancy, error) {
+	totals := r.db.Model(&model.InventoryMovement{}).
+		Select("product_id, SUM(quantity) AS total").Group("product_id")
+
+	discrepancies := make([]*model.StockDiscrepancy, 0)
+	err := r.db.Table("products AS p").
+		Select("p.id AS product_id, p.stock AS stock, COALESCE(m.total, 0) AS movement_total").
+		Joins("LEFT JOIN (?) AS m ON m.product_id = p.id", totals).
+		Where("p.stock <> COALESCE(m.total, 0)").
+		Order("p.id").
+		Scan(&discrepancies).Error
+	if err != nil {
+		return nil, err
+	}
+	return discrepancies, nil
+}
+
+// lowStockSKUs 库存不高于阈值的在售SKU，SKU未设置阈值时使用defaultThreshold
+func (r *InventoryRepository) lowStockSKUs(tx *gorm.DB, defaultThreshold int) *gorm.DB {
+	return tx.Table("product_skus AS s").
+		Joins("JOIN products AS p ON p.id = s.product_id").
+		Where("s.stock <= COALESCE(s.low_stock_threshold, ?) AND p.archived_at IS NULL AND p.deleted_at IS NULL",
+			defaultThreshold)
+}
+
+// ResolveAlerts 关闭库存已回到阈值以上、或已下架删除的SKU的告警，返回关闭的告警数
+func (r *InventoryRepository) ResolveAlerts(defaultThreshold int, now time.Time) (int64, error) {
+	low := r.lowStockSKUs(r.db.Session(&gorm.Session{NewDB: true}), defaultThreshold).Select("s.id")
+	result := r.db.Model(&model.LowStockAlert{}).
+		Where("resolved_at IS NULL AND sku_id NOT IN (?)", low).
+		Update("resolved_at", now)
+	return result.RowsAffected, result.Error
+}
+
+// CreateAlerts 为库存不高于阈值且没有未关闭告警的SKU创建告警
+func (r *InventoryRepository) CreateAlerts(defaultThreshold int) ([]*model.LowStockAlert, error) {
+	var alerts []*model.LowStockAlert
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		open := tx.Session(&gorm.Session{NewDB: true}).Model(&model.LowStockAlert{}).
+			Select("1").Where("low_stock_alerts.sku_id = s.id AND low_stock_alerts.resolved_at IS NULL")
+		err := r.lowStockSKUs(tx, defaultThreshold).
+			Select("s.product_id AS product_id, s.id AS sku_id, s.code AS sku_code, s.stock AS stock, "+
+				"COALESCE(s.low_stock_threshold, ?) AS threshold", defaultThreshold).
+			Where("NOT EXISTS (?)", open).
+			Order("s.id").
+			Scan(&alerts).Error
+		if err != nil {
+			return err
+		}
+		if len(alerts) == 0 {
+			return nil
+		}
+		return tx.Create(&alerts).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return alerts, nil
+}
+
+// ListUndeliveredAlerts 查询尚未送达且重试次数未超过上限的告警
+func (r *InventoryRepository) ListUndeliveredAlerts(maxAttempts, limit int) ([]*model.LowStockAlert, error) {
+	var alerts []*model.LowStockAlert
+	err := r.db.Where("delivered_at IS NULL AND attempts < ?", maxAttempts).
+		Order("id").Limit(limit).Find(&alerts).Error
+	if err != nil {
+		return nil, err
+	}
+	return alerts, nil
+}
+
+// MarkAlertDelivered 记录告警通知已送达
+func (r *InventoryRepository) MarkAlertDelivered(id int64, deliveredAt time.Time) error {
+	return r.db.Model(&model.LowStockAlert{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"delivered_at": deliveredAt,
+		"attempts":     gorm.Expr("attempts + 1"),
+		"last_error":   "",
+	}).Error
+}
+
+// MarkAlertFailed 记录告警通知发送失败
+func (r *InventoryRepository) MarkAlertFailed(id int64, message string) error {
+	if runes := []rune(message); len(runes) > 512 {
+		message = string(runes[:512])
+	}
+	return r.db.Model(&model.LowStockAlert{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"attempts":   gorm.Expr("attempts + 1"),
+		"last_error": message,
+	}).Error
+}
+
+// ListAlerts 按创建时间从新到旧分页查询低库存告警，openOnly为true时只查询未关闭的告警
+func (r *InventoryRepository) ListAlerts(openOnly bool, offset, limit int) ([]*model.LowStockAlert, int64, error) {
+	var alerts []*model.LowStockAlert
+	var total int64
+
+	db := r.db.Model(&model.LowStockAlert{})
+	if openOnly {
+		db = db.Where("resolved_at IS NULL")
+	}
+
+	if err := db.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	if err := db.Order("created_at DESC, id DESC").Offset(offset).Limit(limit).Find(&alerts).Error; err != nil {
+		return nil, 0, err
+	}
+	return alerts, total, nil
+}
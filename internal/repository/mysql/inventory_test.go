@@ -0,0 +1,192 @@
+package mysql
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/ylh990835774/blockchain-shop-demo/internal/model"
+	"github.com/ylh990835774/blockchain-shop-demo/internal/testutil"
+	"github.com/ylh990835774/blockchain-shop-demo/pkg/money"
+	"gorm.io/gorm"
+)
+
+func TestInventoryRepository_MovementsMatchStock(t *testing.T) {
+	db := testutil.NewDB(t)
+	repo := NewProductRepository(db)
+	inventory := NewInventoryRepository(db)
+
+	product := &model.Product{
+		Name:     "T恤",
+		Price:    money.MustParseAmount("10.00"),
+		Currency: "CNY",
+		Stock:    8,
+		SKUs: []model.ProductSKU{
+			{Code: "TEE-M", Stock: 5, IsDefault: true},
+			{Code: "TEE-L", Stock: 3},
+		},
+	}
+	require.NoError(t, repo.Create(product))
+	medium, large := product.SKUs[0], product.SKUs[1]
+
+	orderID := int64(42)
+	require.NoError(t, db.Transaction(func(tx *gorm.DB) error {
+		return repo.DecrementStockWithTx(tx, &model.InventoryMovement{
+			SKUID: medium.ID, Type: model.MovementTypeReservation, Quantity: -2, OrderID: &orderID,
+		})
+	}))
+	require.NoError(t, db.Transaction(func(tx *gorm.DB) error {
+		return repo.RecordSaleWithTx(tx, &model.StockReservation{
+			OrderID: orderID, ProductID: product.ID, SKUID: medium.ID, Quantity: 2,
+		})
+	}))
+	actorID := int64(7)
+	require.NoError(t, repo.UpdateStock(&model.InventoryMovement{
+		SKUID: large.ID, Type: model.MovementTypeRestock, Quantity: 4, ActorID: &actorID, Reason: "到货",
+	}))
+	// 库存不足时不写入流水
+	assert.Equal(t, ErrNotFound, repo.UpdateStock(&model.InventoryMovement{
+		SKUID: medium.ID, Type: model.MovementTypeAdjustment, Quantity: -10,
+	}))
+	require.NoError(t, db.Transaction(func(tx *gorm.DB) error {
+		large.Stock = 6
+		return repo.UpdateSKUWithTx(tx, &large, actorID)
+	}))
+	extra := &model.ProductSKU{ProductID: product.ID, Code: "TEE-XL", Stock: 2}
+	require.NoError(t, db.Transaction(func(tx *gorm.DB) error {
+		return repo.CreateSKUWithTx(tx, extra, actorID)
+	}))
+	require.NoError(t, db.Transaction(func(tx *gorm.DB) error {
+		return repo.DeleteSKUWithTx(tx, extra, actorID)
+	}))
+
+	got, err := repo.GetByID(product.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 9, got.Stock)
+
+	movements, total, err := inventory.ListMovements(model.InventoryMovementFilter{ProductID: product.ID}, 0, 100)
+	require.NoError(t, err)
+	assert.Equal(t, int64(9), total)
+	sum := 0
+	for _, movement := range movements {
+		sum += movement.Quantity
+	}
+	assert.Equal(t, got.Stock, sum)
+
+	restocks, total, err := inventory.ListMovements(model.InventoryMovementFilter{
+		SKUID: large.ID, Type: model.MovementTypeRestock,
+	}, 0, 10)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), total)
+	assert.Equal(t, product.ID, restocks[0].ProductID)
+	assert.Equal(t, 4, restocks[0].Quantity)
+	assert.Equal(t, &actorID, restocks[0].ActorID)
+	assert.Equal(t, "到货", restocks[0].Reason)
+
+	// SKU维护引起的库存变化记为操作人的调整流水
+	adjustments, _, err := inventory.ListMovements(model.InventoryMovementFilter{
+		ProductID: product.ID, Type: model.MovementTypeAdjustment,
+	}, 0, 10)
+	require.NoError(t, err)
+	maintained := 0
+	for _, adjustment := range adjustments {
+		if adjustment.Reason == "创建商品" {
+			assert.Nil(t, adjustment.ActorID)
+			continue
+		}
+		maintained++
+		assert.Equal(t, &actorID, adjustment.ActorID)
+	}
+	assert.Equal(t, 3, maintained)
+
+	sales, _, err := inventory.ListMovements(model.InventoryMovementFilter{Type: model.MovementTypeSale}, 0, 10)
+	require.NoError(t, err)
+	require.Len(t, sales, 1)
+	assert.Equal(t, -2, sales[0].Quantity)
+	assert.Equal(t, &orderID, sales[0].OrderID)
+
+	discrepancies, err := inventory.StockDiscrepancies()
+	require.NoError(t, err)
+	assert.Empty(t, discrepancies)
+
+	// 绕过仓储直接修改的库存能被发现
+	require.NoError(t, db.Model(&model.Product{}).Where("id = ?", product.ID).UpdateColumn("stock", 100).Error)
+	discrepancies, err = inventory.StockDiscrepancies()
+	require.NoError(t, err)
+	assert.Equal(t, []*model.StockDiscrepancy{{ProductID: product.ID, Stock: 100, MovementTotal: 9}}, discrepancies)
+}
+
+func TestInventoryRepository_LowStockAlerts(t *testing.T) {
+	db := testutil.NewDB(t)
+	repo := NewProductRepository(db)
+	inventory := NewInventoryRepository(db)
+
+	threshold := 1
+	product := &model.Product{
+		Name:     "绿茶",
+		Price:    money.MustParseAmount("10.00"),
+		Currency: "CNY",
+		Stock:    5,
+		SKUs: []model.ProductSKU{
+			{Code: "TEA-S", Stock: 3, IsDefault: true},
+			{Code: "TEA-L", Stock: 2, LowStockThreshold: &threshold},
+		},
+	}
+	require.NoError(t, repo.Create(product))
+	small, large := product.SKUs[0], product.SKUs[1]
+
+	// 默认阈值为3时小包装告警，大包装使用自己的阈值1
+	alerts, err := inventory.CreateAlerts(3)
+	require.NoError(t, err)
+	require.Len(t, alerts, 1)
+	assert.Equal(t, small.ID, alerts[0].SKUID)
+	assert.Equal(t, "TEA-S", alerts[0].SKUCode)
+	assert.Equal(t, 3, alerts[0].Stock)
+	assert.Equal(t, 3, alerts[0].Threshold)
+
+	// 未关闭的告警不重复创建
+	alerts, err = inventory.CreateAlerts(3)
+	require.NoError(t, err)
+	assert.Empty(t, alerts)
+
+	require.NoError(t, repo.UpdateStock(&model.InventoryMovement{SKUID: large.ID, Type: model.MovementTypeAdjustment, Quantity: -1}))
+	require.NoError(t, repo.UpdateStock(&model.InventoryMovement{SKUID: small.ID, Type: model.MovementTypeRestock, Quantity: 10}))
+
+	resolved, err := inventory.ResolveAlerts(3, time.Now())
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), resolved)
+	alerts, err = inventory.CreateAlerts(3)
+	require.NoError(t, err)
+	require.Len(t, alerts, 1)
+	assert.Equal(t, large.ID, alerts[0].SKUID)
+	assert.Equal(t, 1, alerts[0].Threshold)
+
+	open, total, err := inventory.ListAlerts(true, 0, 10)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), total)
+	assert.Equal(t, large.ID, open[0].SKUID)
+	_, total, err = inventory.ListAlerts(false, 0, 10)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), total)
+
+	// 发送失败的告警按次数重试，送达后不再发送
+	pending, err := inventory.ListUndeliveredAlerts(2, 10)
+	require.NoError(t, err)
+	require.Len(t, pending, 2)
+	require.NoError(t, inventory.MarkAlertFailed(pending[0].ID, "connection refused"))
+	require.NoError(t, inventory.MarkAlertFailed(pending[0].ID, "connection refused"))
+	require.NoError(t, inventory.MarkAlertDelivered(pending[1].ID, time.Now()))
+	pending, err = inventory.ListUndeliveredAlerts(2, 10)
+	require.NoError(t, err)
+	assert.Empty(t, pending)
+
+	// 下架的商品不告警
+	require.NoError(t, repo.SetArchived(product.ID, true))
+	resolved, err = inventory.ResolveAlerts(3, time.Now())
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), resolved)
+	alerts, err = inventory.CreateAlerts(3)
+	require.NoError(t, err)
+	assert.Empty(t, alerts)
+}
@@ -0,0 +1,75 @@
+package mysql
+
+import (
+	"github.com/ylh990835774/blockchain-shop-demo/internal/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type InvoiceRepository struct {
+	db *gorm.DB
+}
+
+func NewInvoiceRepository(db *gorm.DB) *InvoiceRepository {
+	return &InvoiceRepository{db: db}
+}
+
+// NextSequenceWithTx 分配开票编码的下一个发票编号。计数器行在事务提交前保持锁定，
+// 事务回滚时编号随之回滚，保证编号连续无间断
+func (r *InvoiceRepository) NextSequenceWithTx(tx *gorm.DB, merchantCode string) (int64, error) {
+	if err := tx.Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&model.InvoiceSequence{MerchantCode: merchantCode}).Error; err != nil {
+		return 0, err
+	}
+
+	var seq model.InvoiceSequence
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("merchant_code = ?", merchantCode).First(&seq).Error; err != nil {
+		return 0, err
+	}
+
+	next := seq.LastNumber + 1
+	if err := tx.Model(&seq).Update("last_number", next).Error; err != nil {
+		return 0, err
+	}
+	return next, nil
+}
+
+// CreateWithTx 创建发票及明细行
+func (r *InvoiceRepository) CreateWithTx(tx *gorm.DB, invoice *model.Invoice) error {
+	if err := tx.Create(invoice).Error; err != nil {
+		if err == gorm.ErrDuplicatedKey {
+			return ErrDuplicateKey
+		}
+		return err
+	}
+	return nil
+}
+
+func (r *InvoiceRepository) GetByOrderID(orderID int64) (*model.Invoice, error) {
+	return r.GetByOrderIDWithTx(r.db, orderID)
+}
+
+func (r *InvoiceRepository) GetByOrderIDWithTx(tx *gorm.DB, orderID int64) (*model.Invoice, error) {
+	var invoice model.Invoice
+	err := tx.Preload("Lines").Where("order_id = ?", orderID).First(&invoice).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return &invoice, nil
+}
+
+func (r *InvoiceRepository) GetByNumber(number string) (*model.Invoice, error) {
+	var invoice model.Invoice
+	err := r.db.Preload("Lines").Where("number = ?", number).First(&invoice).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return &invoice, nil
+}
@@ -0,0 +1,89 @@
+package mysql
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/ylh990835774/blockchain-shop-demo/internal/model"
+	"github.com/ylh990835774/blockchain-shop-demo/internal/testutil"
+	"github.com/ylh990835774/blockchain-shop-demo/pkg/money"
+)
+
+func TestInvoiceRepository_NextSequence(t *testing.T) {
+	db := testutil.NewDB(t)
+	repo := NewInvoiceRepository(db)
+
+	next := func(merchantCode string) int64 {
+		n, err := repo.NextSequenceWithTx(db, merchantCode)
+		require.NoError(t, err)
+		return n
+	}
+
+	assert.Equal(t, int64(1), next("SHOP"))
+	assert.Equal(t, int64(2), next("SHOP"))
+	// 每个商户独立编号
+	assert.Equal(t, int64(1), next("OTHER"))
+
+	// 事务回滚后编号不会被占用
+	tx := db.Begin()
+	n, err := repo.NextSequenceWithTx(tx, "SHOP")
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), n)
+	require.NoError(t, tx.Rollback().Error)
+	assert.Equal(t, int64(3), next("SHOP"))
+}
+
+func TestInvoiceRepository_Create(t *testing.T) {
+	db := testutil.NewDB(t)
+	repo := NewInvoiceRepository(db)
+
+	invoice := &model.Invoice{
+		Number:       "INV-SHOP-00000001",
+		MerchantCode: "SHOP",
+		Sequence:     1,
+		OrderID:      1,
+		UserID:       1,
+		SellerName:   "Blockchain Shop",
+		BuyerName:    "testuser",
+		Currency:     "CNY",
+		Subtotal:     money.MustParseAmount("17.68"),
+		TaxTotal:     money.MustParseAmount("2.30"),
+		Total:        money.MustParseAmount("19.98"),
+		Lines: []model.InvoiceLine{{
+			Description: "测试商品",
+			Quantity:    2,
+			UnitPrice:   money.MustParseAmount("9.99"),
+			NetAmount:   money.MustParseAmount("17.68"),
+			TaxRate:     money.MustParseRate("0.13"),
+			TaxAmount:   money.MustParseAmount("2.30"),
+			Amount:      money.MustParseAmount("19.98"),
+		}},
+		IssuedAt: time.Now(),
+	}
+	require.NoError(t, repo.CreateWithTx(db, invoice))
+
+	got, err := repo.GetByOrderID(1)
+	require.NoError(t, err)
+	assert.Equal(t, invoice.Number, got.Number)
+	require.Len(t, got.Lines, 1)
+	assert.Equal(t, money.MustParseRate("0.13"), got.Lines[0].TaxRate)
+
+	got, err = repo.GetByNumber(invoice.Number)
+	require.NoError(t, err)
+	assert.Equal(t, invoice.ID, got.ID)
+
+	// 每个订单只能开具一张发票
+	duplicate := *invoice
+	duplicate.ID = 0
+	duplicate.Number = "INV-SHOP-00000002"
+	duplicate.Sequence = 2
+	duplicate.Lines = nil
+	assert.Equal(t, ErrDuplicateKey, repo.CreateWithTx(db, &duplicate))
+
+	_, err = repo.GetByOrderID(2)
+	assert.Equal(t, ErrNotFound, err)
+	_, err = repo.GetByNumber("INV-SHOP-00000099")
+	assert.Equal(t, ErrNotFound, err)
+}
@@ -3,6 +3,7 @@ package mysql
 import (
 	"github.com/ylh990835774/blockchain-shop-demo/internal/model"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type OrderRepository struct {
@@ -45,7 +46,7 @@ func (r *OrderRepository) UpdateWithTx(tx *gorm.DB, id int64, updates map[string
 
 func (r *OrderRepository) GetByID(id int64) (*model.Order, error) {
 	var order model.Order
-	err := r.db.First(&order, id).Error
+	err := r.db.Preload("TaxLines").First(&order, id).Error
 	if err != nil {
 		if err == gorm.ErrRecordNotFound {
 			return nil, ErrNotFound
@@ -55,21 +56,106 @@ func (r *OrderRepository) GetByID(id int64) (*model.Order, error) {
 	return &order, nil
 }
 
-func (r *OrderRepository) ListByUserID(userID int64, page, pageSize int) ([]*model.Order, int64, error) {
+// GetByIDForUpdateWithTx 获取订单并加行锁
+func (r *OrderRepository) GetByIDForUpdateWithTx(tx *gorm.DB, id int64) (*model.Order, error) {
+	var order model.Order
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&order, id).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return &order, nil
+}
+
+// ListTaxLinesWithTx 获取订单的计税明细
+func (r *OrderRepository) ListTaxLinesWithTx(tx *gorm.DB, orderID int64) ([]model.OrderTaxLine, error) {
+	var lines []model.OrderTaxLine
+	if err := tx.Where("order_id = ?", orderID).Order("id").Find(&lines).Error; err != nil {
+		return nil, err
+	}
+	return lines, nil
+}
+
+// List 按条件分页查询订单，同一排序值的订单按ID排序，保证分页结果稳定
+func (r *OrderRepository) List(filter model.OrderFilter, offset, limit int) ([]*model.Order, int64, error) {
 	var orders []*model.Order
 	var total int64
 
-	offset := (page - 1) * pageSize
-
-	err := r.db.Model(&model.Order{}).Where("user_id = ?", userID).Count(&total).Error
-	if err != nil {
+	if err := r.db.Model(&model.Order{}).Scopes(orderFilterScope(filter)).Count(&total).Error; err != nil {
 		return nil, 0, err
 	}
 
-	err = r.db.Where("user_id = ?", userID).Offset(offset).Limit(pageSize).Find(&orders).Error
+	err := r.db.Preload("TaxLines").Scopes(orderFilterScope(filter), orderSortScope(filter)).
+		Offset(offset).Limit(limit).Find(&orders).Error
 	if err != nil {
 		return nil, 0, err
 	}
 
 	return orders, total, nil
 }
+
+// ListAfter 按条件查询排在after之后的订单，after为空时从第一条开始，不统计总数
+func (r *OrderRepository) ListAfter(filter model.OrderFilter, after *model.OrderCursor, limit int) ([]*model.Order, error) {
+	var orders []*model.Order
+
+	db := r.db.Preload("TaxLines").Scopes(orderFilterScope(filter), orderSortScope(filter))
+	if after != nil {
+		column, value := "created_at", interface{}(after.CreatedAt)
+		if filter.SortBy == model.OrderSortTotal {
+			column, value = "total_price", after.TotalPrice
+		}
+		op := " < "
+		if filter.Ascending {
+			op = " > "
+		}
+		db = db.Where(column+op+"? OR ("+column+" = ? AND id"+op+"?)", value, value, after.ID)
+	}
+
+	if err := db.Limit(limit).Find(&orders).Error; err != nil {
+		return nil, err
+	}
+	return orders, nil
+}
+
+func orderFilterScope(filter model.OrderFilter) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if filter.UserID > 0 {
+			db = db.Where("user_id = ?", filter.UserID)
+		}
+		if len(filter.Statuses) > 0 {
+			db = db.Where("status IN ?", filter.Statuses)
+		}
+		if filter.ProductID > 0 {
+			db = db.Where("product_id = ?", filter.ProductID)
+		}
+		if filter.CreatedFrom != nil {
+			db = db.Where("created_at >= ?", *filter.CreatedFrom)
+		}
+		if filter.CreatedTo != nil {
+			db = db.Where("created_at < ?", *filter.CreatedTo)
+		}
+		if filter.MinTotal != nil {
+			db = db.Where("total_price >= ?", *filter.MinTotal)
+		}
+		if filter.MaxTotal != nil {
+			db = db.Where("total_price <= ?", *filter.MaxTotal)
+		}
+		return db
+	}
+}
+
+func orderSortScope(filter model.OrderFilter) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		column := "created_at"
+		if filter.SortBy == model.OrderSortTotal {
+			column = "total_price"
+		}
+		direction := " DESC"
+		if filter.Ascending {
+			direction = " ASC"
+		}
+		return db.Order(column + direction).Order("id" + direction)
+	}
+}
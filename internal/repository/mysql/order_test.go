@@ -0,0 +1,210 @@
+package mysql
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/ylh990835774/blockchain-shop-demo/internal/model"
+	"github.com/ylh990835774/blockchain-shop-demo/internal/testutil"
+	"github.com/ylh990835774/blockchain-shop-demo/pkg/money"
+)
+
+func TestOrderRepository_TaxLines(t *testing.T) {
+	db := testutil.NewDB(t)
+	repo := NewOrderRepository(db)
+
+	order := &model.Order{
+		UserID:       1,
+		ProductID:    3,
+		Quantity:     2,
+		Subtotal:     money.MustParseAmount("200"),
+		TaxTotal:     money.MustParseAmount("26"),
+		TotalPrice:   money.MustParseAmount("226"),
+		Currency:     "CNY",
+		ExchangeRate: money.RateOne,
+		Status:       model.OrderStatusPending,
+		TaxRegion:    "CN",
+		TaxLines: []model.OrderTaxLine{{
+			ProductID:   3,
+			Quantity:    2,
+			UnitPrice:   money.MustParseAmount("113"),
+			Category:    "standard",
+			Region:      "CN",
+			Rate:        money.MustParseRate("0.13"),
+			Inclusive:   true,
+			NetAmount:   money.MustParseAmount("200"),
+			TaxAmount:   money.MustParseAmount("26"),
+			GrossAmount: money.MustParseAmount("226"),
+		}},
+	}
+	// 计税明细随订单一并写入
+	require.NoError(t, repo.CreateWithTx(db, order))
+
+	lines, err := repo.ListTaxLinesWithTx(db, order.ID)
+	require.NoError(t, err)
+	require.Len(t, lines, 1)
+	assert.Equal(t, order.ID, lines[0].OrderID)
+	assert.Equal(t, money.MustParseRate("0.13"), lines[0].Rate)
+	assert.Equal(t, money.MustParseAmount("26"), lines[0].TaxAmount)
+
+	got, err := repo.GetByID(order.ID)
+	require.NoError(t, err)
+	assert.Equal(t, money.MustParseAmount("200"), got.Subtotal)
+	assert.Equal(t, "CN", got.TaxRegion)
+	require.Len(t, got.TaxLines, 1)
+	assert.True(t, got.TaxLines[0].Inclusive)
+
+	lines, err = repo.ListTaxLinesWithTx(db, order.ID+1)
+	require.NoError(t, err)
+	assert.Empty(t, lines)
+}
+
+func TestOrderRepository_List(t *testing.T) {
+	db := testutil.NewDB(t)
+	repo := NewOrderRepository(db)
+
+	base := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	seed := []struct {
+		userID    int64
+		productID int64
+		total     string
+		status    model.OrderStatus
+		createdAt time.Time
+	}{
+		{1, 3, "100", model.OrderStatusPaid, base},
+		{1, 4, "20", model.OrderStatusPending, base.Add(24 * time.Hour)},
+		{1, 3, "300", model.OrderStatusComplete, base.Add(48 * time.Hour)},
+		{2, 3, "50", model.OrderStatusPaid, base.Add(72 * time.Hour)},
+	}
+	ids := make([]int64, len(seed))
+	for i, s := range seed {
+		order := &model.Order{
+			UserID:       s.userID,
+			ProductID:    s.productID,
+			Quantity:     1,
+			TotalPrice:   money.MustParseAmount(s.total),
+			Currency:     "CNY",
+			ExchangeRate: money.RateOne,
+			Status:       s.status,
+			CreatedAt:    s.createdAt,
+		}
+		require.NoError(t, repo.CreateWithTx(db, order))
+		ids[i] = order.ID
+	}
+
+	from, to := base.Add(24*time.Hour), base.Add(72*time.Hour)
+	minTotal, maxTotal := money.MustParseAmount("50"), money.MustParseAmount("300")
+
+	tests := []struct {
+		name     string
+		filter   model.OrderFilter
+		expected []int64
+	}{
+		{name: "all users, newest first", filter: model.OrderFilter{}, expected: []int64{ids[3], ids[2], ids[1], ids[0]}},
+		{name: "by user", filter: model.OrderFilter{UserID: 1}, expected: []int64{ids[2], ids[1], ids[0]}},
+		{name: "by statuses", filter: model.OrderFilter{Statuses: []model.OrderStatus{model.OrderStatusPaid, model.OrderStatusComplete}}, expected: []int64{ids[3], ids[2], ids[0]}},
+		{name: "by product", filter: model.OrderFilter{UserID: 1, ProductID: 3}, expected: []int64{ids[2], ids[0]}},
+		{name: "by created range", filter: model.OrderFilter{CreatedFrom: &from, CreatedTo: &to}, expected: []int64{ids[2], ids[1]}},
+		{name: "by total range", filter: model.OrderFilter{MinTotal: &minTotal, MaxTotal: &maxTotal}, expected: []int64{ids[3], ids[2], ids[0]}},
+		{name: "sort by total ascending", filter: model.OrderFilter{SortBy: model.OrderSortTotal, Ascending: true}, expected: []int64{ids[1], ids[3], ids[0], ids[2]}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			orders, total, err := repo.List(tt.filter, 0, 10)
+			require.NoError(t, err)
+			assert.Equal(t, int64(len(tt.expected)), total)
+			got := make([]int64, 0, len(orders))
+			for _, order := range orders {
+				got = append(got, order.ID)
+			}
+			assert.Equal(t, tt.expected, got)
+		})
+	}
+
+	// 分页时总数不受offset和limit影响
+	orders, total, err := repo.List(model.OrderFilter{UserID: 1}, 1, 1)
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), total)
+	require.Len(t, orders, 1)
+	assert.Equal(t, ids[1], orders[0].ID)
+}
+
+func TestOrderRepository_ListAfter(t *testing.T) {
+	db := testutil.NewDB(t)
+	repo := NewOrderRepository(db)
+
+	// 前两个订单下单时间相同，金额与下单时间的顺序不同
+	base := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	seed := []struct {
+		userID    int64
+		total     string
+		createdAt time.Time
+	}{
+		{1, "30", base},
+		{1, "10", base},
+		{1, "30", base.Add(time.Hour)},
+		{2, "20", base.Add(2 * time.Hour)},
+		{1, "20", base.Add(3 * time.Hour)},
+	}
+	ids := make([]int64, len(seed))
+	for i, s := range seed {
+		order := &model.Order{
+			UserID:       s.userID,
+			ProductID:    3,
+			Quantity:     1,
+			TotalPrice:   money.MustParseAmount(s.total),
+			Currency:     "CNY",
+			ExchangeRate: money.RateOne,
+			Status:       model.OrderStatusPaid,
+			CreatedAt:    s.createdAt,
+		}
+		require.NoError(t, repo.CreateWithTx(db, order))
+		ids[i] = order.ID
+	}
+
+	tests := []struct {
+		name     string
+		filter   model.OrderFilter
+		expected []int64
+	}{
+		{
+			name:     "newest first",
+			filter:   model.OrderFilter{UserID: 1},
+			expected: []int64{ids[4], ids[2], ids[1], ids[0]},
+		},
+		{
+			name:     "total ascending",
+			filter:   model.OrderFilter{SortBy: model.OrderSortTotal, Ascending: true},
+			expected: []int64{ids[1], ids[3], ids[4], ids[0], ids[2]},
+		},
+		{
+			name:     "total descending",
+			filter:   model.OrderFilter{SortBy: model.OrderSortTotal},
+			expected: []int64{ids[2], ids[0], ids[4], ids[3], ids[1]},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// 每页两条，用上一页最后一个订单作为游标遍历所有页
+			var got []int64
+			var after *model.OrderCursor
+			for {
+				orders, err := repo.ListAfter(tt.filter, after, 2)
+				require.NoError(t, err)
+				if len(orders) == 0 {
+					break
+				}
+				for _, order := range orders {
+					got = append(got, order.ID)
+				}
+				last := orders[len(orders)-1]
+				after = &model.OrderCursor{CreatedAt: last.CreatedAt, TotalPrice: last.TotalPrice, ID: last.ID}
+			}
+			assert.Equal(t, tt.expected, got)
+		})
+	}
+}
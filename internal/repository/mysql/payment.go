@@ -0,0 +1,84 @@
+package mysql
+
+import (
+	"github.com/ylh990835774/blockchain-shop-demo/internal/model"
+	"github.com/ylh990835774/blockchain-shop-demo/pkg/money"
+	"gorm.io/gorm"
+)
+
+type PaymentRepository struct {
+	db *gorm.DB
+}
+
+func NewPaymentRepository(db *gorm.DB) *PaymentRepository {
+	return &PaymentRepository{db: db}
+}
+
+func (r *PaymentRepository) Create(payment *model.Payment) error {
+	return r.db.Create(payment).Error
+}
+
+func (r *PaymentRepository) Update(id int64, updates map[string]interface{}) error {
+	result := r.db.Model(&model.Payment{}).Where("id = ?", id).Updates(updates)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// TransitionStatus 仅当支付处于from状态时将其更新为to状态，返回是否更新成功
+func (r *PaymentRepository) TransitionStatus(id int64, from, to model.PaymentStatus) (bool, error) {
+	result := r.db.Model(&model.Payment{}).Where("id = ? AND status = ?", id, from).Update("status", to)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// TransitionRefund 仅当支付处于from状态且累计退款金额为fromRefunded时，更新为to状态和toRefunded，返回是否更新成功
+func (r *PaymentRepository) TransitionRefund(id int64, from model.PaymentStatus, fromRefunded money.Amount,
+	to model.PaymentStatus, toRefunded money.Amount) (bool, error) {
+	result := r.db.Model(&model.Payment{}).
+		Where("id = ? AND status = ? AND refunded_amount = ?", id, from, fromRefunded).
+		Updates(map[string]interface{}{"status": to, "refunded_amount": toRefunded})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+func (r *PaymentRepository) GetByID(id int64) (*model.Payment, error) {
+	var payment model.Payment
+	err := r.db.First(&payment, id).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return &payment, nil
+}
+
+func (r *PaymentRepository) GetByProviderRef(provider, providerRef string) (*model.Payment, error) {
+	var payment model.Payment
+	err := r.db.Where("provider = ? AND provider_ref = ?", provider, providerRef).First(&payment).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return &payment, nil
+}
+
+func (r *PaymentRepository) ListByOrderID(orderID int64) ([]*model.Payment, error) {
+	var payments []*model.Payment
+	err := r.db.Where("order_id = ?", orderID).Order("id").Find(&payments).Error
+	if err != nil {
+		return nil, err
+	}
+	return payments, nil
+}
@@ -0,0 +1,120 @@
+package mysql
+
+import (
+	"time"
+
+	"github.com/ylh990835774/blockchain-shop-demo/internal/model"
+	"gorm.io/gorm"
+)
+
+// priceEntryBatchSize 批量写入价格表项时每批的条数
+const priceEntryBatchSize = 500
+
+type PriceCommitmentRepository struct {
+	db *gorm.DB
+}
+
+func NewPriceCommitmentRepository(db *gorm.DB) *PriceCommitmentRepository {
+	return &PriceCommitmentRepository{db: db}
+}
+
+// ListCatalogPricesWithTx 查询当前在售商品的价格表，未按顺序排列，Position未设置。
+// 每个SKU有一项基础币种的价格，未单独定价的SKU还有商品各币种定价的项。在事务内调用以获得一致的快照
+func (r *PriceCommitmentRepository) ListCatalogPricesWithTx(tx *gorm.DB) ([]*model.PriceCommitmentEntry, error) {
+	var base []*model.PriceCommitmentEntry
+	err := tx.Table("product_skus AS s").
+		Select("p.id AS product_id, s.code AS sku_code, p.currency AS currency, COALESCE(s.price, p.price) AS price").
+		Joins("JOIN products AS p ON p.id = s.product_id").
+		Where("p.archived_at IS NULL AND p.deleted_at IS NULL").
+		Scan(&base).Error
+	if err != nil {
+		return nil, err
+	}
+
+	var converted []*model.PriceCommitmentEntry
+	err = tx.Table("product_skus AS s").
+		Select("p.id AS product_id, s.code AS sku_code, pp.currency AS currency, pp.price AS price").
+		Joins("JOIN products AS p ON p.id = s.product_id").
+		Joins("JOIN product_prices AS pp ON pp.product_id = p.id").
+		Where("s.price IS NULL AND p.archived_at IS NULL AND p.deleted_at IS NULL").
+		Scan(&converted).Error
+	if err != nil {
+		return nil, err
+	}
+	return append(base, converted...), nil
+}
+
+// CreateWithTx 保存价格表承诺及其所有项
+func (r *PriceCommitmentRepository) CreateWithTx(tx *gorm.DB, commitment *model.PriceCommitment, entries []*model.PriceCommitmentEntry) error {
+	if err := tx.Create(commitment).Error; err != nil {
+		return err
+	}
+	if len(entries) == 0 {
+		return nil
+	}
+	for _, entry := range entries {
+		entry.CommitmentID = commitment.ID
+	}
+	return tx.CreateInBatches(entries, priceEntryBatchSize).Error
+}
+
+// SetTxHashWithTx 记录默克尔根上链记录所在区块的哈希
+func (r *PriceCommitmentRepository) SetTxHashWithTx(tx *gorm.DB, id int64, txHash string) error {
+	result := tx.Model(&model.PriceCommitment{}).Where("id = ?", id).Update("tx_hash", txHash)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// GetLatestWithTx 获取最近一次发布的价格表承诺
+func (r *PriceCommitmentRepository) GetLatestWithTx(tx *gorm.DB) (*model.PriceCommitment, error) {
+	var commitment model.PriceCommitment
+	err := tx.Order("created_at DESC, id DESC").First(&commitment).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return &commitment, nil
+}
+
+// GetLatestBefore 获取在指定时间及之前发布的最近一次价格表承诺
+func (r *PriceCommitmentRepository) GetLatestBefore(t time.Time) (*model.PriceCommitment, error) {
+	var commitment model.PriceCommitment
+	err := r.db.Where("created_at <= ?", t).Order("created_at DESC, id DESC").First(&commitment).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return &commitment, nil
+}
+
+// List 按发布时间从新到旧分页查询价格表承诺
+func (r *PriceCommitmentRepository) List(offset, limit int) ([]*model.PriceCommitment, int64, error) {
+	var commitments []*model.PriceCommitment
+	var total int64
+
+	if err := r.db.Model(&model.PriceCommitment{}).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	if err := r.db.Order("created_at DESC, id DESC").Offset(offset).Limit(limit).Find(&commitments).Error; err != nil {
+		return nil, 0, err
+	}
+	return commitments, total, nil
+}
+
+// ListEntries 按叶子位置顺序查询价格表承诺的所有项
+func (r *PriceCommitmentRepository) ListEntries(commitmentID int64) ([]*model.PriceCommitmentEntry, error) {
+	var entries []*model.PriceCommitmentEntry
+	if err := r.db.Where("commitment_id = ?", commitmentID).Order("position").Find(&entries).Error; err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
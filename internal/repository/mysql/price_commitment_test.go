@@ -0,0 +1,86 @@
+package mysql
+
+import (
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/ylh990835774/blockchain-shop-demo/internal/model"
+	"github.com/ylh990835774/blockchain-shop-demo/internal/testutil"
+	"github.com/ylh990835774/blockchain-shop-demo/pkg/money"
+)
+
+func TestPriceCommitmentRepository_ListCatalogPrices(t *testing.T) {
+	db := testutil.NewDB(t)
+	products := NewProductRepository(db)
+	repo := NewPriceCommitmentRepository(db)
+
+	skuPrice := money.MustParseAmount("40.00")
+	tea := &model.Product{
+		Name:     "绿茶",
+		Price:    money.MustParseAmount("25.00"),
+		Currency: "CNY",
+		Prices:   []model.ProductPrice{{Currency: "USD", Price: money.MustParseAmount("3.50")}},
+		SKUs: []model.ProductSKU{
+			{Code: "TEA-S", Options: model.SKUOptions{"规格": "小"}, IsDefault: true},
+			{Code: "TEA-L", Options: model.SKUOptions{"规格": "大"}, Price: &skuPrice},
+		},
+	}
+	require.NoError(t, products.Create(tea))
+	archived := &model.Product{Name: "咖啡", Price: money.MustParseAmount("30.00"), Currency: "CNY"}
+	require.NoError(t, products.Create(archived))
+	require.NoError(t, products.SetArchived(archived.ID, true))
+	deleted := &model.Product{Name: "可可", Price: money.MustParseAmount("35.00"), Currency: "CNY"}
+	require.NoError(t, products.Create(deleted))
+	require.NoError(t, products.Delete(deleted.ID))
+
+	entries, err := repo.ListCatalogPricesWithTx(db)
+	require.NoError(t, err)
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].SKUCode+entries[i].Currency < entries[j].SKUCode+entries[j].Currency
+	})
+
+	// 已下架和已删除的商品不在价格表中，SKU单独定价时不使用商品的币种定价
+	assert.Equal(t, []*model.PriceCommitmentEntry{
+		{ProductID: tea.ID, SKUCode: "TEA-L", Currency: "CNY", Price: money.MustParseAmount("40.00")},
+		{ProductID: tea.ID, SKUCode: "TEA-S", Currency: "CNY", Price: money.MustParseAmount("25.00")},
+		{ProductID: tea.ID, SKUCode: "TEA-S", Currency: "USD", Price: money.MustParseAmount("3.50")},
+	}, entries)
+}
+
+func TestPriceCommitmentRepository_GetLatestBefore(t *testing.T) {
+	db := testutil.NewDB(t)
+	repo := NewPriceCommitmentRepository(db)
+
+	base := time.Date(2024, 5, 1, 8, 0, 0, 0, time.UTC)
+	for i, root := range []string{"aa", "bb"} {
+		commitment := &model.PriceCommitment{MerkleRoot: root, EntryCount: 1, CreatedAt: base.Add(time.Duration(i) * time.Hour)}
+		entries := []*model.PriceCommitmentEntry{{SKUCode: "TEA-S", Currency: "CNY", Price: money.MustParseAmount("25.00")}}
+		require.NoError(t, repo.CreateWithTx(db, commitment, entries))
+		require.NoError(t, repo.SetTxHashWithTx(db, commitment.ID, "hash-"+root))
+	}
+
+	_, err := repo.GetLatestBefore(base.Add(-time.Minute))
+	assert.Equal(t, ErrNotFound, err)
+
+	got, err := repo.GetLatestBefore(base.Add(30 * time.Minute))
+	require.NoError(t, err)
+	assert.Equal(t, "aa", got.MerkleRoot)
+	assert.Equal(t, "hash-aa", got.TxHash)
+
+	got, err = repo.GetLatestBefore(base.Add(time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, "bb", got.MerkleRoot)
+
+	entries, err := repo.ListEntries(got.ID)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, got.ID, entries[0].CommitmentID)
+
+	commitments, total, err := repo.List(0, 10)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), total)
+	assert.Equal(t, "bb", commitments[0].MerkleRoot)
+}
@@ -1,8 +1,14 @@
 package mysql
 
 import (
+	"sort"
+	"strings"
+	"time"
+
 	"github.com/ylh990835774/blockchain-shop-demo/internal/model"
+	"github.com/ylh990835774/blockchain-shop-demo/pkg/money"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type ProductRepository struct {
@@ -14,44 +20,399 @@ func NewProductRepository(db *gorm.DB) *ProductRepository {
 }
 
 func (r *ProductRepository) Create(product *model.Product) error {
-	return r.db.Create(product).Error
+	return r.CreateWithTx(r.db, product, 0)
 }
 
-func (r *ProductRepository) Update(id int64, updates map[string]interface{}) error {
-	result := r.db.Model(&model.Product{}).Where("id = ?", id).Updates(updates)
+// CreateWithTx 创建商品及其SKU和分类关联，未指定SKU时按商品库存创建一个默认SKU。
+// actorID为创建商品的用户，记录在SKU的期初库存流水中
+func (r *ProductRepository) CreateWithTx(tx *gorm.DB, product *model.Product, actorID int64) error {
+	product.Version = 1
+	if err := tx.Create(product).Error; err != nil {
+		if err == gorm.ErrDuplicatedKey {
+			return ErrDuplicateKey
+		}
+		return err
+	}
+	if len(product.SKUs) == 0 {
+		sku := model.ProductSKU{
+			ProductID: product.ID,
+			Code:      model.DefaultSKUCode(product.ID),
+			Options:   model.SKUOptions{},
+			Stock:     product.Stock,
+			IsDefault: true,
+		}
+		if err := tx.Create(&sku).Error; err != nil {
+			if err == gorm.ErrDuplicatedKey {
+				return ErrDuplicateKey
+			}
+			return err
+		}
+		product.SKUs = []model.ProductSKU{sku}
+	}
+	for i := range product.SKUs {
+		if err := recordAdjustmentWithTx(tx, &product.SKUs[i], product.SKUs[i].Stock, actorID, "创建商品"); err != nil {
+			return err
+		}
+	}
+	if err := r.createPriceHistoryWithTx(tx, product); err != nil {
+		return err
+	}
+	return r.SetCategoriesWithTx(tx, product.ID, product.CategoryIDs)
+}
+
+// createPriceHistoryWithTx 记录新商品的初始定价
+func (r *ProductRepository) createPriceHistoryWithTx(tx *gorm.DB, product *model.Product) error {
+	price := product.Price
+	changes := []*model.ProductPriceHistory{{ProductID: product.ID, Currency: product.Currency, NewPrice: &price}}
+	for i := range product.Prices {
+		changes = append(changes, &model.ProductPriceHistory{
+			ProductID: product.ID,
+			Currency:  product.Prices[i].Currency,
+			NewPrice:  &product.Prices[i].Price,
+		})
+	}
+	for i := range product.SKUs {
+		sku := &product.SKUs[i]
+		if sku.Price != nil {
+			changes = append(changes, &model.ProductPriceHistory{
+				ProductID: product.ID,
+				SKUID:     &sku.ID,
+				Currency:  product.Currency,
+				NewPrice:  sku.Price,
+			})
+		}
+	}
+	return tx.Create(&changes).Error
+}
+
+// SetCategoriesWithTx 替换商品所属的分类
+func (r *ProductRepository) SetCategoriesWithTx(tx *gorm.DB, productID int64, categoryIDs []int64) error {
+	if err := tx.Where("product_id = ?", productID).Delete(&model.ProductCategory{}).Error; err != nil {
+		return err
+	}
+	if len(categoryIDs) == 0 {
+		return nil
+	}
+
+	links := make([]model.ProductCategory, 0, len(categoryIDs))
+	for _, id := range categoryIDs {
+		links = append(links, model.ProductCategory{ProductID: productID, CategoryID: id})
+	}
+	return tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&links).Error
+}
+
+// Update 更新商品并递增版本号。version大于0时仅在商品的当前版本号与之一致时更新，
+// 否则返回ErrVersionConflict，避免并发修改相互覆盖；version为0时不校验版本
+func (r *ProductRepository) Update(id, version int64, updates map[string]interface{}) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		return r.UpdateWithTx(tx, id, version, updates)
+	})
+}
+
+// UpdateWithTx 在事务内更新商品并递增版本号，价格变化时写入价格变更记录
+func (r *ProductRepository) UpdateWithTx(tx *gorm.DB, id, version int64, updates map[string]interface{}) error {
+	var current model.Product
+	price, priceChanged := updates["price"].(money.Amount)
+	if priceChanged {
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id", "price", "currency").
+			Where("id = ?", id).Take(&current).Error
+		if err != nil {
+			if err == gorm.ErrRecordNotFound {
+				return ErrNotFound
+			}
+			return err
+		}
+	}
+
+	values := make(map[string]interface{}, len(updates)+1)
+	for column, value := range updates {
+		values[column] = value
+	}
+	values["version"] = gorm.Expr("version + 1")
+
+	db := tx.Model(&model.Product{}).Where("id = ?", id)
+	if version > 0 {
+		db = db.Where("version = ?", version)
+	}
+	result := db.Updates(values)
 	if result.Error != nil {
 		return result.Error
 	}
 	if result.RowsAffected == 0 {
+		if version > 0 {
+			// 区分商品不存在与版本冲突
+			var count int64
+			if err := tx.Model(&model.Product{}).Where("id = ?", id).Count(&count).Error; err != nil {
+				return err
+			}
+			if count > 0 {
+				return ErrVersionConflict
+			}
+		}
 		return ErrNotFound
 	}
+
+	if priceChanged {
+		return recordPriceChangeWithTx(tx, &model.ProductPriceHistory{
+			ProductID: id,
+			Currency:  current.Currency,
+			OldPrice:  &current.Price,
+			NewPrice:  &price,
+		})
+	}
 	return nil
 }
 
-func (r *ProductRepository) UpdateStock(id int64, quantity int) error {
-	result := r.db.Model(&model.Product{}).Where("id = ? AND stock >= ?", id, -quantity).
-		UpdateColumn("stock", gorm.Expr("stock + ?", quantity))
+// UpdateStock 按库存变动调整SKU库存，movement.Quantity为负数时扣减，库存不会被调整为负数
+func (r *ProductRepository) UpdateStock(movement *model.InventoryMovement) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		return r.UpdateStockWithTx(tx, movement)
+	})
+}
+
+// UpdateStockWithTx 在事务内按库存变动调整SKU库存，同步商品的库存合计并写入库存流水。
+// SKU不存在或库存不足时返回ErrNotFound
+func (r *ProductRepository) UpdateStockWithTx(tx *gorm.DB, movement *model.InventoryMovement) error {
+	result := tx.Model(&model.ProductSKU{}).Where("id = ? AND stock >= ?", movement.SKUID, -movement.Quantity).
+		UpdateColumn("stock", gorm.Expr("stock + ?", movement.Quantity))
 	if result.Error != nil {
 		return result.Error
 	}
 	if result.RowsAffected == 0 {
 		return ErrNotFound
 	}
-	return nil
+	return r.addProductStockWithTx(tx, movement)
+}
+
+// DecrementStockWithTx 按库存变动原子扣减SKU库存，movement.Quantity为负的扣减数量，
+// 由数据库条件更新保证库存不会被扣为负数
+func (r *ProductRepository) DecrementStockWithTx(tx *gorm.DB, movement *model.InventoryMovement) error {
+	result := tx.Model(&model.ProductSKU{}).Where("id = ? AND stock >= ?", movement.SKUID, -movement.Quantity).
+		UpdateColumn("stock", gorm.Expr("stock + ?", movement.Quantity))
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		// 区分SKU不存在与库存不足
+		var count int64
+		if err := tx.Model(&model.ProductSKU{}).Where("id = ?", movement.SKUID).Count(&count).Error; err != nil {
+			return err
+		}
+		if count == 0 {
+			return ErrNotFound
+		}
+		return ErrInsufficientStock
+	}
+	return r.addProductStockWithTx(tx, movement)
+}
+
+// addProductStockWithTx 将SKU的库存变化累加到所属商品的库存合计，并写入库存流水
+func (r *ProductRepository) addProductStockWithTx(tx *gorm.DB, movement *model.InventoryMovement) error {
+	var sku model.ProductSKU
+	if err := tx.Select("id", "product_id").Take(&sku, movement.SKUID).Error; err != nil {
+		return err
+	}
+	if err := tx.Model(&model.Product{}).Where("id = ?", sku.ProductID).
+		UpdateColumn("stock", gorm.Expr("stock + ?", movement.Quantity)).Error; err != nil {
+		return err
+	}
+	movement.ProductID = sku.ProductID
+	return tx.Create(movement).Error
+}
+
+// RecordSaleWithTx 订单支付后将预留的库存转为售出。库存在预留时已扣减，
+// 因此写入一条释放预留和一条售出的流水，库存合计不变
+func (r *ProductRepository) RecordSaleWithTx(tx *gorm.DB, reservation *model.StockReservation) error {
+	orderID := reservation.OrderID
+	movements := []*model.InventoryMovement{
+		{
+			ProductID: reservation.ProductID,
+			SKUID:     reservation.SKUID,
+			Type:      model.MovementTypeReservation,
+			Quantity:  reservation.Quantity,
+			OrderID:   &orderID,
+			Reason:    "订单已支付，释放预留",
+		},
+		{
+			ProductID: reservation.ProductID,
+			SKUID:     reservation.SKUID,
+			Type:      model.MovementTypeSale,
+			Quantity:  -reservation.Quantity,
+			OrderID:   &orderID,
+			Reason:    "订单已支付",
+		},
+	}
+	return tx.Create(&movements).Error
+}
+
+// recordAdjustmentWithTx 记录维护SKU时直接修改库存产生的调整流水，库存没有变化时不记录。
+// actorID为操作人，为零时表示系统操作
+func recordAdjustmentWithTx(tx *gorm.DB, sku *model.ProductSKU, quantity int, actorID int64, reason string) error {
+	if quantity == 0 {
+		return nil
+	}
+	movement := &model.InventoryMovement{
+		ProductID: sku.ProductID,
+		SKUID:     sku.ID,
+		Type:      model.MovementTypeAdjustment,
+		Quantity:  quantity,
+		Reason:    reason,
+	}
+	if actorID > 0 {
+		movement.ActorID = &actorID
+	}
+	return tx.Create(movement).Error
+}
+
+// syncStockWithTx 按SKU库存重新计算商品的库存合计
+func (r *ProductRepository) syncStockWithTx(tx *gorm.DB, productID int64) error {
+	total := tx.Session(&gorm.Session{NewDB: true}).Model(&model.ProductSKU{}).
+		Select("COALESCE(SUM(stock), 0)").Where("product_id = ?", productID)
+	return tx.Model(&model.Product{}).Where("id = ?", productID).
+		UpdateColumn("stock", total).Error
+}
+
+// GetSKUForUpdateWithTx 在事务内查询并锁定SKU
+func (r *ProductRepository) GetSKUForUpdateWithTx(tx *gorm.DB, id int64) (*model.ProductSKU, error) {
+	var sku model.ProductSKU
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&sku, id).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return &sku, nil
+}
+
+// GetSKUByCodeWithTx 在事务内按编码查询并锁定SKU
+func (r *ProductRepository) GetSKUByCodeWithTx(tx *gorm.DB, code string) (*model.ProductSKU, error) {
+	var sku model.ProductSKU
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("code = ?", code).First(&sku).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return &sku, nil
+}
+
+// CreateSKUWithTx 为商品添加SKU并同步商品的库存合计，设为默认SKU时取消其他SKU的默认标记。
+// SKU的库存记为操作人actorID的调整流水
+func (r *ProductRepository) CreateSKUWithTx(tx *gorm.DB, sku *model.ProductSKU, actorID int64) error {
+	if sku.IsDefault {
+		if err := r.clearDefaultSKUWithTx(tx, sku.ProductID); err != nil {
+			return err
+		}
+	}
+	if err := tx.Create(sku).Error; err != nil {
+		if err == gorm.ErrDuplicatedKey {
+			return ErrDuplicateKey
+		}
+		return err
+	}
+	if err := recordAdjustmentWithTx(tx, sku, sku.Stock, actorID, "添加SKU"); err != nil {
+		return err
+	}
+	if err := r.recordSKUPriceChangeWithTx(tx, sku, nil); err != nil {
+		return err
+	}
+	return r.syncStockWithTx(tx, sku.ProductID)
 }
 
-func (r *ProductRepository) UpdateStockWithTx(tx *gorm.DB, id int64, quantity int) error {
-	result := tx.Model(&model.Product{}).Where("id = ? AND stock >= ?", id, -quantity).
-		UpdateColumn("stock", gorm.Expr("stock + ?", quantity))
+// UpdateSKUWithTx 更新SKU的编码、规格、价格、库存和默认标记，并同步商品的库存合计，价格变化时写入价格变更记录，
+// 库存变化记为操作人actorID的调整流水
+func (r *ProductRepository) UpdateSKUWithTx(tx *gorm.DB, sku *model.ProductSKU, actorID int64) error {
+	var current model.ProductSKU
+	if err := tx.Select("id", "price", "stock").Take(&current, sku.ID).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return ErrNotFound
+		}
+		return err
+	}
+
+	if sku.IsDefault {
+		if err := r.clearDefaultSKUWithTx(tx, sku.ProductID); err != nil {
+			return err
+		}
+	}
+	result := tx.Model(&model.ProductSKU{}).Where("id = ?", sku.ID).Updates(map[string]interface{}{
+		"code":                sku.Code,
+		"options":             sku.Options,
+		"price":               sku.Price,
+		"stock":               sku.Stock,
+		"is_default":          sku.IsDefault,
+		"low_stock_threshold": sku.LowStockThreshold,
+	})
 	if result.Error != nil {
+		if result.Error == gorm.ErrDuplicatedKey {
+			return ErrDuplicateKey
+		}
 		return result.Error
 	}
 	if result.RowsAffected == 0 {
 		return ErrNotFound
 	}
-	return nil
+	if err := recordAdjustmentWithTx(tx, sku, sku.Stock-current.Stock, actorID, "修改SKU库存"); err != nil {
+		return err
+	}
+	if err := r.recordSKUPriceChangeWithTx(tx, sku, current.Price); err != nil {
+		return err
+	}
+	return r.syncStockWithTx(tx, sku.ProductID)
 }
 
+// recordSKUPriceChangeWithTx 记录SKU价格的变更，SKU价格以商品的基础币种计价
+func (r *ProductRepository) recordSKUPriceChangeWithTx(tx *gorm.DB, sku *model.ProductSKU, oldPrice *money.Amount) error {
+	if samePrice(oldPrice, sku.Price) {
+		return nil
+	}
+
+	var product model.Product
+	if err := tx.Unscoped().Select("id", "currency").Take(&product, sku.ProductID).Error; err != nil {
+		return err
+	}
+	skuID := sku.ID
+	return recordPriceChangeWithTx(tx, &model.ProductPriceHistory{
+		ProductID: sku.ProductID,
+		SKUID:     &skuID,
+		Currency:  product.Currency,
+		OldPrice:  oldPrice,
+		NewPrice:  sku.Price,
+	})
+}
+
+// DeleteSKUWithTx 删除SKU并同步商品的库存合计，SKU剩余的库存记为操作人actorID的调整出库
+func (r *ProductRepository) DeleteSKUWithTx(tx *gorm.DB, sku *model.ProductSKU, actorID int64) error {
+	var current model.ProductSKU
+	if err := tx.Select("id", "product_id", "stock").Take(&current, sku.ID).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return ErrNotFound
+		}
+		return err
+	}
+
+	result := tx.Delete(&model.ProductSKU{}, sku.ID)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	if err := recordAdjustmentWithTx(tx, &current, -current.Stock, actorID, "删除SKU"); err != nil {
+		return err
+	}
+	return r.syncStockWithTx(tx, sku.ProductID)
+}
+
+// clearDefaultSKUWithTx 取消商品所有SKU的默认标记
+func (r *ProductRepository) clearDefaultSKUWithTx(tx *gorm.DB, productID int64) error {
+	return tx.Model(&model.ProductSKU{}).Where("product_id = ? AND is_default = ?", productID, true).
+		Update("is_default", false).Error
+}
+
+// Delete 软删除商品，保留SKU、图片和分类关联，已删除的商品仍可通过GetByIDUnscoped查询
 func (r *ProductRepository) Delete(id int64) error {
 	result := r.db.Delete(&model.Product{}, id)
 	if result.Error != nil {
@@ -63,43 +424,478 @@ func (r *ProductRepository) Delete(id int64) error {
 	return nil
 }
 
+// SetArchived 下架或重新上架商品
+func (r *ProductRepository) SetArchived(id int64, archived bool) error {
+	var archivedAt *time.Time
+	if archived {
+		now := time.Now()
+		archivedAt = &now
+	}
+	result := r.db.Model(&model.Product{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"archived_at": archivedAt,
+		"version":     gorm.Expr("version + 1"),
+	})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
 func (r *ProductRepository) GetByID(id int64) (*model.Product, error) {
 	var product model.Product
-	err := r.db.First(&product, id).Error
+	err := r.db.Preload("Prices").Preload("SKUs", orderByID).Preload("Images", orderByID).First(&product, id).Error
 	if err != nil {
 		if err == gorm.ErrRecordNotFound {
 			return nil, ErrNotFound
 		}
 		return nil, err
 	}
+	if err := r.loadCategories(r.db, &product); err != nil {
+		return nil, err
+	}
 	return &product, nil
 }
 
 func (r *ProductRepository) GetByIDWithTx(tx *gorm.DB, id int64) (*model.Product, error) {
 	var product model.Product
-	err := tx.First(&product, id).Error
+	err := tx.Preload("Prices").Preload("SKUs", orderByID).Preload("Images", orderByID).First(&product, id).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return &product, nil
+}
+
+// GetByIDUnscoped 查询商品，包括已删除的商品，用于展示订单中的商品
+func (r *ProductRepository) GetByIDUnscoped(id int64) (*model.Product, error) {
+	return r.GetByIDUnscopedWithTx(r.db, id)
+}
+
+// GetByIDUnscopedWithTx 在事务内查询商品，包括已删除的商品
+func (r *ProductRepository) GetByIDUnscopedWithTx(tx *gorm.DB, id int64) (*model.Product, error) {
+	var product model.Product
+	err := tx.Unscoped().Preload("Prices").Preload("SKUs", orderByID).Preload("Images", orderByID).
+		First(&product, id).Error
 	if err != nil {
 		if err == gorm.ErrRecordNotFound {
 			return nil, ErrNotFound
 		}
 		return nil, err
 	}
+	if err := r.loadCategories(tx, &product); err != nil {
+		return nil, err
+	}
 	return &product, nil
 }
 
-func (r *ProductRepository) List(offset, limit int) ([]*model.Product, int64, error) {
+// List 按条件分页查询商品，total为符合条件的商品总数
+func (r *ProductRepository) List(filter model.ProductFilter, offset, limit int) ([]*model.Product, int64, error) {
 	var products []*model.Product
 	var total int64
 
-	err := r.db.Model(&model.Product{}).Count(&total).Error
+	match, err := r.match(filter.Query)
 	if err != nil {
 		return nil, 0, err
 	}
 
-	err = r.db.Offset(offset).Limit(limit).Find(&products).Error
+	scope := productFilterScope(filter, match, true, true)
+	if err := r.db.Model(&model.Product{}).Scopes(scope).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	err = r.db.Preload("Prices").Preload("SKUs", orderByID).Preload("Images", orderByID).Scopes(scope).Order(productOrder(filter, match)).
+		Offset(offset).Limit(limit).Find(&products).Error
 	if err != nil {
 		return nil, 0, err
 	}
+	if err := r.loadCategories(r.db, products...); err != nil {
+		return nil, 0, err
+	}
 
 	return products, total, nil
 }
+
+// ListAfter 按条件和ID顺序查询ID大于afterID的商品，不统计总数
+func (r *ProductRepository) ListAfter(filter model.ProductFilter, afterID int64, limit int) ([]*model.Product, error) {
+	var products []*model.Product
+
+	match, err := r.match(filter.Query)
+	if err != nil {
+		return nil, err
+	}
+
+	err = r.db.Preload("Prices").Preload("SKUs", orderByID).Preload("Images", orderByID).Scopes(productFilterScope(filter, match, true, true)).
+		Where("id > ?", afterID).Order("id").Limit(limit).Find(&products).Error
+	if err != nil {
+		return nil, err
+	}
+	if err := r.loadCategories(r.db, products...); err != nil {
+		return nil, err
+	}
+	return products, nil
+}
+
+// Facets 统计符合条件的商品的库存和价格区间分布，bounds为升序的价格区间分界，第一个区间从bounds[0]开始
+func (r *ProductRepository) Facets(filter model.ProductFilter, bounds []money.Amount) (*model.ProductFacets, error) {
+	match, err := r.match(filter.Query)
+	if err != nil {
+		return nil, err
+	}
+
+	var stock []struct {
+		InStock bool
+		Count   int64
+	}
+	err = r.db.Model(&model.Product{}).Scopes(productFilterScope(filter, match, true, false)).
+		Select("stock > 0 AS in_stock, COUNT(*) AS count").Group("stock > 0").Scan(&stock).Error
+	if err != nil {
+		return nil, err
+	}
+
+	facets := &model.ProductFacets{PriceRanges: make([]model.PriceRangeFacet, 0, len(bounds))}
+	for _, row := range stock {
+		if row.InStock {
+			facets.InStock = row.Count
+		} else {
+			facets.OutOfStock = row.Count
+		}
+	}
+	if len(bounds) == 0 {
+		return facets, nil
+	}
+
+	// 按价格所在区间的序号分组，低于第一个分界的商品不计入
+	bucket := clause.Expr{SQL: "CASE"}
+	for i := len(bounds) - 1; i >= 0; i-- {
+		bucket.SQL += " WHEN price >= ? THEN ?"
+		bucket.Vars = append(bucket.Vars, bounds[i], i)
+	}
+	bucket.SQL += " ELSE -1 END"
+
+	var prices []struct {
+		Bucket int
+		Count  int64
+	}
+	err = r.db.Model(&model.Product{}).Scopes(productFilterScope(filter, match, false, true)).
+		Select("? AS bucket, COUNT(*) AS count", bucket).Group("bucket").Scan(&prices).Error
+	if err != nil {
+		return nil, err
+	}
+
+	counts := make(map[int]int64, len(prices))
+	for _, row := range prices {
+		counts[row.Bucket] = row.Count
+	}
+	for i, min := range bounds {
+		facet := model.PriceRangeFacet{Min: min, Count: counts[i]}
+		if i+1 < len(bounds) {
+			max := bounds[i+1]
+			facet.Max = &max
+		}
+		facets.PriceRanges = append(facets.PriceRanges, facet)
+	}
+	return facets, nil
+}
+
+// orderByID 关联数据按ID排序
+func orderByID(db *gorm.DB) *gorm.DB {
+	return db.Order("id")
+}
+
+// loadCategories 批量加载商品所属的分类
+func (r *ProductRepository) loadCategories(tx *gorm.DB, products ...*model.Product) error {
+	if len(products) == 0 {
+		return nil
+	}
+
+	ids := make([]int64, 0, len(products))
+	byID := make(map[int64]*model.Product, len(products))
+	for _, product := range products {
+		product.CategoryIDs = []int64{}
+		ids = append(ids, product.ID)
+		byID[product.ID] = product
+	}
+
+	var links []model.ProductCategory
+	if err := tx.Where("product_id IN ?", ids).Order("category_id").Find(&links).Error; err != nil {
+		return err
+	}
+	for _, link := range links {
+		product := byID[link.ProductID]
+		product.CategoryIDs = append(product.CategoryIDs, link.CategoryID)
+	}
+	return nil
+}
+
+// textMatch 关键词搜索的过滤条件和相关度排序
+type textMatch struct {
+	where     clause.Expr
+	relevance clause.Expr
+}
+
+// match 生成关键词搜索条件。MySQL使用名称和描述上的FULLTEXT索引；其他数据库（如测试使用的SQLite）不支持全文索引，
+// 在内存中匹配后按商品ID过滤，仅适用于数据量很小的场景
+func (r *ProductRepository) match(query string) (*textMatch, error) {
+	if query == "" {
+		return nil, nil
+	}
+
+	if r.db.Dialector.Name() == "mysql" {
+		against := "MATCH(name, description) AGAINST (? IN NATURAL LANGUAGE MODE)"
+		return &textMatch{
+			where:     clause.Expr{SQL: against, Vars: []interface{}{query}},
+			relevance: clause.Expr{SQL: against + " DESC, id", Vars: []interface{}{query}},
+		}, nil
+	}
+
+	var rows []struct {
+		ID          int64
+		Name        string
+		Description string
+	}
+	if err := r.db.Model(&model.Product{}).Select("id, name, description").Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	type hit struct {
+		id    int64
+		score int
+	}
+	var hits []hit
+	for _, row := range rows {
+		if score := matchScore(query, row.Name, row.Description); score > 0 {
+			hits = append(hits, hit{id: row.ID, score: score})
+		}
+	}
+	sort.SliceStable(hits, func(i, j int) bool {
+		if hits[i].score != hits[j].score {
+			return hits[i].score > hits[j].score
+		}
+		return hits[i].id < hits[j].id
+	})
+
+	if len(hits) == 0 {
+		return &textMatch{where: clause.Expr{SQL: "1 = 0"}, relevance: clause.Expr{SQL: "id"}}, nil
+	}
+
+	ids := make([]int64, 0, len(hits))
+	relevance := clause.Expr{SQL: "CASE id"}
+	for i, h := range hits {
+		ids = append(ids, h.id)
+		relevance.SQL += " WHEN ? THEN ?"
+		relevance.Vars = append(relevance.Vars, h.id, i)
+	}
+	relevance.SQL += " END"
+	return &textMatch{
+		where:     clause.Expr{SQL: "id IN ?", Vars: []interface{}{ids}},
+		relevance: relevance,
+	}, nil
+}
+
+// matchScore 计算关键词在名称和描述中出现的次数，名称中的匹配计两倍，不区分大小写
+func matchScore(query, name, description string) int {
+	name, description = strings.ToLower(name), strings.ToLower(description)
+
+	score := 0
+	for _, term := range strings.Fields(strings.ToLower(query)) {
+		score += 2*strings.Count(name, term) + strings.Count(description, term)
+	}
+	return score
+}
+
+// productFilterScope 生成商品过滤条件，withPrice和withStock控制是否应用价格和库存条件，用于分面统计
+func productFilterScope(filter model.ProductFilter, match *textMatch, withPrice, withStock bool) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if match != nil {
+			db = db.Where(match.where)
+		}
+		if len(filter.CategoryIDs) > 0 {
+			db = db.Where("id IN (?)", db.Session(&gorm.Session{NewDB: true}).Model(&model.ProductCategory{}).
+				Select("product_id").Where("category_id IN ?", filter.CategoryIDs))
+		}
+		if filter.Currency != "" {
+			db = db.Where("currency = ?", filter.Currency)
+		}
+		if withPrice && filter.MinPrice != nil {
+			db = db.Where("price >= ?", *filter.MinPrice)
+		}
+		if withPrice && filter.MaxPrice != nil {
+			db = db.Where("price <= ?", *filter.MaxPrice)
+		}
+		if withStock && filter.InStock {
+			db = db.Where("stock > 0")
+		}
+		if !filter.WithArchived {
+			db = db.Where("archived_at IS NULL")
+		}
+		return db
+	}
+}
+
+// productOrder 生成商品排序，同一排序值的商品按ID排序，保证分页结果稳定
+func productOrder(filter model.ProductFilter, match *textMatch) clause.OrderBy {
+	if filter.SortBy == model.ProductSortRelevance && match != nil {
+		return clause.OrderBy{Expression: match.relevance}
+	}
+
+	column := ""
+	switch filter.SortBy {
+	case model.ProductSortCreatedAt:
+		column = "created_at"
+	case model.ProductSortPrice:
+		column = "price"
+	case model.ProductSortName:
+		column = "name"
+	default:
+		return clause.OrderBy{Columns: []clause.OrderByColumn{{Column: clause.Column{Name: "id"}}}}
+	}
+	return clause.OrderBy{Columns: []clause.OrderByColumn{
+		{Column: clause.Column{Name: column}, Desc: !filter.Ascending},
+		{Column: clause.Column{Name: "id"}, Desc: !filter.Ascending},
+	}}
+}
+
+// CreateImage 保存商品图片，同一商品已有相同内容的图片时返回ErrDuplicateKey
+func (r *ProductRepository) CreateImage(image *model.ProductImage) error {
+	if err := r.db.Create(image).Error; err != nil {
+		if err == gorm.ErrDuplicatedKey {
+			return ErrDuplicateKey
+		}
+		return err
+	}
+	return nil
+}
+
+// GetImage 查询商品的图片
+func (r *ProductRepository) GetImage(productID, imageID int64) (*model.ProductImage, error) {
+	var image model.ProductImage
+	err := r.db.Where("product_id = ?", productID).First(&image, imageID).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return &image, nil
+}
+
+// DeleteImage 删除商品图片记录
+func (r *ProductRepository) DeleteImage(id int64) error {
+	result := r.db.Delete(&model.ProductImage{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// SetPrice 设置商品在指定币种下的定价，已存在时覆盖，并写入价格变更记录
+func (r *ProductRepository) SetPrice(price *model.ProductPrice) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var count int64
+		if err := tx.Model(&model.Product{}).Where("id = ?", price.ProductID).Count(&count).Error; err != nil {
+			return err
+		}
+		if count == 0 {
+			return ErrNotFound
+		}
+
+		current, err := r.lockPriceWithTx(tx, price.ProductID, price.Currency)
+		if err != nil && err != ErrNotFound {
+			return err
+		}
+		var oldPrice *money.Amount
+		if current != nil {
+			oldPrice = &current.Price
+		}
+
+		err = tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "product_id"}, {Name: "currency"}},
+			DoUpdates: clause.AssignmentColumns([]string{"price", "updated_at"}),
+		}).Create(price).Error
+		if err != nil {
+			return err
+		}
+		newPrice := price.Price
+		return recordPriceChangeWithTx(tx, &model.ProductPriceHistory{
+			ProductID: price.ProductID,
+			Currency:  price.Currency,
+			OldPrice:  oldPrice,
+			NewPrice:  &newPrice,
+		})
+	})
+}
+
+// DeletePrice 删除商品在指定币种下的定价，并写入价格变更记录
+func (r *ProductRepository) DeletePrice(productID int64, currency string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		current, err := r.lockPriceWithTx(tx, productID, currency)
+		if err != nil {
+			return err
+		}
+
+		result := tx.Delete(&model.ProductPrice{}, current.ID)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrNotFound
+		}
+		return recordPriceChangeWithTx(tx, &model.ProductPriceHistory{
+			ProductID: productID,
+			Currency:  currency,
+			OldPrice:  &current.Price,
+		})
+	})
+}
+
+// lockPriceWithTx 在事务内查询并锁定商品在指定币种下的定价
+func (r *ProductRepository) lockPriceWithTx(tx *gorm.DB, productID int64, currency string) (*model.ProductPrice, error) {
+	var price model.ProductPrice
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("product_id = ? AND currency = ?", productID, currency).Take(&price).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return &price, nil
+}
+
+// ListPriceHistory 按时间从新到旧分页查询商品的价格变更记录，包括已删除商品的记录
+func (r *ProductRepository) ListPriceHistory(productID int64, offset, limit int) ([]*model.ProductPriceHistory, int64, error) {
+	var changes []*model.ProductPriceHistory
+	var total int64
+
+	db := r.db.Model(&model.ProductPriceHistory{}).Where("product_id = ?", productID)
+	if err := db.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	if err := db.Order("created_at DESC, id DESC").Offset(offset).Limit(limit).Find(&changes).Error; err != nil {
+		return nil, 0, err
+	}
+	return changes, total, nil
+}
+
+// recordPriceChangeWithTx 写入价格变更记录，价格没有变化时不写入
+func recordPriceChangeWithTx(tx *gorm.DB, change *model.ProductPriceHistory) error {
+	if samePrice(change.OldPrice, change.NewPrice) {
+		return nil
+	}
+	return tx.Create(change).Error
+}
+
+func samePrice(a, b *money.Amount) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
@@ -0,0 +1,486 @@
+package mysql
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/ylh990835774/blockchain-shop-demo/internal/model"
+	"github.com/ylh990835774/blockchain-shop-demo/internal/testutil"
+	"github.com/ylh990835774/blockchain-shop-demo/pkg/money"
+	"gorm.io/gorm"
+)
+
+func TestProductRepository_PriceRoundTrip(t *testing.T) {
+	db := testutil.NewDB(t)
+	repo := NewProductRepository(db)
+
+	for _, price := range []string{"0.10", "0.20", "19.99", "12345678.01"} {
+		product := &model.Product{Name: "price " + price, Price: money.MustParseAmount(price), Currency: "CNY", Stock: 1}
+		require.NoError(t, repo.Create(product))
+
+		got, err := repo.GetByID(product.ID)
+		require.NoError(t, err)
+		assert.Equal(t, price, got.Price.String())
+		assert.Equal(t, money.MustParseAmount(price).Mul(3), got.Price.Mul(3))
+	}
+}
+
+func TestProductRepository_DecrementStockWithTx(t *testing.T) {
+	db := testutil.NewDB(t)
+	repo := NewProductRepository(db)
+
+	product := &model.Product{Name: "test product", Price: money.MustParseAmount("9.99"), Stock: 5}
+	require.NoError(t, repo.Create(product))
+	require.Len(t, product.SKUs, 1)
+	skuID := product.SKUs[0].ID
+
+	tests := []struct {
+		name          string
+		skuID         int64
+		quantity      int
+		expectedErr   error
+		expectedStock int
+	}{
+		{
+			name:          "enough stock",
+			skuID:         skuID,
+			quantity:      3,
+			expectedErr:   nil,
+			expectedStock: 2,
+		},
+		{
+			name:          "insufficient stock",
+			skuID:         skuID,
+			quantity:      3,
+			expectedErr:   ErrInsufficientStock,
+			expectedStock: 2,
+		},
+		{
+			name:          "exact remaining stock",
+			skuID:         skuID,
+			quantity:      2,
+			expectedErr:   nil,
+			expectedStock: 0,
+		},
+		{
+			name:          "sku not found",
+			skuID:         skuID + 1,
+			quantity:      1,
+			expectedErr:   ErrNotFound,
+			expectedStock: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := db.Transaction(func(tx *gorm.DB) error {
+				return repo.DecrementStockWithTx(tx, &model.InventoryMovement{
+					SKUID: tt.skuID, Type: model.MovementTypeReservation, Quantity: -tt.quantity,
+				})
+			})
+			assert.Equal(t, tt.expectedErr, err)
+
+			got, err := repo.GetByID(product.ID)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectedStock, got.Stock)
+			assert.Equal(t, tt.expectedStock, got.SKUs[0].Stock)
+		})
+	}
+}
+
+// TestProductRepository_DecrementStockWithTx_Concurrent 多个买家同时在各自的事务中扣减库存，
+// 成功的扣减数等于初始库存，库存不会变为负数
+func TestProductRepository_DecrementStockWithTx_Concurrent(t *testing.T) {
+	db := testutil.NewDB(t)
+	repo := NewProductRepository(db)
+
+	const (
+		initialStock = 10
+		buyers       = 30
+	)
+
+	product := &model.Product{Name: "hot product", Price: money.MustParseAmount("1.00"), Stock: initialStock}
+	require.NoError(t, repo.Create(product))
+	require.Len(t, product.SKUs, 1)
+	skuID := product.SKUs[0].ID
+
+	var (
+		wg           sync.WaitGroup
+		mu           sync.Mutex
+		succeeded    int
+		insufficient int
+		unexpected   []error
+	)
+	start := make(chan struct{})
+
+	for i := 0; i < buyers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+
+			err := db.Transaction(func(tx *gorm.DB) error {
+				return repo.DecrementStockWithTx(tx, &model.InventoryMovement{
+					SKUID: skuID, Type: model.MovementTypeReservation, Quantity: -1,
+				})
+			})
+
+			mu.Lock()
+			defer mu.Unlock()
+			switch err {
+			case nil:
+				succeeded++
+			case ErrInsufficientStock:
+				insufficient++
+			default:
+				unexpected = append(unexpected, err)
+			}
+		}()
+	}
+
+	close(start)
+	wg.Wait()
+
+	require.Empty(t, unexpected)
+	assert.Equal(t, initialStock, succeeded)
+	assert.Equal(t, buyers-initialStock, insufficient)
+
+	// 直接读取两张表，商品合计与SKU库存都恰好扣减为零
+	var productStock, skuStock int
+	require.NoError(t, db.Model(&model.Product{}).Where("id = ?", product.ID).Pluck("stock", &productStock).Error)
+	require.NoError(t, db.Model(&model.ProductSKU{}).Where("id = ?", skuID).Pluck("stock", &skuStock).Error)
+	assert.Equal(t, 0, productStock)
+	assert.Equal(t, 0, skuStock)
+
+	var movements int64
+	require.NoError(t, db.Model(&model.InventoryMovement{}).
+		Where("sku_id = ? AND type = ?", skuID, model.MovementTypeReservation).Count(&movements).Error)
+	assert.Equal(t, int64(initialStock), movements)
+}
+
+func TestProductRepository_SetPrice(t *testing.T) {
+	db := testutil.NewDB(t)
+	repo := NewProductRepository(db)
+
+	product := &model.Product{Name: "priced product", Price: money.MustParseAmount("100.00"), Currency: "CNY", Stock: 1}
+	require.NoError(t, repo.Create(product))
+
+	require.NoError(t, repo.SetPrice(&model.ProductPrice{ProductID: product.ID, Currency: "USD", Price: money.MustParseAmount("14.99")}))
+	// 再次设置同一币种覆盖原定价
+	require.NoError(t, repo.SetPrice(&model.ProductPrice{ProductID: product.ID, Currency: "USD", Price: money.MustParseAmount("13.99")}))
+	require.NoError(t, repo.SetPrice(&model.ProductPrice{ProductID: product.ID, Currency: "EUR", Price: money.MustParseAmount("12.99")}))
+
+	got, err := repo.GetByID(product.ID)
+	require.NoError(t, err)
+	assert.Len(t, got.Prices, 2)
+	price, ok := got.PriceIn("USD")
+	assert.True(t, ok)
+	assert.Equal(t, money.MustParseAmount("13.99"), price)
+
+	assert.Equal(t, ErrNotFound, repo.SetPrice(&model.ProductPrice{ProductID: product.ID + 1, Currency: "USD", Price: 1}))
+
+	require.NoError(t, repo.DeletePrice(product.ID, "USD"))
+	assert.Equal(t, ErrNotFound, repo.DeletePrice(product.ID, "USD"))
+
+	got, err = repo.GetByID(product.ID)
+	require.NoError(t, err)
+	_, ok = got.PriceIn("USD")
+	assert.False(t, ok)
+}
+
+func TestProductRepository_ListAfter(t *testing.T) {
+	db := testutil.NewDB(t)
+	repo := NewProductRepository(db)
+
+	ids := make([]int64, 0, 5)
+	for i := 0; i < 5; i++ {
+		product := &model.Product{Name: "product", Price: money.MustParseAmount("1"), Currency: "CNY", Stock: 1}
+		require.NoError(t, repo.Create(product))
+		ids = append(ids, product.ID)
+	}
+
+	var got []int64
+	var afterID int64
+	for {
+		products, err := repo.ListAfter(model.ProductFilter{}, afterID, 2)
+		require.NoError(t, err)
+		if len(products) == 0 {
+			break
+		}
+		for _, product := range products {
+			got = append(got, product.ID)
+		}
+		afterID = products[len(products)-1].ID
+	}
+	assert.Equal(t, ids, got)
+}
+
+func TestProductRepository_Search(t *testing.T) {
+	db := testutil.NewDB(t)
+	repo := NewProductRepository(db)
+
+	seed := []model.Product{
+		{Name: "Blue Tea Cup", Description: "porcelain cup", Price: money.MustParseAmount("30"), Currency: "CNY", Stock: 5},
+		{Name: "Green Tea", Description: "loose leaf tea, makes a fine cup of tea", Price: money.MustParseAmount("80"), Currency: "CNY", Stock: 0},
+		{Name: "Coffee Mug", Description: "large mug", Price: money.MustParseAmount("120"), Currency: "CNY", Stock: 2},
+		{Name: "Tea Set", Description: "teapot and four cups", Price: money.MustParseAmount("20"), Currency: "USD", Stock: 1},
+		{Name: "Espresso Machine", Description: "", Price: money.MustParseAmount("1500"), Currency: "CNY", Stock: 3},
+	}
+	ids := make([]int64, len(seed))
+	for i := range seed {
+		require.NoError(t, repo.Create(&seed[i]))
+		ids[i] = seed[i].ID
+	}
+
+	minPrice, maxPrice := money.MustParseAmount("30"), money.MustParseAmount("120")
+
+	tests := []struct {
+		name          string
+		filter        model.ProductFilter
+		expected      []int64
+		expectedTotal int64
+	}{
+		{name: "no filter keeps id order", filter: model.ProductFilter{}, expected: ids, expectedTotal: 5},
+		{
+			name:          "keyword by relevance",
+			filter:        model.ProductFilter{Query: "TEA", SortBy: model.ProductSortRelevance},
+			expected:      []int64{ids[1], ids[3], ids[0]},
+			expectedTotal: 3,
+		},
+		{
+			name:          "keyword any term",
+			filter:        model.ProductFilter{Query: "mug espresso"},
+			expected:      []int64{ids[2], ids[4]},
+			expectedTotal: 2,
+		},
+		{
+			name:          "keyword without match",
+			filter:        model.ProductFilter{Query: "kettle"},
+			expected:      []int64{},
+			expectedTotal: 0,
+		},
+		{
+			name:          "price range and stock",
+			filter:        model.ProductFilter{MinPrice: &minPrice, MaxPrice: &maxPrice, InStock: true},
+			expected:      []int64{ids[0], ids[2]},
+			expectedTotal: 2,
+		},
+		{
+			name:          "currency sorted by price",
+			filter:        model.ProductFilter{Currency: "CNY", SortBy: model.ProductSortPrice},
+			expected:      []int64{ids[4], ids[2], ids[1], ids[0]},
+			expectedTotal: 4,
+		},
+		{
+			name:          "sorted by name ascending",
+			filter:        model.ProductFilter{Query: "tea", SortBy: model.ProductSortName, Ascending: true},
+			expected:      []int64{ids[0], ids[1], ids[3]},
+			expectedTotal: 3,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			products, total, err := repo.List(tt.filter, 0, 10)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectedTotal, total)
+			got := make([]int64, 0, len(products))
+			for _, product := range products {
+				got = append(got, product.ID)
+			}
+			assert.Equal(t, tt.expected, got)
+		})
+	}
+
+	// 分页时总数不受offset和limit影响
+	products, total, err := repo.List(model.ProductFilter{Query: "tea", SortBy: model.ProductSortRelevance}, 1, 1)
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), total)
+	require.Len(t, products, 1)
+	assert.Equal(t, ids[3], products[0].ID)
+}
+
+func TestProductRepository_Facets(t *testing.T) {
+	db := testutil.NewDB(t)
+	repo := NewProductRepository(db)
+
+	for _, p := range []struct {
+		price string
+		stock int
+	}{{"10", 1}, {"49.99", 0}, {"50", 3}, {"150", 0}, {"150", 2}} {
+		product := &model.Product{Name: "tea " + p.price, Price: money.MustParseAmount(p.price), Currency: "CNY", Stock: p.stock}
+		require.NoError(t, repo.Create(product))
+	}
+	require.NoError(t, repo.Create(&model.Product{Name: "coffee", Price: money.MustParseAmount("10"), Currency: "CNY", Stock: 1}))
+
+	bounds := []money.Amount{0, money.MustParseAmount("50"), money.MustParseAmount("100")}
+	max50, max100 := money.MustParseAmount("50"), money.MustParseAmount("100")
+	minPrice := money.MustParseAmount("100")
+
+	// 库存分面不受库存条件影响，价格分面不受价格条件影响
+	facets, err := repo.Facets(model.ProductFilter{Query: "tea", InStock: true, MinPrice: &minPrice}, bounds)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), facets.InStock)
+	assert.Equal(t, int64(1), facets.OutOfStock)
+	assert.Equal(t, []model.PriceRangeFacet{
+		{Min: 0, Max: &max50, Count: 1},
+		{Min: money.MustParseAmount("50"), Max: &max100, Count: 1},
+		{Min: money.MustParseAmount("100"), Count: 1},
+	}, facets.PriceRanges)
+
+	facets, err = repo.Facets(model.ProductFilter{}, bounds)
+	require.NoError(t, err)
+	assert.Equal(t, int64(4), facets.InStock)
+	assert.Equal(t, int64(2), facets.OutOfStock)
+	assert.Equal(t, []int64{3, 1, 2}, []int64{facets.PriceRanges[0].Count, facets.PriceRanges[1].Count, facets.PriceRanges[2].Count})
+}
+
+func TestProductRepository_DeleteIsSoft(t *testing.T) {
+	db := testutil.NewDB(t)
+	repo := NewProductRepository(db)
+
+	product := &model.Product{Name: "discontinued", Price: money.MustParseAmount("5"), Currency: "CNY", Stock: 1}
+	require.NoError(t, repo.Create(product))
+	require.NoError(t, repo.Delete(product.ID))
+
+	_, err := repo.GetByID(product.ID)
+	assert.Equal(t, ErrNotFound, err)
+	products, total, err := repo.List(model.ProductFilter{WithArchived: true}, 0, 10)
+	require.NoError(t, err)
+	assert.Empty(t, products)
+	assert.Zero(t, total)
+
+	// 订单仍可查询到已删除的商品及其SKU
+	got, err := repo.GetByIDUnscoped(product.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "discontinued", got.Name)
+	assert.Len(t, got.SKUs, 1)
+
+	assert.Equal(t, ErrNotFound, repo.Delete(product.ID))
+	assert.Equal(t, ErrNotFound, repo.SetArchived(product.ID, true))
+}
+
+func TestProductRepository_SetArchived(t *testing.T) {
+	db := testutil.NewDB(t)
+	repo := NewProductRepository(db)
+
+	active := &model.Product{Name: "active", Price: money.MustParseAmount("5"), Currency: "CNY", Stock: 1}
+	archived := &model.Product{Name: "archived", Price: money.MustParseAmount("5"), Currency: "CNY", Stock: 1}
+	require.NoError(t, repo.Create(active))
+	require.NoError(t, repo.Create(archived))
+	require.NoError(t, repo.SetArchived(archived.ID, true))
+
+	products, total, err := repo.List(model.ProductFilter{}, 0, 10)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), total)
+	require.Len(t, products, 1)
+	assert.Equal(t, active.ID, products[0].ID)
+
+	products, total, err = repo.List(model.ProductFilter{WithArchived: true}, 0, 10)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), total)
+	assert.Len(t, products, 2)
+
+	// 已下架的商品仍可按ID查询
+	got, err := repo.GetByID(archived.ID)
+	require.NoError(t, err)
+	assert.NotNil(t, got.ArchivedAt)
+
+	require.NoError(t, repo.SetArchived(archived.ID, false))
+	got, err = repo.GetByID(archived.ID)
+	require.NoError(t, err)
+	assert.Nil(t, got.ArchivedAt)
+
+	assert.Equal(t, ErrNotFound, repo.SetArchived(999, true))
+}
+
+func TestProductRepository_UpdateVersion(t *testing.T) {
+	db := testutil.NewDB(t)
+	repo := NewProductRepository(db)
+
+	product := &model.Product{Name: "versioned", Price: money.MustParseAmount("5"), Currency: "CNY", Stock: 1}
+	require.NoError(t, repo.Create(product))
+	assert.Equal(t, int64(1), product.Version)
+
+	// 两个请求基于同一版本修改，后提交的请求检测到冲突
+	require.NoError(t, repo.Update(product.ID, 1, map[string]interface{}{"name": "first"}))
+	assert.Equal(t, ErrVersionConflict, repo.Update(product.ID, 1, map[string]interface{}{"name": "second"}))
+
+	got, err := repo.GetByID(product.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "first", got.Name)
+	assert.Equal(t, int64(2), got.Version)
+
+	// 不校验版本时仍递增版本号，写入相同的值也视为更新
+	require.NoError(t, repo.Update(product.ID, 0, map[string]interface{}{"name": "first"}))
+	require.NoError(t, repo.SetArchived(product.ID, true))
+	got, err = repo.GetByID(product.ID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(4), got.Version)
+
+	assert.Equal(t, ErrNotFound, repo.Update(999, 1, map[string]interface{}{"name": "missing"}))
+	assert.Equal(t, ErrNotFound, repo.Update(999, 0, map[string]interface{}{"name": "missing"}))
+}
+
+func TestProductRepository_PriceHistory(t *testing.T) {
+	db := testutil.NewDB(t)
+	repo := NewProductRepository(db)
+
+	product := &model.Product{
+		Name:     "绿茶",
+		Price:    money.MustParseAmount("25.00"),
+		Currency: "CNY",
+		Prices:   []model.ProductPrice{{Currency: "USD", Price: money.MustParseAmount("3.50")}},
+	}
+	require.NoError(t, repo.Create(product))
+
+	require.NoError(t, repo.Update(product.ID, 0, map[string]interface{}{"price": money.MustParseAmount("26.00")}))
+	// 价格未变化或更新失败时不记录
+	require.NoError(t, repo.Update(product.ID, 0, map[string]interface{}{"price": money.MustParseAmount("26.00")}))
+	assert.Equal(t, ErrVersionConflict, repo.Update(product.ID, 1, map[string]interface{}{"price": money.MustParseAmount("27.00")}))
+	require.NoError(t, repo.Update(product.ID, 0, map[string]interface{}{"name": "龙井"}))
+
+	require.NoError(t, repo.SetPrice(&model.ProductPrice{ProductID: product.ID, Currency: "USD", Price: money.MustParseAmount("3.80")}))
+	require.NoError(t, repo.DeletePrice(product.ID, "USD"))
+
+	skuPrice := money.MustParseAmount("40.00")
+	sku := &model.ProductSKU{ProductID: product.ID, Code: "TEA-L", Options: model.SKUOptions{}, Price: &skuPrice}
+	require.NoError(t, db.Transaction(func(tx *gorm.DB) error {
+		return repo.CreateSKUWithTx(tx, sku, 0)
+	}))
+	sku.Price, sku.Stock = nil, 3
+	require.NoError(t, db.Transaction(func(tx *gorm.DB) error {
+		return repo.UpdateSKUWithTx(tx, sku, 0)
+	}))
+
+	changes, total, err := repo.ListPriceHistory(product.ID, 0, 10)
+	require.NoError(t, err)
+	assert.Equal(t, int64(7), total)
+
+	type change struct {
+		sku      bool
+		currency string
+		old, new string
+	}
+	format := func(amount *money.Amount) string {
+		if amount == nil {
+			return ""
+		}
+		return amount.String()
+	}
+	var got []change
+	for _, c := range changes {
+		got = append(got, change{sku: c.SKUID != nil, currency: c.Currency, old: format(c.OldPrice), new: format(c.NewPrice)})
+	}
+	assert.Equal(t, []change{
+		{sku: true, currency: "CNY", old: "40.00"},
+		{sku: true, currency: "CNY", new: "40.00"},
+		{currency: "USD", old: "3.80"},
+		{currency: "USD", old: "3.50", new: "3.80"},
+		{currency: "CNY", old: "25.00", new: "26.00"},
+		{currency: "USD", new: "3.50"},
+		{currency: "CNY", new: "25.00"},
+	}, got)
+}
@@ -0,0 +1,57 @@
+package mysql
+
+import (
+	"time"
+
+	"github.com/ylh990835774/blockchain-shop-demo/internal/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type ReservationRepository struct {
+	db *gorm.DB
+}
+
+func NewReservationRepository(db *gorm.DB) *ReservationRepository {
+	return &ReservationRepository{db: db}
+}
+
+func (r *ReservationRepository) CreateWithTx(tx *gorm.DB, reservation *model.StockReservation) error {
+	return tx.Create(reservation).Error
+}
+
+// GetByOrderIDForUpdateWithTx 获取订单的库存预留并加行锁
+func (r *ReservationRepository) GetByOrderIDForUpdateWithTx(tx *gorm.DB, orderID int64) (*model.StockReservation, error) {
+	var reservation model.StockReservation
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("order_id = ?", orderID).First(&reservation).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return &reservation, nil
+}
+
+func (r *ReservationRepository) UpdateStatusWithTx(tx *gorm.DB, id int64, status model.ReservationStatus) error {
+	result := tx.Model(&model.StockReservation{}).Where("id = ?", id).Update("status", status)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// ListExpired 获取已过期但仍处于预留状态的记录
+func (r *ReservationRepository) ListExpired(now time.Time, limit int) ([]*model.StockReservation, error) {
+	var reservations []*model.StockReservation
+	err := r.db.Where("status = ? AND expires_at <= ?", model.ReservationStatusActive, now).
+		Order("expires_at").Limit(limit).Find(&reservations).Error
+	if err != nil {
+		return nil, err
+	}
+	return reservations, nil
+}
@@ -0,0 +1,97 @@
+package mysql
+
+import (
+	"github.com/ylh990835774/blockchain-shop-demo/internal/model"
+	"github.com/ylh990835774/blockchain-shop-demo/pkg/money"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type ReturnRepository struct {
+	db *gorm.DB
+}
+
+func NewReturnRepository(db *gorm.DB) *ReturnRepository {
+	return &ReturnRepository{db: db}
+}
+
+func (r *ReturnRepository) CreateWithTx(tx *gorm.DB, ret *model.ReturnRequest) error {
+	return tx.Create(ret).Error
+}
+
+func (r *ReturnRepository) Update(id int64, updates map[string]interface{}) error {
+	return r.UpdateWithTx(r.db, id, updates)
+}
+
+func (r *ReturnRepository) UpdateWithTx(tx *gorm.DB, id int64, updates map[string]interface{}) error {
+	result := tx.Model(&model.ReturnRequest{}).Where("id = ?", id).Updates(updates)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// TransitionStatus 仅当退货申请处于from状态时将其更新为to状态，返回是否更新成功
+func (r *ReturnRepository) TransitionStatus(id int64, from, to model.ReturnStatus) (bool, error) {
+	result := r.db.Model(&model.ReturnRequest{}).Where("id = ? AND status = ?", id, from).Update("status", to)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+func (r *ReturnRepository) GetByID(id int64) (*model.ReturnRequest, error) {
+	var ret model.ReturnRequest
+	err := r.db.First(&ret, id).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return &ret, nil
+}
+
+// GetByIDForUpdateWithTx 获取退货申请并加行锁
+func (r *ReturnRepository) GetByIDForUpdateWithTx(tx *gorm.DB, id int64) (*model.ReturnRequest, error) {
+	var ret model.ReturnRequest
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&ret, id).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return &ret, nil
+}
+
+func (r *ReturnRepository) ListByOrderID(orderID int64) ([]*model.ReturnRequest, error) {
+	var rets []*model.ReturnRequest
+	err := r.db.Where("order_id = ?", orderID).Order("id").Find(&rets).Error
+	if err != nil {
+		return nil, err
+	}
+	return rets, nil
+}
+
+// ReturnTotal 订单退货数量和退款金额合计
+type ReturnTotal struct {
+	Quantity     int
+	RefundAmount money.Amount
+}
+
+// SumWithTx 统计订单中指定状态的退货数量和退款金额
+func (r *ReturnRepository) SumWithTx(tx *gorm.DB, orderID int64, statuses ...model.ReturnStatus) (*ReturnTotal, error) {
+	var total ReturnTotal
+	err := tx.Model(&model.ReturnRequest{}).
+		Where("order_id = ? AND status IN ?", orderID, statuses).
+		Select("COALESCE(SUM(quantity), 0) AS quantity, COALESCE(SUM(refund_amount), 0) AS refund_amount").
+		Scan(&total).Error
+	if err != nil {
+		return nil, err
+	}
+	return &total, nil
+}
@@ -0,0 +1,58 @@
+package mysql
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/ylh990835774/blockchain-shop-demo/internal/model"
+	"github.com/ylh990835774/blockchain-shop-demo/internal/testutil"
+	"github.com/ylh990835774/blockchain-shop-demo/pkg/money"
+)
+
+func TestReturnRepository(t *testing.T) {
+	db := testutil.NewDB(t)
+	repo := NewReturnRepository(db)
+
+	returns := []*model.ReturnRequest{
+		{OrderID: 1, UserID: 1, Quantity: 1, Reason: "尺码不合适", Status: model.ReturnStatusRefunded, RefundAmount: money.MustParseAmount("3.33"), Currency: "CNY"},
+		{OrderID: 1, UserID: 1, Quantity: 2, Reason: "质量问题", Status: model.ReturnStatusRejected, RefundAmount: money.MustParseAmount("6.66"), Currency: "CNY"},
+		{OrderID: 1, UserID: 1, Quantity: 1, Reason: "不想要了", Status: model.ReturnStatusRequested, RefundAmount: money.MustParseAmount("3.33"), Currency: "CNY"},
+		{OrderID: 2, UserID: 1, Quantity: 5, Reason: "质量问题", Status: model.ReturnStatusRequested, RefundAmount: money.MustParseAmount("50"), Currency: "CNY"},
+	}
+	for _, ret := range returns {
+		require.NoError(t, repo.CreateWithTx(db, ret))
+	}
+
+	// 被拒绝的申请不占用可退数量
+	total, err := repo.SumWithTx(db, 1, model.ReturnStatusRequested, model.ReturnStatusRefunded)
+	require.NoError(t, err)
+	assert.Equal(t, 2, total.Quantity)
+	assert.Equal(t, money.MustParseAmount("6.66"), total.RefundAmount)
+
+	total, err = repo.SumWithTx(db, 3, model.ReturnStatusRequested)
+	require.NoError(t, err)
+	assert.Equal(t, 0, total.Quantity)
+	assert.Equal(t, money.Amount(0), total.RefundAmount)
+
+	// 只有处于指定状态时才能转换
+	ok, err := repo.TransitionStatus(returns[2].ID, model.ReturnStatusApproved, model.ReturnStatusReceived)
+	require.NoError(t, err)
+	assert.False(t, ok)
+	ok, err = repo.TransitionStatus(returns[2].ID, model.ReturnStatusRequested, model.ReturnStatusApproved)
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	got, err := repo.GetByID(returns[2].ID)
+	require.NoError(t, err)
+	assert.Equal(t, model.ReturnStatusApproved, got.Status)
+
+	list, err := repo.ListByOrderID(1)
+	require.NoError(t, err)
+	require.Len(t, list, 3)
+	assert.Equal(t, returns[0].ID, list[0].ID)
+
+	_, err = repo.GetByID(returns[3].ID + 1)
+	assert.Equal(t, ErrNotFound, err)
+	assert.Equal(t, ErrNotFound, repo.Update(returns[3].ID+1, map[string]interface{}{"refund_ref": "x"}))
+}
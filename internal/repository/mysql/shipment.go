@@ -0,0 +1,98 @@
+package mysql
+
+import (
+	"github.com/ylh990835774/blockchain-shop-demo/internal/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type ShipmentRepository struct {
+	db *gorm.DB
+}
+
+func NewShipmentRepository(db *gorm.DB) *ShipmentRepository {
+	return &ShipmentRepository{db: db}
+}
+
+func (r *ShipmentRepository) CreateWithTx(tx *gorm.DB, shipment *model.Shipment) error {
+	if err := tx.Create(shipment).Error; err != nil {
+		if err == gorm.ErrDuplicatedKey {
+			return ErrDuplicateKey
+		}
+		return err
+	}
+	return nil
+}
+
+func (r *ShipmentRepository) UpdateWithTx(tx *gorm.DB, id int64, updates map[string]interface{}) error {
+	result := tx.Model(&model.Shipment{}).Where("id = ?", id).Updates(updates)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *ShipmentRepository) CreateEventWithTx(tx *gorm.DB, event *model.ShipmentEvent) error {
+	return tx.Create(event).Error
+}
+
+// GetByID 获取发货记录及按发生时间排序的跟踪事件
+func (r *ShipmentRepository) GetByID(id int64) (*model.Shipment, error) {
+	var shipment model.Shipment
+	err := r.withEvents(r.db).First(&shipment, id).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return &shipment, nil
+}
+
+// GetByOrderID 获取订单的发货记录及跟踪事件
+func (r *ShipmentRepository) GetByOrderID(orderID int64) (*model.Shipment, error) {
+	var shipment model.Shipment
+	err := r.withEvents(r.db).Where("order_id = ?", orderID).First(&shipment).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return &shipment, nil
+}
+
+// GetByIDForUpdateWithTx 获取发货记录并加行锁
+func (r *ShipmentRepository) GetByIDForUpdateWithTx(tx *gorm.DB, id int64) (*model.Shipment, error) {
+	var shipment model.Shipment
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&shipment, id).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return &shipment, nil
+}
+
+// GetByOrderIDForUpdateWithTx 获取订单的发货记录并加行锁
+func (r *ShipmentRepository) GetByOrderIDForUpdateWithTx(tx *gorm.DB, orderID int64) (*model.Shipment, error) {
+	var shipment model.Shipment
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("order_id = ?", orderID).First(&shipment).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return &shipment, nil
+}
+
+func (r *ShipmentRepository) withEvents(db *gorm.DB) *gorm.DB {
+	return db.Preload("Events", func(db *gorm.DB) *gorm.DB {
+		return db.Order("occurred_at, id")
+	})
+}
@@ -0,0 +1,62 @@
+package mysql
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/ylh990835774/blockchain-shop-demo/internal/model"
+	"github.com/ylh990835774/blockchain-shop-demo/internal/testutil"
+)
+
+func TestShipmentRepository(t *testing.T) {
+	db := testutil.NewDB(t)
+	repo := NewShipmentRepository(db)
+
+	shippedAt := time.Now().Add(-48 * time.Hour).Truncate(time.Second)
+	shipment := &model.Shipment{
+		OrderID:        1,
+		Carrier:        "SF",
+		TrackingNumber: "SF1234567890",
+		Status:         model.ShipmentStatusShipped,
+		ShippedAt:      shippedAt,
+	}
+	require.NoError(t, repo.CreateWithTx(db, shipment))
+
+	// 每个订单只能有一条发货记录
+	duplicate := *shipment
+	duplicate.ID = 0
+	assert.Equal(t, ErrDuplicateKey, repo.CreateWithTx(db, &duplicate))
+
+	// 事件按发生时间而非写入顺序返回
+	events := []model.ShipmentEvent{
+		{ShipmentID: shipment.ID, Status: model.ShipmentStatusInTransit, Location: "杭州", OccurredAt: shippedAt.Add(24 * time.Hour)},
+		{ShipmentID: shipment.ID, Status: model.ShipmentStatusShipped, Location: "深圳", OccurredAt: shippedAt},
+	}
+	for i := range events {
+		require.NoError(t, repo.CreateEventWithTx(db, &events[i]))
+	}
+
+	got, err := repo.GetByOrderID(1)
+	require.NoError(t, err)
+	assert.Equal(t, shipment.ID, got.ID)
+	require.Len(t, got.Events, 2)
+	assert.Equal(t, "深圳", got.Events[0].Location)
+	assert.Equal(t, "杭州", got.Events[1].Location)
+
+	deliveredAt := time.Now().Truncate(time.Second)
+	require.NoError(t, repo.UpdateWithTx(db, shipment.ID, map[string]interface{}{
+		"status":       model.ShipmentStatusDelivered,
+		"delivered_at": deliveredAt,
+	}))
+	got, err = repo.GetByID(shipment.ID)
+	require.NoError(t, err)
+	assert.Equal(t, model.ShipmentStatusDelivered, got.Status)
+	require.NotNil(t, got.DeliveredAt)
+	assert.True(t, deliveredAt.Equal(*got.DeliveredAt))
+
+	_, err = repo.GetByOrderID(2)
+	assert.Equal(t, ErrNotFound, err)
+	assert.Equal(t, ErrNotFound, repo.UpdateWithTx(db, shipment.ID+1, map[string]interface{}{"carrier": "EMS"}))
+}
@@ -0,0 +1,128 @@
+package mysql
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/ylh990835774/blockchain-shop-demo/internal/model"
+	"github.com/ylh990835774/blockchain-shop-demo/internal/testutil"
+	"github.com/ylh990835774/blockchain-shop-demo/pkg/money"
+	"gorm.io/gorm"
+)
+
+func TestProductRepository_CreateDefaultSKU(t *testing.T) {
+	db := testutil.NewDB(t)
+	repo := NewProductRepository(db)
+
+	product := &model.Product{Name: "绿茶", Price: money.MustParseAmount("10.00"), Currency: "CNY", Stock: 7}
+	require.NoError(t, repo.Create(product))
+
+	got, err := repo.GetByID(product.ID)
+	require.NoError(t, err)
+	require.Len(t, got.SKUs, 1)
+	assert.Equal(t, fmt.Sprintf("P%d", product.ID), got.SKUs[0].Code)
+	assert.True(t, got.SKUs[0].IsDefault)
+	assert.Equal(t, 7, got.SKUs[0].Stock)
+	assert.Nil(t, got.SKUs[0].Price)
+	assert.Equal(t, model.SKUOptions{}, got.SKUs[0].Options)
+}
+
+func TestProductRepository_SKUStock(t *testing.T) {
+	db := testutil.NewDB(t)
+	repo := NewProductRepository(db)
+
+	price := money.MustParseAmount("12.50")
+	product := &model.Product{
+		Name:     "T恤",
+		Price:    money.MustParseAmount("10.00"),
+		Currency: "CNY",
+		Stock:    8,
+		SKUs: []model.ProductSKU{
+			{Code: "TEE-RED-M", Options: model.SKUOptions{"颜色": "红", "尺码": "M"}, Stock: 5, IsDefault: true},
+			{Code: "TEE-RED-L", Options: model.SKUOptions{"颜色": "红", "尺码": "L"}, Price: &price, Stock: 3},
+		},
+	}
+	require.NoError(t, repo.Create(product))
+	medium, large := product.SKUs[0], product.SKUs[1]
+
+	stock := func() (int, []int) {
+		got, err := repo.GetByID(product.ID)
+		require.NoError(t, err)
+		var skus []int
+		for _, sku := range got.SKUs {
+			skus = append(skus, sku.Stock)
+		}
+		return got.Stock, skus
+	}
+
+	require.NoError(t, db.Transaction(func(tx *gorm.DB) error {
+		return repo.DecrementStockWithTx(tx, &model.InventoryMovement{SKUID: large.ID, Type: model.MovementTypeReservation, Quantity: -2})
+	}))
+	total, skus := stock()
+	assert.Equal(t, 6, total)
+	assert.Equal(t, []int{5, 1}, skus)
+
+	// 其他SKU的库存不能抵扣
+	err := db.Transaction(func(tx *gorm.DB) error {
+		return repo.DecrementStockWithTx(tx, &model.InventoryMovement{SKUID: large.ID, Type: model.MovementTypeReservation, Quantity: -2})
+	})
+	assert.Equal(t, ErrInsufficientStock, err)
+
+	require.NoError(t, repo.UpdateStock(&model.InventoryMovement{SKUID: large.ID, Type: model.MovementTypeRestock, Quantity: 2}))
+	assert.Equal(t, ErrNotFound, repo.UpdateStock(&model.InventoryMovement{SKUID: medium.ID, Type: model.MovementTypeAdjustment, Quantity: -6}))
+	total, skus = stock()
+	assert.Equal(t, 8, total)
+	assert.Equal(t, []int{5, 3}, skus)
+
+	// 修改SKU时按SKU库存重新计算商品库存，设为默认时取消原默认SKU
+	large.Stock = 10
+	large.IsDefault = true
+	require.NoError(t, db.Transaction(func(tx *gorm.DB) error {
+		return repo.UpdateSKUWithTx(tx, &large, 0)
+	}))
+	got, err := repo.GetByID(product.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 15, got.Stock)
+	def, ok := got.DefaultSKU()
+	require.True(t, ok)
+	assert.Equal(t, large.ID, def.ID)
+	assert.Equal(t, price, *def.Price)
+
+	require.NoError(t, db.Transaction(func(tx *gorm.DB) error {
+		return repo.DeleteSKUWithTx(tx, &medium, 0)
+	}))
+	total, skus = stock()
+	assert.Equal(t, 10, total)
+	assert.Equal(t, []int{10}, skus)
+
+	duplicate := &model.ProductSKU{ProductID: product.ID, Code: "TEE-RED-L", Stock: 1}
+	assert.Equal(t, ErrDuplicateKey, db.Transaction(func(tx *gorm.DB) error {
+		return repo.CreateSKUWithTx(tx, duplicate, 0)
+	}))
+}
+
+func TestProductRepository_GetSKUByCodeWithTx(t *testing.T) {
+	db := testutil.NewDB(t)
+	repo := NewProductRepository(db)
+
+	product := &model.Product{
+		Name:     "T恤",
+		Price:    money.MustParseAmount("10.00"),
+		Currency: "CNY",
+		SKUs:     []model.ProductSKU{{Code: "TEE-RED-M", Options: model.SKUOptions{"颜色": "红"}, Stock: 5, IsDefault: true}},
+	}
+	require.NoError(t, repo.Create(product))
+
+	require.NoError(t, db.Transaction(func(tx *gorm.DB) error {
+		sku, err := repo.GetSKUByCodeWithTx(tx, "TEE-RED-M")
+		require.NoError(t, err)
+		assert.Equal(t, product.ID, sku.ProductID)
+		assert.Equal(t, 5, sku.Stock)
+
+		_, err = repo.GetSKUByCodeWithTx(tx, "TEE-BLUE-M")
+		assert.Equal(t, ErrNotFound, err)
+		return nil
+	}))
+}
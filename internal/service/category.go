@@ -0,0 +1,219 @@
+package service
+
+import (
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/ylh990835774/blockchain-shop-demo/internal/model"
+	"github.com/ylh990835774/blockchain-shop-demo/internal/repository/mysql"
+	"github.com/ylh990835774/blockchain-shop-demo/pkg/errors"
+	"gorm.io/gorm"
+)
+
+var categorySlugPattern = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+
+type CategoryService struct {
+	repo *mysql.CategoryRepository
+	db   *gorm.DB
+}
+
+// 确保CategoryService实现了ICategoryService接口
+var _ ICategoryService = (*CategoryService)(nil)
+
+func NewCategoryService(repo *mysql.CategoryRepository, db *gorm.DB) ICategoryService {
+	return &CategoryService{
+		repo: repo,
+		db:   db,
+	}
+}
+
+func (s *CategoryService) Create(category *model.Category) error {
+	if category == nil {
+		return errors.ErrInvalidInput
+	}
+	category.ID = 0
+	category.Children = nil
+	if err := normalizeCategory(category); err != nil {
+		return err
+	}
+
+	if category.ParentID != nil {
+		if _, err := s.repo.GetByID(*category.ParentID); err != nil {
+			if err == mysql.ErrNotFound {
+				return errors.ErrInvalidInput
+			}
+			return err
+		}
+	}
+
+	if err := s.repo.Create(category); err != nil {
+		if err == mysql.ErrDuplicateKey {
+			return errors.ErrDuplicateEntry
+		}
+		return err
+	}
+	return nil
+}
+
+// Update 替换分类的名称、标识、父分类和显示顺序，分类不能移动到自身或其子孙分类下
+func (s *CategoryService) Update(id int64, category *model.Category) (*model.Category, error) {
+	if id <= 0 || category == nil {
+		return nil, errors.ErrInvalidInput
+	}
+	if err := normalizeCategory(category); err != nil {
+		return nil, err
+	}
+
+	categories, err := s.repo.List()
+	if err != nil {
+		return nil, err
+	}
+	_, byID := buildCategoryTree(categories)
+	current, ok := byID[id]
+	if !ok {
+		return nil, errors.ErrNotFound
+	}
+	if category.ParentID != nil {
+		if _, ok := byID[*category.ParentID]; !ok {
+			return nil, errors.ErrInvalidInput
+		}
+		for _, descendant := range categoryDescendants(current) {
+			if descendant == *category.ParentID {
+				return nil, errors.ErrInvalidInput
+			}
+		}
+	}
+
+	if err := s.repo.Update(id, map[string]interface{}{
+		"parent_id": category.ParentID,
+		"name":      category.Name,
+		"slug":      category.Slug,
+		"position":  category.Position,
+	}); err != nil {
+		switch err {
+		case mysql.ErrNotFound:
+			return nil, errors.ErrNotFound
+		case mysql.ErrDuplicateKey:
+			return nil, errors.ErrDuplicateEntry
+		}
+		return nil, err
+	}
+	return s.GetByID(id)
+}
+
+// Delete 删除没有子分类的分类，分类下的商品解除关联
+func (s *CategoryService) Delete(id int64) error {
+	if id <= 0 {
+		return errors.ErrInvalidInput
+	}
+
+	tx := s.db.Begin()
+	if tx.Error != nil {
+		return tx.Error
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	count, err := s.repo.CountChildrenWithTx(tx, id)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	if count > 0 {
+		tx.Rollback()
+		return errors.ErrCategoryNotEmpty
+	}
+	if err := s.repo.DeleteWithTx(tx, id); err != nil {
+		tx.Rollback()
+		if err == mysql.ErrNotFound {
+			return errors.ErrNotFound
+		}
+		return err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+	return nil
+}
+
+// GetByID 获取分类及其所有子孙分类
+func (s *CategoryService) GetByID(id int64) (*model.Category, error) {
+	if id <= 0 {
+		return nil, errors.ErrInvalidInput
+	}
+
+	categories, err := s.repo.List()
+	if err != nil {
+		return nil, err
+	}
+	_, byID := buildCategoryTree(categories)
+	category, ok := byID[id]
+	if !ok {
+		return nil, errors.ErrNotFound
+	}
+	return category, nil
+}
+
+func (s *CategoryService) Tree() ([]*model.Category, error) {
+	categories, err := s.repo.List()
+	if err != nil {
+		return nil, err
+	}
+	roots, _ := buildCategoryTree(categories)
+	return roots, nil
+}
+
+// normalizeCategory 规范化分类名称和标识并校验
+func normalizeCategory(category *model.Category) error {
+	category.Name = strings.TrimSpace(category.Name)
+	category.Slug = strings.ToLower(strings.TrimSpace(category.Slug))
+	if category.Name == "" || utf8.RuneCountInString(category.Name) > 64 {
+		return errors.ErrInvalidInput
+	}
+	if len(category.Slug) > 64 || !categorySlugPattern.MatchString(category.Slug) {
+		return errors.ErrInvalidInput
+	}
+	if category.ParentID != nil && *category.ParentID <= 0 {
+		return errors.ErrInvalidInput
+	}
+	if category.Position < 0 {
+		return errors.ErrInvalidInput
+	}
+	return nil
+}
+
+// buildCategoryTree 将分类组装为树，返回顶级分类和按ID索引的所有分类。父分类不存在的分类作为顶级分类
+func buildCategoryTree(categories []*model.Category) ([]*model.Category, map[int64]*model.Category) {
+	byID := make(map[int64]*model.Category, len(categories))
+	for _, category := range categories {
+		category.Children = nil
+		byID[category.ID] = category
+	}
+
+	roots := make([]*model.Category, 0)
+	for _, category := range categories {
+		if category.ParentID != nil {
+			if parent, ok := byID[*category.ParentID]; ok {
+				parent.Children = append(parent.Children, category)
+				continue
+			}
+		}
+		roots = append(roots, category)
+	}
+	return roots, byID
+}
+
+// categoryDescendants 返回分类自身及其所有子孙分类的ID
+func categoryDescendants(category *model.Category) []int64 {
+	ids := []int64{category.ID}
+	for _, child := range category.Children {
+		ids = append(ids, categoryDescendants(child)...)
+	}
+	return ids
+}
@@ -0,0 +1,69 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/ylh990835774/blockchain-shop-demo/internal/model"
+	"github.com/ylh990835774/blockchain-shop-demo/pkg/errors"
+)
+
+func TestBuildCategoryTree(t *testing.T) {
+	id := func(v int64) *int64 { return &v }
+
+	// 列表已按显示顺序排序，子分类保持相同顺序
+	categories := []*model.Category{
+		{ID: 1, Slug: "drinks"},
+		{ID: 2, Slug: "tea", ParentID: id(1)},
+		{ID: 3, Slug: "green-tea", ParentID: id(2)},
+		{ID: 4, Slug: "coffee", ParentID: id(1)},
+		{ID: 5, Slug: "food"},
+		{ID: 6, Slug: "orphan", ParentID: id(99)},
+	}
+
+	roots, byID := buildCategoryTree(categories)
+	require.Len(t, roots, 3)
+	assert.Equal(t, []int64{1, 5, 6}, []int64{roots[0].ID, roots[1].ID, roots[2].ID})
+	require.Len(t, roots[0].Children, 2)
+	assert.Equal(t, int64(2), roots[0].Children[0].ID)
+	assert.Equal(t, int64(4), roots[0].Children[1].ID)
+
+	assert.Equal(t, []int64{1, 2, 3, 4}, categoryDescendants(byID[1]))
+	assert.Equal(t, []int64{2, 3}, categoryDescendants(byID[2]))
+	assert.Equal(t, []int64{5}, categoryDescendants(byID[5]))
+
+	// 重新组装时不会重复追加子分类
+	_, byID = buildCategoryTree(categories)
+	assert.Len(t, byID[1].Children, 2)
+}
+
+func TestNormalizeCategory(t *testing.T) {
+	zero := int64(0)
+
+	tests := []struct {
+		name     string
+		category model.Category
+		wantErr  bool
+	}{
+		{name: "valid", category: model.Category{Name: " 绿茶 ", Slug: " Green-Tea "}},
+		{name: "missing name", category: model.Category{Slug: "tea"}, wantErr: true},
+		{name: "slug with spaces", category: model.Category{Name: "茶", Slug: "green tea"}, wantErr: true},
+		{name: "slug with trailing dash", category: model.Category{Name: "茶", Slug: "tea-"}, wantErr: true},
+		{name: "invalid parent", category: model.Category{Name: "茶", Slug: "tea", ParentID: &zero}, wantErr: true},
+		{name: "negative position", category: model.Category{Name: "茶", Slug: "tea", Position: -1}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := normalizeCategory(&tt.category)
+			if tt.wantErr {
+				assert.Equal(t, errors.ErrInvalidInput, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, "绿茶", tt.category.Name)
+			assert.Equal(t, "green-tea", tt.category.Slug)
+		})
+	}
+}
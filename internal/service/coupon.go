@@ -0,0 +1,259 @@
+package service
+
+import (
+	"strings"
+
+	"github.com/ylh990835774/blockchain-shop-demo/internal/model"
+	"github.com/ylh990835774/blockchain-shop-demo/internal/repository/mysql"
+	"github.com/ylh990835774/blockchain-shop-demo/pkg/errors"
+	"github.com/ylh990835774/blockchain-shop-demo/pkg/money"
+	"gorm.io/gorm"
+)
+
+type CouponService struct {
+	repo        *mysql.CouponRepository
+	productRepo *mysql.ProductRepository
+	db          *gorm.DB
+}
+
+// 确保CouponService实现了ICouponService接口
+var _ ICouponService = (*CouponService)(nil)
+
+func NewCouponService(repo *mysql.CouponRepository, productRepo *mysql.ProductRepository, db *gorm.DB) ICouponService {
+	return &CouponService{
+		repo:        repo,
+		productRepo: productRepo,
+		db:          db,
+	}
+}
+
+func (s *CouponService) Create(coupon *model.Coupon) error {
+	if coupon == nil {
+		return errors.ErrInvalidInput
+	}
+	coupon.ID = 0
+	coupon.UsedCount = 0
+	if err := normalizeCoupon(coupon); err != nil {
+		return err
+	}
+
+	tx := s.db.Begin()
+	if tx.Error != nil {
+		return tx.Error
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	if err := s.checkProducts(tx, coupon.ProductIDs); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := s.repo.CreateWithTx(tx, coupon); err != nil {
+		tx.Rollback()
+		if err == mysql.ErrDuplicateKey {
+			return errors.ErrDuplicateEntry
+		}
+		return err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+	return nil
+}
+
+// Update 替换优惠券的配置，已使用次数保持不变
+func (s *CouponService) Update(id int64, coupon *model.Coupon) (*model.Coupon, error) {
+	if id <= 0 || coupon == nil {
+		return nil, errors.ErrInvalidInput
+	}
+	if err := normalizeCoupon(coupon); err != nil {
+		return nil, err
+	}
+
+	tx := s.db.Begin()
+	if tx.Error != nil {
+		return nil, tx.Error
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	if err := s.checkProducts(tx, coupon.ProductIDs); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	if err := s.repo.UpdateWithTx(tx, id, map[string]interface{}{
+		"code":           coupon.Code,
+		"type":           coupon.Type,
+		"percent_off":    coupon.PercentOff,
+		"amount_off":     coupon.AmountOff,
+		"currency":       coupon.Currency,
+		"min_amount":     coupon.MinAmount,
+		"usage_limit":    coupon.UsageLimit,
+		"per_user_limit": coupon.PerUserLimit,
+		"starts_at":      coupon.StartsAt,
+		"ends_at":        coupon.EndsAt,
+		"disabled":       coupon.Disabled,
+	}); err != nil {
+		tx.Rollback()
+		switch err {
+		case mysql.ErrNotFound:
+			return nil, errors.ErrNotFound
+		case mysql.ErrDuplicateKey:
+			return nil, errors.ErrDuplicateEntry
+		}
+		return nil, err
+	}
+	if err := s.repo.SetProductsWithTx(tx, id, coupon.ProductIDs); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	return s.GetByID(id)
+}
+
+func (s *CouponService) Delete(id int64) error {
+	if id <= 0 {
+		return errors.ErrInvalidInput
+	}
+
+	tx := s.db.Begin()
+	if tx.Error != nil {
+		return tx.Error
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	if err := s.repo.DeleteWithTx(tx, id); err != nil {
+		tx.Rollback()
+		if err == mysql.ErrNotFound {
+			return errors.ErrNotFound
+		}
+		return err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+	return nil
+}
+
+func (s *CouponService) GetByID(id int64) (*model.Coupon, error) {
+	if id <= 0 {
+		return nil, errors.ErrInvalidInput
+	}
+
+	coupon, err := s.repo.GetByID(id)
+	if err != nil {
+		if err == mysql.ErrNotFound {
+			return nil, errors.ErrNotFound
+		}
+		return nil, err
+	}
+	return coupon, nil
+}
+
+func (s *CouponService) List(page, pageSize int) ([]*model.Coupon, int64, error) {
+	if page <= 0 {
+		page = 1
+	}
+	if pageSize <= 0 {
+		pageSize = 10
+	}
+
+	offset := (page - 1) * pageSize
+	return s.repo.List(offset, pageSize)
+}
+
+// checkProducts 检查限定的商品是否存在
+func (s *CouponService) checkProducts(tx *gorm.DB, productIDs []int64) error {
+	for _, id := range productIDs {
+		if _, err := s.productRepo.GetByIDWithTx(tx, id); err != nil {
+			if err == mysql.ErrNotFound {
+				return errors.ErrInvalidInput
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// normalizeCoupon 规范化券码和币种并校验优惠券配置
+func normalizeCoupon(coupon *model.Coupon) error {
+	coupon.Code = normalizeCouponCode(coupon.Code)
+	coupon.Currency = strings.ToUpper(coupon.Currency)
+	if coupon.Code == "" || len(coupon.Code) > 32 {
+		return errors.ErrInvalidInput
+	}
+
+	switch coupon.Type {
+	case model.CouponTypePercent:
+		if coupon.PercentOff <= 0 || coupon.PercentOff > money.RateOne || coupon.AmountOff != 0 {
+			return errors.ErrInvalidInput
+		}
+	case model.CouponTypeFixed:
+		if coupon.AmountOff <= 0 || coupon.PercentOff != 0 {
+			return errors.ErrInvalidInput
+		}
+	default:
+		return errors.ErrInvalidInput
+	}
+
+	// 减免金额和最低消费与订单金额比较，必须指明币种
+	if (coupon.AmountOff > 0 || coupon.MinAmount > 0) && len(coupon.Currency) != 3 {
+		return errors.ErrInvalidInput
+	}
+	if coupon.MinAmount < 0 || coupon.UsageLimit < 0 || coupon.PerUserLimit < 0 {
+		return errors.ErrInvalidInput
+	}
+	if coupon.StartsAt != nil && coupon.EndsAt != nil && !coupon.EndsAt.After(*coupon.StartsAt) {
+		return errors.ErrInvalidInput
+	}
+	for _, id := range coupon.ProductIDs {
+		if id <= 0 {
+			return errors.ErrInvalidInput
+		}
+	}
+	return nil
+}
+
+func normalizeCouponCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// couponDiscount 计算优惠券对订单商品金额的优惠，优惠不超过商品金额
+func couponDiscount(coupon *model.Coupon, currency string, amount money.Amount) (money.Amount, error) {
+	if coupon.Currency != "" && coupon.Currency != currency {
+		return 0, errors.ErrCouponNotApplicable
+	}
+	if amount < coupon.MinAmount {
+		return 0, errors.ErrCouponNotApplicable
+	}
+
+	var discount money.Amount
+	switch coupon.Type {
+	case model.CouponTypePercent:
+		discount = coupon.PercentOff.Convert(amount)
+	case model.CouponTypeFixed:
+		discount = coupon.AmountOff
+	}
+	if discount > amount {
+		discount = amount
+	}
+	return discount, nil
+}
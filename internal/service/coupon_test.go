@@ -0,0 +1,151 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/ylh990835774/blockchain-shop-demo/internal/model"
+	"github.com/ylh990835774/blockchain-shop-demo/pkg/errors"
+	"github.com/ylh990835774/blockchain-shop-demo/pkg/money"
+)
+
+func TestCouponDiscount(t *testing.T) {
+	tests := []struct {
+		name        string
+		coupon      *model.Coupon
+		currency    string
+		amount      string
+		expected    string
+		expectedErr error
+	}{
+		{
+			name:     "percent",
+			coupon:   &model.Coupon{Type: model.CouponTypePercent, PercentOff: money.MustParseRate("0.15")},
+			currency: "USD",
+			amount:   "33.33",
+			expected: "5.00",
+		},
+		{
+			name:     "fixed",
+			coupon:   &model.Coupon{Type: model.CouponTypeFixed, AmountOff: money.MustParseAmount("20"), Currency: "CNY"},
+			currency: "CNY",
+			amount:   "100",
+			expected: "20",
+		},
+		{
+			name:     "fixed capped at amount",
+			coupon:   &model.Coupon{Type: model.CouponTypeFixed, AmountOff: money.MustParseAmount("50"), Currency: "CNY"},
+			currency: "CNY",
+			amount:   "30",
+			expected: "30",
+		},
+		{
+			name: "min basket reached",
+			coupon: &model.Coupon{Type: model.CouponTypeFixed, AmountOff: money.MustParseAmount("10"), Currency: "CNY",
+				MinAmount: money.MustParseAmount("99")},
+			currency: "CNY",
+			amount:   "99",
+			expected: "10",
+		},
+		{
+			name: "below min basket",
+			coupon: &model.Coupon{Type: model.CouponTypeFixed, AmountOff: money.MustParseAmount("10"), Currency: "CNY",
+				MinAmount: money.MustParseAmount("99")},
+			currency:    "CNY",
+			amount:      "98.99",
+			expectedErr: errors.ErrCouponNotApplicable,
+		},
+		{
+			name:        "currency mismatch",
+			coupon:      &model.Coupon{Type: model.CouponTypeFixed, AmountOff: money.MustParseAmount("10"), Currency: "CNY"},
+			currency:    "USD",
+			amount:      "100",
+			expectedErr: errors.ErrCouponNotApplicable,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			discount, err := couponDiscount(tt.coupon, tt.currency, money.MustParseAmount(tt.amount))
+			if tt.expectedErr != nil {
+				assert.Equal(t, tt.expectedErr, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, money.MustParseAmount(tt.expected), discount)
+		})
+	}
+}
+
+func TestNormalizeCoupon(t *testing.T) {
+	now := time.Now()
+	later := now.Add(time.Hour)
+
+	tests := []struct {
+		name    string
+		coupon  model.Coupon
+		wantErr bool
+	}{
+		{
+			name:   "percent",
+			coupon: model.Coupon{Code: " spring20 ", Type: model.CouponTypePercent, PercentOff: money.MustParseRate("0.2")},
+		},
+		{
+			name:   "fixed with min basket",
+			coupon: model.Coupon{Code: "CNY20", Type: model.CouponTypeFixed, AmountOff: money.MustParseAmount("20"), Currency: "cny", MinAmount: money.MustParseAmount("100")},
+		},
+		{
+			name:    "missing code",
+			coupon:  model.Coupon{Type: model.CouponTypePercent, PercentOff: money.MustParseRate("0.2")},
+			wantErr: true,
+		},
+		{
+			name:    "percent over 100%",
+			coupon:  model.Coupon{Code: "X", Type: model.CouponTypePercent, PercentOff: money.MustParseRate("1.5")},
+			wantErr: true,
+		},
+		{
+			name:    "fixed without currency",
+			coupon:  model.Coupon{Code: "X", Type: model.CouponTypeFixed, AmountOff: money.MustParseAmount("20")},
+			wantErr: true,
+		},
+		{
+			name:    "min basket without currency",
+			coupon:  model.Coupon{Code: "X", Type: model.CouponTypePercent, PercentOff: money.MustParseRate("0.1"), MinAmount: money.MustParseAmount("50")},
+			wantErr: true,
+		},
+		{
+			name:    "window ends before start",
+			coupon:  model.Coupon{Code: "X", Type: model.CouponTypePercent, PercentOff: money.MustParseRate("0.1"), StartsAt: &later, EndsAt: &now},
+			wantErr: true,
+		},
+		{
+			name:    "unknown type",
+			coupon:  model.Coupon{Code: "X", Type: "bogo"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := normalizeCoupon(&tt.coupon)
+			if tt.wantErr {
+				assert.Equal(t, errors.ErrInvalidInput, err)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestCoupon_Valid(t *testing.T) {
+	now := time.Now()
+	past, future := now.Add(-time.Hour), now.Add(time.Hour)
+
+	assert.True(t, (&model.Coupon{}).Valid(now))
+	assert.True(t, (&model.Coupon{StartsAt: &past, EndsAt: &future}).Valid(now))
+	assert.False(t, (&model.Coupon{StartsAt: &future}).Valid(now))
+	assert.False(t, (&model.Coupon{EndsAt: &past}).Valid(now))
+	assert.False(t, (&model.Coupon{Disabled: true}).Valid(now))
+}
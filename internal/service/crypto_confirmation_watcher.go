@@ -0,0 +1,46 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/ylh990835774/blockchain-shop-demo/pkg/logger"
+)
+
+// CryptoConfirmationWatcher 定期检查链上转账的确认数，达到要求后完成订单支付
+type CryptoConfirmationWatcher struct {
+	cryptoPaymentService ICryptoPaymentService
+	interval             time.Duration
+}
+
+func NewCryptoConfirmationWatcher(cryptoPaymentService ICryptoPaymentService, interval time.Duration) *CryptoConfirmationWatcher {
+	if interval <= 0 {
+		interval = defaultSweepInterval
+	}
+	return &CryptoConfirmationWatcher{
+		cryptoPaymentService: cryptoPaymentService,
+		interval:             interval,
+	}
+}
+
+// Run 按间隔检查待确认的转账，直到ctx被取消
+func (w *CryptoConfirmationWatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			n, err := w.cryptoPaymentService.ConfirmPending()
+			if err != nil {
+				logger.Error("检查链上转账确认数失败", logger.Err(err))
+				continue
+			}
+			if n > 0 {
+				logger.Info("链上转账已确认", logger.Int64("count", int64(n)))
+			}
+		}
+	}
+}
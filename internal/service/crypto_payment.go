@@ -0,0 +1,374 @@
+package service
+
+import (
+	"crypto/ed25519"
+	"sync"
+	"time"
+
+	"github.com/ylh990835774/blockchain-shop-demo/internal/blockchain"
+	"github.com/ylh990835774/blockchain-shop-demo/internal/model"
+	"github.com/ylh990835774/blockchain-shop-demo/internal/repository/mysql"
+	"github.com/ylh990835774/blockchain-shop-demo/pkg/cursor"
+	"github.com/ylh990835774/blockchain-shop-demo/pkg/errors"
+	"github.com/ylh990835774/blockchain-shop-demo/pkg/logger"
+	"github.com/ylh990835774/blockchain-shop-demo/pkg/money"
+)
+
+// blockCursorSort 区块游标的排序方式，区块按高度从高到低分页
+const blockCursorSort = "height"
+
+// confirmBatchSize 每轮最多检查的待确认转账数量
+const confirmBatchSize = 100
+
+type CryptoPaymentService struct {
+	blockchainRepo  *mysql.BlockchainRepository
+	orderService    IOrderService
+	chain           blockchain.Service
+	merchantKey     ed25519.PrivateKey
+	merchantAddress string
+	confirmations   int
+	faucetLimit     money.Amount
+
+	// 串行化确认流程，避免同一笔转账被重复确认
+	confirmMu sync.Mutex
+}
+
+// 确保CryptoPaymentService实现了ICryptoPaymentService接口
+var _ ICryptoPaymentService = (*CryptoPaymentService)(nil)
+
+// NewCryptoPaymentService 创建链上转账支付服务，faucetLimit为空时禁用测试币发放
+func NewCryptoPaymentService(blockchainRepo *mysql.BlockchainRepository, orderService IOrderService, chain blockchain.Service,
+	merchantKey ed25519.PrivateKey, confirmations int, faucetLimit string) (ICryptoPaymentService, error) {
+	if confirmations <= 0 {
+		confirmations = 1
+	}
+
+	var limit money.Amount
+	if faucetLimit != "" {
+		var err error
+		if limit, err = blockchain.ParseValue(faucetLimit); err != nil {
+			return nil, err
+		}
+	}
+
+	return &CryptoPaymentService{
+		blockchainRepo:  blockchainRepo,
+		orderService:    orderService,
+		chain:           chain,
+		merchantKey:     merchantKey,
+		merchantAddress: blockchain.AddressFromPublicKey(merchantKey.Public().(ed25519.PublicKey)),
+		confirmations:   confirmations,
+		faucetLimit:     limit,
+	}, nil
+}
+
+func (s *CryptoPaymentService) Pay(userID, orderID int64, transfer *blockchain.Transfer) (*model.Transaction, error) {
+	order, err := s.orderService.GetByID(orderID)
+	if err != nil {
+		return nil, err
+	}
+	if order.UserID != userID {
+		return nil, errors.ErrUnauthorized
+	}
+	if order.Status != model.OrderStatusPending {
+		return nil, errors.ErrInvalidOrderStatus
+	}
+
+	// 链上账户只有一种计价币种，其他币种的订单需通过支付渠道支付
+	if order.Currency != blockchain.LedgerCurrency {
+		return nil, errors.ErrUnsupportedCurrency
+	}
+	if transfer.To != s.merchantAddress || transfer.OrderID != orderID {
+		return nil, errors.ErrInvalidInput
+	}
+	value, err := blockchain.ParseValue(transfer.Value)
+	if err != nil {
+		return nil, errors.ErrInvalidInput
+	}
+	if value != order.TotalPrice {
+		return nil, errors.ErrPaymentAmountMismatch
+	}
+
+	txHash, err := s.chain.SubmitTransfer(transfer)
+	if err != nil {
+		return nil, chainError(err)
+	}
+
+	tx := &model.Transaction{
+		TxHash:    txHash,
+		From:      transfer.From,
+		To:        transfer.To,
+		Value:     transfer.Value,
+		Currency:  blockchain.LedgerCurrency,
+		Status:    false,
+		Timestamp: time.Now(),
+		OrderID:   orderID,
+	}
+	if err := s.blockchainRepo.SaveTransaction(tx); err != nil {
+		// 转账已上链但没有记录，确认流程无法处理，将转账退回用户钱包
+		s.confirmMu.Lock()
+		refundHash, refundErr := s.refund(tx)
+		s.confirmMu.Unlock()
+		if refundErr != nil {
+			logger.Error("退回未记录的链上转账失败",
+				logger.Int64("order_id", orderID),
+				logger.String("tx_hash", txHash),
+				logger.Err(refundErr),
+			)
+		} else {
+			logger.Info("链上转账记录保存失败，转账已退回",
+				logger.Int64("order_id", orderID),
+				logger.String("tx_hash", txHash),
+				logger.String("refund_tx_hash", refundHash),
+			)
+		}
+		return nil, err
+	}
+
+	// 所需确认数为1时转账上链即完成支付
+	if err := s.confirm(tx); err != nil {
+		logger.Error("确认链上转账失败", logger.String("tx_hash", txHash), logger.Err(err))
+	}
+
+	return tx, nil
+}
+
+func (s *CryptoPaymentService) ConfirmPending() (int, error) {
+	txs, err := s.blockchainRepo.ListUnconfirmed(confirmBatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	confirmed := 0
+	for _, tx := range txs {
+		if err := s.confirm(tx); err != nil {
+			logger.Error("确认链上转账失败", logger.String("tx_hash", tx.TxHash), logger.Err(err))
+			continue
+		}
+		if tx.Status {
+			confirmed++
+		}
+	}
+	return confirmed, nil
+}
+
+func (s *CryptoPaymentService) Faucet(address, value string) (string, error) {
+	if !s.faucetLimit.IsPositive() {
+		return "", errors.ErrForbidden
+	}
+	if !blockchain.ValidAddress(address) {
+		return "", errors.ErrInvalidInput
+	}
+	amount, err := blockchain.ParseValue(value)
+	if err != nil || !amount.IsPositive() || amount > s.faucetLimit {
+		return "", errors.ErrInvalidInput
+	}
+
+	txHash, err := s.chain.Mint(address, value)
+	if err != nil {
+		return "", chainError(err)
+	}
+	return txHash, nil
+}
+
+func (s *CryptoPaymentService) GetAccount(address string) (*blockchain.Account, error) {
+	if !blockchain.ValidAddress(address) {
+		return nil, errors.ErrInvalidInput
+	}
+	return s.chain.GetAccount(address), nil
+}
+
+func (s *CryptoPaymentService) Refund(orderID int64, amount money.Amount) (string, error) {
+	if orderID <= 0 || !amount.IsPositive() {
+		return "", errors.ErrInvalidInput
+	}
+
+	// 与确认流程共用商户nonce，并且不能与确认流程的自动退回同时处理同一笔转账，需要串行化
+	s.confirmMu.Lock()
+	defer s.confirmMu.Unlock()
+
+	txs, err := s.blockchainRepo.ListByOrderID(orderID)
+	if err != nil {
+		return "", err
+	}
+	for _, tx := range txs {
+		// 已全额退回的转账不能再次退款
+		if !tx.Status || tx.Refunded || tx.To != s.merchantAddress {
+			continue
+		}
+		value, err := blockchain.ParseValue(tx.Value)
+		if err != nil {
+			return "", err
+		}
+		// 累计退款不能超过转账金额
+		if tx.RefundedValue+amount > value {
+			return "", errors.ErrInvalidInput
+		}
+		return s.refundPartial(tx, value, amount)
+	}
+	return "", errors.ErrNotFound
+}
+
+// refundPartial 先记入累计退回金额再提交退款转账，提交失败时撤销记入的金额。
+// 累计退回金额达到转账金额时转账标记为已退回；转账已被并发修改时返回ErrPreconditionFailed。调用方需持有confirmMu
+func (s *CryptoPaymentService) refundPartial(tx *model.Transaction, value, amount money.Amount) (string, error) {
+	refunded := tx.RefundedValue + amount
+	claimed, err := s.blockchainRepo.TransitionRefund(tx.TxHash, tx.RefundedValue, refunded, refunded == value)
+	if err != nil {
+		return "", err
+	}
+	if !claimed {
+		return "", errors.ErrPreconditionFailed
+	}
+
+	refund := &blockchain.Transfer{
+		From:    s.merchantAddress,
+		To:      tx.From,
+		Value:   blockchain.FormatValue(amount),
+		Nonce:   s.chain.GetAccount(s.merchantAddress).Nonce,
+		OrderID: tx.OrderID,
+	}
+	refund.Sign(s.merchantKey)
+
+	refundHash, err := s.chain.SubmitTransfer(refund)
+	if err != nil {
+		if _, revertErr := s.blockchainRepo.TransitionRefund(tx.TxHash, refunded, tx.RefundedValue, false); revertErr != nil {
+			logger.Error("撤销链上转账退回金额失败", logger.String("tx_hash", tx.TxHash), logger.Err(revertErr))
+		}
+		return "", chainError(err)
+	}
+	tx.RefundedValue, tx.Refunded = refunded, refunded == value
+	return refundHash, nil
+}
+
+// ListBlocks 按高度从高到低列出after游标之后的一页区块，返回下一页的游标，已到创世区块时游标为空
+func (s *CryptoPaymentService) ListBlocks(after string, limit int) ([]*blockchain.Block, string, error) {
+	limit = pageLimit(limit)
+
+	before := -1
+	if after != "" {
+		c, err := cursor.Decode(after, blockCursorSort)
+		if err != nil {
+			return nil, "", errors.ErrInvalidInput
+		}
+		before = int(c.ID)
+	}
+
+	blocks := s.chain.ListBlocks(before, limit)
+	if len(blocks) < limit || blocks[len(blocks)-1].Index == 0 {
+		return blocks, "", nil
+	}
+
+	next := &cursor.Cursor{Sort: blockCursorSort, ID: int64(blocks[len(blocks)-1].Index)}
+	return blocks, next.Encode(), nil
+}
+
+func (s *CryptoPaymentService) MerchantAddress() string {
+	return s.merchantAddress
+}
+
+// confirm 确认数达到要求后将订单标记为已支付，订单无法支付时将转账退回
+func (s *CryptoPaymentService) confirm(tx *model.Transaction) error {
+	s.confirmMu.Lock()
+	defer s.confirmMu.Unlock()
+
+	current, err := s.blockchainRepo.GetTransaction(tx.TxHash)
+	if err != nil {
+		return err
+	}
+	if current.Status {
+		tx.Status = true
+		tx.Refunded = current.Refunded
+		return nil
+	}
+
+	n, err := s.chain.Confirmations(tx.TxHash)
+	if err != nil {
+		return err
+	}
+	if n < s.confirmations {
+		return nil
+	}
+
+	if err := s.orderService.MarkPaid(tx.OrderID); err != nil {
+		switch err {
+		case errors.ErrReservationExpired, errors.ErrInvalidOrderStatus:
+			// 订单已超时取消或已通过其他方式支付，将转账退回用户钱包
+			return s.refundUnpaid(tx)
+		default:
+			return err
+		}
+	}
+
+	if err := s.blockchainRepo.UpdateStatus(tx.TxHash, true); err != nil {
+		return err
+	}
+	tx.Status = true
+	return nil
+}
+
+// refundUnpaid 将无法完成支付的转账整笔退回。先标记转账已退回再提交退款转账，提交失败时撤销标记，
+// 保证同一笔转账最多退回一次。调用方需持有confirmMu
+func (s *CryptoPaymentService) refundUnpaid(tx *model.Transaction) error {
+	value, err := blockchain.ParseValue(tx.Value)
+	if err != nil {
+		return err
+	}
+	claimed, err := s.blockchainRepo.MarkRefunded(tx.TxHash, value)
+	if err != nil {
+		return err
+	}
+	if !claimed {
+		return nil
+	}
+
+	refundHash, err := s.refund(tx)
+	if err != nil {
+		if revertErr := s.blockchainRepo.RevertRefunded(tx.TxHash); revertErr != nil {
+			logger.Error("撤销链上转账退回标记失败", logger.String("tx_hash", tx.TxHash), logger.Err(revertErr))
+		}
+		return err
+	}
+
+	tx.Status = true
+	tx.Refunded = true
+	tx.RefundedValue = value
+	tx.RefundTxHash = refundHash
+	if err := s.blockchainRepo.SetRefundTxHash(tx.TxHash, refundHash); err != nil {
+		logger.Error("保存退款转账哈希失败", logger.String("tx_hash", tx.TxHash), logger.Err(err))
+	}
+
+	logger.Info("订单无法完成支付，链上转账已退回",
+		logger.Int64("order_id", tx.OrderID),
+		logger.String("tx_hash", tx.TxHash),
+		logger.String("refund_tx_hash", refundHash),
+	)
+	return nil
+}
+
+// refund 从商户地址将转账整笔退回付款地址，调用方需持有confirmMu
+func (s *CryptoPaymentService) refund(tx *model.Transaction) (string, error) {
+	refund := &blockchain.Transfer{
+		From:    s.merchantAddress,
+		To:      tx.From,
+		Value:   tx.Value,
+		Nonce:   s.chain.GetAccount(s.merchantAddress).Nonce,
+		OrderID: tx.OrderID,
+	}
+	refund.Sign(s.merchantKey)
+
+	return s.chain.SubmitTransfer(refund)
+}
+
+// chainError 将区块链错误转换为业务错误
+func chainError(err error) error {
+	switch err {
+	case blockchain.ErrInvalidSignature:
+		return errors.ErrInvalidSignature
+	case blockchain.ErrInsufficientBalance:
+		return errors.ErrInsufficientBalance
+	case blockchain.ErrInvalidNonce, blockchain.ErrInvalidValue, blockchain.ErrInvalidAddress:
+		return errors.ErrInvalidInput
+	}
+	return err
+}
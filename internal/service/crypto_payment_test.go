@@ -0,0 +1,266 @@
+package service
+
+import (
+	"crypto/ed25519"
+	stderrors "errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/ylh990835774/blockchain-shop-demo/internal/blockchain"
+	"github.com/ylh990835774/blockchain-shop-demo/internal/model"
+	"github.com/ylh990835774/blockchain-shop-demo/pkg/errors"
+	"github.com/ylh990835774/blockchain-shop-demo/pkg/money"
+)
+
+// cryptoPaymentFixture 一个待支付的CNY订单及持有100.00余额的买家钱包
+type cryptoPaymentFixture struct {
+	shop     *testShop
+	chain    *fakeChain
+	service  *CryptoPaymentService
+	order    *model.Order
+	buyerKey ed25519.PrivateKey
+	buyer    string
+	merchant string
+}
+
+func newCryptoPaymentFixture(t *testing.T, confirmations int) *cryptoPaymentFixture {
+	t.Helper()
+	setupTestLogger(t)
+
+	shop := newTestShop(t, nil)
+	chain := newFakeChain()
+	merchantKey, _ := newTestWallet(t)
+	svc, err := NewCryptoPaymentService(shop.blockchainRepo, shop.orders, chain, merchantKey, confirmations, "")
+	require.NoError(t, err)
+
+	user := shop.createUser(t, "buyer")
+	product := shop.createProduct(t, "19.98", 5)
+	order, err := shop.orders.Create(&CreateOrderInput{UserID: user.ID, ProductID: product.ID, Quantity: 1})
+	require.NoError(t, err)
+
+	buyerKey, buyer := newTestWallet(t)
+	_, err = chain.Mint(buyer, "100.00")
+	require.NoError(t, err)
+
+	return &cryptoPaymentFixture{
+		shop:     shop,
+		chain:    chain,
+		service:  svc.(*CryptoPaymentService),
+		order:    order,
+		buyerKey: buyerKey,
+		buyer:    buyer,
+		merchant: svc.MerchantAddress(),
+	}
+}
+
+// transfer 买家向商户转账支付订单
+func (f *cryptoPaymentFixture) transfer(value string) *blockchain.Transfer {
+	transfer := &blockchain.Transfer{
+		From:    f.buyer,
+		To:      f.merchant,
+		Value:   value,
+		Nonce:   f.chain.GetAccount(f.buyer).Nonce,
+		OrderID: f.order.ID,
+	}
+	transfer.Sign(f.buyerKey)
+	return transfer
+}
+
+func (f *cryptoPaymentFixture) orderStatus(t *testing.T) model.OrderStatus {
+	t.Helper()
+
+	order, err := f.shop.orders.GetByID(f.order.ID)
+	require.NoError(t, err)
+	return order.Status
+}
+
+func (f *cryptoPaymentFixture) storedTransaction(t *testing.T, txHash string) *model.Transaction {
+	t.Helper()
+
+	tx, err := f.shop.blockchainRepo.GetTransaction(txHash)
+	require.NoError(t, err)
+	return tx
+}
+
+func TestCryptoPaymentService_Pay(t *testing.T) {
+	t.Run("confirmed immediately", func(t *testing.T) {
+		f := newCryptoPaymentFixture(t, 1)
+
+		tx, err := f.service.Pay(f.order.UserID, f.order.ID, f.transfer("19.98"))
+		require.NoError(t, err)
+		assert.True(t, tx.Status)
+		assert.False(t, tx.Refunded)
+		assert.Equal(t, model.OrderStatusPaid, f.orderStatus(t))
+		assert.Equal(t, "19.98", f.chain.balance(f.merchant))
+		assert.True(t, f.storedTransaction(t, tx.TxHash).Status)
+	})
+
+	t.Run("rejected before submitting", func(t *testing.T) {
+		f := newCryptoPaymentFixture(t, 1)
+
+		_, err := f.service.Pay(f.order.UserID, f.order.ID, f.transfer("9.99"))
+		assert.Equal(t, errors.ErrPaymentAmountMismatch, err)
+		_, err = f.service.Pay(f.order.UserID+1, f.order.ID, f.transfer("19.98"))
+		assert.Equal(t, errors.ErrUnauthorized, err)
+
+		assert.Empty(t, f.chain.transfers)
+		assert.Equal(t, model.OrderStatusPending, f.orderStatus(t))
+	})
+
+	t.Run("transfer refunded when it cannot be saved", func(t *testing.T) {
+		f := newCryptoPaymentFixture(t, 1)
+		require.NoError(t, f.shop.db.Migrator().DropTable(&model.Transaction{}))
+
+		_, err := f.service.Pay(f.order.UserID, f.order.ID, f.transfer("19.98"))
+		assert.Error(t, err)
+
+		require.Len(t, f.chain.transfers, 2)
+		assert.Equal(t, f.buyer, f.chain.transfers[1].To)
+		assert.Equal(t, "100.00", f.chain.balance(f.buyer))
+		assert.Equal(t, "0.00", f.chain.balance(f.merchant))
+		assert.Equal(t, model.OrderStatusPending, f.orderStatus(t))
+	})
+}
+
+func TestCryptoPaymentService_ConfirmPending(t *testing.T) {
+	t.Run("paid after enough confirmations", func(t *testing.T) {
+		f := newCryptoPaymentFixture(t, 2)
+
+		tx, err := f.service.Pay(f.order.UserID, f.order.ID, f.transfer("19.98"))
+		require.NoError(t, err)
+		assert.False(t, tx.Status)
+		assert.Equal(t, model.OrderStatusPending, f.orderStatus(t))
+
+		f.chain.confirmations = 2
+		confirmed, err := f.service.ConfirmPending()
+		require.NoError(t, err)
+		assert.Equal(t, 1, confirmed)
+		assert.Equal(t, model.OrderStatusPaid, f.orderStatus(t))
+		assert.Len(t, f.chain.transfers, 1)
+	})
+
+	t.Run("expired order refunded once", func(t *testing.T) {
+		f := newCryptoPaymentFixture(t, 2)
+
+		tx, err := f.service.Pay(f.order.UserID, f.order.ID, f.transfer("19.98"))
+		require.NoError(t, err)
+		f.shop.expireReservation(t, f.order.ID)
+		f.chain.confirmations = 2
+
+		_, err = f.service.ConfirmPending()
+		require.NoError(t, err)
+
+		stored := f.storedTransaction(t, tx.TxHash)
+		assert.True(t, stored.Status)
+		assert.True(t, stored.Refunded)
+		assert.Equal(t, money.MustParseAmount("19.98"), stored.RefundedValue)
+		assert.NotEmpty(t, stored.RefundTxHash)
+		assert.Equal(t, "100.00", f.chain.balance(f.buyer))
+
+		// 再次确认同一笔转账不会重复退款
+		require.NoError(t, f.service.confirm(tx))
+		_, err = f.service.ConfirmPending()
+		require.NoError(t, err)
+		assert.Len(t, f.chain.transfers, 2)
+		assert.Equal(t, "100.00", f.chain.balance(f.buyer))
+	})
+
+	t.Run("failed refund retried", func(t *testing.T) {
+		f := newCryptoPaymentFixture(t, 2)
+
+		tx, err := f.service.Pay(f.order.UserID, f.order.ID, f.transfer("19.98"))
+		require.NoError(t, err)
+		f.shop.expireReservation(t, f.order.ID)
+		f.chain.confirmations = 2
+
+		f.chain.submitErr = stderrors.New("node unavailable")
+		confirmed, err := f.service.ConfirmPending()
+		require.NoError(t, err)
+		assert.Equal(t, 0, confirmed)
+		stored := f.storedTransaction(t, tx.TxHash)
+		assert.False(t, stored.Status)
+		assert.False(t, stored.Refunded)
+
+		f.chain.submitErr = nil
+		_, err = f.service.ConfirmPending()
+		require.NoError(t, err)
+		assert.True(t, f.storedTransaction(t, tx.TxHash).Refunded)
+		assert.Len(t, f.chain.transfers, 2)
+		assert.Equal(t, "100.00", f.chain.balance(f.buyer))
+	})
+
+	t.Run("duplicate transfer refunded", func(t *testing.T) {
+		f := newCryptoPaymentFixture(t, 2)
+
+		first, err := f.service.Pay(f.order.UserID, f.order.ID, f.transfer("19.98"))
+		require.NoError(t, err)
+		second, err := f.service.Pay(f.order.UserID, f.order.ID, f.transfer("19.98"))
+		require.NoError(t, err)
+		f.chain.confirmations = 2
+
+		confirmed, err := f.service.ConfirmPending()
+		require.NoError(t, err)
+		assert.Equal(t, 2, confirmed)
+		assert.Equal(t, model.OrderStatusPaid, f.orderStatus(t))
+		assert.False(t, f.storedTransaction(t, first.TxHash).Refunded)
+		assert.True(t, f.storedTransaction(t, second.TxHash).Refunded)
+		assert.Equal(t, "80.02", f.chain.balance(f.buyer))
+		assert.Equal(t, "19.98", f.chain.balance(f.merchant))
+	})
+}
+
+func TestCryptoPaymentService_Refund(t *testing.T) {
+	t.Run("partial refunds of paid transfer", func(t *testing.T) {
+		f := newCryptoPaymentFixture(t, 1)
+		tx, err := f.service.Pay(f.order.UserID, f.order.ID, f.transfer("19.98"))
+		require.NoError(t, err)
+
+		_, err = f.service.Refund(f.order.ID, money.MustParseAmount("20.00"))
+		assert.Equal(t, errors.ErrInvalidInput, err)
+
+		refundHash, err := f.service.Refund(f.order.ID, money.MustParseAmount("5.00"))
+		require.NoError(t, err)
+		assert.NotEmpty(t, refundHash)
+		assert.Equal(t, "85.02", f.chain.balance(f.buyer))
+		stored := f.storedTransaction(t, tx.TxHash)
+		assert.Equal(t, money.MustParseAmount("5.00"), stored.RefundedValue)
+		assert.False(t, stored.Refunded)
+
+		// 累计退款不能超过转账金额
+		_, err = f.service.Refund(f.order.ID, money.MustParseAmount("15.00"))
+		assert.Equal(t, errors.ErrInvalidInput, err)
+
+		// 退款转账提交失败时撤销记入的金额
+		f.chain.submitErr = stderrors.New("node unavailable")
+		_, err = f.service.Refund(f.order.ID, money.MustParseAmount("14.98"))
+		assert.Error(t, err)
+		assert.Equal(t, money.MustParseAmount("5.00"), f.storedTransaction(t, tx.TxHash).RefundedValue)
+
+		// 累计退款达到转账金额后标记为已退回，不再用于退款
+		f.chain.submitErr = nil
+		_, err = f.service.Refund(f.order.ID, money.MustParseAmount("14.98"))
+		require.NoError(t, err)
+		stored = f.storedTransaction(t, tx.TxHash)
+		assert.Equal(t, money.MustParseAmount("19.98"), stored.RefundedValue)
+		assert.True(t, stored.Refunded)
+		assert.Equal(t, "100.00", f.chain.balance(f.buyer))
+
+		_, err = f.service.Refund(f.order.ID, money.MustParseAmount("0.01"))
+		assert.Equal(t, errors.ErrNotFound, err)
+	})
+
+	t.Run("refunded transfer skipped", func(t *testing.T) {
+		f := newCryptoPaymentFixture(t, 2)
+		_, err := f.service.Pay(f.order.UserID, f.order.ID, f.transfer("19.98"))
+		require.NoError(t, err)
+		f.shop.expireReservation(t, f.order.ID)
+		f.chain.confirmations = 2
+		_, err = f.service.ConfirmPending()
+		require.NoError(t, err)
+
+		_, err = f.service.Refund(f.order.ID, money.MustParseAmount("19.98"))
+		assert.Equal(t, errors.ErrNotFound, err)
+		assert.Equal(t, "100.00", f.chain.balance(f.buyer))
+	})
+}
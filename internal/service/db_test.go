@@ -0,0 +1,227 @@
+package service
+
+import (
+	"crypto/ed25519"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/ylh990835774/blockchain-shop-demo/internal/blockchain"
+	"github.com/ylh990835774/blockchain-shop-demo/internal/exchange"
+	"github.com/ylh990835774/blockchain-shop-demo/internal/model"
+	"github.com/ylh990835774/blockchain-shop-demo/internal/repository/mysql"
+	"github.com/ylh990835774/blockchain-shop-demo/internal/tax"
+	"github.com/ylh990835774/blockchain-shop-demo/internal/testutil"
+	applogger "github.com/ylh990835774/blockchain-shop-demo/pkg/logger"
+	"github.com/ylh990835774/blockchain-shop-demo/pkg/money"
+	"gorm.io/gorm"
+)
+
+// setupTestLogger 初始化服务记录日志使用的logger
+func setupTestLogger(t *testing.T) {
+	t.Helper()
+
+	require.NoError(t, applogger.Setup(&applogger.Config{
+		Level:    "info",
+		Format:   "console",
+		Console:  true,
+		Filename: "",
+	}))
+}
+
+// testShop 基于测试数据库组装的订单服务及其依赖
+type testShop struct {
+	db              *gorm.DB
+	orders          *OrderService
+	products        *ProductService
+	productRepo     *mysql.ProductRepository
+	userRepo        *mysql.UserRepository
+	couponRepo      *mysql.CouponRepository
+	reservationRepo *mysql.ReservationRepository
+	blockchainRepo  *mysql.BlockchainRepository
+}
+
+// newTestShop 创建订单服务，taxes为nil时不计税
+func newTestShop(t *testing.T, taxes *tax.Calculator) *testShop {
+	t.Helper()
+
+	if taxes == nil {
+		taxes = tax.NoTax("CN")
+	}
+	rates, err := exchange.NewStaticRateProvider(money.DefaultCurrency, nil)
+	require.NoError(t, err)
+
+	db := testutil.NewDB(t)
+	shop := &testShop{
+		db:              db,
+		productRepo:     mysql.NewProductRepository(db),
+		userRepo:        mysql.NewUserRepository(db),
+		couponRepo:      mysql.NewCouponRepository(db),
+		reservationRepo: mysql.NewReservationRepository(db),
+		blockchainRepo:  mysql.NewBlockchainRepository(db),
+	}
+	shop.products = NewProductService(shop.productRepo, mysql.NewCategoryRepository(db), rates, taxes, db).(*ProductService)
+	shop.orders = NewOrderService(mysql.NewOrderRepository(db), shop.productRepo, shop.blockchainRepo, shop.reservationRepo,
+		shop.couponRepo, shop.userRepo, shop.products, taxes, db, time.Minute).(*OrderService)
+	return shop
+}
+
+func (s *testShop) createUser(t *testing.T, username string) *model.User {
+	t.Helper()
+
+	user := &model.User{Username: username, Password: "hashed", Role: model.UserRoleCustomer}
+	require.NoError(t, s.userRepo.Create(user))
+	return user
+}
+
+func (s *testShop) createProduct(t *testing.T, price string, stock int) *model.Product {
+	t.Helper()
+
+	product := &model.Product{
+		Name:        fmt.Sprintf("product-%s-%d", price, stock),
+		Price:       money.MustParseAmount(price),
+		Currency:    money.DefaultCurrency,
+		TaxCategory: tax.CategoryStandard,
+		Stock:       stock,
+	}
+	require.NoError(t, s.productRepo.Create(product))
+	return product
+}
+
+// skuStock 读取SKU的当前库存
+func (s *testShop) skuStock(t *testing.T, skuID int64) int {
+	t.Helper()
+
+	var sku model.ProductSKU
+	require.NoError(t, s.db.First(&sku, skuID).Error)
+	return sku.Stock
+}
+
+// expireReservation 将订单的库存预留改为已过期
+func (s *testShop) expireReservation(t *testing.T, orderID int64) {
+	t.Helper()
+
+	require.NoError(t, s.db.Model(&model.StockReservation{}).Where("order_id = ?", orderID).
+		Update("expires_at", time.Now().Add(-time.Second)).Error)
+}
+
+// reservation 读取订单的库存预留
+func (s *testShop) reservation(t *testing.T, orderID int64) *model.StockReservation {
+	t.Helper()
+
+	var reservation model.StockReservation
+	require.NoError(t, s.db.Where("order_id = ?", orderID).First(&reservation).Error)
+	return &reservation
+}
+
+// fakeChain 内存中的链上账本，转账即时上链，确认数固定
+type fakeChain struct {
+	mu            sync.Mutex
+	balances      map[string]money.Amount
+	nonces        map[string]uint64
+	transfers     []*blockchain.Transfer
+	records       int
+	confirmations int
+	submitErr     error // 不为nil时拒绝所有转账
+}
+
+var _ blockchain.Service = (*fakeChain)(nil)
+
+func newFakeChain() *fakeChain {
+	return &fakeChain{
+		balances:      make(map[string]money.Amount),
+		nonces:        make(map[string]uint64),
+		confirmations: 1,
+	}
+}
+
+func (c *fakeChain) RecordTransaction(data []byte) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.records++
+	return fmt.Sprintf("record-%d", c.records), nil
+}
+
+func (c *fakeChain) GetTransaction(txHash string) ([]byte, error) {
+	return nil, blockchain.ErrTransactionNotFound
+}
+
+func (c *fakeChain) SubmitTransfer(transfer *blockchain.Transfer) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.submitErr != nil {
+		return "", c.submitErr
+	}
+	if err := transfer.Verify(); err != nil {
+		return "", err
+	}
+	value, err := blockchain.ParseValue(transfer.Value)
+	if err != nil {
+		return "", err
+	}
+	if transfer.Nonce != c.nonces[transfer.From] {
+		return "", blockchain.ErrInvalidNonce
+	}
+	if c.balances[transfer.From] < value {
+		return "", blockchain.ErrInsufficientBalance
+	}
+
+	c.balances[transfer.From] -= value
+	c.balances[transfer.To] += value
+	c.nonces[transfer.From]++
+	c.transfers = append(c.transfers, transfer)
+	return fmt.Sprintf("transfer-%d", len(c.transfers)), nil
+}
+
+func (c *fakeChain) Mint(to, value string) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	amount, err := blockchain.ParseValue(value)
+	if err != nil {
+		return "", err
+	}
+	c.balances[to] += amount
+	c.records++
+	return fmt.Sprintf("record-%d", c.records), nil
+}
+
+func (c *fakeChain) GetAccount(address string) *blockchain.Account {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return &blockchain.Account{
+		Address: address,
+		Balance: blockchain.FormatValue(c.balances[address]),
+		Nonce:   c.nonces[address],
+	}
+}
+
+func (c *fakeChain) Confirmations(txHash string) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.confirmations, nil
+}
+
+func (c *fakeChain) ListBlocks(before, limit int) []*blockchain.Block {
+	return nil
+}
+
+// balance 返回地址的余额
+func (c *fakeChain) balance(address string) string {
+	return c.GetAccount(address).Balance
+}
+
+// newTestWallet 生成钱包私钥和地址
+func newTestWallet(t *testing.T) (ed25519.PrivateKey, string) {
+	t.Helper()
+
+	key, err := blockchain.GenerateKey()
+	require.NoError(t, err)
+	return key, blockchain.AddressFromPublicKey(key.Public().(ed25519.PublicKey))
+}
@@ -0,0 +1,116 @@
+package service
+
+import (
+	"time"
+
+	"github.com/ylh990835774/blockchain-shop-demo/internal/model"
+	"github.com/ylh990835774/blockchain-shop-demo/internal/repository/mysql"
+	"github.com/ylh990835774/blockchain-shop-demo/pkg/errors"
+)
+
+const (
+	// defaultIdempotencyTTL 未配置时幂等键的有效期
+	defaultIdempotencyTTL = 24 * time.Hour
+	// defaultIdempotencyLockTimeout 未配置时处理中的幂等键的占用时长
+	defaultIdempotencyLockTimeout = time.Minute
+)
+
+type IdempotencyService struct {
+	repo        *mysql.IdempotencyRepository
+	ttl         time.Duration
+	lockTimeout time.Duration // 超过该时长仍未完成的请求视为已中断，幂等键可被重新占用
+}
+
+// 确保IdempotencyService实现了IIdempotencyService接口
+var _ IIdempotencyService = (*IdempotencyService)(nil)
+
+func NewIdempotencyService(repo *mysql.IdempotencyRepository, ttl, lockTimeout time.Duration) IIdempotencyService {
+	if ttl <= 0 {
+		ttl = defaultIdempotencyTTL
+	}
+	if lockTimeout <= 0 {
+		lockTimeout = defaultIdempotencyLockTimeout
+	}
+	return &IdempotencyService{
+		repo:        repo,
+		ttl:         ttl,
+		lockTimeout: lockTimeout,
+	}
+}
+
+func (s *IdempotencyService) Acquire(userID int64, key, fingerprint string) (*model.IdempotencyKey, error) {
+	if userID <= 0 || key == "" {
+		return nil, errors.ErrInvalidInput
+	}
+
+	now := time.Now()
+	record, err := s.repo.Get(userID, key)
+	switch {
+	case err == mysql.ErrNotFound:
+	case err != nil:
+		return nil, err
+	case record.Expired(now):
+		// 过期的幂等键视为不存在
+		if err := s.takeOver(record); err != nil {
+			return nil, err
+		}
+	case record.Fingerprint != fingerprint:
+		return nil, errors.ErrIdempotencyKeyReused
+	case !record.Completed():
+		if now.Sub(record.CreatedAt) < s.lockTimeout {
+			return nil, errors.ErrIdempotencyKeyInProgress
+		}
+		// 首次请求在保存响应前中断，允许重试
+		if err := s.takeOver(record); err != nil {
+			return nil, err
+		}
+	default:
+		return record, nil
+	}
+
+	record = &model.IdempotencyKey{
+		UserID:      userID,
+		Key:         key,
+		Fingerprint: fingerprint,
+		ExpiresAt:   now.Add(s.ttl),
+	}
+	if err := s.repo.Create(record); err != nil {
+		// 并发请求抢先占用了同一个幂等键
+		if err == mysql.ErrDuplicateKey {
+			return nil, errors.ErrIdempotencyKeyInProgress
+		}
+		return nil, err
+	}
+
+	return record, nil
+}
+
+// takeOver 删除过期或已中断的幂等键记录以便重新占用，记录已被并发请求接管时返回处理中
+func (s *IdempotencyService) takeOver(record *model.IdempotencyKey) error {
+	deleted, err := s.repo.DeleteByID(record.ID)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return errors.ErrIdempotencyKeyInProgress
+	}
+	return nil
+}
+
+func (s *IdempotencyService) Complete(id int64, statusCode int, body []byte) error {
+	if err := s.repo.Complete(id, statusCode, body); err != nil {
+		if err == mysql.ErrNotFound {
+			return errors.ErrNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+func (s *IdempotencyService) Release(id int64) error {
+	return s.repo.DeleteInProgress(id)
+}
+
+func (s *IdempotencyService) PurgeExpired() (int64, error) {
+	return s.repo.DeleteExpired(time.Now())
+}
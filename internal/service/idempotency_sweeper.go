@@ -0,0 +1,56 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/ylh990835774/blockchain-shop-demo/pkg/logger"
+)
+
+// defaultIdempotencyPurgeInterval 未配置时清理过期幂等键的间隔
+const defaultIdempotencyPurgeInterval = time.Hour
+
+// IdempotencySweeper 定期清理过期的幂等键
+type IdempotencySweeper struct {
+	idempotencyService IIdempotencyService
+	interval           time.Duration
+}
+
+func NewIdempotencySweeper(idempotencyService IIdempotencyService, interval time.Duration) *IdempotencySweeper {
+	if interval <= 0 {
+		interval = defaultIdempotencyPurgeInterval
+	}
+	return &IdempotencySweeper{
+		idempotencyService: idempotencyService,
+		interval:           interval,
+	}
+}
+
+// Run 按间隔执行清理，直到ctx被取消
+func (s *IdempotencySweeper) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := s.Sweep(); err != nil {
+				logger.Error("清理过期幂等键失败", logger.Err(err))
+			}
+		}
+	}
+}
+
+// Sweep 执行一轮清理，返回被删除的幂等键数量
+func (s *IdempotencySweeper) Sweep() (int64, error) {
+	n, err := s.idempotencyService.PurgeExpired()
+	if err != nil {
+		return 0, err
+	}
+	if n > 0 {
+		logger.Info("已清理过期幂等键", logger.Int64("count", n))
+	}
+	return n, nil
+}
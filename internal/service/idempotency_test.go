@@ -0,0 +1,159 @@
+package service
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/ylh990835774/blockchain-shop-demo/internal/model"
+	"github.com/ylh990835774/blockchain-shop-demo/internal/repository/mysql"
+	"github.com/ylh990835774/blockchain-shop-demo/internal/testutil"
+	"github.com/ylh990835774/blockchain-shop-demo/pkg/errors"
+	"gorm.io/gorm"
+)
+
+// getIdempotencyKey 读取幂等键记录
+func getIdempotencyKey(t *testing.T, db *gorm.DB, userID int64, key string) *model.IdempotencyKey {
+	t.Helper()
+
+	var record model.IdempotencyKey
+	require.NoError(t, db.Where("user_id = ? AND idempotency_key = ?", userID, key).First(&record).Error)
+	return &record
+}
+
+func TestIdempotencyService_DefaultTTL(t *testing.T) {
+	db := testutil.NewDB(t)
+	svc := NewIdempotencyService(mysql.NewIdempotencyRepository(db), 0, 0)
+
+	acquired, err := svc.Acquire(1, "key-1", "fp")
+	require.NoError(t, err)
+	require.NotNil(t, acquired)
+	assert.False(t, acquired.Completed())
+
+	// 未配置有效期时使用默认值，而不是立即过期
+	stored := getIdempotencyKey(t, db, 1, "key-1")
+	assert.Equal(t, acquired.ID, stored.ID)
+	assert.WithinDuration(t, time.Now().Add(defaultIdempotencyTTL), stored.ExpiresAt, time.Minute)
+
+	require.NoError(t, svc.Complete(acquired.ID, 200, []byte(`{"code":0}`)))
+	record, err := svc.Acquire(1, "key-1", "fp")
+	require.NoError(t, err)
+	require.True(t, record.Completed())
+	assert.Equal(t, 200, record.StatusCode)
+	assert.Equal(t, []byte(`{"code":0}`), record.ResponseBody)
+
+	// 已保存的响应不会被覆盖，也不会被释放
+	assert.Equal(t, errors.ErrNotFound, svc.Complete(acquired.ID, 500, []byte(`{"code":-1}`)))
+	require.NoError(t, svc.Release(acquired.ID))
+	assert.Equal(t, 200, getIdempotencyKey(t, db, 1, "key-1").StatusCode)
+}
+
+func TestIdempotencyService_Acquire_InProgress(t *testing.T) {
+	db := testutil.NewDB(t)
+	svc := NewIdempotencyService(mysql.NewIdempotencyRepository(db), time.Hour, time.Minute)
+
+	first, err := svc.Acquire(1, "key-1", "fp")
+	require.NoError(t, err)
+
+	// 首次请求仍在处理中
+	_, err = svc.Acquire(1, "key-1", "fp")
+	assert.Equal(t, errors.ErrIdempotencyKeyInProgress, err)
+	_, err = svc.Acquire(1, "key-1", "other")
+	assert.Equal(t, errors.ErrIdempotencyKeyReused, err)
+
+	// 首次请求中断后超过占用时长，相同请求可以重新占用
+	stale := getIdempotencyKey(t, db, 1, "key-1")
+	require.NoError(t, db.Model(stale).UpdateColumn("created_at", time.Now().Add(-2*time.Minute)).Error)
+	retry, err := svc.Acquire(1, "key-1", "fp")
+	require.NoError(t, err)
+	assert.False(t, retry.Completed())
+	assert.NotEqual(t, first.ID, retry.ID)
+
+	_, err = svc.Acquire(1, "key-1", "fp")
+	assert.Equal(t, errors.ErrIdempotencyKeyInProgress, err)
+
+	// 被接管的首次请求最终完成时，既不能释放也不能覆盖重试请求的记录
+	require.NoError(t, svc.Release(first.ID))
+	assert.Equal(t, errors.ErrNotFound, svc.Complete(first.ID, 201, []byte(`{"id":1}`)))
+	assert.Equal(t, retry.ID, getIdempotencyKey(t, db, 1, "key-1").ID)
+
+	require.NoError(t, svc.Complete(retry.ID, 201, []byte(`{"id":2}`)))
+	record, err := svc.Acquire(1, "key-1", "fp")
+	require.NoError(t, err)
+	assert.Equal(t, []byte(`{"id":2}`), record.ResponseBody)
+}
+
+func TestIdempotencyService_Acquire_Concurrent(t *testing.T) {
+	db := testutil.NewDB(t)
+	svc := NewIdempotencyService(mysql.NewIdempotencyRepository(db), time.Hour, time.Minute)
+
+	const requests = 10
+	var (
+		wg         sync.WaitGroup
+		mu         sync.Mutex
+		acquired   int
+		inProgress int
+		unexpected []error
+	)
+	start := make(chan struct{})
+
+	for i := 0; i < requests; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+
+			_, err := svc.Acquire(1, "key-1", "fp")
+
+			mu.Lock()
+			defer mu.Unlock()
+			switch err {
+			case nil:
+				acquired++
+			case errors.ErrIdempotencyKeyInProgress:
+				inProgress++
+			default:
+				unexpected = append(unexpected, err)
+			}
+		}()
+	}
+
+	close(start)
+	wg.Wait()
+
+	// 唯一索引保证同一幂等键只有一个请求能占用
+	require.Empty(t, unexpected)
+	assert.Equal(t, 1, acquired)
+	assert.Equal(t, requests-1, inProgress)
+
+	var count int64
+	require.NoError(t, db.Model(&model.IdempotencyKey{}).Count(&count).Error)
+	assert.Equal(t, int64(1), count)
+
+	// 读取之后、写入之前被抢先占用时由唯一索引拒绝
+	duplicate := &model.IdempotencyKey{UserID: 1, Key: "key-1", Fingerprint: "fp", ExpiresAt: time.Now().Add(time.Hour)}
+	assert.Equal(t, mysql.ErrDuplicateKey, mysql.NewIdempotencyRepository(db).Create(duplicate))
+}
+
+func TestIdempotencySweeper_Sweep(t *testing.T) {
+	db := testutil.NewDB(t)
+	svc := NewIdempotencyService(mysql.NewIdempotencyRepository(db), time.Hour, 0)
+
+	_, err := svc.Acquire(1, "expired", "fp")
+	require.NoError(t, err)
+	_, err = svc.Acquire(1, "live", "fp")
+	require.NoError(t, err)
+	require.NoError(t, db.Model(&model.IdempotencyKey{}).Where("idempotency_key = ?", "expired").
+		UpdateColumn("expires_at", time.Now().Add(-time.Second)).Error)
+
+	setupTestLogger(t)
+	n, err := NewIdempotencySweeper(svc, 0).Sweep()
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), n)
+
+	var keys []string
+	require.NoError(t, db.Model(&model.IdempotencyKey{}).Pluck("idempotency_key", &keys).Error)
+	assert.Equal(t, []string{"live"}, keys)
+}
@@ -0,0 +1,139 @@
+package service
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/ylh990835774/blockchain-shop-demo/internal/media"
+	"github.com/ylh990835774/blockchain-shop-demo/internal/model"
+	"github.com/ylh990835774/blockchain-shop-demo/internal/repository/mysql"
+	"github.com/ylh990835774/blockchain-shop-demo/internal/storage"
+	"github.com/ylh990835774/blockchain-shop-demo/pkg/errors"
+	"github.com/ylh990835774/blockchain-shop-demo/pkg/logger"
+)
+
+// defaultMaxImageBytes 未配置时单张图片的大小上限
+const defaultMaxImageBytes = 5 << 20
+
+// ProductImageService 商品图片服务。图片按内容哈希命名保存在对象存储中，数据库记录访问地址和哈希
+type ProductImageService struct {
+	repo     *mysql.ProductRepository
+	store    storage.BlobStore
+	maxBytes int64
+}
+
+func NewProductImageService(repo *mysql.ProductRepository, store storage.BlobStore, maxBytes int64) IProductImageService {
+	if maxBytes <= 0 {
+		maxBytes = defaultMaxImageBytes
+	}
+	return &ProductImageService{
+		repo:     repo,
+		store:    store,
+		maxBytes: maxBytes,
+	}
+}
+
+// Upload 校验图片格式和大小，保存原图和缩略图。同一商品重复上传相同内容的图片返回ErrDuplicateEntry
+func (s *ProductImageService) Upload(productID int64, r io.Reader) (*model.ProductImage, error) {
+	if productID <= 0 || r == nil {
+		return nil, errors.ErrInvalidInput
+	}
+	if _, err := s.repo.GetByID(productID); err != nil {
+		if err == mysql.ErrNotFound {
+			return nil, errors.ErrNotFound
+		}
+		return nil, err
+	}
+
+	// 多读取一个字节判断是否超过大小上限
+	data, err := io.ReadAll(io.LimitReader(r, s.maxBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > s.maxBytes {
+		return nil, errors.ErrPayloadTooLarge
+	}
+	if len(data) == 0 {
+		return nil, errors.ErrInvalidInput
+	}
+
+	img, err := media.Process(data)
+	if err != nil {
+		switch err {
+		case media.ErrUnsupportedType:
+			return nil, errors.ErrUnsupportedMediaType
+		case media.ErrTooManyPixels:
+			return nil, errors.ErrPayloadTooLarge
+		}
+		return nil, err
+	}
+
+	// 以内容哈希作为对象键，相同内容的图片对应相同的对象
+	key := fmt.Sprintf("products/%d/%s.%s", productID, img.SHA256, img.Ext)
+	thumbnailKey := fmt.Sprintf("products/%d/%s_thumb.%s", productID, img.SHA256, img.ThumbnailExt)
+	if err := s.store.Put(key, img.Data, img.ContentType); err != nil {
+		return nil, err
+	}
+	if err := s.store.Put(thumbnailKey, img.Thumbnail, img.ThumbnailContentType); err != nil {
+		return nil, err
+	}
+
+	image := &model.ProductImage{
+		ProductID:    productID,
+		Key:          key,
+		ThumbnailKey: thumbnailKey,
+		URL:          s.store.URL(key),
+		ThumbnailURL: s.store.URL(thumbnailKey),
+		ContentType:  img.ContentType,
+		Size:         int64(len(img.Data)),
+		Width:        img.Width,
+		Height:       img.Height,
+		SHA256:       img.SHA256,
+	}
+	if err := s.repo.CreateImage(image); err != nil {
+		// 重复上传时对象属于已有的图片记录，不能删除
+		if err == mysql.ErrDuplicateKey {
+			return nil, errors.ErrDuplicateEntry
+		}
+		s.deleteObjects(image)
+		return nil, err
+	}
+	return image, nil
+}
+
+// Delete 删除图片记录及其对象，对象删除失败只记录日志
+func (s *ProductImageService) Delete(productID, imageID int64) error {
+	if productID <= 0 || imageID <= 0 {
+		return errors.ErrInvalidInput
+	}
+
+	image, err := s.repo.GetImage(productID, imageID)
+	if err != nil {
+		if err == mysql.ErrNotFound {
+			return errors.ErrNotFound
+		}
+		return err
+	}
+	if err := s.repo.DeleteImage(image.ID); err != nil {
+		if err == mysql.ErrNotFound {
+			return errors.ErrNotFound
+		}
+		return err
+	}
+
+	s.deleteObjects(image)
+	return nil
+}
+
+// deleteObjects 删除图片的原图和缩略图对象
+func (s *ProductImageService) deleteObjects(image *model.ProductImage) {
+	for _, key := range []string{image.Key, image.ThumbnailKey} {
+		if err := s.store.Delete(key); err != nil {
+			logger.Warn("删除图片对象失败",
+				logger.Int64("product_id", image.ProductID),
+				logger.String("key", key),
+				logger.Err(err),
+			)
+		}
+	}
+}
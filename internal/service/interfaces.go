@@ -1,7 +1,13 @@
 package service
 
 import (
+	"context"
+	"io"
+
+	"github.com/ylh990835774/blockchain-shop-demo/internal/blockchain"
+	"github.com/ylh990835774/blockchain-shop-demo/internal/catalog"
 	"github.com/ylh990835774/blockchain-shop-demo/internal/model"
+	"github.com/ylh990835774/blockchain-shop-demo/pkg/money"
 )
 
 // IUserService 用户服务接口
@@ -9,7 +15,8 @@ type IUserService interface {
 	Register(username, password string) (*model.User, error)
 	Login(username, password string) (*model.User, string, error) // 返回用户信息和token
 	GetByID(id int64) (*model.User, error)
-	Update(id int64, updates map[string]interface{}) error
+	// Update 更新用户资料，只更新patch中非nil的字段
+	Update(id int64, patch model.UserPatch) error
 	GetByUsername(username string) (*model.User, error)
 }
 
@@ -21,17 +28,192 @@ type IJWTService interface {
 
 // IProductService 商品服务接口
 type IProductService interface {
-	Create(product *model.Product) error
-	Update(id int64, updates map[string]interface{}) error
+	// Create 创建商品，actorID为操作人，SKU的期初库存记入库存流水
+	Create(actorID int64, product *model.Product) error
+	// Update 更新商品patch中非nil的字段并返回更新后的商品。version大于0时仅在商品的当前版本号一致时更新，
+	// 否则返回ErrPreconditionFailed；version为0时不校验版本
+	Update(id, version int64, patch model.ProductPatch) (*model.Product, error)
 	Delete(id int64) error
 	GetByID(id int64) (*model.Product, error)
-	List(page, pageSize int) ([]*model.Product, int64, error)
+	// List 按条件分页查询商品，返回符合条件的商品总数
+	List(filter model.ProductFilter, page, pageSize int) ([]*model.Product, int64, error)
+	// Facets 统计符合条件的商品的库存和价格区间分布
+	Facets(filter model.ProductFilter) (*model.ProductFacets, error)
+	// ListByCursor 按条件和ID顺序查询after游标之后的商品，返回下一页的游标，没有更多商品时游标为空
+	ListByCursor(filter model.ProductFilter, after string, limit int) ([]*model.Product, string, error)
+	// Archive 下架商品，下架后不在商品列表中展示且不能下单
+	Archive(id int64) error
+	// Unarchive 重新上架商品
+	Unarchive(id int64) error
+	// SetCategories 替换商品所属的分类
+	SetCategories(productID int64, categoryIDs []int64) error
+	// SetPrice 设置商品在指定币种下的定价
+	SetPrice(productID int64, currency string, price money.Amount) error
+	// DeletePrice 删除商品在指定币种下的定价，删除后按汇率换算
+	DeletePrice(productID int64, currency string) error
+	// PriceHistory 按时间从新到旧分页查询商品的价格变更记录
+	PriceHistory(productID int64, page, pageSize int) ([]*model.ProductPriceHistory, int64, error)
+	// CreateSKU 为商品添加SKU，actorID为操作人，库存变化记入库存流水，下同
+	CreateSKU(actorID, productID int64, sku *model.ProductSKU) error
+	// UpdateSKU 更新商品的SKU，sku.ID指定要更新的SKU
+	UpdateSKU(actorID, productID int64, sku *model.ProductSKU) error
+	// DeleteSKU 删除商品的SKU，默认SKU不能删除
+	DeleteSKU(actorID, productID, skuID int64) error
+	// Import 从CSV或JSON Lines文件按SKU编码批量新增或更新商品，返回每行的导入错误
+	Import(r io.Reader, format catalog.Format, opts ProductImportOptions) (*ProductImportResult, error)
+	// Export 按条件分批查询商品（包括已下架的商品），每批调用一次fn
+	Export(filter model.ProductFilter, fn func([]*model.Product) error) error
+	// Quote 计算商品SKU在指定币种下的单价，返回单价和商品基础币种到该币种的汇率；sku为空时按商品价格，币种为空时使用基础币种
+	Quote(product *model.Product, sku *model.ProductSKU, currency string) (money.Amount, money.Rate, error)
 }
 
 // IOrderService 订单服务接口
 type IOrderService interface {
-	Create(order *model.Order) error
+	// Create 创建订单，在事务内由商品价格、汇率、优惠券和税率计算订单金额和计税明细
+	Create(input *CreateOrderInput) (*model.Order, error)
 	GetByID(id int64) (*model.Order, error)
-	ListByUserID(userID int64, page, pageSize int) ([]*model.Order, int64, error)
+	// List 按条件分页查询订单，filter.UserID为零时查询所有用户的订单
+	List(filter model.OrderFilter, page, pageSize int) ([]*model.Order, int64, error)
+	// ListByCursor 按条件查询after游标之后的订单，返回下一页的游标，没有更多订单时游标为空
+	ListByCursor(filter model.OrderFilter, after string, limit int) ([]*model.Order, string, error)
 	GetTransaction(orderID int64) (*model.Transaction, error)
+	// MarkPaid 标记订单已支付，库存预留转为销售
+	MarkPaid(id int64) error
+	// Expire 取消超时未支付的订单并释放库存预留
+	Expire(id int64) error
+}
+
+// ICategoryService 商品分类管理服务接口
+type ICategoryService interface {
+	// Create 创建分类，父分类必须存在
+	Create(category *model.Category) error
+	// Update 替换分类的名称、标识、父分类和显示顺序，分类不能移动到自身或其子孙分类下
+	Update(id int64, category *model.Category) (*model.Category, error)
+	// Delete 删除没有子分类的分类，分类下的商品解除关联
+	Delete(id int64) error
+	// GetByID 获取分类及其所有子孙分类
+	GetByID(id int64) (*model.Category, error)
+	// Tree 获取完整的分类树，返回顶级分类
+	Tree() ([]*model.Category, error)
+}
+
+// IProductImageService 商品图片服务接口
+type IProductImageService interface {
+	// Upload 校验并保存商品图片，生成缩略图并记录内容哈希
+	Upload(productID int64, r io.Reader) (*model.ProductImage, error)
+	// Delete 删除商品图片
+	Delete(productID, imageID int64) error
+}
+
+// ICouponService 优惠券管理服务接口
+type ICouponService interface {
+	Create(coupon *model.Coupon) error
+	// Update 替换优惠券的配置，已使用次数保持不变
+	Update(id int64, coupon *model.Coupon) (*model.Coupon, error)
+	Delete(id int64) error
+	GetByID(id int64) (*model.Coupon, error)
+	List(page, pageSize int) ([]*model.Coupon, int64, error)
+}
+
+// IPaymentService 支付服务接口
+type IPaymentService interface {
+	// Create 为用户的待支付订单发起支付
+	Create(userID, orderID int64, provider string) (*model.Payment, error)
+	// HandleWebhook 处理支付渠道的签名回调
+	HandleWebhook(provider string, payload []byte, signature string) error
+	ListByOrderID(orderID int64) ([]*model.Payment, error)
+	// Refund 对订单通过支付渠道成功的支付退款指定金额，返回退款流水号；订单未通过支付渠道支付时返回ErrNotFound
+	Refund(orderID int64, amount money.Amount) (string, error)
+}
+
+// ICryptoPaymentService 链上转账支付服务接口
+type ICryptoPaymentService interface {
+	// Pay 提交从用户钱包到商户地址的签名转账来支付订单
+	Pay(userID, orderID int64, transfer *blockchain.Transfer) (*model.Transaction, error)
+	// ConfirmPending 检查待确认的转账，确认数达到要求后将订单标记为已支付
+	ConfirmPending() (int, error)
+	// Faucet 向地址发放测试币，仅测试环境可用
+	Faucet(address, value string) (string, error)
+	GetAccount(address string) (*blockchain.Account, error)
+	// ListBlocks 按高度从高到低列出after游标之后的区块，返回下一页的游标，已到创世区块时游标为空
+	ListBlocks(after string, limit int) ([]*blockchain.Block, string, error)
+	MerchantAddress() string
+	// Refund 从商户地址向付款钱包转账退款，返回退款交易哈希；订单未通过链上转账支付时返回ErrNotFound
+	Refund(orderID int64, amount money.Amount) (string, error)
+}
+
+// IShipmentService 发货和物流跟踪服务接口
+type IShipmentService interface {
+	// Create 为已支付订单创建发货记录，订单转为已发货
+	Create(orderID int64, carrier, trackingNumber string) (*model.Shipment, error)
+	// AddEvent 记录物流跟踪事件，签收事件将订单转为已完成
+	AddEvent(shipmentID int64, event *model.ShipmentEvent) (*model.Shipment, error)
+	// ConfirmDelivery 买家确认收货，订单转为已完成
+	ConfirmDelivery(userID, orderID int64) (*model.Shipment, error)
+	GetByOrderID(orderID int64) (*model.Shipment, error)
+}
+
+// IReturnService 退货服务接口
+type IReturnService interface {
+	// Request 买家为已完成订单申请退货，可退部分数量
+	Request(userID, orderID int64, quantity int, reason string) (*model.ReturnRequest, error)
+	// Approve 商户同意退货申请
+	Approve(id int64, note string) (*model.ReturnRequest, error)
+	// Reject 商户拒绝退货申请
+	Reject(id int64, note string) (*model.ReturnRequest, error)
+	// Receive 商户确认收到退回商品，恢复库存并退款；退款失败时可重试
+	Receive(id int64) (*model.ReturnRequest, error)
+	GetByID(id int64) (*model.ReturnRequest, error)
+	ListByOrderID(orderID int64) ([]*model.ReturnRequest, error)
+}
+
+// IInvoiceService 发票服务接口
+type IInvoiceService interface {
+	// GetOrIssue 获取订单发票，尚未开具时为已支付的订单开具发票
+	GetOrIssue(userID, orderID int64) (*model.Invoice, error)
+	// Verify 按发票号和核验令牌获取发票，用于公开核验，令牌不符时返回ErrNotFound
+	Verify(number, token string) (*model.Invoice, error)
+	// RenderPDF 渲染发票PDF
+	RenderPDF(invoice *model.Invoice) ([]byte, error)
+	// VerifyURL 发票核验地址
+	VerifyURL(invoice *model.Invoice) string
+}
+
+// IPriceCommitmentService 商品价格表承诺服务接口
+type IPriceCommitmentService interface {
+	// Commit 生成当前的价格表并将其默克尔根上链，返回是否发布了新的承诺；价格表与上次相同时返回上次的承诺，
+	// 没有在售商品时返回nil
+	Commit() (*model.PriceCommitment, bool, error)
+	// List 按发布时间从新到旧分页查询价格表承诺
+	List(page, pageSize int) ([]*model.PriceCommitment, int64, error)
+	// ProveOrder 生成订单单价与下单前最近一次发布的价格表一致的默克尔证明
+	ProveOrder(userID, orderID int64) (*model.OrderPriceProof, error)
+}
+
+// IInventoryService 库存流水和低库存告警服务接口
+type IInventoryService interface {
+	// Restock 商户为SKU补货入库，记录操作人和原因
+	Restock(actorID, productID, skuID int64, quantity int, reason string) (*model.InventoryMovement, error)
+	// Adjust 调整SKU库存，quantity为负数时扣减，扣减后库存不能为负数
+	Adjust(actorID, productID, skuID int64, quantity int, reason string) (*model.InventoryMovement, error)
+	// ListMovements 按时间从新到旧分页查询库存流水
+	ListMovements(filter model.InventoryMovementFilter, page, pageSize int) ([]*model.InventoryMovement, int64, error)
+	// ListAlerts 分页查询低库存告警，openOnly为true时只查询未关闭的告警
+	ListAlerts(openOnly bool, page, pageSize int) ([]*model.LowStockAlert, int64, error)
+	// Discrepancies 查询库存合计与库存流水之和不一致的商品
+	Discrepancies() ([]*model.StockDiscrepancy, error)
+	// CheckLowStock 检查低库存并推送告警，返回新创建的告警数
+	CheckLowStock(ctx context.Context) (int, error)
+}
+
+// IIdempotencyService 幂等键服务接口
+type IIdempotencyService interface {
+	// Acquire 占用幂等键并返回新建的处理中记录；若已有相同请求的完成记录则返回该记录用于重放
+	Acquire(userID int64, key, fingerprint string) (*model.IdempotencyKey, error)
+	// Complete 保存请求的响应，id为Acquire返回的记录；记录已完成或已被超时接管时返回ErrNotFound
+	Complete(id int64, statusCode int, body []byte) error
+	// Release 释放Acquire占用的处理中记录，允许客户端重试；记录已被接管时不做任何操作
+	Release(id int64) error
+	// PurgeExpired 清理已过期的幂等键
+	PurgeExpired() (int64, error)
 }
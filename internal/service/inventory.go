@@ -0,0 +1,192 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/ylh990835774/blockchain-shop-demo/internal/model"
+	"github.com/ylh990835774/blockchain-shop-demo/internal/repository/mysql"
+	"github.com/ylh990835774/blockchain-shop-demo/internal/webhook"
+	"github.com/ylh990835774/blockchain-shop-demo/pkg/errors"
+	"github.com/ylh990835774/blockchain-shop-demo/pkg/logger"
+	"gorm.io/gorm"
+)
+
+const (
+	// LowStockEvent 低库存告警的事件类型
+	LowStockEvent = "inventory.low_stock"
+
+	maxMovementReasonLength = 255
+	// maxAlertAttempts 告警通知的最大发送次数，超过后不再重试
+	maxAlertAttempts = 5
+	// alertBatchSize 每次检查最多发送的告警数
+	alertBatchSize = 100
+)
+
+type InventoryService struct {
+	repo              *mysql.InventoryRepository
+	productRepo       *mysql.ProductRepository
+	notifier          webhook.Notifier
+	lowStockThreshold int
+	db                *gorm.DB
+}
+
+func NewInventoryService(repo *mysql.InventoryRepository, productRepo *mysql.ProductRepository, notifier webhook.Notifier,
+	lowStockThreshold int, db *gorm.DB) IInventoryService {
+	return &InventoryService{
+		repo:              repo,
+		productRepo:       productRepo,
+		notifier:          notifier,
+		lowStockThreshold: lowStockThreshold,
+		db:                db,
+	}
+}
+
+// Restock 商户为SKU补货入库
+func (s *InventoryService) Restock(actorID, productID, skuID int64, quantity int, reason string) (*model.InventoryMovement, error) {
+	if quantity <= 0 {
+		return nil, errors.ErrInvalidInput
+	}
+	return s.move(actorID, productID, skuID, model.MovementTypeRestock, quantity, reason, false)
+}
+
+// Adjust 按盘点结果等调整SKU库存，quantity为负数时扣减，必须说明原因
+func (s *InventoryService) Adjust(actorID, productID, skuID int64, quantity int, reason string) (*model.InventoryMovement, error) {
+	if quantity == 0 {
+		return nil, errors.ErrInvalidInput
+	}
+	return s.move(actorID, productID, skuID, model.MovementTypeAdjustment, quantity, reason, true)
+}
+
+func (s *InventoryService) move(actorID, productID, skuID int64, movementType model.MovementType, quantity int,
+	reason string, reasonRequired bool) (*model.InventoryMovement, error) {
+	reason = strings.TrimSpace(reason)
+	if actorID <= 0 || productID <= 0 || skuID <= 0 ||
+		(reasonRequired && reason == "") || utf8.RuneCountInString(reason) > maxMovementReasonLength {
+		return nil, errors.ErrInvalidInput
+	}
+
+	tx := s.db.Begin()
+	if tx.Error != nil {
+		return nil, tx.Error
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	sku, err := s.productRepo.GetSKUForUpdateWithTx(tx, skuID)
+	if err != nil {
+		tx.Rollback()
+		if err == mysql.ErrNotFound {
+			return nil, errors.ErrNotFound
+		}
+		return nil, err
+	}
+	if sku.ProductID != productID {
+		tx.Rollback()
+		return nil, errors.ErrNotFound
+	}
+	if sku.Stock+quantity < 0 {
+		tx.Rollback()
+		return nil, errors.ErrInsufficientStock
+	}
+
+	movement := &model.InventoryMovement{
+		SKUID:    skuID,
+		Type:     movementType,
+		Quantity: quantity,
+		ActorID:  &actorID,
+		Reason:   reason,
+	}
+	if err := s.productRepo.UpdateStockWithTx(tx, movement); err != nil {
+		tx.Rollback()
+		if err == mysql.ErrNotFound {
+			return nil, errors.ErrNotFound
+		}
+		return nil, err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	return movement, nil
+}
+
+func (s *InventoryService) ListMovements(filter model.InventoryMovementFilter, page, pageSize int) ([]*model.InventoryMovement, int64, error) {
+	if filter.Type != "" && !filter.Type.Valid() {
+		return nil, 0, errors.ErrInvalidInput
+	}
+	if page <= 0 {
+		page = 1
+	}
+	pageSize = pageLimit(pageSize)
+
+	offset := (page - 1) * pageSize
+	return s.repo.ListMovements(filter, offset, pageSize)
+}
+
+func (s *InventoryService) ListAlerts(openOnly bool, page, pageSize int) ([]*model.LowStockAlert, int64, error) {
+	if page <= 0 {
+		page = 1
+	}
+	pageSize = pageLimit(pageSize)
+
+	offset := (page - 1) * pageSize
+	return s.repo.ListAlerts(openOnly, offset, pageSize)
+}
+
+func (s *InventoryService) Discrepancies() ([]*model.StockDiscrepancy, error) {
+	return s.repo.StockDiscrepancies()
+}
+
+// CheckLowStock 关闭已恢复库存的告警，为库存降到阈值及以下的SKU创建告警，并推送尚未送达的告警，
+// 返回新创建的告警数。推送失败的告警在下次检查时重试
+func (s *InventoryService) CheckLowStock(ctx context.Context) (int, error) {
+	if _, err := s.repo.ResolveAlerts(s.lowStockThreshold, time.Now()); err != nil {
+		return 0, err
+	}
+	alerts, err := s.repo.CreateAlerts(s.lowStockThreshold)
+	if err != nil {
+		return 0, err
+	}
+
+	pending, err := s.repo.ListUndeliveredAlerts(maxAlertAttempts, alertBatchSize)
+	if err != nil {
+		return len(alerts), err
+	}
+	for _, alert := range pending {
+		if ctx.Err() != nil {
+			return len(alerts), ctx.Err()
+		}
+		s.deliver(ctx, alert)
+	}
+	return len(alerts), nil
+}
+
+// deliver 推送告警并记录结果，推送失败不影响其他告警
+func (s *InventoryService) deliver(ctx context.Context, alert *model.LowStockAlert) {
+	err := s.notifier.Notify(ctx, &webhook.Event{
+		Type:      LowStockEvent,
+		CreatedAt: alert.CreatedAt,
+		Data:      alert,
+	})
+	if err != nil {
+		logger.Error("推送低库存告警失败",
+			logger.Int64("alert_id", alert.ID),
+			logger.Int64("attempts", int64(alert.Attempts+1)),
+			logger.Err(err),
+		)
+		if err := s.repo.MarkAlertFailed(alert.ID, err.Error()); err != nil {
+			logger.Error("记录低库存告警推送结果失败", logger.Int64("alert_id", alert.ID), logger.Err(err))
+		}
+		return
+	}
+	if err := s.repo.MarkAlertDelivered(alert.ID, time.Now()); err != nil {
+		logger.Error("记录低库存告警推送结果失败", logger.Int64("alert_id", alert.ID), logger.Err(err))
+	}
+}
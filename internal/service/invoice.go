@@ -0,0 +1,244 @@
+package service
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"github.com/ylh990835774/blockchain-shop-demo/internal/invoice"
+	"github.com/ylh990835774/blockchain-shop-demo/internal/model"
+	"github.com/ylh990835774/blockchain-shop-demo/internal/repository"
+	"github.com/ylh990835774/blockchain-shop-demo/internal/repository/mysql"
+	"github.com/ylh990835774/blockchain-shop-demo/pkg/errors"
+	"github.com/ylh990835774/blockchain-shop-demo/pkg/money"
+	"gorm.io/gorm"
+)
+
+// InvoiceIssuer 开票方信息
+type InvoiceIssuer struct {
+	MerchantCode string // 商城的开票编码。商城是唯一的销售方，全店发票共用该编码下的一个连续编号序列
+	NumberPrefix string // 发票号前缀
+	Name         string // 销售方名称
+	TaxID        string // 销售方纳税人识别号
+	Address      string // 销售方地址
+}
+
+type InvoiceService struct {
+	repo        *mysql.InvoiceRepository
+	orderRepo   *mysql.OrderRepository
+	productRepo *mysql.ProductRepository
+	userRepo    repository.UserRepository
+	renderer    *invoice.Renderer
+	issuer      InvoiceIssuer
+	db          *gorm.DB
+}
+
+// 确保InvoiceService实现了IInvoiceService接口
+var _ IInvoiceService = (*InvoiceService)(nil)
+
+func NewInvoiceService(repo *mysql.InvoiceRepository, orderRepo *mysql.OrderRepository, productRepo *mysql.ProductRepository,
+	userRepo repository.UserRepository, renderer *invoice.Renderer, issuer InvoiceIssuer, db *gorm.DB) IInvoiceService {
+	return &InvoiceService{
+		repo:        repo,
+		orderRepo:   orderRepo,
+		productRepo: productRepo,
+		userRepo:    userRepo,
+		renderer:    renderer,
+		issuer:      issuer,
+		db:          db,
+	}
+}
+
+func (s *InvoiceService) GetOrIssue(userID, orderID int64) (*model.Invoice, error) {
+	if orderID <= 0 {
+		return nil, errors.ErrInvalidInput
+	}
+
+	inv, err := s.repo.GetByOrderID(orderID)
+	if err == nil {
+		if inv.UserID != userID {
+			return nil, errors.ErrUnauthorized
+		}
+		return inv, nil
+	}
+	if err != mysql.ErrNotFound {
+		return nil, err
+	}
+
+	return s.issue(userID, orderID)
+}
+
+func (s *InvoiceService) Verify(number, token string) (*model.Invoice, error) {
+	if number == "" || token == "" {
+		return nil, errors.ErrInvalidInput
+	}
+
+	inv, err := s.repo.GetByNumber(number)
+	if err != nil {
+		if err == mysql.ErrNotFound {
+			return nil, errors.ErrNotFound
+		}
+		return nil, err
+	}
+	// 令牌不符与发票不存在返回相同的错误
+	if subtle.ConstantTimeCompare([]byte(inv.VerifyToken), []byte(token)) != 1 {
+		return nil, errors.ErrNotFound
+	}
+	return inv, nil
+}
+
+func (s *InvoiceService) RenderPDF(inv *model.Invoice) ([]byte, error) {
+	return s.renderer.RenderPDF(inv)
+}
+
+func (s *InvoiceService) VerifyURL(inv *model.Invoice) string {
+	return s.renderer.VerifyURL(inv.Number, inv.VerifyToken)
+}
+
+// issue 为订单开具发票，编号在订单行锁和计数器行锁下分配
+func (s *InvoiceService) issue(userID, orderID int64) (*model.Invoice, error) {
+	tx := s.db.Begin()
+	if tx.Error != nil {
+		return nil, tx.Error
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	order, err := s.orderRepo.GetByIDForUpdateWithTx(tx, orderID)
+	if err != nil {
+		tx.Rollback()
+		if err == mysql.ErrNotFound {
+			return nil, errors.ErrNotFound
+		}
+		return nil, err
+	}
+	if order.UserID != userID {
+		tx.Rollback()
+		return nil, errors.ErrUnauthorized
+	}
+
+	// 并发请求已为订单开具发票
+	if inv, err := s.repo.GetByOrderIDWithTx(tx, orderID); err != mysql.ErrNotFound {
+		tx.Rollback()
+		return inv, err
+	}
+
+	switch order.Status {
+	case model.OrderStatusPaid, model.OrderStatusShipped, model.OrderStatusComplete:
+	default:
+		tx.Rollback()
+		return nil, errors.ErrInvalidOrderStatus
+	}
+
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	// 优先使用下单时保存的商品名称，历史订单没有快照时再查询商品（含已删除的商品）
+	description := order.ProductName
+	if description == "" {
+		description = fmt.Sprintf("商品 #%d", order.ProductID)
+		if product, err := s.productRepo.GetByIDUnscopedWithTx(tx, order.ProductID); err == nil {
+			description = product.Name
+		} else if err != mysql.ErrNotFound {
+			tx.Rollback()
+			return nil, err
+		}
+	}
+
+	taxLines, err := s.orderRepo.ListTaxLinesWithTx(tx, orderID)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	sequence, err := s.repo.NextSequenceWithTx(tx, s.issuer.MerchantCode)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	token, err := newVerifyToken()
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	lines := invoiceLines(description, order, taxLines)
+	inv := &model.Invoice{
+		Number:        fmt.Sprintf("%s-%s-%08d", s.issuer.NumberPrefix, s.issuer.MerchantCode, sequence),
+		VerifyToken:   token,
+		MerchantCode:  s.issuer.MerchantCode,
+		Sequence:      sequence,
+		OrderID:       order.ID,
+		UserID:        order.UserID,
+		SellerName:    s.issuer.Name,
+		SellerTaxID:   s.issuer.TaxID,
+		SellerAddress: s.issuer.Address,
+		BuyerName:     user.Username,
+		BuyerAddress:  order.ShippingAddress,
+		Currency:      order.Currency,
+		Subtotal:      order.Subtotal,
+		TaxTotal:      order.TaxTotal,
+		Total:         order.TotalPrice,
+		TxHash:        order.TxHash,
+		Lines:         lines,
+		IssuedAt:      time.Now(),
+	}
+	if err := s.repo.CreateWithTx(tx, inv); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	return inv, nil
+}
+
+// newVerifyToken 生成发票核验令牌
+func newVerifyToken() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("generate verify token: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// invoiceLines 由订单计税明细生成发票明细行，没有计税明细的历史订单按零税率开具
+func invoiceLines(description string, order *model.Order, taxLines []model.OrderTaxLine) []model.InvoiceLine {
+	if len(taxLines) == 0 {
+		order.Subtotal = order.TotalPrice
+		order.TaxTotal = 0
+		return []model.InvoiceLine{{
+			Description: description,
+			Quantity:    order.Quantity,
+			UnitPrice:   money.Amount(int64(order.TotalPrice) / int64(order.Quantity)),
+			NetAmount:   order.TotalPrice,
+			Amount:      order.TotalPrice,
+		}}
+	}
+
+	lines := make([]model.InvoiceLine, 0, len(taxLines))
+	for _, line := range taxLines {
+		lines = append(lines, model.InvoiceLine{
+			Description: description,
+			Quantity:    line.Quantity,
+			UnitPrice:   line.UnitPrice,
+			Discount:    line.Discount,
+			NetAmount:   line.NetAmount,
+			TaxRate:     line.Rate,
+			TaxAmount:   line.TaxAmount,
+			Amount:      line.GrossAmount,
+		})
+	}
+	return lines
+}
@@ -0,0 +1,41 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/ylh990835774/blockchain-shop-demo/internal/invoice"
+	"github.com/ylh990835774/blockchain-shop-demo/internal/repository/mysql"
+	"github.com/ylh990835774/blockchain-shop-demo/pkg/errors"
+)
+
+func TestInvoiceService_Verify(t *testing.T) {
+	shop := newTestShop(t, nil)
+	svc := NewInvoiceService(mysql.NewInvoiceRepository(shop.db), mysql.NewOrderRepository(shop.db), shop.productRepo,
+		shop.userRepo, invoice.NewRenderer("", "http://localhost"), InvoiceIssuer{MerchantCode: "SHOP", NumberPrefix: "INV", Name: "区块链商城"}, shop.db)
+
+	user := shop.createUser(t, "buyer")
+	product := shop.createProduct(t, "19.98", 5)
+	order, err := shop.orders.Create(&CreateOrderInput{UserID: user.ID, ProductID: product.ID, Quantity: 1})
+	require.NoError(t, err)
+	require.NoError(t, shop.orders.MarkPaid(order.ID))
+
+	inv, err := svc.GetOrIssue(user.ID, order.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "INV-SHOP-00000001", inv.Number)
+	assert.Len(t, inv.VerifyToken, 32)
+	assert.Equal(t, "http://localhost/api/v1/invoices/INV-SHOP-00000001/verify?token="+inv.VerifyToken, svc.VerifyURL(inv))
+
+	verified, err := svc.Verify(inv.Number, inv.VerifyToken)
+	require.NoError(t, err)
+	assert.Equal(t, inv.ID, verified.ID)
+
+	// 只知道发票号无法核验，令牌不符与发票不存在无法区分
+	_, err = svc.Verify(inv.Number, "")
+	assert.Equal(t, errors.ErrInvalidInput, err)
+	_, err = svc.Verify(inv.Number, "00000000000000000000000000000000")
+	assert.Equal(t, errors.ErrNotFound, err)
+	_, err = svc.Verify("INV-SHOP-00000002", inv.VerifyToken)
+	assert.Equal(t, errors.ErrNotFound, err)
+}
@@ -0,0 +1,53 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/ylh990835774/blockchain-shop-demo/pkg/logger"
+)
+
+// defaultLowStockCheckInterval 未配置时检查低库存的间隔
+const defaultLowStockCheckInterval = time.Minute
+
+// LowStockMonitor 定期检查低库存并推送告警
+type LowStockMonitor struct {
+	inventoryService IInventoryService
+	interval         time.Duration
+}
+
+func NewLowStockMonitor(inventoryService IInventoryService, interval time.Duration) *LowStockMonitor {
+	if interval <= 0 {
+		interval = defaultLowStockCheckInterval
+	}
+	return &LowStockMonitor{
+		inventoryService: inventoryService,
+		interval:         interval,
+	}
+}
+
+// Run 按间隔检查低库存，直到ctx被取消
+func (m *LowStockMonitor) Run(ctx context.Context) {
+	ticker := time.NewTicker(m.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			m.check(ctx)
+		}
+	}
+}
+
+func (m *LowStockMonitor) check(ctx context.Context) {
+	created, err := m.inventoryService.CheckLowStock(ctx)
+	if err != nil {
+		logger.Error("检查低库存失败", logger.Err(err))
+		return
+	}
+	if created > 0 {
+		logger.Info("发现低库存商品", logger.Int64("alerts", int64(created)))
+	}
+}
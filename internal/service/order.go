@@ -1,32 +1,102 @@
 package service
 
 import (
+	"time"
+
 	"github.com/ylh990835774/blockchain-shop-demo/internal/model"
+	"github.com/ylh990835774/blockchain-shop-demo/internal/repository"
 	"github.com/ylh990835774/blockchain-shop-demo/internal/repository/mysql"
+	"github.com/ylh990835774/blockchain-shop-demo/internal/tax"
+	"github.com/ylh990835774/blockchain-shop-demo/pkg/cursor"
 	"github.com/ylh990835774/blockchain-shop-demo/pkg/errors"
+	"github.com/ylh990835774/blockchain-shop-demo/pkg/money"
 	"gorm.io/gorm"
 )
 
+// defaultReservationTTL 未配置时待支付订单的库存预留时长
+const defaultReservationTTL = 30 * time.Minute
+
 type OrderService struct {
-	repo           *mysql.OrderRepository
-	productRepo    *mysql.ProductRepository
-	blockchainRepo *mysql.BlockchainRepository
-	db             *gorm.DB
+	repo            *mysql.OrderRepository
+	productRepo     *mysql.ProductRepository
+	blockchainRepo  *mysql.BlockchainRepository
+	reservationRepo *mysql.ReservationRepository
+	couponRepo      *mysql.CouponRepository
+	userRepo        repository.UserRepository
+	productService  IProductService
+	taxes           *tax.Calculator
+	db              *gorm.DB
+	reservationTTL  time.Duration
 }
 
-func NewOrderService(repo *mysql.OrderRepository, productRepo *mysql.ProductRepository, blockchainRepo *mysql.BlockchainRepository, db *gorm.DB) IOrderService {
+func NewOrderService(repo *mysql.OrderRepository, productRepo *mysql.ProductRepository, blockchainRepo *mysql.BlockchainRepository,
+	reservationRepo *mysql.ReservationRepository, couponRepo *mysql.CouponRepository, userRepo repository.UserRepository,
+	productService IProductService, taxes *tax.Calculator, db *gorm.DB, reservationTTL time.Duration) IOrderService {
+	if reservationTTL <= 0 {
+		reservationTTL = defaultReservationTTL
+	}
 	return &OrderService{
-		repo:           repo,
-		productRepo:    productRepo,
-		blockchainRepo: blockchainRepo,
-		db:             db,
+		repo:            repo,
+		productRepo:     productRepo,
+		blockchainRepo:  blockchainRepo,
+		reservationRepo: reservationRepo,
+		couponRepo:      couponRepo,
+		userRepo:        userRepo,
+		productService:  productService,
+		taxes:           taxes,
+		db:              db,
+		reservationTTL:  reservationTTL,
 	}
 }
 
-func (s *OrderService) Create(order *model.Order) error {
+// CreateOrderInput 创建订单的参数。订单金额不由调用方提供，由订单服务在扣减库存的事务内计算
+type CreateOrderInput struct {
+	UserID          int64
+	ProductID       int64
+	SKUID           int64 // 下单的SKU，为零时使用商品的默认SKU
+	Quantity        int
+	Currency        string // 订单币种，为空时依次使用用户偏好币种和商品基础币种
+	TaxRegion       string // 计税税区，为空时使用默认税区
+	CouponCode      string // 优惠券码，可选
+	ShippingAddress string // 收货地址，为空时使用用户资料中的地址
+}
+
+// Create 创建订单。商品在事务内读取，订单金额由下单时的商品价格、币种、优惠券和税区计算
+func (s *OrderService) Create(input *CreateOrderInput) (*model.Order, error) {
+	if input == nil || input.UserID <= 0 || input.ProductID <= 0 || input.Quantity <= 0 {
+		return nil, errors.ErrInvalidInput
+	}
+
+	user, err := s.userRepo.GetByID(input.UserID)
+	if err != nil {
+		if err == mysql.ErrNotFound {
+			return nil, errors.ErrNotFound
+		}
+		return nil, err
+	}
+
+	// 保存下单时的收货地址，之后修改用户资料不影响已创建的订单
+	order := &model.Order{
+		UserID:          input.UserID,
+		ProductID:       input.ProductID,
+		SKUID:           input.SKUID,
+		Quantity:        input.Quantity,
+		Currency:        input.Currency,
+		TaxRegion:       input.TaxRegion,
+		CouponCode:      input.CouponCode,
+		ShippingAddress: input.ShippingAddress,
+		Status:          model.OrderStatusPending,
+	}
+	if order.Currency == "" {
+		order.Currency = user.Currency
+	}
+	if order.ShippingAddress == "" {
+		order.ShippingAddress = user.Address
+	}
+
 	tx := s.db.Begin()
 	if tx.Error != nil {
-		return tx.Error
+		return nil, tx.Error
 	}
 	defer func() {
 		if r := recover(); r != nil {
@@ -34,36 +104,97 @@ func (s *OrderService) Create(order *model.Order) error {
 		}
 	}()
 
-	// 获取商品信息（使用事务）
 	product, err := s.productRepo.GetByIDWithTx(tx, order.ProductID)
 	if err != nil {
 		tx.Rollback()
-		return err
+		if err == mysql.ErrNotFound {
+			return nil, errors.ErrNotFound
+		}
+		return nil, err
 	}
-
-	// 检查库存
-	if product.Stock < order.Quantity {
+	if product.ArchivedAt != nil {
 		tx.Rollback()
-		return errors.ErrInsufficientStock
+		return nil, errors.ErrProductArchived
 	}
+	sku, err := orderSKU(product, order.SKUID)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	// 保存下单时的商品名称和SKU编码，之后修改或删除商品不影响订单展示
+	order.SKUID = sku.ID
+	order.ProductName = product.Name
+	order.SKUCode = sku.Code
 
-	// 更新商品库存
-	if err := s.productRepo.UpdateStockWithTx(tx, order.ProductID, -order.Quantity); err != nil {
+	var coupon *model.Coupon
+	if order.CouponCode != "" {
+		if coupon, err = s.lockCoupon(tx, order); err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+	}
+	if err := s.price(order, product, sku, coupon); err != nil {
 		tx.Rollback()
-		return err
+		return nil, err
 	}
 
 	// 创建订单
 	if err := s.repo.CreateWithTx(tx, order); err != nil {
 		tx.Rollback()
-		return err
+		return nil, err
+	}
+
+	// 原子扣减库存，库存检查由数据库条件更新完成，避免并发超卖
+	orderID, userID := order.ID, order.UserID
+	if err := s.productRepo.DecrementStockWithTx(tx, &model.InventoryMovement{
+		SKUID:    order.SKUID,
+		Type:     model.MovementTypeReservation,
+		Quantity: -order.Quantity,
+		ActorID:  &userID,
+		OrderID:  &orderID,
+		Reason:   "下单预留",
+	}); err != nil {
+		tx.Rollback()
+		switch err {
+		case mysql.ErrNotFound:
+			return nil, errors.ErrNotFound
+		case mysql.ErrInsufficientStock:
+			return nil, errors.ErrInsufficientStock
+		}
+		return nil, err
+	}
+
+	if coupon != nil {
+		if err := s.couponRepo.RedeemWithTx(tx, &model.CouponRedemption{
+			CouponID: coupon.ID,
+			UserID:   order.UserID,
+			OrderID:  order.ID,
+			Discount: order.Discount,
+			Currency: order.Currency,
+		}); err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+	}
+
+	// 预留库存，超时未支付将由清理任务释放
+	if err := s.reservationRepo.CreateWithTx(tx, &model.StockReservation{
+		OrderID:   order.ID,
+		ProductID: order.ProductID,
+		SKUID:     order.SKUID,
+		Quantity:  order.Quantity,
+		Status:    model.ReservationStatusActive,
+		ExpiresAt: time.Now().Add(s.reservationTTL),
+	}); err != nil {
+		tx.Rollback()
+		return nil, err
 	}
 
 	// 创建区块链交易
-	transaction, err := s.blockchainRepo.CreateTransaction(order.ID, order.TotalPrice)
+	transaction, err := s.blockchainRepo.CreateTransactionWithTx(tx, order)
 	if err != nil {
 		tx.Rollback()
-		return err
+		return nil, err
 	}
 
 	// 更新订单的TxHash
@@ -72,15 +203,112 @@ func (s *OrderService) Create(order *model.Order) error {
 		"tx_hash": order.TxHash,
 	}); err != nil {
 		tx.Rollback()
-		return err
+		return nil, err
 	}
 
 	// 提交事务
 	if err := tx.Commit().Error; err != nil {
 		tx.Rollback()
+		return nil, err
+	}
+
+	return order, nil
+}
+
+// orderSKU 返回订单购买的商品SKU，skuID为零时使用默认SKU，SKU不属于该商品时返回ErrNotFound
+func orderSKU(product *model.Product, skuID int64) (*model.ProductSKU, error) {
+	if skuID == 0 {
+		if sku, ok := product.DefaultSKU(); ok {
+			return sku, nil
+		}
+		return nil, errors.ErrNotFound
+	}
+	if sku, ok := product.SKU(skuID); ok {
+		return sku, nil
+	}
+	return nil, errors.ErrNotFound
+}
+
+// lockCoupon 锁定订单使用的优惠券并检查有效期、适用商品和使用次数
+func (s *OrderService) lockCoupon(tx *gorm.DB, order *model.Order) (*model.Coupon, error) {
+	order.CouponCode = normalizeCouponCode(order.CouponCode)
+	coupon, err := s.couponRepo.GetByCodeForUpdateWithTx(tx, order.CouponCode)
+	if err != nil {
+		if err == mysql.ErrNotFound {
+			return nil, errors.ErrCouponInvalid
+		}
+		return nil, err
+	}
+
+	if !coupon.Valid(time.Now()) {
+		return nil, errors.ErrCouponInvalid
+	}
+	if !coupon.AppliesTo(order.ProductID) {
+		return nil, errors.ErrCouponNotApplicable
+	}
+	if coupon.UsageLimit > 0 && coupon.UsedCount >= coupon.UsageLimit {
+		return nil, errors.ErrCouponUsageExceeded
+	}
+	if coupon.PerUserLimit > 0 {
+		used, err := s.couponRepo.CountRedemptionsWithTx(tx, coupon.ID, order.UserID)
+		if err != nil {
+			return nil, err
+		}
+		if used >= int64(coupon.PerUserLimit) {
+			return nil, errors.ErrCouponUsageExceeded
+		}
+	}
+	return coupon, nil
+}
+
+// price 按下单时的SKU价格、汇率、优惠和税率计算订单金额和计税明细，优惠从商品金额中扣除后再计税
+func (s *OrderService) price(order *model.Order, product *model.Product, sku *model.ProductSKU, coupon *model.Coupon) error {
+	if order.Currency == "" {
+		order.Currency = product.Currency
+	}
+
+	unitPrice, rate, err := s.productService.Quote(product, sku, order.Currency)
+	if err != nil {
+		return err
+	}
+
+	amount := unitPrice.Mul(order.Quantity)
+	var discount money.Amount
+	if coupon != nil {
+		if discount, err = couponDiscount(coupon, order.Currency, amount); err != nil {
+			return err
+		}
+	}
+
+	line, err := s.taxes.ComputeAmount(order.TaxRegion, product.TaxCategory, amount-discount)
+	if err != nil {
+		switch err {
+		case tax.ErrUnknownRegion, tax.ErrUnknownCategory:
+			return errors.ErrInvalidInput
+		}
 		return err
 	}
 
+	order.ExchangeRate = rate
+	order.UnitPrice = unitPrice
+	order.TaxRegion = line.Region
+	order.Discount = discount
+	order.Subtotal = line.Net
+	order.TaxTotal = line.Tax
+	order.TotalPrice = line.Gross
+	order.TaxLines = []model.OrderTaxLine{{
+		ProductID:   product.ID,
+		Quantity:    order.Quantity,
+		UnitPrice:   unitPrice,
+		Discount:    discount,
+		Category:    line.Category,
+		Region:      line.Region,
+		Rate:        line.Rate,
+		Inclusive:   line.Inclusive,
+		NetAmount:   line.Net,
+		TaxAmount:   line.Tax,
+		GrossAmount: line.Gross,
+	}}
 	return nil
 }
 
@@ -97,15 +325,105 @@ func (s *OrderService) GetByID(id int64) (*model.Order, error) {
 		return nil, err
 	}
 
+	// 商品删除后仍可查询，供订单详情展示
+	product, err := s.productRepo.GetByIDUnscoped(order.ProductID)
+	if err != nil && err != mysql.ErrNotFound {
+		return nil, err
+	}
+	order.Product = product
+
 	return order, nil
 }
 
-func (s *OrderService) ListByUserID(userID int64, page, pageSize int) ([]*model.Order, int64, error) {
-	if userID <= 0 {
-		return nil, 0, errors.ErrInvalidInput
+// List 按条件分页查询订单
+func (s *OrderService) List(filter model.OrderFilter, page, pageSize int) ([]*model.Order, int64, error) {
+	if err := normalizeOrderFilter(&filter); err != nil {
+		return nil, 0, err
+	}
+	if page <= 0 {
+		page = 1
+	}
+	pageSize = pageLimit(pageSize)
+
+	return s.repo.List(filter, (page-1)*pageSize, pageSize)
+}
+
+// ListByCursor 按条件查询after游标之后的一页订单，返回下一页的游标，没有更多订单时游标为空
+func (s *OrderService) ListByCursor(filter model.OrderFilter, after string, limit int) ([]*model.Order, string, error) {
+	if err := normalizeOrderFilter(&filter); err != nil {
+		return nil, "", err
+	}
+	limit = pageLimit(limit)
+
+	sort := filter.SortBy + ":desc"
+	if filter.Ascending {
+		sort = filter.SortBy + ":asc"
+	}
+
+	var position *model.OrderCursor
+	if after != "" {
+		c, err := cursor.Decode(after, sort)
+		if err != nil {
+			return nil, "", errors.ErrInvalidInput
+		}
+		position = &model.OrderCursor{ID: c.ID}
+		if filter.SortBy == model.OrderSortTotal {
+			position.TotalPrice, err = money.ParseAmount(c.Value)
+		} else {
+			position.CreatedAt, err = time.Parse(time.RFC3339Nano, c.Value)
+		}
+		if err != nil {
+			return nil, "", errors.ErrInvalidInput
+		}
+	}
+
+	// 多查询一条判断是否还有下一页
+	orders, err := s.repo.ListAfter(filter, position, limit+1)
+	if err != nil {
+		return nil, "", err
+	}
+	if len(orders) <= limit {
+		return orders, "", nil
+	}
+
+	orders = orders[:limit]
+	last := orders[limit-1]
+	next := &cursor.Cursor{Sort: sort, ID: last.ID}
+	if filter.SortBy == model.OrderSortTotal {
+		next.Value = last.TotalPrice.String()
+	} else {
+		next.Value = last.CreatedAt.UTC().Format(time.RFC3339Nano)
 	}
+	return orders, next.Encode(), nil
+}
 
-	return s.repo.ListByUserID(userID, page, pageSize)
+// normalizeOrderFilter 校验订单查询条件，未指定排序字段时按下单时间排序
+func normalizeOrderFilter(filter *model.OrderFilter) error {
+	if filter.UserID < 0 || filter.ProductID < 0 {
+		return errors.ErrInvalidInput
+	}
+	for _, status := range filter.Statuses {
+		switch status {
+		case model.OrderStatusPending, model.OrderStatusPaid, model.OrderStatusShipped,
+			model.OrderStatusComplete, model.OrderStatusCancelled, model.OrderStatusRefunded:
+		default:
+			return errors.ErrInvalidInput
+		}
+	}
+	switch filter.SortBy {
+	case "":
+		filter.SortBy = model.OrderSortCreatedAt
+	case model.OrderSortCreatedAt, model.OrderSortTotal:
+	default:
+		return errors.ErrInvalidInput
+	}
+	if filter.CreatedFrom != nil && filter.CreatedTo != nil && !filter.CreatedTo.After(*filter.CreatedFrom) {
+		return errors.ErrInvalidInput
+	}
+	if filter.MinTotal != nil && filter.MaxTotal != nil && *filter.MinTotal > *filter.MaxTotal {
+		return errors.ErrInvalidInput
+	}
+	return nil
 }
 
 func (s *OrderService) GetTransaction(orderID int64) (*model.Transaction, error) {
@@ -128,3 +446,148 @@ func (s *OrderService) GetTransaction(orderID int64) (*model.Transaction, error)
 
 	return transaction, nil
 }
+
+// MarkPaid 将待支付订单标记为已支付，库存预留转为销售
+func (s *OrderService) MarkPaid(id int64) error {
+	if id <= 0 {
+		return errors.ErrInvalidInput
+	}
+
+	tx := s.db.Begin()
+	if tx.Error != nil {
+		return tx.Error
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	order, err := s.repo.GetByIDForUpdateWithTx(tx, id)
+	if err != nil {
+		tx.Rollback()
+		if err == mysql.ErrNotFound {
+			return errors.ErrNotFound
+		}
+		return err
+	}
+
+	if order.Status != model.OrderStatusPending {
+		tx.Rollback()
+		return errors.ErrInvalidOrderStatus
+	}
+
+	// 没有预留记录的历史订单直接标记为已支付
+	reservation, err := s.reservationRepo.GetByOrderIDForUpdateWithTx(tx, id)
+	if err != nil && err != mysql.ErrNotFound {
+		tx.Rollback()
+		return err
+	}
+	if reservation != nil {
+		if reservation.Status != model.ReservationStatusActive || reservation.Expired(time.Now()) {
+			tx.Rollback()
+			return errors.ErrReservationExpired
+		}
+		if err := s.reservationRepo.UpdateStatusWithTx(tx, reservation.ID, model.ReservationStatusConverted); err != nil {
+			tx.Rollback()
+			return err
+		}
+		if err := s.productRepo.RecordSaleWithTx(tx, reservation); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	if err := s.repo.UpdateWithTx(tx, id, map[string]interface{}{
+		"status": model.OrderStatusPaid,
+	}); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return nil
+}
+
+// Expire 取消预留已过期的待支付订单并归还库存，订单已支付或预留未过期时不做处理
+func (s *OrderService) Expire(id int64) error {
+	if id <= 0 {
+		return errors.ErrInvalidInput
+	}
+
+	tx := s.db.Begin()
+	if tx.Error != nil {
+		return tx.Error
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	order, err := s.repo.GetByIDForUpdateWithTx(tx, id)
+	if err != nil {
+		tx.Rollback()
+		if err == mysql.ErrNotFound {
+			return errors.ErrNotFound
+		}
+		return err
+	}
+
+	reservation, err := s.reservationRepo.GetByOrderIDForUpdateWithTx(tx, id)
+	if err != nil {
+		tx.Rollback()
+		if err == mysql.ErrNotFound {
+			return nil
+		}
+		return err
+	}
+
+	if order.Status != model.OrderStatusPending ||
+		reservation.Status != model.ReservationStatusActive ||
+		!reservation.Expired(time.Now()) {
+		tx.Rollback()
+		return nil
+	}
+
+	// 归还预留的库存
+	if err := s.productRepo.UpdateStockWithTx(tx, &model.InventoryMovement{
+		SKUID:    reservation.SKUID,
+		Type:     model.MovementTypeReservation,
+		Quantity: reservation.Quantity,
+		OrderID:  &reservation.OrderID,
+		Reason:   "订单超时未支付，释放预留",
+	}); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := s.reservationRepo.UpdateStatusWithTx(tx, reservation.ID, model.ReservationStatusReleased); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	// 退还订单占用的优惠券使用次数
+	if err := s.couponRepo.ReleaseWithTx(tx, id); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := s.repo.UpdateWithTx(tx, id, map[string]interface{}{
+		"status": model.OrderStatusCancelled,
+	}); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,256 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/ylh990835774/blockchain-shop-demo/internal/model"
+	"github.com/ylh990835774/blockchain-shop-demo/internal/tax"
+	"github.com/ylh990835774/blockchain-shop-demo/pkg/errors"
+	"github.com/ylh990835774/blockchain-shop-demo/pkg/money"
+)
+
+// newTestTaxes 含税价计税的CN税区和价外计税的US税区
+func newTestTaxes(t *testing.T) *tax.Calculator {
+	t.Helper()
+
+	taxes, err := tax.NewCalculator("CN", map[string]tax.Region{
+		"CN": {Inclusive: true, Rates: map[string]money.Rate{tax.CategoryStandard: money.MustParseRate("0.13")}},
+		"US": {Inclusive: false, Rates: map[string]money.Rate{tax.CategoryStandard: money.MustParseRate("0.10")}},
+	})
+	require.NoError(t, err)
+	return taxes
+}
+
+func TestOrderService_Create_Pricing(t *testing.T) {
+	tests := []struct {
+		name      string
+		price     string
+		region    string
+		coupon    *model.Coupon
+		subtotal  string
+		taxTotal  string
+		total     string
+		discount  string
+		inclusive bool
+	}{
+		{
+			name:      "tax inclusive",
+			price:     "113.00",
+			region:    "CN",
+			subtotal:  "100.00",
+			taxTotal:  "13.00",
+			total:     "113.00",
+			discount:  "0.00",
+			inclusive: true,
+		},
+		{
+			name:     "tax exclusive",
+			price:    "100.00",
+			region:   "US",
+			subtotal: "100.00",
+			taxTotal: "10.00",
+			total:    "110.00",
+			discount: "0.00",
+		},
+		{
+			name:      "coupon discount taxed after discount",
+			price:     "113.00",
+			region:    "CN",
+			coupon:    &model.Coupon{Code: "SAVE10", Type: model.CouponTypePercent, PercentOff: money.MustParseRate("0.1")},
+			subtotal:  "90.00",
+			taxTotal:  "11.70",
+			total:     "101.70",
+			discount:  "11.30",
+			inclusive: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			shop := newTestShop(t, newTestTaxes(t))
+			user := shop.createUser(t, "buyer")
+			product := shop.createProduct(t, tt.price, 5)
+
+			input := &CreateOrderInput{UserID: user.ID, ProductID: product.ID, Quantity: 1, TaxRegion: tt.region}
+			if tt.coupon != nil {
+				require.NoError(t, shop.couponRepo.CreateWithTx(shop.db, tt.coupon))
+				input.CouponCode = "save10"
+			}
+
+			order, err := shop.orders.Create(input)
+			require.NoError(t, err)
+			assert.Equal(t, tt.subtotal, order.Subtotal.String())
+			assert.Equal(t, tt.taxTotal, order.TaxTotal.String())
+			assert.Equal(t, tt.total, order.TotalPrice.String())
+			assert.Equal(t, tt.discount, order.Discount.String())
+			require.Len(t, order.TaxLines, 1)
+			assert.Equal(t, tt.inclusive, order.TaxLines[0].Inclusive)
+			assert.Equal(t, 4, shop.skuStock(t, product.SKUs[0].ID))
+
+			// 链上交易记录与订单在同一事务中创建，记录实际应付金额
+			transaction, err := shop.orders.GetTransaction(order.ID)
+			require.NoError(t, err)
+			assert.Equal(t, tt.total, transaction.Value)
+		})
+	}
+}
+
+func TestOrderService_Create_CouponLimits(t *testing.T) {
+	t.Run("per user limit", func(t *testing.T) {
+		shop := newTestShop(t, nil)
+		user := shop.createUser(t, "buyer")
+		other := shop.createUser(t, "other")
+		product := shop.createProduct(t, "50.00", 5)
+		require.NoError(t, shop.couponRepo.CreateWithTx(shop.db, &model.Coupon{
+			Code: "ONCE", Type: model.CouponTypeFixed, AmountOff: money.MustParseAmount("5"), Currency: "CNY", PerUserLimit: 1,
+		}))
+
+		_, err := shop.orders.Create(&CreateOrderInput{UserID: user.ID, ProductID: product.ID, Quantity: 1, CouponCode: "ONCE"})
+		require.NoError(t, err)
+		_, err = shop.orders.Create(&CreateOrderInput{UserID: user.ID, ProductID: product.ID, Quantity: 1, CouponCode: "ONCE"})
+		assert.Equal(t, errors.ErrCouponUsageExceeded, err)
+		_, err = shop.orders.Create(&CreateOrderInput{UserID: other.ID, ProductID: product.ID, Quantity: 1, CouponCode: "ONCE"})
+		assert.NoError(t, err)
+
+		// 被拒绝的订单不占用库存
+		assert.Equal(t, 3, shop.skuStock(t, product.SKUs[0].ID))
+	})
+
+	t.Run("global limit", func(t *testing.T) {
+		shop := newTestShop(t, nil)
+		user := shop.createUser(t, "buyer")
+		other := shop.createUser(t, "other")
+		product := shop.createProduct(t, "50.00", 5)
+		coupon := &model.Coupon{
+			Code: "FIRST", Type: model.CouponTypeFixed, AmountOff: money.MustParseAmount("5"), Currency: "CNY", UsageLimit: 1,
+		}
+		require.NoError(t, shop.couponRepo.CreateWithTx(shop.db, coupon))
+
+		_, err := shop.orders.Create(&CreateOrderInput{UserID: user.ID, ProductID: product.ID, Quantity: 1, CouponCode: "FIRST"})
+		require.NoError(t, err)
+		_, err = shop.orders.Create(&CreateOrderInput{UserID: other.ID, ProductID: product.ID, Quantity: 1, CouponCode: "FIRST"})
+		assert.Equal(t, errors.ErrCouponUsageExceeded, err)
+
+		stored, err := shop.couponRepo.GetByID(coupon.ID)
+		require.NoError(t, err)
+		assert.Equal(t, 1, stored.UsedCount)
+	})
+}
+
+func TestOrderService_Create_Rejected(t *testing.T) {
+	tests := []struct {
+		name        string
+		stock       int
+		quantity    int
+		archive     bool
+		expectedErr error
+	}{
+		{name: "archived product", stock: 5, quantity: 1, archive: true, expectedErr: errors.ErrProductArchived},
+		{name: "insufficient stock", stock: 1, quantity: 2, expectedErr: errors.ErrInsufficientStock},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			shop := newTestShop(t, nil)
+			user := shop.createUser(t, "buyer")
+			product := shop.createProduct(t, "10.00", tt.stock)
+			if tt.archive {
+				require.NoError(t, shop.productRepo.SetArchived(product.ID, true))
+			}
+
+			_, err := shop.orders.Create(&CreateOrderInput{UserID: user.ID, ProductID: product.ID, Quantity: tt.quantity})
+			assert.Equal(t, tt.expectedErr, err)
+
+			// 事务回滚，不留下订单、交易记录或库存变动
+			var orders, transactions int64
+			require.NoError(t, shop.db.Model(&model.Order{}).Count(&orders).Error)
+			require.NoError(t, shop.db.Model(&model.Transaction{}).Count(&transactions).Error)
+			assert.Zero(t, orders)
+			assert.Zero(t, transactions)
+			assert.Equal(t, tt.stock, shop.skuStock(t, product.SKUs[0].ID))
+		})
+	}
+}
+
+func TestOrderService_MarkPaid(t *testing.T) {
+	shop := newTestShop(t, nil)
+	user := shop.createUser(t, "buyer")
+	product := shop.createProduct(t, "10.00", 5)
+
+	order, err := shop.orders.Create(&CreateOrderInput{UserID: user.ID, ProductID: product.ID, Quantity: 2})
+	require.NoError(t, err)
+	require.NoError(t, shop.orders.MarkPaid(order.ID))
+
+	paid, err := shop.orders.GetByID(order.ID)
+	require.NoError(t, err)
+	assert.Equal(t, model.OrderStatusPaid, paid.Status)
+	assert.Equal(t, model.ReservationStatusConverted, shop.reservation(t, order.ID).Status)
+	assert.Equal(t, 3, shop.skuStock(t, product.SKUs[0].ID))
+
+	// 已支付的订单不能重复标记
+	assert.Equal(t, errors.ErrInvalidOrderStatus, shop.orders.MarkPaid(order.ID))
+	assert.Equal(t, errors.ErrNotFound, shop.orders.MarkPaid(order.ID+100))
+}
+
+func TestOrderService_MarkPaid_ExpiredReservation(t *testing.T) {
+	shop := newTestShop(t, nil)
+	user := shop.createUser(t, "buyer")
+	product := shop.createProduct(t, "10.00", 5)
+
+	order, err := shop.orders.Create(&CreateOrderInput{UserID: user.ID, ProductID: product.ID, Quantity: 2})
+	require.NoError(t, err)
+	shop.expireReservation(t, order.ID)
+
+	// 预留已过期但尚未被清理时也不能完成支付
+	assert.Equal(t, errors.ErrReservationExpired, shop.orders.MarkPaid(order.ID))
+	pending, err := shop.orders.GetByID(order.ID)
+	require.NoError(t, err)
+	assert.Equal(t, model.OrderStatusPending, pending.Status)
+	assert.Equal(t, model.ReservationStatusActive, shop.reservation(t, order.ID).Status)
+
+	// 超时取消后订单状态不再是待支付
+	require.NoError(t, shop.orders.Expire(order.ID))
+	assert.Equal(t, errors.ErrInvalidOrderStatus, shop.orders.MarkPaid(order.ID))
+}
+
+func TestOrderService_Expire(t *testing.T) {
+	shop := newTestShop(t, nil)
+	user := shop.createUser(t, "buyer")
+	product := shop.createProduct(t, "50.00", 5)
+	coupon := &model.Coupon{
+		Code: "SAVE5", Type: model.CouponTypeFixed, AmountOff: money.MustParseAmount("5"), Currency: "CNY", UsageLimit: 1,
+	}
+	require.NoError(t, shop.couponRepo.CreateWithTx(shop.db, coupon))
+
+	order, err := shop.orders.Create(&CreateOrderInput{UserID: user.ID, ProductID: product.ID, Quantity: 2, CouponCode: "SAVE5"})
+	require.NoError(t, err)
+	assert.Equal(t, 3, shop.skuStock(t, product.SKUs[0].ID))
+
+	// 预留未过期时不取消订单
+	require.NoError(t, shop.orders.Expire(order.ID))
+	pending, err := shop.orders.GetByID(order.ID)
+	require.NoError(t, err)
+	assert.Equal(t, model.OrderStatusPending, pending.Status)
+
+	shop.expireReservation(t, order.ID)
+	require.NoError(t, shop.orders.Expire(order.ID))
+
+	cancelled, err := shop.orders.GetByID(order.ID)
+	require.NoError(t, err)
+	assert.Equal(t, model.OrderStatusCancelled, cancelled.Status)
+	assert.Equal(t, model.ReservationStatusReleased, shop.reservation(t, order.ID).Status)
+	assert.Equal(t, 5, shop.skuStock(t, product.SKUs[0].ID))
+	stored, err := shop.couponRepo.GetByID(coupon.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 0, stored.UsedCount)
+
+	// 重复取消不会重复归还库存
+	require.NoError(t, shop.orders.Expire(order.ID))
+	assert.Equal(t, 5, shop.skuStock(t, product.SKUs[0].ID))
+
+	// 归还的优惠券可以再次使用
+	_, err = shop.orders.Create(&CreateOrderInput{UserID: user.ID, ProductID: product.ID, Quantity: 1, CouponCode: "SAVE5"})
+	assert.NoError(t, err)
+}
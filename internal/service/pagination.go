@@ -0,0 +1,19 @@
+package service
+
+const (
+	// defaultPageSize 未指定时列表单页条数
+	defaultPageSize = 10
+	// maxPageSize 列表单页最大条数
+	maxPageSize = 100
+)
+
+// pageLimit 规范化单页条数，未指定时使用默认值，超出上限时取上限
+func pageLimit(size int) int {
+	if size <= 0 {
+		return defaultPageSize
+	}
+	if size > maxPageSize {
+		return maxPageSize
+	}
+	return size
+}
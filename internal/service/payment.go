@@ -0,0 +1,239 @@
+package service
+
+import (
+	"github.com/ylh990835774/blockchain-shop-demo/internal/blockchain"
+	"github.com/ylh990835774/blockchain-shop-demo/internal/model"
+	"github.com/ylh990835774/blockchain-shop-demo/internal/payment"
+	"github.com/ylh990835774/blockchain-shop-demo/internal/repository/mysql"
+	"github.com/ylh990835774/blockchain-shop-demo/pkg/errors"
+	"github.com/ylh990835774/blockchain-shop-demo/pkg/logger"
+	"github.com/ylh990835774/blockchain-shop-demo/pkg/money"
+)
+
+type PaymentService struct {
+	repo         *mysql.PaymentRepository
+	orderService IOrderService
+	chain        blockchain.Service
+	gateways     map[string]payment.PaymentGateway
+}
+
+// 确保PaymentService实现了IPaymentService接口
+var _ IPaymentService = (*PaymentService)(nil)
+
+func NewPaymentService(repo *mysql.PaymentRepository, orderService IOrderService, chain blockchain.Service,
+	gateways ...payment.PaymentGateway) IPaymentService {
+	registry := make(map[string]payment.PaymentGateway, len(gateways))
+	for _, gateway := range gateways {
+		registry[gateway.Name()] = gateway
+	}
+	return &PaymentService{
+		repo:         repo,
+		orderService: orderService,
+		chain:        chain,
+		gateways:     registry,
+	}
+}
+
+func (s *PaymentService) Create(userID, orderID int64, provider string) (*model.Payment, error) {
+	gateway, ok := s.gateways[provider]
+	if !ok {
+		return nil, errors.ErrInvalidInput
+	}
+
+	order, err := s.orderService.GetByID(orderID)
+	if err != nil {
+		return nil, err
+	}
+	if order.UserID != userID {
+		return nil, errors.ErrUnauthorized
+	}
+	if order.Status != model.OrderStatusPending {
+		return nil, errors.ErrInvalidOrderStatus
+	}
+
+	p := &model.Payment{
+		OrderID:  order.ID,
+		UserID:   userID,
+		Amount:   order.TotalPrice,
+		Currency: order.Currency,
+		Provider: provider,
+		Status:   model.PaymentStatusPending,
+	}
+	if err := s.repo.Create(p); err != nil {
+		return nil, err
+	}
+
+	charge, err := gateway.CreateCharge(payment.ChargeRequest{
+		PaymentID: p.ID,
+		OrderID:   p.OrderID,
+		Amount:    p.Amount,
+		Currency:  p.Currency,
+	})
+	if err != nil {
+		if _, updateErr := s.repo.TransitionStatus(p.ID, model.PaymentStatusPending, model.PaymentStatusFailed); updateErr != nil {
+			logger.Error("更新支付状态失败", logger.Int64("payment_id", p.ID), logger.Err(updateErr))
+		}
+		return nil, err
+	}
+
+	p.ProviderRef = charge.ProviderRef
+	p.PaymentURL = charge.PaymentURL
+	if err := s.repo.Update(p.ID, map[string]interface{}{
+		"provider_ref": p.ProviderRef,
+		"payment_url":  p.PaymentURL,
+	}); err != nil {
+		return nil, err
+	}
+
+	return p, nil
+}
+
+func (s *PaymentService) HandleWebhook(provider string, payload []byte, signature string) error {
+	gateway, ok := s.gateways[provider]
+	if !ok {
+		return errors.ErrNotFound
+	}
+
+	event, err := gateway.ParseWebhook(payload, signature)
+	if err != nil {
+		if err == payment.ErrInvalidSignature {
+			return errors.ErrInvalidSignature
+		}
+		return errors.ErrInvalidInput
+	}
+
+	p, err := s.repo.GetByProviderRef(provider, event.ProviderRef)
+	if err != nil {
+		if err == mysql.ErrNotFound {
+			return errors.ErrNotFound
+		}
+		return err
+	}
+
+	if event.Status == payment.EventStatusFailed {
+		_, err := s.repo.TransitionStatus(p.ID, model.PaymentStatusPending, model.PaymentStatusFailed)
+		return err
+	}
+
+	if event.Currency != p.Currency || event.Amount != p.Amount {
+		return errors.ErrPaymentAmountMismatch
+	}
+
+	// 先抢占支付状态，重复投递的回调不会重复处理
+	claimed, err := s.repo.TransitionStatus(p.ID, model.PaymentStatusPending, model.PaymentStatusSucceeded)
+	if err != nil {
+		return err
+	}
+	if !claimed {
+		return nil
+	}
+
+	if err := s.orderService.MarkPaid(p.OrderID); err != nil {
+		switch err {
+		case errors.ErrReservationExpired, errors.ErrInvalidOrderStatus:
+			// 订单已超时取消或已通过其他支付完成，退回本次支付
+			return s.refund(gateway, p)
+		}
+		if _, revertErr := s.repo.TransitionStatus(p.ID, model.PaymentStatusSucceeded, model.PaymentStatusPending); revertErr != nil {
+			logger.Error("回滚支付状态失败", logger.Int64("payment_id", p.ID), logger.Err(revertErr))
+		}
+		return err
+	}
+
+	s.recordOnChain(p)
+	return nil
+}
+
+func (s *PaymentService) ListByOrderID(orderID int64) ([]*model.Payment, error) {
+	if orderID <= 0 {
+		return nil, errors.ErrInvalidInput
+	}
+	return s.repo.ListByOrderID(orderID)
+}
+
+func (s *PaymentService) Refund(orderID int64, amount money.Amount) (string, error) {
+	if orderID <= 0 || !amount.IsPositive() {
+		return "", errors.ErrInvalidInput
+	}
+
+	payments, err := s.repo.ListByOrderID(orderID)
+	if err != nil {
+		return "", err
+	}
+	for _, p := range payments {
+		if p.Status != model.PaymentStatusSucceeded {
+			continue
+		}
+		// 累计退款不能超过支付金额
+		if p.Refunded+amount > p.Amount {
+			return "", errors.ErrInvalidInput
+		}
+		gateway, ok := s.gateways[p.Provider]
+		if !ok {
+			return "", errors.ErrNotFound
+		}
+		return s.refundPayment(gateway, p, amount)
+	}
+	return "", errors.ErrNotFound
+}
+
+// refundPayment 先记入退款金额再调用支付渠道退款，退款失败时撤销记入的金额。
+// 累计退款达到支付金额时支付变为已退款；支付已被并发修改时返回ErrPreconditionFailed
+func (s *PaymentService) refundPayment(gateway payment.PaymentGateway, p *model.Payment, amount money.Amount) (string, error) {
+	refunded := p.Refunded + amount
+	status := model.PaymentStatusSucceeded
+	if refunded == p.Amount {
+		status = model.PaymentStatusRefunded
+	}
+	claimed, err := s.repo.TransitionRefund(p.ID, model.PaymentStatusSucceeded, p.Refunded, status, refunded)
+	if err != nil {
+		return "", err
+	}
+	if !claimed {
+		return "", errors.ErrPreconditionFailed
+	}
+
+	refundRef, err := gateway.Refund(p.ProviderRef, amount)
+	if err != nil {
+		if _, revertErr := s.repo.TransitionRefund(p.ID, status, refunded, model.PaymentStatusSucceeded, p.Refunded); revertErr != nil {
+			logger.Error("撤销退款金额失败", logger.Int64("payment_id", p.ID), logger.Err(revertErr))
+		}
+		return "", err
+	}
+	p.Status, p.Refunded = status, refunded
+	return refundRef, nil
+}
+
+func (s *PaymentService) refund(gateway payment.PaymentGateway, p *model.Payment) error {
+	p.Status = model.PaymentStatusSucceeded
+	refundRef, err := s.refundPayment(gateway, p, p.Amount)
+	if err != nil {
+		return err
+	}
+
+	logger.Info("订单无法完成支付，已自动退款",
+		logger.Int64("order_id", p.OrderID),
+		logger.Int64("payment_id", p.ID),
+		logger.String("refund_ref", refundRef),
+	)
+	return nil
+}
+
+// recordOnChain 将支付结果上链。订单已标记为已支付，上链失败只记录日志，不影响回调结果
+func (s *PaymentService) recordOnChain(p *model.Payment) {
+	txHash, err := blockchain.RecordOrderEvent(s.chain, p.OrderID, blockchain.EventPaymentSucceeded, map[string]interface{}{
+		"payment_id":   p.ID,
+		"provider":     p.Provider,
+		"provider_ref": p.ProviderRef,
+		"amount":       p.Amount,
+		"currency":     p.Currency,
+	})
+	if err != nil {
+		logger.Error("支付记录上链失败", logger.Int64("payment_id", p.ID), logger.Err(err))
+		return
+	}
+
+	if err := s.repo.Update(p.ID, map[string]interface{}{"tx_hash": txHash}); err != nil {
+		logger.Error("保存支付上链哈希失败", logger.Int64("payment_id", p.ID), logger.Err(err))
+	}
+}
@@ -0,0 +1,267 @@
+package service
+
+import (
+	"encoding/json"
+	stderrors "errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/ylh990835774/blockchain-shop-demo/internal/model"
+	"github.com/ylh990835774/blockchain-shop-demo/internal/payment"
+	"github.com/ylh990835774/blockchain-shop-demo/internal/repository/mysql"
+	"github.com/ylh990835774/blockchain-shop-demo/pkg/errors"
+	"github.com/ylh990835774/blockchain-shop-demo/pkg/money"
+)
+
+// recordingGateway 记录退款请求的模拟支付渠道
+type recordingGateway struct {
+	*payment.MockGateway
+	refunds   []string
+	refundErr error // 非空时退款失败
+}
+
+func (g *recordingGateway) Refund(providerRef string, amount money.Amount) (string, error) {
+	if g.refundErr != nil {
+		return "", g.refundErr
+	}
+	g.refunds = append(g.refunds, providerRef)
+	return g.MockGateway.Refund(providerRef, amount)
+}
+
+// failingMarkPaid 标记订单已支付时返回指定错误的订单服务
+type failingMarkPaid struct {
+	IOrderService
+	err error
+}
+
+func (s *failingMarkPaid) MarkPaid(orderID int64) error {
+	return s.err
+}
+
+// paymentFixture 一个待支付的订单及其在模拟渠道创建的支付
+type paymentFixture struct {
+	shop    *testShop
+	chain   *fakeChain
+	gateway *recordingGateway
+	repo    *mysql.PaymentRepository
+	service *PaymentService
+	order   *model.Order
+	payment *model.Payment
+}
+
+func newPaymentFixture(t *testing.T) *paymentFixture {
+	t.Helper()
+	setupTestLogger(t)
+
+	shop := newTestShop(t, nil)
+	chain := newFakeChain()
+	gateway := &recordingGateway{MockGateway: payment.NewMockGateway("secret", "http://localhost")}
+	repo := mysql.NewPaymentRepository(shop.db)
+	svc := NewPaymentService(repo, shop.orders, chain, gateway)
+
+	user := shop.createUser(t, "buyer")
+	product := shop.createProduct(t, "19.98", 5)
+	order, err := shop.orders.Create(&CreateOrderInput{UserID: user.ID, ProductID: product.ID, Quantity: 1})
+	require.NoError(t, err)
+	p, err := svc.Create(user.ID, order.ID, payment.MockGatewayName)
+	require.NoError(t, err)
+
+	return &paymentFixture{
+		shop:    shop,
+		chain:   chain,
+		gateway: gateway,
+		repo:    repo,
+		service: svc.(*PaymentService),
+		order:   order,
+		payment: p,
+	}
+}
+
+// webhook 投递一次签名正确的回调
+func (f *paymentFixture) webhook(t *testing.T, status payment.EventStatus, amount string, currency string) error {
+	t.Helper()
+
+	payload, err := json.Marshal(payment.WebhookEvent{
+		ProviderRef: f.payment.ProviderRef,
+		Status:      status,
+		Amount:      money.MustParseAmount(amount),
+		Currency:    currency,
+	})
+	require.NoError(t, err)
+	return f.service.HandleWebhook(payment.MockGatewayName, payload, f.gateway.Sign(payload))
+}
+
+func (f *paymentFixture) paymentStatus(t *testing.T) model.PaymentStatus {
+	t.Helper()
+
+	p, err := f.repo.GetByID(f.payment.ID)
+	require.NoError(t, err)
+	return p.Status
+}
+
+func (f *paymentFixture) orderStatus(t *testing.T) model.OrderStatus {
+	t.Helper()
+
+	order, err := f.shop.orders.GetByID(f.order.ID)
+	require.NoError(t, err)
+	return order.Status
+}
+
+func TestPaymentService_HandleWebhook_Succeeded(t *testing.T) {
+	f := newPaymentFixture(t)
+
+	require.NoError(t, f.webhook(t, payment.EventStatusSucceeded, "19.98", "CNY"))
+	assert.Equal(t, model.PaymentStatusSucceeded, f.paymentStatus(t))
+	assert.Equal(t, model.OrderStatusPaid, f.orderStatus(t))
+	stored, err := f.repo.GetByID(f.payment.ID)
+	require.NoError(t, err)
+	assert.NotEmpty(t, stored.TxHash)
+	records := f.chain.records
+
+	// 重复投递的回调不会重复标记订单或上链
+	require.NoError(t, f.webhook(t, payment.EventStatusSucceeded, "19.98", "CNY"))
+	assert.Equal(t, model.PaymentStatusSucceeded, f.paymentStatus(t))
+	assert.Equal(t, records, f.chain.records)
+	assert.Empty(t, f.gateway.refunds)
+}
+
+func TestPaymentService_HandleWebhook_AmountMismatch(t *testing.T) {
+	tests := []struct {
+		name     string
+		amount   string
+		currency string
+	}{
+		{name: "amount", amount: "9.99", currency: "CNY"},
+		{name: "currency", amount: "19.98", currency: "USD"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newPaymentFixture(t)
+
+			assert.Equal(t, errors.ErrPaymentAmountMismatch, f.webhook(t, payment.EventStatusSucceeded, tt.amount, tt.currency))
+			assert.Equal(t, model.PaymentStatusPending, f.paymentStatus(t))
+			assert.Equal(t, model.OrderStatusPending, f.orderStatus(t))
+
+			// 金额正确的回调仍可完成支付
+			require.NoError(t, f.webhook(t, payment.EventStatusSucceeded, "19.98", "CNY"))
+			assert.Equal(t, model.OrderStatusPaid, f.orderStatus(t))
+		})
+	}
+}
+
+func TestPaymentService_HandleWebhook_Failed(t *testing.T) {
+	f := newPaymentFixture(t)
+
+	require.NoError(t, f.webhook(t, payment.EventStatusFailed, "19.98", "CNY"))
+	assert.Equal(t, model.PaymentStatusFailed, f.paymentStatus(t))
+	assert.Equal(t, model.OrderStatusPending, f.orderStatus(t))
+
+	// 已失败的支付不再接受成功回调
+	require.NoError(t, f.webhook(t, payment.EventStatusSucceeded, "19.98", "CNY"))
+	assert.Equal(t, model.PaymentStatusFailed, f.paymentStatus(t))
+	assert.Equal(t, model.OrderStatusPending, f.orderStatus(t))
+}
+
+func TestPaymentService_HandleWebhook_RefundsUnpayableOrder(t *testing.T) {
+	f := newPaymentFixture(t)
+	f.shop.expireReservation(t, f.order.ID)
+
+	require.NoError(t, f.webhook(t, payment.EventStatusSucceeded, "19.98", "CNY"))
+	assert.Equal(t, model.PaymentStatusRefunded, f.paymentStatus(t))
+	assert.Equal(t, []string{f.payment.ProviderRef}, f.gateway.refunds)
+	assert.NotEqual(t, model.OrderStatusPaid, f.orderStatus(t))
+
+	// 重复投递的回调不会重复退款
+	require.NoError(t, f.webhook(t, payment.EventStatusSucceeded, "19.98", "CNY"))
+	assert.Len(t, f.gateway.refunds, 1)
+}
+
+func TestPaymentService_HandleWebhook_RefundsSecondPayment(t *testing.T) {
+	f := newPaymentFixture(t)
+	second, err := f.service.Create(f.order.UserID, f.order.ID, payment.MockGatewayName)
+	require.NoError(t, err)
+
+	require.NoError(t, f.webhook(t, payment.EventStatusSucceeded, "19.98", "CNY"))
+	assert.Equal(t, model.OrderStatusPaid, f.orderStatus(t))
+
+	// 订单已通过第一笔支付完成，第二笔支付被退回
+	first := f.payment
+	f.payment = second
+	require.NoError(t, f.webhook(t, payment.EventStatusSucceeded, "19.98", "CNY"))
+	assert.Equal(t, model.PaymentStatusRefunded, f.paymentStatus(t))
+	assert.Equal(t, []string{second.ProviderRef}, f.gateway.refunds)
+
+	f.payment = first
+	assert.Equal(t, model.PaymentStatusSucceeded, f.paymentStatus(t))
+	assert.Equal(t, model.OrderStatusPaid, f.orderStatus(t))
+}
+
+func TestPaymentService_HandleWebhook_RevertsOnMarkPaidError(t *testing.T) {
+	f := newPaymentFixture(t)
+	markPaidErr := stderrors.New("database is down")
+	f.service.orderService = &failingMarkPaid{IOrderService: f.shop.orders, err: markPaidErr}
+
+	assert.Equal(t, markPaidErr, f.webhook(t, payment.EventStatusSucceeded, "19.98", "CNY"))
+	assert.Equal(t, model.PaymentStatusPending, f.paymentStatus(t))
+	assert.Empty(t, f.gateway.refunds)
+
+	// 支付状态已回滚，渠道重试的回调可以完成支付
+	f.service.orderService = f.shop.orders
+	require.NoError(t, f.webhook(t, payment.EventStatusSucceeded, "19.98", "CNY"))
+	assert.Equal(t, model.PaymentStatusSucceeded, f.paymentStatus(t))
+	assert.Equal(t, model.OrderStatusPaid, f.orderStatus(t))
+}
+
+func TestPaymentService_Refund_Cumulative(t *testing.T) {
+	f := newPaymentFixture(t)
+	require.NoError(t, f.webhook(t, payment.EventStatusSucceeded, "19.98", "CNY"))
+
+	refunded := func() money.Amount {
+		p, err := f.repo.GetByID(f.payment.ID)
+		require.NoError(t, err)
+		return p.Refunded
+	}
+
+	// 部分退款后支付仍为成功状态，累计退款金额增加
+	_, err := f.service.Refund(f.order.ID, money.MustParseAmount("10.00"))
+	require.NoError(t, err)
+	assert.Equal(t, model.PaymentStatusSucceeded, f.paymentStatus(t))
+	assert.Equal(t, money.MustParseAmount("10.00"), refunded())
+
+	// 累计退款不能超过支付金额
+	_, err = f.service.Refund(f.order.ID, money.MustParseAmount("10.00"))
+	assert.Equal(t, errors.ErrInvalidInput, err)
+
+	// 渠道退款失败时撤销记入的金额
+	refundErr := stderrors.New("gateway unavailable")
+	f.gateway.refundErr = refundErr
+	_, err = f.service.Refund(f.order.ID, money.MustParseAmount("9.98"))
+	assert.Equal(t, refundErr, err)
+	assert.Equal(t, model.PaymentStatusSucceeded, f.paymentStatus(t))
+	assert.Equal(t, money.MustParseAmount("10.00"), refunded())
+
+	// 退还剩余金额后支付变为已退款
+	f.gateway.refundErr = nil
+	_, err = f.service.Refund(f.order.ID, money.MustParseAmount("9.98"))
+	require.NoError(t, err)
+	assert.Equal(t, model.PaymentStatusRefunded, f.paymentStatus(t))
+	assert.Equal(t, money.MustParseAmount("19.98"), refunded())
+	assert.Len(t, f.gateway.refunds, 2)
+
+	_, err = f.service.Refund(f.order.ID, money.MustParseAmount("0.01"))
+	assert.Equal(t, errors.ErrNotFound, err)
+}
+
+func TestPaymentService_HandleWebhook_Rejected(t *testing.T) {
+	f := newPaymentFixture(t)
+
+	payload := []byte(`{"provider_ref":"` + f.payment.ProviderRef + `","status":"succeeded","amount":"19.98","currency":"CNY"}`)
+	assert.Equal(t, errors.ErrInvalidSignature, f.service.HandleWebhook(payment.MockGatewayName, payload, "bad"))
+	assert.Equal(t, errors.ErrNotFound, f.service.HandleWebhook("unknown", payload, f.gateway.Sign(payload)))
+
+	unknownRef := []byte(`{"provider_ref":"mock_ch_unknown","status":"succeeded","amount":"19.98","currency":"CNY"}`)
+	assert.Equal(t, errors.ErrNotFound, f.service.HandleWebhook(payment.MockGatewayName, unknownRef, f.gateway.Sign(unknownRef)))
+	assert.Equal(t, model.PaymentStatusPending, f.paymentStatus(t))
+}
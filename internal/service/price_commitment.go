@@ -0,0 +1,249 @@
+package service
+
+import (
+	"encoding/hex"
+	"sort"
+	"time"
+
+	"github.com/ylh990835774/blockchain-shop-demo/internal/blockchain"
+	"github.com/ylh990835774/blockchain-shop-demo/internal/model"
+	"github.com/ylh990835774/blockchain-shop-demo/internal/repository/mysql"
+	"github.com/ylh990835774/blockchain-shop-demo/pkg/errors"
+	"github.com/ylh990835774/blockchain-shop-demo/pkg/merkle"
+	"gorm.io/gorm"
+)
+
+type PriceCommitmentService struct {
+	repo        *mysql.PriceCommitmentRepository
+	orderRepo   *mysql.OrderRepository
+	productRepo *mysql.ProductRepository
+	chain       blockchain.Service
+	db          *gorm.DB
+}
+
+func NewPriceCommitmentService(repo *mysql.PriceCommitmentRepository, orderRepo *mysql.OrderRepository,
+	productRepo *mysql.ProductRepository, chain blockchain.Service, db *gorm.DB) IPriceCommitmentService {
+	return &PriceCommitmentService{
+		repo:        repo,
+		orderRepo:   orderRepo,
+		productRepo: productRepo,
+		chain:       chain,
+		db:          db,
+	}
+}
+
+// Commit 生成当前的价格表并将其默克尔根上链，上链失败时不保存
+func (s *PriceCommitmentService) Commit() (*model.PriceCommitment, bool, error) {
+	now := time.Now()
+
+	tx := s.db.Begin()
+	if tx.Error != nil {
+		return nil, false, tx.Error
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	entries, err := s.repo.ListCatalogPricesWithTx(tx)
+	if err != nil {
+		tx.Rollback()
+		return nil, false, err
+	}
+	if len(entries) == 0 {
+		tx.Rollback()
+		return nil, false, nil
+	}
+	sortPriceEntries(entries)
+	root := hex.EncodeToString(merkle.Root(priceLeaves(entries)))
+
+	latest, err := s.repo.GetLatestWithTx(tx)
+	if err != nil && err != mysql.ErrNotFound {
+		tx.Rollback()
+		return nil, false, err
+	}
+	// 价格表没有变化时不重复发布，此前发布的承诺仍然有效
+	if latest != nil && latest.MerkleRoot == root {
+		tx.Rollback()
+		return latest, false, nil
+	}
+
+	commitment := &model.PriceCommitment{
+		MerkleRoot: root,
+		EntryCount: len(entries),
+		CreatedAt:  now,
+	}
+	if err := s.repo.CreateWithTx(tx, commitment, entries); err != nil {
+		tx.Rollback()
+		return nil, false, err
+	}
+
+	txHash, err := blockchain.RecordPriceCommitment(s.chain, root, len(entries), now)
+	if err != nil {
+		tx.Rollback()
+		return nil, false, err
+	}
+	if err := s.repo.SetTxHashWithTx(tx, commitment.ID, txHash); err != nil {
+		tx.Rollback()
+		return nil, false, err
+	}
+	commitment.TxHash = txHash
+
+	if err := tx.Commit().Error; err != nil {
+		tx.Rollback()
+		return nil, false, err
+	}
+	return commitment, true, nil
+}
+
+func (s *PriceCommitmentService) List(page, pageSize int) ([]*model.PriceCommitment, int64, error) {
+	if page <= 0 {
+		page = 1
+	}
+	pageSize = pageLimit(pageSize)
+
+	offset := (page - 1) * pageSize
+	return s.repo.List(offset, pageSize)
+}
+
+// ProveOrder 生成订单单价的包含证明。使用下单前最近一次发布的价格表，价格表中有订单币种的价格时直接比较，
+// 否则以基础币种的价格按订单记录的汇率换算后比较
+func (s *PriceCommitmentService) ProveOrder(userID, orderID int64) (*model.OrderPriceProof, error) {
+	if orderID <= 0 {
+		return nil, errors.ErrInvalidInput
+	}
+
+	order, err := s.orderRepo.GetByID(orderID)
+	if err != nil {
+		if err == mysql.ErrNotFound {
+			return nil, errors.ErrNotFound
+		}
+		return nil, err
+	}
+	if order.UserID != userID {
+		return nil, errors.ErrUnauthorized
+	}
+	if order.SKUCode == "" {
+		return nil, errors.ErrNotFound
+	}
+
+	commitment, err := s.repo.GetLatestBefore(order.CreatedAt)
+	if err != nil {
+		if err == mysql.ErrNotFound {
+			return nil, errors.ErrNotFound
+		}
+		return nil, err
+	}
+	entries, err := s.repo.ListEntries(commitment.ID)
+	if err != nil {
+		return nil, err
+	}
+	entry, err := s.orderEntry(order, entries)
+	if err != nil {
+		return nil, err
+	}
+
+	leaves := priceLeaves(entries)
+	steps, err := merkle.Proof(leaves, entry.Position)
+	if err != nil {
+		return nil, err
+	}
+	rootHash, err := hex.DecodeString(commitment.MerkleRoot)
+	if err != nil {
+		return nil, err
+	}
+
+	proof := &model.OrderPriceProof{
+		OrderID:      order.ID,
+		SKUCode:      order.SKUCode,
+		Currency:     order.Currency,
+		UnitPrice:    order.UnitPrice,
+		ExchangeRate: order.ExchangeRate,
+		Commitment:   commitment,
+		Entry:        entry,
+		Proof:        make([]model.MerkleProofStep, 0, len(steps)),
+		LeafData:     string(leaves[entry.Position]),
+	}
+	for _, step := range steps {
+		position := "right"
+		if step.Left {
+			position = "left"
+		}
+		proof.Proof = append(proof.Proof, model.MerkleProofStep{Hash: hex.EncodeToString(step.Hash), Position: position})
+	}
+
+	if entry.Currency == order.Currency {
+		proof.Matched = entry.Price == order.UnitPrice
+	} else {
+		proof.Matched = order.ExchangeRate.Convert(entry.Price) == order.UnitPrice
+	}
+	onChain, err := s.onChain(commitment)
+	if err != nil {
+		return nil, err
+	}
+	proof.OnChain = onChain
+	proof.Verified = proof.Matched && proof.OnChain && merkle.Verify(leaves[entry.Position], steps, rootHash)
+	return proof, nil
+}
+
+// orderEntry 在价格表中查找订单SKU在订单币种下的价格，没有时查找商品基础币种的价格
+func (s *PriceCommitmentService) orderEntry(order *model.Order, entries []*model.PriceCommitmentEntry) (*model.PriceCommitmentEntry, error) {
+	for _, entry := range entries {
+		if entry.SKUCode == order.SKUCode && entry.Currency == order.Currency {
+			return entry, nil
+		}
+	}
+
+	product, err := s.productRepo.GetByIDUnscoped(order.ProductID)
+	if err != nil {
+		if err == mysql.ErrNotFound {
+			return nil, errors.ErrNotFound
+		}
+		return nil, err
+	}
+	for _, entry := range entries {
+		if entry.SKUCode == order.SKUCode && entry.Currency == product.Currency {
+			return entry, nil
+		}
+	}
+	// 下单的SKU不在价格表中，如价格表发布后才上架
+	return nil, errors.ErrNotFound
+}
+
+// onChain 检查链上记录的默克尔根是否与价格表一致
+func (s *PriceCommitmentService) onChain(commitment *model.PriceCommitment) (bool, error) {
+	if commitment.TxHash == "" {
+		return false, nil
+	}
+	data, err := s.chain.GetTransaction(commitment.TxHash)
+	if err != nil {
+		if err == blockchain.ErrTransactionNotFound {
+			return false, nil
+		}
+		return false, err
+	}
+	record, ok := blockchain.ParsePriceCommitment(data)
+	return ok && record.MerkleRoot == commitment.MerkleRoot && record.EntryCount == commitment.EntryCount, nil
+}
+
+// sortPriceEntries 按SKU编码和币种排序并设置叶子位置，相同的价格表总是得到相同的默克尔根
+func sortPriceEntries(entries []*model.PriceCommitmentEntry) {
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].SKUCode != entries[j].SKUCode {
+			return entries[i].SKUCode < entries[j].SKUCode
+		}
+		return entries[i].Currency < entries[j].Currency
+	})
+	for i, entry := range entries {
+		entry.Position = i
+	}
+}
+
+func priceLeaves(entries []*model.PriceCommitmentEntry) [][]byte {
+	leaves := make([][]byte, len(entries))
+	for i, entry := range entries {
+		leaves[i] = entry.LeafData()
+	}
+	return leaves
+}
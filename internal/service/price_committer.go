@@ -0,0 +1,57 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/ylh990835774/blockchain-shop-demo/pkg/logger"
+)
+
+// defaultCommitInterval 未配置时发布价格表承诺的间隔
+const defaultCommitInterval = time.Hour
+
+// PriceCommitter 定期发布商品价格表的默克尔根并上链
+type PriceCommitter struct {
+	priceCommitmentService IPriceCommitmentService
+	interval               time.Duration
+}
+
+func NewPriceCommitter(priceCommitmentService IPriceCommitmentService, interval time.Duration) *PriceCommitter {
+	if interval <= 0 {
+		interval = defaultCommitInterval
+	}
+	return &PriceCommitter{
+		priceCommitmentService: priceCommitmentService,
+		interval:               interval,
+	}
+}
+
+// Run 启动时立即发布一次，之后按间隔发布，直到ctx被取消
+func (c *PriceCommitter) Run(ctx context.Context) {
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+
+	for {
+		c.commit()
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (c *PriceCommitter) commit() {
+	commitment, created, err := c.priceCommitmentService.Commit()
+	if err != nil {
+		logger.Error("发布价格表承诺失败", logger.Err(err))
+		return
+	}
+	if created {
+		logger.Info("价格表承诺已上链",
+			logger.Int64("commitment_id", commitment.ID),
+			logger.String("merkle_root", commitment.MerkleRoot),
+			logger.String("tx_hash", commitment.TxHash),
+		)
+	}
+}
@@ -1,43 +1,195 @@
 package service
 
 import (
+	"strings"
+	"unicode/utf8"
+
+	"github.com/ylh990835774/blockchain-shop-demo/internal/exchange"
 	"github.com/ylh990835774/blockchain-shop-demo/internal/model"
 	"github.com/ylh990835774/blockchain-shop-demo/internal/repository/mysql"
+	"github.com/ylh990835774/blockchain-shop-demo/internal/tax"
+	"github.com/ylh990835774/blockchain-shop-demo/pkg/cursor"
 	"github.com/ylh990835774/blockchain-shop-demo/pkg/errors"
+	"github.com/ylh990835774/blockchain-shop-demo/pkg/money"
+	"gorm.io/gorm"
 )
 
+// productCursorSort 商品游标的排序方式，商品按ID顺序分页
+const productCursorSort = "id"
+
+// maxProductQueryLength 商品搜索关键词的最大长度
+const maxProductQueryLength = 100
+
+// maxSKUCodeLength SKU编码的最大长度
+const maxSKUCodeLength = 64
+
+// productPriceBounds 价格区间分面的分界
+var productPriceBounds = []money.Amount{
+	money.MustParseAmount("0"),
+	money.MustParseAmount("50"),
+	money.MustParseAmount("100"),
+	money.MustParseAmount("500"),
+	money.MustParseAmount("1000"),
+}
+
 type ProductService struct {
-	repo *mysql.ProductRepository
+	repo         *mysql.ProductRepository
+	categoryRepo *mysql.CategoryRepository
+	rates        exchange.RateProvider
+	taxes        *tax.Calculator
+	db           *gorm.DB
 }
 
-func NewProductService(repo *mysql.ProductRepository) IProductService {
+func NewProductService(repo *mysql.ProductRepository, categoryRepo *mysql.CategoryRepository, rates exchange.RateProvider,
+	taxes *tax.Calculator, db *gorm.DB) IProductService {
 	return &ProductService{
-		repo: repo,
+		repo:         repo,
+		categoryRepo: categoryRepo,
+		rates:        rates,
+		taxes:        taxes,
+		db:           db,
 	}
 }
 
-func (s *ProductService) Create(product *model.Product) error {
+// Create 创建商品，SKU的期初库存记为操作人actorID的调整流水
+func (s *ProductService) Create(actorID int64, product *model.Product) error {
 	if product == nil {
 		return errors.ErrInvalidInput
 	}
-	return s.repo.Create(product)
+	if product.Currency == "" {
+		product.Currency = money.DefaultCurrency
+	}
+	if !s.supported(product.Currency) {
+		return errors.ErrUnsupportedCurrency
+	}
+	if product.TaxCategory == "" {
+		product.TaxCategory = tax.CategoryStandard
+	}
+	if !s.taxes.HasCategory(product.TaxCategory) {
+		return errors.ErrInvalidInput
+	}
+	for _, price := range product.Prices {
+		if price.Currency == product.Currency || !price.Price.IsPositive() {
+			return errors.ErrInvalidInput
+		}
+		if !s.supported(price.Currency) {
+			return errors.ErrUnsupportedCurrency
+		}
+	}
+	if err := normalizeProductSKUs(product); err != nil {
+		return err
+	}
+	if err := s.checkCategories(product.CategoryIDs); err != nil {
+		return err
+	}
+
+	tx := s.db.Begin()
+	if tx.Error != nil {
+		return tx.Error
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	if err := s.repo.CreateWithTx(tx, product, actorID); err != nil {
+		tx.Rollback()
+		if err == mysql.ErrDuplicateKey {
+			return errors.ErrDuplicateEntry
+		}
+		return err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+	return nil
 }
 
-func (s *ProductService) Update(id int64, updates map[string]interface{}) error {
-	if id <= 0 {
+func (s *ProductService) SetCategories(productID int64, categoryIDs []int64) error {
+	if productID <= 0 {
 		return errors.ErrInvalidInput
 	}
+	if err := s.checkCategories(categoryIDs); err != nil {
+		return err
+	}
 
-	if err := s.repo.Update(id, updates); err != nil {
+	tx := s.db.Begin()
+	if tx.Error != nil {
+		return tx.Error
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	if _, err := s.repo.GetByIDWithTx(tx, productID); err != nil {
+		tx.Rollback()
 		if err == mysql.ErrNotFound {
 			return errors.ErrNotFound
 		}
 		return err
 	}
+	if err := s.repo.SetCategoriesWithTx(tx, productID, categoryIDs); err != nil {
+		tx.Rollback()
+		return err
+	}
 
+	if err := tx.Commit().Error; err != nil {
+		tx.Rollback()
+		return err
+	}
 	return nil
 }
 
+func (s *ProductService) Update(id, version int64, patch model.ProductPatch) (*model.Product, error) {
+	if id <= 0 || version < 0 {
+		return nil, errors.ErrInvalidInput
+	}
+	if patch.IsEmpty() {
+		return nil, errors.ErrNoFieldsToUpdate
+	}
+
+	// 只写入允许修改的字段，库存、版本号等由专门的接口维护
+	updates := make(map[string]interface{})
+	if patch.Name != nil {
+		if strings.TrimSpace(*patch.Name) == "" {
+			return nil, errors.ErrInvalidInput
+		}
+		updates["name"] = *patch.Name
+	}
+	if patch.Description != nil {
+		updates["description"] = *patch.Description
+	}
+	if patch.Price != nil {
+		if !patch.Price.IsPositive() {
+			return nil, errors.ErrInvalidInput
+		}
+		updates["price"] = *patch.Price
+	}
+	if patch.TaxCategory != nil {
+		if !s.taxes.HasCategory(*patch.TaxCategory) {
+			return nil, errors.ErrInvalidInput
+		}
+		updates["tax_category"] = *patch.TaxCategory
+	}
+
+	if err := s.repo.Update(id, version, updates); err != nil {
+		switch err {
+		case mysql.ErrNotFound:
+			return nil, errors.ErrNotFound
+		case mysql.ErrVersionConflict:
+			return nil, errors.ErrPreconditionFailed
+		}
+		return nil, err
+	}
+
+	return s.GetByID(id)
+}
+
 func (s *ProductService) Delete(id int64) error {
 	if id <= 0 {
 		return errors.ErrInvalidInput
@@ -53,6 +205,28 @@ func (s *ProductService) Delete(id int64) error {
 	return nil
 }
 
+func (s *ProductService) Archive(id int64) error {
+	return s.setArchived(id, true)
+}
+
+func (s *ProductService) Unarchive(id int64) error {
+	return s.setArchived(id, false)
+}
+
+func (s *ProductService) setArchived(id int64, archived bool) error {
+	if id <= 0 {
+		return errors.ErrInvalidInput
+	}
+
+	if err := s.repo.SetArchived(id, archived); err != nil {
+		if err == mysql.ErrNotFound {
+			return errors.ErrNotFound
+		}
+		return err
+	}
+	return nil
+}
+
 func (s *ProductService) GetByID(id int64) (*model.Product, error) {
 	if id <= 0 {
 		return nil, errors.ErrInvalidInput
@@ -69,11 +243,437 @@ func (s *ProductService) GetByID(id int64) (*model.Product, error) {
 	return product, nil
 }
 
-func (s *ProductService) List(page, pageSize int) ([]*model.Product, int64, error) {
+func (s *ProductService) List(filter model.ProductFilter, page, pageSize int) ([]*model.Product, int64, error) {
 	if page <= 0 || pageSize <= 0 {
 		return nil, 0, errors.ErrInvalidInput
 	}
+	if err := s.prepareFilter(&filter); err != nil {
+		return nil, 0, err
+	}
 
 	offset := (page - 1) * pageSize
-	return s.repo.List(offset, pageSize)
+	return s.repo.List(filter, offset, pageSize)
+}
+
+// Facets 统计符合条件的商品的库存和价格区间分布
+func (s *ProductService) Facets(filter model.ProductFilter) (*model.ProductFacets, error) {
+	if err := s.prepareFilter(&filter); err != nil {
+		return nil, err
+	}
+	return s.repo.Facets(filter, productPriceBounds)
+}
+
+// ListByCursor 按条件和ID顺序查询after游标之后的一页商品，返回下一页的游标，没有更多商品时游标为空
+func (s *ProductService) ListByCursor(filter model.ProductFilter, after string, limit int) ([]*model.Product, string, error) {
+	if err := s.prepareFilter(&filter); err != nil {
+		return nil, "", err
+	}
+	// 游标按ID记录位置，不支持其他排序
+	if filter.SortBy != "" {
+		return nil, "", errors.ErrInvalidInput
+	}
+	limit = pageLimit(limit)
+
+	var afterID int64
+	if after != "" {
+		c, err := cursor.Decode(after, productCursorSort)
+		if err != nil {
+			return nil, "", errors.ErrInvalidInput
+		}
+		afterID = c.ID
+	}
+
+	// 多查询一条判断是否还有下一页
+	products, err := s.repo.ListAfter(filter, afterID, limit+1)
+	if err != nil {
+		return nil, "", err
+	}
+	if len(products) <= limit {
+		return products, "", nil
+	}
+
+	products = products[:limit]
+	next := &cursor.Cursor{Sort: productCursorSort, ID: products[limit-1].ID}
+	return products, next.Encode(), nil
+}
+
+func (s *ProductService) SetPrice(productID int64, currency string, price money.Amount) error {
+	if productID <= 0 || !price.IsPositive() {
+		return errors.ErrInvalidInput
+	}
+	if !s.supported(currency) {
+		return errors.ErrUnsupportedCurrency
+	}
+
+	product, err := s.GetByID(productID)
+	if err != nil {
+		return err
+	}
+	// 基础币种的价格通过更新商品修改
+	if currency == product.Currency {
+		return errors.ErrInvalidInput
+	}
+
+	if err := s.repo.SetPrice(&model.ProductPrice{
+		ProductID: productID,
+		Currency:  currency,
+		Price:     price,
+	}); err != nil {
+		if err == mysql.ErrNotFound {
+			return errors.ErrNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+func (s *ProductService) DeletePrice(productID int64, currency string) error {
+	if productID <= 0 || !money.ValidCurrency(currency) {
+		return errors.ErrInvalidInput
+	}
+
+	if err := s.repo.DeletePrice(productID, currency); err != nil {
+		if err == mysql.ErrNotFound {
+			return errors.ErrNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+func (s *ProductService) PriceHistory(productID int64, page, pageSize int) ([]*model.ProductPriceHistory, int64, error) {
+	if productID <= 0 {
+		return nil, 0, errors.ErrInvalidInput
+	}
+	if page <= 0 {
+		page = 1
+	}
+	pageSize = pageLimit(pageSize)
+
+	offset := (page - 1) * pageSize
+	return s.repo.ListPriceHistory(productID, offset, pageSize)
+}
+
+// Quote 计算商品SKU在指定币种下的单价。SKU单独定价时按汇率由SKU价格换算，否则使用商品的币种定价或基础价格
+func (s *ProductService) Quote(product *model.Product, sku *model.ProductSKU, currency string) (money.Amount, money.Rate, error) {
+	if product == nil {
+		return 0, 0, errors.ErrInvalidInput
+	}
+	if currency == "" {
+		currency = product.Currency
+	}
+
+	rate, err := s.rates.Rate(product.Currency, currency)
+	if err != nil {
+		if err == exchange.ErrUnsupportedCurrency {
+			return 0, 0, errors.ErrUnsupportedCurrency
+		}
+		return 0, 0, err
+	}
+
+	// 商品的币种定价只适用于商品基础价格，SKU价格统一按汇率换算
+	if sku == nil || sku.Price == nil {
+		if price, ok := product.PriceIn(currency); ok {
+			return price, rate, nil
+		}
+	}
+	price := rate.Convert(product.BasePrice(sku))
+	if !price.IsPositive() {
+		return 0, 0, errors.ErrUnsupportedCurrency
+	}
+	return price, rate, nil
+}
+
+// CreateSKU 为商品添加SKU，库存计入商品的库存合计
+func (s *ProductService) CreateSKU(actorID, productID int64, sku *model.ProductSKU) error {
+	if productID <= 0 || sku == nil {
+		return errors.ErrInvalidInput
+	}
+	if err := normalizeSKU(sku); err != nil {
+		return err
+	}
+	if model.IsReservedSKUCode(sku.Code) {
+		return errors.ErrInvalidInput
+	}
+	sku.ID = 0
+	sku.ProductID = productID
+
+	tx := s.db.Begin()
+	if tx.Error != nil {
+		return tx.Error
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	if _, err := s.repo.GetByIDWithTx(tx, productID); err != nil {
+		tx.Rollback()
+		if err == mysql.ErrNotFound {
+			return errors.ErrNotFound
+		}
+		return err
+	}
+	if err := s.repo.CreateSKUWithTx(tx, sku, actorID); err != nil {
+		tx.Rollback()
+		if err == mysql.ErrDuplicateKey {
+			return errors.ErrDuplicateEntry
+		}
+		return err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+	return nil
+}
+
+// UpdateSKU 更新商品的SKU。商品必须始终有一个默认SKU，因此不能直接取消默认SKU的默认标记，应将其他SKU设为默认
+func (s *ProductService) UpdateSKU(actorID, productID int64, sku *model.ProductSKU) error {
+	if productID <= 0 || sku == nil || sku.ID <= 0 {
+		return errors.ErrInvalidInput
+	}
+	if err := normalizeSKU(sku); err != nil {
+		return err
+	}
+	sku.ProductID = productID
+
+	tx := s.db.Begin()
+	if tx.Error != nil {
+		return tx.Error
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	current, err := s.lockSKU(tx, productID, sku.ID)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	// 默认SKU可以保留生成的编码，但不能改用其他保留编码
+	if (current.IsDefault && !sku.IsDefault) || (sku.Code != current.Code && model.IsReservedSKUCode(sku.Code)) {
+		tx.Rollback()
+		return errors.ErrInvalidInput
+	}
+	if err := s.repo.UpdateSKUWithTx(tx, sku, actorID); err != nil {
+		tx.Rollback()
+		switch err {
+		case mysql.ErrNotFound:
+			return errors.ErrNotFound
+		case mysql.ErrDuplicateKey:
+			return errors.ErrDuplicateEntry
+		}
+		return err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+	return nil
+}
+
+// DeleteSKU 删除商品的SKU，默认SKU不能删除
+func (s *ProductService) DeleteSKU(actorID, productID, skuID int64) error {
+	if productID <= 0 || skuID <= 0 {
+		return errors.ErrInvalidInput
+	}
+
+	tx := s.db.Begin()
+	if tx.Error != nil {
+		return tx.Error
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	sku, err := s.lockSKU(tx, productID, skuID)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	if sku.IsDefault {
+		tx.Rollback()
+		return errors.ErrInvalidInput
+	}
+	if err := s.repo.DeleteSKUWithTx(tx, sku, actorID); err != nil {
+		tx.Rollback()
+		if err == mysql.ErrNotFound {
+			return errors.ErrNotFound
+		}
+		return err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+	return nil
+}
+
+// lockSKU 锁定商品的SKU，SKU不存在或不属于该商品时返回ErrNotFound
+func (s *ProductService) lockSKU(tx *gorm.DB, productID, skuID int64) (*model.ProductSKU, error) {
+	sku, err := s.repo.GetSKUForUpdateWithTx(tx, skuID)
+	if err != nil {
+		if err == mysql.ErrNotFound {
+			return nil, errors.ErrNotFound
+		}
+		return nil, err
+	}
+	if sku.ProductID != productID {
+		return nil, errors.ErrNotFound
+	}
+	return sku, nil
+}
+
+// supported 汇率来源是否支持该币种
+func (s *ProductService) supported(currency string) bool {
+	if !money.ValidCurrency(currency) {
+		return false
+	}
+	_, err := s.rates.Rate(currency, currency)
+	return err == nil
+}
+
+// prepareFilter 校验商品查询条件，并将分类条件扩展为分类及其所有子孙分类；分类不存在时返回ErrNotFound
+func (s *ProductService) prepareFilter(filter *model.ProductFilter) error {
+	if err := normalizeProductFilter(filter); err != nil {
+		return err
+	}
+	if len(filter.CategoryIDs) == 0 {
+		return nil
+	}
+
+	categories, err := s.categoryRepo.List()
+	if err != nil {
+		return err
+	}
+	_, byID := buildCategoryTree(categories)
+
+	var ids []int64
+	for _, id := range filter.CategoryIDs {
+		category, ok := byID[id]
+		if !ok {
+			return errors.ErrNotFound
+		}
+		ids = append(ids, categoryDescendants(category)...)
+	}
+	filter.CategoryIDs = ids
+	return nil
+}
+
+// checkCategories 检查商品关联的分类是否存在
+func (s *ProductService) checkCategories(categoryIDs []int64) error {
+	if len(categoryIDs) == 0 {
+		return nil
+	}
+
+	categories, err := s.categoryRepo.List()
+	if err != nil {
+		return err
+	}
+	_, byID := buildCategoryTree(categories)
+	for _, id := range categoryIDs {
+		if _, ok := byID[id]; !ok {
+			return errors.ErrInvalidInput
+		}
+	}
+	return nil
+}
+
+// normalizeProductSKUs 校验创建商品时指定的SKU，未标记默认SKU时第一个SKU为默认SKU，商品库存为SKU库存合计
+func normalizeProductSKUs(product *model.Product) error {
+	if len(product.SKUs) == 0 {
+		if product.Stock < 0 {
+			return errors.ErrInvalidInput
+		}
+		return nil
+	}
+
+	codes := make(map[string]bool, len(product.SKUs))
+	defaults := 0
+	product.Stock = 0
+	for i := range product.SKUs {
+		sku := &product.SKUs[i]
+		if err := normalizeSKU(sku); err != nil {
+			return err
+		}
+		if model.IsReservedSKUCode(sku.Code) {
+			return errors.ErrInvalidInput
+		}
+		if codes[sku.Code] {
+			return errors.ErrDuplicateEntry
+		}
+		codes[sku.Code] = true
+		if sku.IsDefault {
+			defaults++
+		}
+		product.Stock += sku.Stock
+	}
+	if defaults > 1 {
+		return errors.ErrInvalidInput
+	}
+	if defaults == 0 {
+		product.SKUs[0].IsDefault = true
+	}
+	return nil
+}
+
+// normalizeSKU 规范化SKU编码和规格属性，并校验价格和库存
+func normalizeSKU(sku *model.ProductSKU) error {
+	sku.Code = strings.TrimSpace(sku.Code)
+	if sku.Code == "" || utf8.RuneCountInString(sku.Code) > maxSKUCodeLength {
+		return errors.ErrInvalidInput
+	}
+	if sku.Stock < 0 || (sku.Price != nil && !sku.Price.IsPositive()) ||
+		(sku.LowStockThreshold != nil && *sku.LowStockThreshold < 0) {
+		return errors.ErrInvalidInput
+	}
+
+	options := make(model.SKUOptions, len(sku.Options))
+	for name, value := range sku.Options {
+		name, value = strings.TrimSpace(name), strings.TrimSpace(value)
+		if name == "" || value == "" {
+			return errors.ErrInvalidInput
+		}
+		options[name] = value
+	}
+	sku.Options = options
+	return nil
+}
+
+// normalizeProductFilter 规范化关键词和币种并校验商品查询条件
+func normalizeProductFilter(filter *model.ProductFilter) error {
+	filter.Query = strings.TrimSpace(filter.Query)
+	filter.Currency = strings.ToUpper(filter.Currency)
+	if utf8.RuneCountInString(filter.Query) > maxProductQueryLength {
+		return errors.ErrInvalidInput
+	}
+	if filter.Currency != "" && len(filter.Currency) != 3 {
+		return errors.ErrInvalidInput
+	}
+	if (filter.MinPrice != nil && *filter.MinPrice < 0) || (filter.MaxPrice != nil && *filter.MaxPrice < 0) {
+		return errors.ErrInvalidInput
+	}
+	if filter.MinPrice != nil && filter.MaxPrice != nil && *filter.MinPrice > *filter.MaxPrice {
+		return errors.ErrInvalidInput
+	}
+
+	switch filter.SortBy {
+	case "", model.ProductSortCreatedAt, model.ProductSortPrice, model.ProductSortName:
+	case model.ProductSortRelevance:
+		if filter.Query == "" {
+			return errors.ErrInvalidInput
+		}
+	default:
+		return errors.ErrInvalidInput
+	}
+	return nil
 }
@@ -0,0 +1,437 @@
+package service
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/ylh990835774/blockchain-shop-demo/internal/catalog"
+	"github.com/ylh990835774/blockchain-shop-demo/internal/model"
+	"github.com/ylh990835774/blockchain-shop-demo/internal/repository/mysql"
+	"github.com/ylh990835774/blockchain-shop-demo/internal/tax"
+	"github.com/ylh990835774/blockchain-shop-demo/pkg/errors"
+	"github.com/ylh990835774/blockchain-shop-demo/pkg/money"
+	"gorm.io/gorm"
+)
+
+const (
+	// maxImportRows 单次导入的最大行数
+	maxImportRows = 10000
+	// exportBatchSize 导出时每批查询的商品数
+	exportBatchSize = 200
+	// importSavepoint 演练导入时每组行使用的保存点
+	importSavepoint = "product_import_chunk"
+)
+
+// ProductImportOptions 商品导入选项
+type ProductImportOptions struct {
+	DryRun    bool  // 演练导入，校验并在事务内执行后回滚，不保存任何修改
+	ChunkSize int   // 每个事务导入的行数，出错的行所在的事务整体回滚；为0时在一个事务内导入全部行，任一行出错时不导入任何行
+	ActorID   int64 // 执行导入的用户，导入引起的库存变化记为该用户的调整流水
+}
+
+// ProductImportResult 商品导入结果
+type ProductImportResult struct {
+	DryRun  bool               `json:"dry_run"`
+	Total   int                `json:"total"`   // 文件中的数据行数
+	Created int                `json:"created"` // 新增的SKU数
+	Updated int                `json:"updated"` // 更新的SKU数
+	Failed  int                `json:"failed"`  // 出错的行数
+	Skipped int                `json:"skipped"` // 没有错误，但因同一事务中的其他行出错而未导入的行数
+	Errors  []catalog.RowError `json:"errors"`  // 按行号排序
+}
+
+// Import 按SKU编码批量新增或更新商品和SKU。已存在的SKU更新文件中非空的字段，并同时更新所属商品；
+// 不存在的SKU添加到product_id指定的商品，未指定时归入本次导入中同名的新商品，没有时新建商品
+func (s *ProductService) Import(r io.Reader, format catalog.Format, opts ProductImportOptions) (*ProductImportResult, error) {
+	if r == nil || opts.ChunkSize < 0 {
+		return nil, errors.ErrInvalidInput
+	}
+
+	rows, rowErrors, err := catalog.Decode(r, format)
+	if err != nil {
+		return nil, err
+	}
+	if len(rows)+len(rowErrors) > maxImportRows {
+		return nil, errors.ErrPayloadTooLarge
+	}
+
+	result := &ProductImportResult{
+		DryRun: opts.DryRun,
+		Total:  len(rows) + len(rowErrors),
+		Errors: append([]catalog.RowError{}, rowErrors...),
+	}
+
+	// 先校验所有行，出错的行所在的事务不执行
+	invalid := make(map[int]bool)
+	firstLine := make(map[string]int, len(rows))
+	for i := range rows {
+		row := &rows[i]
+		message := s.validateImportRow(row)
+		if message == "" {
+			if line, ok := firstLine[row.SKU]; ok {
+				message = fmt.Sprintf("SKU编码与第%d行重复", line)
+			} else {
+				firstLine[row.SKU] = row.Line
+			}
+		}
+		if message != "" {
+			result.Errors = append(result.Errors, catalog.RowError{Line: row.Line, SKU: row.SKU, Message: message})
+			invalid[i] = true
+		}
+	}
+
+	chunkSize := opts.ChunkSize
+	if chunkSize == 0 {
+		if len(result.Errors) > 0 {
+			result.Skipped = len(rows) - len(invalid)
+		} else {
+			chunkSize = len(rows)
+		}
+	}
+
+	// 演练时所有行在同一个事务内导入，结束后整体回滚
+	var dryRun *gorm.DB
+	if opts.DryRun && chunkSize > 0 {
+		dryRun = s.db.Begin()
+		if dryRun.Error != nil {
+			return nil, dryRun.Error
+		}
+		defer dryRun.Rollback()
+	}
+
+	// 本次导入新建的商品，按名称归组同一商品的SKU
+	created := make(map[string]int64)
+	for start := 0; chunkSize > 0 && start < len(rows); start += chunkSize {
+		end := start + chunkSize
+		if end > len(rows) {
+			end = len(rows)
+		}
+
+		failed := 0
+		for i := start; i < end; i++ {
+			if invalid[i] {
+				failed++
+			}
+		}
+		if failed > 0 {
+			result.Skipped += end - start - failed
+			continue
+		}
+
+		counts, rowErr, err := s.importChunk(dryRun, rows[start:end], created, opts.ActorID)
+		if err != nil {
+			return nil, err
+		}
+		if rowErr != nil {
+			result.Errors = append(result.Errors, *rowErr)
+			result.Skipped += end - start - 1
+			continue
+		}
+		result.Created += counts[0]
+		result.Updated += counts[1]
+	}
+
+	sort.SliceStable(result.Errors, func(i, j int) bool {
+		return result.Errors[i].Line < result.Errors[j].Line
+	})
+	result.Failed = len(result.Errors)
+	return result, nil
+}
+
+// validateImportRow 规范化并校验一行的取值，返回错误信息
+func (s *ProductService) validateImportRow(row *catalog.Row) string {
+	row.SKU = strings.TrimSpace(row.SKU)
+	row.Name = strings.TrimSpace(row.Name)
+	row.Currency = strings.ToUpper(strings.TrimSpace(row.Currency))
+	row.TaxCategory = strings.TrimSpace(row.TaxCategory)
+
+	switch {
+	case row.SKU == "" || utf8.RuneCountInString(row.SKU) > maxSKUCodeLength:
+		return "sku不能为空且不能超过64个字符"
+	case row.ProductID < 0:
+		return "product_id的值无效"
+	case utf8.RuneCountInString(row.Name) > model.MaxProductNameLength:
+		return "name不能超过255个字符"
+	case utf8.RuneCountInString(row.Description) > model.MaxProductDescriptionLength:
+		return "description不能超过10000个字符"
+	case row.Price != nil && !row.Price.IsPositive():
+		return "price必须大于零"
+	case row.SKUPrice != nil && !row.SKUPrice.IsPositive():
+		return "sku_price必须大于零"
+	case row.Stock != nil && *row.Stock < 0:
+		return "stock不能为负数"
+	case row.Currency != "" && !s.supported(row.Currency):
+		return "不支持的币种"
+	case row.TaxCategory != "" && !s.taxes.HasCategory(row.TaxCategory):
+		return "未知的税目"
+	}
+
+	if row.Options != nil {
+		sku := model.ProductSKU{Code: row.SKU, Options: row.Options}
+		if err := normalizeSKU(&sku); err != nil {
+			return "options的规格名称和取值不能为空"
+		}
+		row.Options = sku.Options
+	}
+	return ""
+}
+
+// importChunk 在一个事务内导入一组行，返回新增和更新的SKU数。任一行出错时回滚并返回该行的错误。
+// 演练时dryRun为演练事务，各组行在其保存点内导入，出错时回滚到保存点，成功时保留修改供后续的行引用
+func (s *ProductService) importChunk(dryRun *gorm.DB, rows []catalog.Row, created map[string]int64, actorID int64) ([2]int, *catalog.RowError, error) {
+	var counts [2]int
+
+	tx := dryRun
+	rollback := func() { tx.RollbackTo(importSavepoint) }
+	if dryRun != nil {
+		if err := tx.SavePoint(importSavepoint).Error; err != nil {
+			return counts, nil, err
+		}
+	} else {
+		tx = s.db.Begin()
+		if tx.Error != nil {
+			return counts, nil, tx.Error
+		}
+		rollback = func() { tx.Rollback() }
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			rollback()
+		}
+	}()
+
+	// 本事务新建的商品，提交后才能被后续事务引用
+	chunkCreated := make(map[string]int64)
+	for i := range rows {
+		row := &rows[i]
+		isNew, message, err := s.importRow(tx, row, created, chunkCreated, actorID)
+		if err != nil {
+			rollback()
+			return counts, nil, err
+		}
+		if message != "" {
+			rollback()
+			return [2]int{}, &catalog.RowError{Line: row.Line, SKU: row.SKU, Message: message}, nil
+		}
+		if isNew {
+			counts[0]++
+		} else {
+			counts[1]++
+		}
+	}
+
+	if dryRun == nil {
+		if err := tx.Commit().Error; err != nil {
+			return counts, nil, err
+		}
+	}
+	for name, id := range chunkCreated {
+		created[name] = id
+	}
+	return counts, nil, nil
+}
+
+// importRow 导入一行，返回是否新增了SKU；数据不满足导入条件时返回错误信息
+func (s *ProductService) importRow(tx *gorm.DB, row *catalog.Row, created, chunkCreated map[string]int64, actorID int64) (bool, string, error) {
+	sku, err := s.repo.GetSKUByCodeWithTx(tx, row.SKU)
+	if err == nil {
+		message, err := s.importExistingSKU(tx, row, sku, actorID)
+		return false, message, err
+	}
+	if err != mysql.ErrNotFound {
+		return false, "", err
+	}
+	if model.IsReservedSKUCode(row.SKU) {
+		return false, "P加数字的SKU编码保留给默认SKU", nil
+	}
+
+	productID := row.ProductID
+	if productID == 0 && row.Name != "" {
+		if id, ok := chunkCreated[row.Name]; ok {
+			productID = id
+		} else {
+			productID = created[row.Name]
+		}
+	}
+	if productID == 0 {
+		return s.importNewProduct(tx, row, chunkCreated, actorID)
+	}
+
+	product, err := s.repo.GetByIDWithTx(tx, productID)
+	if err != nil {
+		if err == mysql.ErrNotFound {
+			return false, "商品不存在", nil
+		}
+		return false, "", err
+	}
+	if message, err := s.importProductFields(tx, product, row); message != "" || err != nil {
+		return false, message, err
+	}
+
+	newSKU := importedSKU(row, productID)
+	if err := s.repo.CreateSKUWithTx(tx, newSKU, actorID); err != nil {
+		if err == mysql.ErrDuplicateKey {
+			return false, "SKU编码已存在", nil
+		}
+		return false, "", err
+	}
+	return true, "", nil
+}
+
+// importNewProduct 以行的SKU作为默认SKU新建商品
+func (s *ProductService) importNewProduct(tx *gorm.DB, row *catalog.Row, chunkCreated map[string]int64, actorID int64) (bool, string, error) {
+	if row.Name == "" || row.Price == nil {
+		return false, "新商品的name和price不能为空", nil
+	}
+
+	product := &model.Product{
+		Name:        row.Name,
+		Description: row.Description,
+		Price:       *row.Price,
+		Currency:    row.Currency,
+		TaxCategory: row.TaxCategory,
+	}
+	if product.Currency == "" {
+		product.Currency = money.DefaultCurrency
+	}
+	if product.TaxCategory == "" {
+		product.TaxCategory = tax.CategoryStandard
+	}
+	sku := importedSKU(row, 0)
+	sku.IsDefault = true
+	product.SKUs = []model.ProductSKU{*sku}
+	product.Stock = sku.Stock
+
+	if err := s.repo.CreateWithTx(tx, product, actorID); err != nil {
+		if err == mysql.ErrDuplicateKey {
+			return false, "SKU编码已存在", nil
+		}
+		return false, "", err
+	}
+	chunkCreated[row.Name] = product.ID
+	return true, "", nil
+}
+
+// importExistingSKU 更新已存在的SKU及其所属商品
+func (s *ProductService) importExistingSKU(tx *gorm.DB, row *catalog.Row, sku *model.ProductSKU, actorID int64) (string, error) {
+	if row.ProductID > 0 && row.ProductID != sku.ProductID {
+		return "SKU属于其他商品", nil
+	}
+
+	product, err := s.repo.GetByIDWithTx(tx, sku.ProductID)
+	if err != nil {
+		if err == mysql.ErrNotFound {
+			return "SKU所属的商品已删除", nil
+		}
+		return "", err
+	}
+	if message, err := s.importProductFields(tx, product, row); message != "" || err != nil {
+		return message, err
+	}
+
+	// 只在有变化时更新，避免没有实际修改时被当作记录不存在
+	changed := false
+	if row.SKUPrice != nil && (sku.Price == nil || *sku.Price != *row.SKUPrice) {
+		sku.Price, changed = row.SKUPrice, true
+	}
+	if row.Stock != nil && *row.Stock != sku.Stock {
+		sku.Stock, changed = *row.Stock, true
+	}
+	if row.Options != nil && !sameOptions(sku.Options, row.Options) {
+		sku.Options, changed = row.Options, true
+	}
+	if !changed {
+		return "", nil
+	}
+	return "", s.repo.UpdateSKUWithTx(tx, sku, actorID)
+}
+
+// importProductFields 用行中非空的商品字段更新商品
+func (s *ProductService) importProductFields(tx *gorm.DB, product *model.Product, row *catalog.Row) (string, error) {
+	if row.Currency != "" && row.Currency != product.Currency {
+		return "商品的基础币种不能修改", nil
+	}
+
+	updates := make(map[string]interface{})
+	if row.Name != "" && row.Name != product.Name {
+		updates["name"] = row.Name
+	}
+	if row.Description != "" && row.Description != product.Description {
+		updates["description"] = row.Description
+	}
+	if row.Price != nil && *row.Price != product.Price {
+		updates["price"] = *row.Price
+	}
+	if row.TaxCategory != "" && row.TaxCategory != product.TaxCategory {
+		updates["tax_category"] = row.TaxCategory
+	}
+	if len(updates) == 0 {
+		return "", nil
+	}
+
+	if err := s.repo.UpdateWithTx(tx, product.ID, 0, updates); err != nil {
+		if err == mysql.ErrNotFound {
+			return "商品不存在", nil
+		}
+		return "", err
+	}
+	return "", nil
+}
+
+// importedSKU 由行创建新的SKU，未提供的库存为零
+func importedSKU(row *catalog.Row, productID int64) *model.ProductSKU {
+	sku := &model.ProductSKU{
+		ProductID: productID,
+		Code:      row.SKU,
+		Options:   row.Options,
+		Price:     row.SKUPrice,
+	}
+	if sku.Options == nil {
+		sku.Options = model.SKUOptions{}
+	}
+	if row.Stock != nil {
+		sku.Stock = *row.Stock
+	}
+	return sku
+}
+
+func sameOptions(a, b model.SKUOptions) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for name, value := range a {
+		if other, ok := b[name]; !ok || other != value {
+			return false
+		}
+	}
+	return true
+}
+
+// Export 按条件分批查询商品（包括已下架的商品），每批调用一次fn，用于流式导出
+func (s *ProductService) Export(filter model.ProductFilter, fn func([]*model.Product) error) error {
+	filter.WithArchived = true
+	if err := s.prepareFilter(&filter); err != nil {
+		return err
+	}
+
+	var afterID int64
+	for {
+		products, err := s.repo.ListAfter(filter, afterID, exportBatchSize)
+		if err != nil {
+			return err
+		}
+		if len(products) == 0 {
+			return nil
+		}
+		if err := fn(products); err != nil {
+			return err
+		}
+		if len(products) < exportBatchSize {
+			return nil
+		}
+		afterID = products[len(products)-1].ID
+	}
+}
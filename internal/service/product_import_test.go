@@ -0,0 +1,67 @@
+package service
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/ylh990835774/blockchain-shop-demo/internal/catalog"
+	"github.com/ylh990835774/blockchain-shop-demo/internal/model"
+)
+
+// 第二行没有价格，只能归入第一行在前一个事务中新建的同名商品
+const importGroupedCSV = "sku,name,price,stock\nTEA-S,绿茶,25.00,5\nTEA-L,绿茶,,3\n"
+
+func TestProductService_Import_DryRunMatchesImport(t *testing.T) {
+	shop := newTestShop(t, nil)
+
+	dryRun, err := shop.products.Import(strings.NewReader(importGroupedCSV), catalog.FormatCSV,
+		ProductImportOptions{DryRun: true, ChunkSize: 1, ActorID: 7})
+	require.NoError(t, err)
+	assert.True(t, dryRun.DryRun)
+	assert.Empty(t, dryRun.Errors)
+	assert.Equal(t, 2, dryRun.Created)
+
+	// 演练不保存任何修改
+	var products, movements int64
+	require.NoError(t, shop.db.Model(&model.Product{}).Count(&products).Error)
+	require.NoError(t, shop.db.Model(&model.InventoryMovement{}).Count(&movements).Error)
+	assert.Zero(t, products)
+	assert.Zero(t, movements)
+
+	result, err := shop.products.Import(strings.NewReader(importGroupedCSV), catalog.FormatCSV,
+		ProductImportOptions{ChunkSize: 1, ActorID: 7})
+	require.NoError(t, err)
+	dryRun.DryRun = false
+	assert.Equal(t, dryRun, result)
+
+	var skus []model.ProductSKU
+	require.NoError(t, shop.db.Order("id").Find(&skus).Error)
+	require.Len(t, skus, 2)
+	assert.Equal(t, skus[0].ProductID, skus[1].ProductID)
+
+	// 导入引起的库存变化记为执行导入的用户
+	var imported []model.InventoryMovement
+	require.NoError(t, shop.db.Find(&imported).Error)
+	require.Len(t, imported, 2)
+	for _, movement := range imported {
+		require.NotNil(t, movement.ActorID)
+		assert.Equal(t, int64(7), *movement.ActorID)
+	}
+}
+
+func TestProductService_Import_DryRunRollsBackFailedChunk(t *testing.T) {
+	shop := newTestShop(t, nil)
+
+	// 第二组出错时只回滚该组，后续的行仍能引用第一组新建的商品
+	csv := "sku,name,price,product_id\nTEA-S,绿茶,25.00,\nTEA-M,,,999\nTEA-L,绿茶,,\n"
+	result, err := shop.products.Import(strings.NewReader(csv), catalog.FormatCSV, ProductImportOptions{DryRun: true, ChunkSize: 1})
+	require.NoError(t, err)
+	assert.Equal(t, 2, result.Created)
+	assert.Equal(t, []catalog.RowError{{Line: 3, SKU: "TEA-M", Message: "商品不存在"}}, result.Errors)
+
+	var products int64
+	require.NoError(t, shop.db.Model(&model.Product{}).Count(&products).Error)
+	assert.Zero(t, products)
+}
@@ -0,0 +1,204 @@
+package service
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/ylh990835774/blockchain-shop-demo/internal/catalog"
+	"github.com/ylh990835774/blockchain-shop-demo/internal/exchange"
+	"github.com/ylh990835774/blockchain-shop-demo/internal/model"
+	"github.com/ylh990835774/blockchain-shop-demo/pkg/errors"
+	"github.com/ylh990835774/blockchain-shop-demo/pkg/money"
+)
+
+func TestNormalizeProductFilter(t *testing.T) {
+	low, high := money.MustParseAmount("10"), money.MustParseAmount("20")
+	negative := money.MustParseAmount("-1")
+
+	tests := []struct {
+		name     string
+		filter   model.ProductFilter
+		expected model.ProductFilter
+		wantErr  bool
+	}{
+		{
+			name:     "trims query and uppercases currency",
+			filter:   model.ProductFilter{Query: "  绿茶 ", Currency: "cny", SortBy: model.ProductSortRelevance},
+			expected: model.ProductFilter{Query: "绿茶", Currency: "CNY", SortBy: model.ProductSortRelevance},
+		},
+		{
+			name:     "price range",
+			filter:   model.ProductFilter{MinPrice: &low, MaxPrice: &high, SortBy: model.ProductSortPrice},
+			expected: model.ProductFilter{MinPrice: &low, MaxPrice: &high, SortBy: model.ProductSortPrice},
+		},
+		{name: "relevance without query", filter: model.ProductFilter{SortBy: model.ProductSortRelevance}, wantErr: true},
+		{name: "unknown sort", filter: model.ProductFilter{SortBy: "stock"}, wantErr: true},
+		{name: "min above max", filter: model.ProductFilter{MinPrice: &high, MaxPrice: &low}, wantErr: true},
+		{name: "negative price", filter: model.ProductFilter{MinPrice: &negative}, wantErr: true},
+		{name: "malformed currency", filter: model.ProductFilter{Currency: "YUAN"}, wantErr: true},
+		{name: "query too long", filter: model.ProductFilter{Query: strings.Repeat("茶", maxProductQueryLength+1)}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := normalizeProductFilter(&tt.filter)
+			if tt.wantErr {
+				assert.Equal(t, errors.ErrInvalidInput, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, tt.filter)
+		})
+	}
+}
+
+func TestNormalizeProductSKUs(t *testing.T) {
+	price := money.MustParseAmount("12.00")
+	zero := money.Amount(0)
+
+	tests := []struct {
+		name         string
+		skus         []model.ProductSKU
+		expectedErr  error
+		defaultIndex int
+		stock        int
+	}{
+		{
+			name: "first sku becomes default",
+			skus: []model.ProductSKU{
+				{Code: " TEE-M ", Options: model.SKUOptions{" 尺码 ": "M"}, Stock: 2},
+				{Code: "TEE-L", Options: model.SKUOptions{"尺码": "L"}, Price: &price, Stock: 3},
+			},
+			defaultIndex: 0,
+			stock:        5,
+		},
+		{
+			name: "explicit default",
+			skus: []model.ProductSKU{
+				{Code: "TEE-M", Stock: 2},
+				{Code: "TEE-L", Stock: 1, IsDefault: true},
+			},
+			defaultIndex: 1,
+			stock:        3,
+		},
+		{
+			name:        "two defaults",
+			skus:        []model.ProductSKU{{Code: "A", IsDefault: true}, {Code: "B", IsDefault: true}},
+			expectedErr: errors.ErrInvalidInput,
+		},
+		{
+			name:        "duplicate code",
+			skus:        []model.ProductSKU{{Code: "A"}, {Code: " A"}},
+			expectedErr: errors.ErrDuplicateEntry,
+		},
+		{name: "empty code", skus: []model.ProductSKU{{Code: " "}}, expectedErr: errors.ErrInvalidInput},
+		{name: "reserved default code", skus: []model.ProductSKU{{Code: " P42"}}, expectedErr: errors.ErrInvalidInput},
+		{
+			name:         "codes resembling reserved codes",
+			skus:         []model.ProductSKU{{Code: "P42-RED", Stock: 1}, {Code: "P"}, {Code: "p42"}},
+			defaultIndex: 0,
+			stock:        1,
+		},
+		{name: "negative stock", skus: []model.ProductSKU{{Code: "A", Stock: -1}}, expectedErr: errors.ErrInvalidInput},
+		{name: "zero price", skus: []model.ProductSKU{{Code: "A", Price: &zero}}, expectedErr: errors.ErrInvalidInput},
+		{
+			name:        "empty option value",
+			skus:        []model.ProductSKU{{Code: "A", Options: model.SKUOptions{"颜色": ""}}},
+			expectedErr: errors.ErrInvalidInput,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			product := &model.Product{Stock: 100, SKUs: tt.skus}
+			err := normalizeProductSKUs(product)
+			assert.Equal(t, tt.expectedErr, err)
+			if tt.expectedErr != nil {
+				return
+			}
+			assert.Equal(t, tt.stock, product.Stock)
+			def, ok := product.DefaultSKU()
+			assert.True(t, ok)
+			assert.Equal(t, &product.SKUs[tt.defaultIndex], def)
+			for _, sku := range product.SKUs {
+				assert.Equal(t, strings.TrimSpace(sku.Code), sku.Code)
+				for name := range sku.Options {
+					assert.Equal(t, strings.TrimSpace(name), name)
+				}
+			}
+		})
+	}
+}
+
+func TestProductService_QuoteSKU(t *testing.T) {
+	rates, err := exchange.NewStaticRateProvider("CNY", map[string]money.Rate{"USD": money.MustParseRate("0.5")})
+	assert.NoError(t, err)
+	s := &ProductService{rates: rates}
+
+	override := money.MustParseAmount("30.00")
+	product := &model.Product{
+		Price:    money.MustParseAmount("10.00"),
+		Currency: "CNY",
+		Prices:   []model.ProductPrice{{Currency: "USD", Price: money.MustParseAmount("4.00")}},
+	}
+	plain := &model.ProductSKU{}
+	priced := &model.ProductSKU{Price: &override}
+
+	tests := []struct {
+		name     string
+		sku      *model.ProductSKU
+		currency string
+		expected string
+	}{
+		{name: "product price", sku: nil, currency: "CNY", expected: "10.00"},
+		{name: "sku without price uses product price", sku: plain, currency: "CNY", expected: "10.00"},
+		{name: "sku without price uses currency price", sku: plain, currency: "USD", expected: "4.00"},
+		{name: "sku price", sku: priced, currency: "CNY", expected: "30.00"},
+		{name: "sku price converted by rate", sku: priced, currency: "USD", expected: "15.00"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			price, _, err := s.Quote(product, tt.sku, tt.currency)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, price.String())
+		})
+	}
+}
+
+func TestProductService_ReservedSKUCodes(t *testing.T) {
+	shop := newTestShop(t, nil)
+	product := shop.createProduct(t, "10.00", 5)
+	defaultSKU := product.SKUs[0]
+	require.Equal(t, model.DefaultSKUCode(product.ID), defaultSKU.Code)
+
+	// 商户不能占用之后创建的商品的默认SKU编码
+	reserved := model.DefaultSKUCode(product.ID + 1)
+	assert.Equal(t, errors.ErrInvalidInput, shop.products.CreateSKU(7, product.ID, &model.ProductSKU{Code: reserved, Stock: 1}))
+	next := shop.createProduct(t, "20.00", 1)
+	assert.Equal(t, reserved, next.SKUs[0].Code)
+
+	// 默认SKU更新时可以保留生成的编码
+	defaultSKU.Stock = 8
+	require.NoError(t, shop.products.UpdateSKU(7, product.ID, &defaultSKU))
+	assert.Equal(t, 8, shop.skuStock(t, defaultSKU.ID))
+
+	// 其他SKU不能改用保留编码
+	extra := &model.ProductSKU{Code: "TEA-L", Stock: 1}
+	require.NoError(t, shop.products.CreateSKU(7, product.ID, extra))
+	extra.Code = model.DefaultSKUCode(product.ID + 100)
+	assert.Equal(t, errors.ErrInvalidInput, shop.products.UpdateSKU(7, product.ID, extra))
+
+	// 导入可以按生成的编码更新默认SKU，但不能以保留编码新增SKU
+	csv := "sku,product_id,stock\n" + defaultSKU.Code + ",,3\n" + model.DefaultSKUCode(product.ID+100) + "," +
+		strconv.FormatInt(product.ID, 10) + ",1\n"
+	result, err := shop.products.Import(strings.NewReader(csv), catalog.FormatCSV, ProductImportOptions{ChunkSize: 1})
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.Updated)
+	require.Len(t, result.Errors, 1)
+	assert.Equal(t, 3, result.Errors[0].Line)
+	assert.Equal(t, 3, shop.skuStock(t, defaultSKU.ID))
+}
@@ -0,0 +1,78 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/ylh990835774/blockchain-shop-demo/internal/repository/mysql"
+	"github.com/ylh990835774/blockchain-shop-demo/pkg/logger"
+)
+
+const (
+	// defaultSweepInterval 未配置时清理任务的执行间隔
+	defaultSweepInterval = time.Minute
+	// sweepBatchSize 每轮最多处理的过期预留数量
+	sweepBatchSize = 100
+)
+
+// ReservationSweeper 定期取消超时未支付的订单并释放库存预留
+type ReservationSweeper struct {
+	orderService    IOrderService
+	reservationRepo *mysql.ReservationRepository
+	interval        time.Duration
+}
+
+func NewReservationSweeper(orderService IOrderService, reservationRepo *mysql.ReservationRepository, interval time.Duration) *ReservationSweeper {
+	if interval <= 0 {
+		interval = defaultSweepInterval
+	}
+	return &ReservationSweeper{
+		orderService:    orderService,
+		reservationRepo: reservationRepo,
+		interval:        interval,
+	}
+}
+
+// Run 按间隔执行清理，直到ctx被取消
+func (s *ReservationSweeper) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := s.Sweep(); err != nil {
+				logger.Error("清理过期库存预留失败", logger.Err(err))
+			}
+		}
+	}
+}
+
+// Sweep 执行一轮清理，返回被取消的订单数量
+func (s *ReservationSweeper) Sweep() (int, error) {
+	reservations, err := s.reservationRepo.ListExpired(time.Now(), sweepBatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	expired := 0
+	for _, reservation := range reservations {
+		// 通过订单服务取消订单，保证状态变更与库存归还在同一事务中
+		if err := s.orderService.Expire(reservation.OrderID); err != nil {
+			logger.Error("取消超时订单失败",
+				logger.Int64("order_id", reservation.OrderID),
+				logger.Err(err),
+			)
+			continue
+		}
+		logger.Info("超时订单已取消",
+			logger.Int64("order_id", reservation.OrderID),
+			logger.Int64("product_id", reservation.ProductID),
+		)
+		expired++
+	}
+
+	return expired, nil
+}
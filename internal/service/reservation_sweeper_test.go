@@ -0,0 +1,56 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/ylh990835774/blockchain-shop-demo/internal/model"
+	"github.com/ylh990835774/blockchain-shop-demo/pkg/money"
+)
+
+func TestReservationSweeper_Sweep(t *testing.T) {
+	setupTestLogger(t)
+
+	shop := newTestShop(t, nil)
+	user := shop.createUser(t, "buyer")
+	product := shop.createProduct(t, "50.00", 5)
+	coupon := &model.Coupon{
+		Code: "SAVE5", Type: model.CouponTypeFixed, AmountOff: money.MustParseAmount("5"), Currency: "CNY",
+	}
+	require.NoError(t, shop.couponRepo.CreateWithTx(shop.db, coupon))
+
+	expired, err := shop.orders.Create(&CreateOrderInput{UserID: user.ID, ProductID: product.ID, Quantity: 2, CouponCode: "SAVE5"})
+	require.NoError(t, err)
+	live, err := shop.orders.Create(&CreateOrderInput{UserID: user.ID, ProductID: product.ID, Quantity: 1})
+	require.NoError(t, err)
+	paid, err := shop.orders.Create(&CreateOrderInput{UserID: user.ID, ProductID: product.ID, Quantity: 1})
+	require.NoError(t, err)
+	require.NoError(t, shop.orders.MarkPaid(paid.ID))
+	shop.expireReservation(t, expired.ID)
+	assert.Equal(t, 1, shop.skuStock(t, product.SKUs[0].ID))
+
+	sweeper := NewReservationSweeper(shop.orders, shop.reservationRepo, 0)
+	n, err := sweeper.Sweep()
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+
+	// 只有过期的预留被释放，库存和优惠券使用次数归还
+	assert.Equal(t, 3, shop.skuStock(t, product.SKUs[0].ID))
+	assert.Equal(t, model.ReservationStatusReleased, shop.reservation(t, expired.ID).Status)
+	assert.Equal(t, model.ReservationStatusActive, shop.reservation(t, live.ID).Status)
+	assert.Equal(t, model.ReservationStatusConverted, shop.reservation(t, paid.ID).Status)
+	stored, err := shop.couponRepo.GetByID(coupon.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 0, stored.UsedCount)
+
+	order, err := shop.orders.GetByID(expired.ID)
+	require.NoError(t, err)
+	assert.Equal(t, model.OrderStatusCancelled, order.Status)
+
+	// 已释放的预留不会被再次处理
+	n, err = sweeper.Sweep()
+	require.NoError(t, err)
+	assert.Zero(t, n)
+	assert.Equal(t, 3, shop.skuStock(t, product.SKUs[0].ID))
+}
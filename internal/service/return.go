@@ -0,0 +1,382 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ylh990835774/blockchain-shop-demo/internal/blockchain"
+	"github.com/ylh990835774/blockchain-shop-demo/internal/model"
+	"github.com/ylh990835774/blockchain-shop-demo/internal/repository/mysql"
+	"github.com/ylh990835774/blockchain-shop-demo/pkg/errors"
+	"github.com/ylh990835774/blockchain-shop-demo/pkg/logger"
+	"github.com/ylh990835774/blockchain-shop-demo/pkg/money"
+	"gorm.io/gorm"
+)
+
+// activeReturnStatuses 占用订单可退数量的退货状态
+var activeReturnStatuses = []model.ReturnStatus{
+	model.ReturnStatusRequested,
+	model.ReturnStatusApproved,
+	model.ReturnStatusReceived,
+	model.ReturnStatusRefunded,
+}
+
+type ReturnService struct {
+	repo                 *mysql.ReturnRepository
+	orderRepo            *mysql.OrderRepository
+	productRepo          *mysql.ProductRepository
+	paymentService       IPaymentService
+	cryptoPaymentService ICryptoPaymentService
+	chain                blockchain.Service
+	db                   *gorm.DB
+}
+
+// 确保ReturnService实现了IReturnService接口
+var _ IReturnService = (*ReturnService)(nil)
+
+// NewReturnService 创建退货服务，cryptoPaymentService为空时不支持链上转账支付订单的退款
+func NewReturnService(repo *mysql.ReturnRepository, orderRepo *mysql.OrderRepository, productRepo *mysql.ProductRepository,
+	paymentService IPaymentService, cryptoPaymentService ICryptoPaymentService, chain blockchain.Service, db *gorm.DB) IReturnService {
+	return &ReturnService{
+		repo:                 repo,
+		orderRepo:            orderRepo,
+		productRepo:          productRepo,
+		paymentService:       paymentService,
+		cryptoPaymentService: cryptoPaymentService,
+		chain:                chain,
+		db:                   db,
+	}
+}
+
+func (s *ReturnService) Request(userID, orderID int64, quantity int, reason string) (*model.ReturnRequest, error) {
+	reason = strings.TrimSpace(reason)
+	if orderID <= 0 || quantity <= 0 || reason == "" {
+		return nil, errors.ErrInvalidInput
+	}
+
+	tx := s.db.Begin()
+	if tx.Error != nil {
+		return nil, tx.Error
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	// 锁定订单，避免并发申请超出订单数量
+	order, err := s.orderRepo.GetByIDForUpdateWithTx(tx, orderID)
+	if err != nil {
+		tx.Rollback()
+		if err == mysql.ErrNotFound {
+			return nil, errors.ErrNotFound
+		}
+		return nil, err
+	}
+	if order.UserID != userID {
+		tx.Rollback()
+		return nil, errors.ErrUnauthorized
+	}
+	if order.Status != model.OrderStatusComplete {
+		tx.Rollback()
+		return nil, errors.ErrInvalidOrderStatus
+	}
+
+	returned, err := s.repo.SumWithTx(tx, orderID, activeReturnStatuses...)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	if returned.Quantity+quantity > order.Quantity {
+		tx.Rollback()
+		return nil, errors.ErrInvalidInput
+	}
+
+	// 按数量比例退款，退完最后一件时退还剩余金额，保证退款合计等于订单金额
+	refundAmount := order.TotalPrice - returned.RefundAmount
+	if returned.Quantity+quantity < order.Quantity {
+		refundAmount = money.Amount(int64(order.TotalPrice) * int64(quantity) / int64(order.Quantity))
+	}
+
+	ret := &model.ReturnRequest{
+		OrderID:      orderID,
+		UserID:       userID,
+		Quantity:     quantity,
+		Reason:       reason,
+		Status:       model.ReturnStatusRequested,
+		RefundAmount: refundAmount,
+		Currency:     order.Currency,
+	}
+	if err := s.repo.CreateWithTx(tx, ret); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	s.recordOnChain(ret, blockchain.EventReturnRequested, map[string]interface{}{
+		"quantity":      ret.Quantity,
+		"reason":        ret.Reason,
+		"refund_amount": ret.RefundAmount,
+		"currency":      ret.Currency,
+	})
+	return ret, nil
+}
+
+func (s *ReturnService) Approve(id int64, note string) (*model.ReturnRequest, error) {
+	return s.review(id, model.ReturnStatusApproved, note, blockchain.EventReturnApproved)
+}
+
+func (s *ReturnService) Reject(id int64, note string) (*model.ReturnRequest, error) {
+	return s.review(id, model.ReturnStatusRejected, note, blockchain.EventReturnRejected)
+}
+
+func (s *ReturnService) Receive(id int64) (*model.ReturnRequest, error) {
+	if id <= 0 {
+		return nil, errors.ErrInvalidInput
+	}
+
+	ret, err := s.repo.GetByID(id)
+	if err != nil {
+		if err == mysql.ErrNotFound {
+			return nil, errors.ErrNotFound
+		}
+		return nil, err
+	}
+
+	// 上次退款失败的申请已恢复库存，直接重试退款
+	if ret.Status != model.ReturnStatusReceived {
+		if err := s.restock(ret); err != nil {
+			return nil, err
+		}
+	}
+
+	// 先抢占退货状态，避免并发请求重复退款
+	claimed, err := s.repo.TransitionStatus(id, model.ReturnStatusReceived, model.ReturnStatusRefunded)
+	if err != nil {
+		return nil, err
+	}
+	if !claimed {
+		return nil, errors.ErrInvalidOrderStatus
+	}
+
+	refundRef, err := s.refund(ret)
+	if err != nil {
+		if _, revertErr := s.repo.TransitionStatus(id, model.ReturnStatusRefunded, model.ReturnStatusReceived); revertErr != nil {
+			logger.Error("回滚退货状态失败", logger.Int64("return_id", id), logger.Err(revertErr))
+		}
+		return nil, err
+	}
+
+	ret.Status = model.ReturnStatusRefunded
+	ret.RefundRef = refundRef
+	if err := s.repo.Update(id, map[string]interface{}{"refund_ref": refundRef}); err != nil {
+		logger.Error("保存退款流水号失败", logger.Int64("return_id", id), logger.String("refund_ref", refundRef), logger.Err(err))
+	}
+	s.markOrderRefunded(ret.OrderID)
+
+	s.recordOnChain(ret, blockchain.EventReturnRefunded, map[string]interface{}{
+		"refund_amount": ret.RefundAmount,
+		"currency":      ret.Currency,
+		"refund_ref":    ret.RefundRef,
+	})
+	return ret, nil
+}
+
+func (s *ReturnService) GetByID(id int64) (*model.ReturnRequest, error) {
+	if id <= 0 {
+		return nil, errors.ErrInvalidInput
+	}
+
+	ret, err := s.repo.GetByID(id)
+	if err != nil {
+		if err == mysql.ErrNotFound {
+			return nil, errors.ErrNotFound
+		}
+		return nil, err
+	}
+	return ret, nil
+}
+
+func (s *ReturnService) ListByOrderID(orderID int64) ([]*model.ReturnRequest, error) {
+	if orderID <= 0 {
+		return nil, errors.ErrInvalidInput
+	}
+	return s.repo.ListByOrderID(orderID)
+}
+
+// review 审核待处理的退货申请
+func (s *ReturnService) review(id int64, status model.ReturnStatus, note, eventType string) (*model.ReturnRequest, error) {
+	if id <= 0 {
+		return nil, errors.ErrInvalidInput
+	}
+	note = strings.TrimSpace(note)
+
+	tx := s.db.Begin()
+	if tx.Error != nil {
+		return nil, tx.Error
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	ret, err := s.repo.GetByIDForUpdateWithTx(tx, id)
+	if err != nil {
+		tx.Rollback()
+		if err == mysql.ErrNotFound {
+			return nil, errors.ErrNotFound
+		}
+		return nil, err
+	}
+	if ret.Status != model.ReturnStatusRequested {
+		tx.Rollback()
+		return nil, errors.ErrInvalidOrderStatus
+	}
+
+	if err := s.repo.UpdateWithTx(tx, id, map[string]interface{}{
+		"status":        status,
+		"merchant_note": note,
+	}); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	ret.Status = status
+	ret.MerchantNote = note
+	s.recordOnChain(ret, eventType, map[string]interface{}{
+		"note": ret.MerchantNote,
+	})
+	return ret, nil
+}
+
+// restock 确认收到退回商品并恢复库存
+func (s *ReturnService) restock(ret *model.ReturnRequest) error {
+	tx := s.db.Begin()
+	if tx.Error != nil {
+		return tx.Error
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	// 与申请退货保持相同的加锁顺序：先订单后退货申请
+	order, err := s.orderRepo.GetByIDForUpdateWithTx(tx, ret.OrderID)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	current, err := s.repo.GetByIDForUpdateWithTx(tx, ret.ID)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	if current.Status != model.ReturnStatusApproved {
+		tx.Rollback()
+		return errors.ErrInvalidOrderStatus
+	}
+
+	if err := s.productRepo.UpdateStockWithTx(tx, &model.InventoryMovement{
+		SKUID:    order.SKUID,
+		Type:     model.MovementTypeReturn,
+		Quantity: current.Quantity,
+		OrderID:  &order.ID,
+		Reason:   fmt.Sprintf("退货申请#%d", current.ID),
+	}); err != nil {
+		if err != mysql.ErrNotFound {
+			tx.Rollback()
+			return err
+		}
+		// 商品或SKU已删除时无需恢复库存
+		logger.Warn("退货商品不存在，跳过恢复库存",
+			logger.Int64("return_id", ret.ID),
+			logger.Int64("product_id", order.ProductID),
+			logger.Int64("sku_id", order.SKUID),
+		)
+	}
+
+	if err := s.repo.UpdateWithTx(tx, ret.ID, map[string]interface{}{
+		"status": model.ReturnStatusReceived,
+	}); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	ret.Status = model.ReturnStatusReceived
+	s.recordOnChain(ret, blockchain.EventReturnReceived, map[string]interface{}{
+		"product_id": order.ProductID,
+		"quantity":   ret.Quantity,
+	})
+	return nil
+}
+
+// refund 通过订单的原支付方式退款
+func (s *ReturnService) refund(ret *model.ReturnRequest) (string, error) {
+	refundRef, err := s.paymentService.Refund(ret.OrderID, ret.RefundAmount)
+	if err != errors.ErrNotFound {
+		return refundRef, err
+	}
+
+	if s.cryptoPaymentService != nil {
+		refundRef, err = s.cryptoPaymentService.Refund(ret.OrderID, ret.RefundAmount)
+		if err != errors.ErrNotFound {
+			return refundRef, err
+		}
+	}
+
+	logger.Error("未找到订单的成功支付记录，无法退款", logger.Int64("return_id", ret.ID), logger.Int64("order_id", ret.OrderID))
+	return "", errors.ErrInvalidOrderStatus
+}
+
+// markOrderRefunded 订单全部商品退款后将订单标记为已退款
+func (s *ReturnService) markOrderRefunded(orderID int64) {
+	order, err := s.orderRepo.GetByID(orderID)
+	if err != nil {
+		logger.Error("获取订单失败", logger.Int64("order_id", orderID), logger.Err(err))
+		return
+	}
+
+	refunded, err := s.repo.SumWithTx(s.db, orderID, model.ReturnStatusRefunded)
+	if err != nil {
+		logger.Error("统计订单退款失败", logger.Int64("order_id", orderID), logger.Err(err))
+		return
+	}
+	if refunded.Quantity < order.Quantity {
+		return
+	}
+
+	if err := s.orderRepo.Update(orderID, map[string]interface{}{
+		"status": model.OrderStatusRefunded,
+	}); err != nil {
+		logger.Error("更新订单状态失败", logger.Int64("order_id", orderID), logger.Err(err))
+	}
+}
+
+// recordOnChain 将退货流程的每一步上链，状态已更新，上链失败只记录日志
+func (s *ReturnService) recordOnChain(ret *model.ReturnRequest, eventType string, payload map[string]interface{}) {
+	payload["return_id"] = ret.ID
+	payload["status"] = ret.Status
+	if _, err := blockchain.RecordOrderEvent(s.chain, ret.OrderID, eventType, payload); err != nil {
+		logger.Error("退货记录上链失败",
+			logger.Int64("return_id", ret.ID),
+			logger.String("event", eventType),
+			logger.Err(err),
+		)
+	}
+}
@@ -0,0 +1,288 @@
+package service
+
+import (
+	"strings"
+	"time"
+
+	"github.com/ylh990835774/blockchain-shop-demo/internal/blockchain"
+	"github.com/ylh990835774/blockchain-shop-demo/internal/model"
+	"github.com/ylh990835774/blockchain-shop-demo/internal/repository/mysql"
+	"github.com/ylh990835774/blockchain-shop-demo/pkg/errors"
+	"github.com/ylh990835774/blockchain-shop-demo/pkg/logger"
+	"gorm.io/gorm"
+)
+
+type ShipmentService struct {
+	repo      *mysql.ShipmentRepository
+	orderRepo *mysql.OrderRepository
+	chain     blockchain.Service
+	db        *gorm.DB
+}
+
+// 确保ShipmentService实现了IShipmentService接口
+var _ IShipmentService = (*ShipmentService)(nil)
+
+func NewShipmentService(repo *mysql.ShipmentRepository, orderRepo *mysql.OrderRepository, chain blockchain.Service,
+	db *gorm.DB) IShipmentService {
+	return &ShipmentService{
+		repo:      repo,
+		orderRepo: orderRepo,
+		chain:     chain,
+		db:        db,
+	}
+}
+
+func (s *ShipmentService) Create(orderID int64, carrier, trackingNumber string) (*model.Shipment, error) {
+	carrier = strings.TrimSpace(carrier)
+	trackingNumber = strings.TrimSpace(trackingNumber)
+	if orderID <= 0 || carrier == "" || trackingNumber == "" {
+		return nil, errors.ErrInvalidInput
+	}
+
+	tx := s.db.Begin()
+	if tx.Error != nil {
+		return nil, tx.Error
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	order, err := s.orderRepo.GetByIDForUpdateWithTx(tx, orderID)
+	if err != nil {
+		tx.Rollback()
+		if err == mysql.ErrNotFound {
+			return nil, errors.ErrNotFound
+		}
+		return nil, err
+	}
+	if order.Status != model.OrderStatusPaid {
+		tx.Rollback()
+		return nil, errors.ErrInvalidOrderStatus
+	}
+
+	now := time.Now()
+	shipment := &model.Shipment{
+		OrderID:        orderID,
+		Carrier:        carrier,
+		TrackingNumber: trackingNumber,
+		Status:         model.ShipmentStatusShipped,
+		ShippedAt:      now,
+	}
+	if err := s.repo.CreateWithTx(tx, shipment); err != nil {
+		tx.Rollback()
+		if err == mysql.ErrDuplicateKey {
+			return nil, errors.ErrDuplicateEntry
+		}
+		return nil, err
+	}
+
+	event := model.ShipmentEvent{
+		ShipmentID:  shipment.ID,
+		Status:      model.ShipmentStatusShipped,
+		Description: "已发货",
+		OccurredAt:  now,
+	}
+	if err := s.repo.CreateEventWithTx(tx, &event); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	shipment.Events = []model.ShipmentEvent{event}
+
+	if err := s.orderRepo.UpdateWithTx(tx, orderID, map[string]interface{}{
+		"status": model.OrderStatusShipped,
+	}); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	s.recordOnChain(orderID, blockchain.EventOrderShipped, map[string]interface{}{
+		"shipment_id":     shipment.ID,
+		"carrier":         shipment.Carrier,
+		"tracking_number": shipment.TrackingNumber,
+	})
+	return shipment, nil
+}
+
+func (s *ShipmentService) AddEvent(shipmentID int64, event *model.ShipmentEvent) (*model.Shipment, error) {
+	if shipmentID <= 0 || event == nil || !event.Status.Valid() {
+		return nil, errors.ErrInvalidInput
+	}
+	if event.OccurredAt.IsZero() {
+		event.OccurredAt = time.Now()
+	}
+	event.ShipmentID = shipmentID
+
+	tx := s.db.Begin()
+	if tx.Error != nil {
+		return nil, tx.Error
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	// 与确认收货保持相同的加锁顺序：先订单后发货记录
+	current, err := s.repo.GetByID(shipmentID)
+	if err != nil {
+		tx.Rollback()
+		if err == mysql.ErrNotFound {
+			return nil, errors.ErrNotFound
+		}
+		return nil, err
+	}
+	order, err := s.orderRepo.GetByIDForUpdateWithTx(tx, current.OrderID)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	shipment, err := s.repo.GetByIDForUpdateWithTx(tx, shipmentID)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	if err := s.addEventWithTx(tx, order, shipment, event); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	if event.Status == model.ShipmentStatusDelivered {
+		s.recordDelivered(shipment, event)
+	}
+	return s.repo.GetByID(shipmentID)
+}
+
+func (s *ShipmentService) ConfirmDelivery(userID, orderID int64) (*model.Shipment, error) {
+	if orderID <= 0 {
+		return nil, errors.ErrInvalidInput
+	}
+
+	tx := s.db.Begin()
+	if tx.Error != nil {
+		return nil, tx.Error
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	order, err := s.orderRepo.GetByIDForUpdateWithTx(tx, orderID)
+	if err != nil {
+		tx.Rollback()
+		if err == mysql.ErrNotFound {
+			return nil, errors.ErrNotFound
+		}
+		return nil, err
+	}
+	if order.UserID != userID {
+		tx.Rollback()
+		return nil, errors.ErrUnauthorized
+	}
+
+	shipment, err := s.repo.GetByOrderIDForUpdateWithTx(tx, orderID)
+	if err != nil {
+		tx.Rollback()
+		if err == mysql.ErrNotFound {
+			return nil, errors.ErrInvalidOrderStatus
+		}
+		return nil, err
+	}
+
+	event := &model.ShipmentEvent{
+		ShipmentID:  shipment.ID,
+		Status:      model.ShipmentStatusDelivered,
+		Description: "买家确认收货",
+		OccurredAt:  time.Now(),
+	}
+	if err := s.addEventWithTx(tx, order, shipment, event); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	s.recordDelivered(shipment, event)
+	return s.repo.GetByID(shipment.ID)
+}
+
+func (s *ShipmentService) GetByOrderID(orderID int64) (*model.Shipment, error) {
+	if orderID <= 0 {
+		return nil, errors.ErrInvalidInput
+	}
+
+	shipment, err := s.repo.GetByOrderID(orderID)
+	if err != nil {
+		if err == mysql.ErrNotFound {
+			return nil, errors.ErrNotFound
+		}
+		return nil, err
+	}
+	return shipment, nil
+}
+
+// addEventWithTx 记录跟踪事件并更新发货状态，签收时将订单标记为已完成
+func (s *ShipmentService) addEventWithTx(tx *gorm.DB, order *model.Order, shipment *model.Shipment,
+	event *model.ShipmentEvent) error {
+	if shipment.Status == model.ShipmentStatusDelivered {
+		return errors.ErrInvalidOrderStatus
+	}
+	if event.Status == model.ShipmentStatusDelivered && order.Status != model.OrderStatusShipped {
+		return errors.ErrInvalidOrderStatus
+	}
+
+	if err := s.repo.CreateEventWithTx(tx, event); err != nil {
+		return err
+	}
+
+	updates := map[string]interface{}{"status": event.Status}
+	if event.Status == model.ShipmentStatusDelivered {
+		updates["delivered_at"] = event.OccurredAt
+		if err := s.orderRepo.UpdateWithTx(tx, order.ID, map[string]interface{}{
+			"status": model.OrderStatusComplete,
+		}); err != nil {
+			return err
+		}
+	}
+
+	if err := s.repo.UpdateWithTx(tx, shipment.ID, updates); err != nil {
+		return err
+	}
+	shipment.Status = event.Status
+	return nil
+}
+
+func (s *ShipmentService) recordDelivered(shipment *model.Shipment, event *model.ShipmentEvent) {
+	s.recordOnChain(shipment.OrderID, blockchain.EventOrderDelivered, map[string]interface{}{
+		"shipment_id":  shipment.ID,
+		"delivered_at": event.OccurredAt,
+		"description":  event.Description,
+	})
+}
+
+// recordOnChain 将履约事件上链，订单状态已更新，上链失败只记录日志
+func (s *ShipmentService) recordOnChain(orderID int64, eventType string, payload map[string]interface{}) {
+	if _, err := blockchain.RecordOrderEvent(s.chain, orderID, eventType, payload); err != nil {
+		logger.Error("履约事件上链失败",
+			logger.Int64("order_id", orderID),
+			logger.String("event", eventType),
+			logger.Err(err),
+		)
+	}
+}
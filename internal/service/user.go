@@ -32,6 +32,7 @@ func (s *userService) Register(username, password string) (*model.User, error) {
 	// 创建新用户
 	user := &model.User{
 		Username: username,
+		Role:     model.UserRoleCustomer,
 	}
 
 	// 设置加密密码
@@ -86,10 +87,25 @@ func (s *userService) GetByID(id int64) (*model.User, error) {
 	return user, nil
 }
 
-func (s *userService) Update(id int64, updates map[string]interface{}) error {
+func (s *userService) Update(id int64, patch model.UserPatch) error {
 	if id <= 0 {
 		return errors.ErrInvalidInput
 	}
+	if patch.IsEmpty() {
+		return errors.ErrNoFieldsToUpdate
+	}
+
+	// 只写入允许修改的字段
+	updates := make(map[string]interface{})
+	if patch.Phone != nil {
+		updates["phone"] = *patch.Phone
+	}
+	if patch.Address != nil {
+		updates["address"] = *patch.Address
+	}
+	if patch.Currency != nil {
+		updates["currency"] = *patch.Currency
+	}
 
 	if err := s.repo.Update(id, updates); err != nil {
 		if err == mysql.ErrNotFound {
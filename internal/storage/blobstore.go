@@ -0,0 +1,39 @@
+// Package storage 提供商品图片等二进制对象的存储
+package storage
+
+import (
+	"errors"
+	"strings"
+)
+
+var (
+	// ErrNotFound 对象不存在
+	ErrNotFound = errors.New("object not found")
+	// ErrInvalidKey 对象键为空或包含不允许的路径
+	ErrInvalidKey = errors.New("invalid object key")
+)
+
+// BlobStore 二进制对象存储接口，对象以键标识，键使用/分隔层级
+type BlobStore interface {
+	// Put 写入对象，已存在时覆盖
+	Put(key string, data []byte, contentType string) error
+	// Get 读取对象，不存在时返回ErrNotFound
+	Get(key string) ([]byte, error)
+	// Delete 删除对象，对象不存在时不返回错误
+	Delete(key string) error
+	// URL 返回对象的公开访问地址
+	URL(key string) string
+}
+
+// validKey 检查对象键不为空且不包含空路径段或相对路径
+func validKey(key string) bool {
+	if key == "" || strings.HasPrefix(key, "/") || strings.Contains(key, "\\") {
+		return false
+	}
+	for _, segment := range strings.Split(key, "/") {
+		if segment == "" || segment == "." || segment == ".." {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,39 @@
+package storage
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLocalStore(t *testing.T) {
+	dir := t.TempDir()
+	store, err := NewLocalStore(filepath.Join(dir, "media"), "http://localhost:38080/media/")
+	require.NoError(t, err)
+
+	require.NoError(t, store.Put("products/1/a.png", []byte("png"), "image/png"))
+	data, err := store.Get("products/1/a.png")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("png"), data)
+	assert.Equal(t, "http://localhost:38080/media/products/1/a.png", store.URL("products/1/a.png"))
+
+	// 覆盖已有对象
+	require.NoError(t, store.Put("products/1/a.png", []byte("png2"), "image/png"))
+	data, err = store.Get("products/1/a.png")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("png2"), data)
+
+	require.NoError(t, store.Delete("products/1/a.png"))
+	require.NoError(t, store.Delete("products/1/a.png"))
+	_, err = store.Get("products/1/a.png")
+	assert.Equal(t, ErrNotFound, err)
+
+	for _, key := range []string{"", "/etc/passwd", "../outside", "products/../../outside", "products//a.png", `a\b`} {
+		assert.Equal(t, ErrInvalidKey, store.Put(key, []byte("x"), ""), key)
+	}
+	_, err = os.Stat(filepath.Join(dir, "outside"))
+	assert.True(t, os.IsNotExist(err))
+}
@@ -0,0 +1,158 @@
+package tax
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/ylh990835774/blockchain-shop-demo/pkg/money"
+)
+
+// CategoryStandard 未指定税目的商品使用标准税率
+const CategoryStandard = "standard"
+
+var (
+	ErrUnknownRegion   = errors.New("unknown tax region")
+	ErrUnknownCategory = errors.New("unknown tax category")
+)
+
+// Region 税区的税率配置
+type Region struct {
+	Inclusive bool                  // 商品价格是否为含税价
+	Rates     map[string]money.Rate // 税目到税率的映射，零税率的税目税额为零
+}
+
+// Line 一行商品的计税结果
+type Line struct {
+	Region    string
+	Category  string
+	Rate      money.Rate
+	Inclusive bool
+	Net       money.Amount // 不含税金额
+	Tax       money.Amount
+	Gross     money.Amount // 含税金额，即应付金额
+}
+
+// Calculator 按税区和商品税目计算税额
+type Calculator struct {
+	defaultRegion string
+	regions       map[string]Region
+	categories    []string
+}
+
+// NewCalculator 创建计税器。每个税区必须配置相同的税目，且包含标准税目
+func NewCalculator(defaultRegion string, regions map[string]Region) (*Calculator, error) {
+	c := &Calculator{
+		defaultRegion: strings.ToUpper(defaultRegion),
+		regions:       make(map[string]Region, len(regions)),
+	}
+
+	for code, region := range regions {
+		code = strings.ToUpper(code)
+		rates := make(map[string]money.Rate, len(region.Rates))
+		for category, rate := range region.Rates {
+			if rate < 0 {
+				return nil, fmt.Errorf("tax region %s: negative rate for %s", code, category)
+			}
+			rates[strings.ToLower(category)] = rate
+		}
+		if _, ok := rates[CategoryStandard]; !ok {
+			return nil, fmt.Errorf("tax region %s: missing %s rate", code, CategoryStandard)
+		}
+		c.regions[code] = Region{Inclusive: region.Inclusive, Rates: rates}
+	}
+
+	def, ok := c.regions[c.defaultRegion]
+	if !ok {
+		return nil, fmt.Errorf("default tax region %q is not configured", defaultRegion)
+	}
+	for category := range def.Rates {
+		c.categories = append(c.categories, category)
+	}
+	sort.Strings(c.categories)
+
+	for code, region := range c.regions {
+		if len(region.Rates) != len(def.Rates) {
+			return nil, fmt.Errorf("tax region %s: categories differ from default region", code)
+		}
+		for category := range def.Rates {
+			if _, ok := region.Rates[category]; !ok {
+				return nil, fmt.Errorf("tax region %s: missing %s rate", code, category)
+			}
+		}
+	}
+	return c, nil
+}
+
+// NoTax 返回不计税的计税器，价格视为含税价，税额为零
+func NoTax(region string) *Calculator {
+	c, _ := NewCalculator(region, map[string]Region{
+		region: {Inclusive: true, Rates: map[string]money.Rate{CategoryStandard: 0}},
+	})
+	return c
+}
+
+// DefaultRegion 未指定税区时使用的税区
+func (c *Calculator) DefaultRegion() string {
+	return c.defaultRegion
+}
+
+// Categories 已配置的税目
+func (c *Calculator) Categories() []string {
+	return c.categories
+}
+
+// HasCategory 税目是否已配置，空税目视为标准税目
+func (c *Calculator) HasCategory(category string) bool {
+	_, ok := c.regions[c.defaultRegion].Rates[normalizeCategory(category)]
+	return ok
+}
+
+// Compute 计算一行商品的税额。region为空时使用默认税区，category为空时使用标准税目；
+// 含税价的税区从金额中拆分税额，不含税价的税区在金额之上加收税额
+func (c *Calculator) Compute(region, category string, unitPrice money.Amount, quantity int) (*Line, error) {
+	return c.ComputeAmount(region, category, unitPrice.Mul(quantity))
+}
+
+// ComputeAmount 计算指定金额的税额，用于扣除优惠后的商品金额，规则同Compute
+func (c *Calculator) ComputeAmount(region, category string, amount money.Amount) (*Line, error) {
+	if region == "" {
+		region = c.defaultRegion
+	}
+	region = strings.ToUpper(region)
+	category = normalizeCategory(category)
+
+	r, ok := c.regions[region]
+	if !ok {
+		return nil, ErrUnknownRegion
+	}
+	rate, ok := r.Rates[category]
+	if !ok {
+		return nil, ErrUnknownCategory
+	}
+
+	line := &Line{
+		Region:    region,
+		Category:  category,
+		Rate:      rate,
+		Inclusive: r.Inclusive,
+	}
+	if r.Inclusive {
+		line.Gross = amount
+		line.Net = (money.RateOne + rate).ConvertBack(amount)
+		line.Tax = line.Gross - line.Net
+	} else {
+		line.Net = amount
+		line.Tax = rate.Convert(amount)
+		line.Gross = line.Net + line.Tax
+	}
+	return line, nil
+}
+
+func normalizeCategory(category string) string {
+	if category == "" {
+		return CategoryStandard
+	}
+	return strings.ToLower(category)
+}
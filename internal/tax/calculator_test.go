@@ -0,0 +1,135 @@
+package tax
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/ylh990835774/blockchain-shop-demo/pkg/money"
+)
+
+func newTestCalculator(t *testing.T) *Calculator {
+	c, err := NewCalculator("cn", map[string]Region{
+		"cn": {Inclusive: true, Rates: map[string]money.Rate{
+			"standard": money.MustParseRate("0.13"),
+			"reduced":  money.MustParseRate("0.09"),
+			"exempt":   0,
+		}},
+		"US": {Inclusive: false, Rates: map[string]money.Rate{
+			"Standard": money.MustParseRate("0.0725"),
+			"reduced":  money.MustParseRate("0.0725"),
+			"exempt":   0,
+		}},
+	})
+	require.NoError(t, err)
+	return c
+}
+
+func TestCalculator_Compute(t *testing.T) {
+	c := newTestCalculator(t)
+
+	tests := []struct {
+		name        string
+		region      string
+		category    string
+		unitPrice   string
+		quantity    int
+		expected    Line
+		expectedErr error
+	}{
+		{
+			name:      "inclusive splits tax from price",
+			unitPrice: "9.99",
+			quantity:  2,
+			expected: Line{Region: "CN", Category: "standard", Rate: money.MustParseRate("0.13"), Inclusive: true,
+				Net: money.MustParseAmount("17.68"), Tax: money.MustParseAmount("2.30"), Gross: money.MustParseAmount("19.98")},
+		},
+		{
+			name:      "reduced category",
+			region:    "cn",
+			category:  "reduced",
+			unitPrice: "109",
+			quantity:  1,
+			expected: Line{Region: "CN", Category: "reduced", Rate: money.MustParseRate("0.09"), Inclusive: true,
+				Net: money.MustParseAmount("100"), Tax: money.MustParseAmount("9"), Gross: money.MustParseAmount("109")},
+		},
+		{
+			name:      "exclusive adds tax on top",
+			region:    "US",
+			unitPrice: "10",
+			quantity:  3,
+			expected: Line{Region: "US", Category: "standard", Rate: money.MustParseRate("0.0725"), Inclusive: false,
+				Net: money.MustParseAmount("30"), Tax: money.MustParseAmount("2.18"), Gross: money.MustParseAmount("32.18")},
+		},
+		{
+			name:      "exempt",
+			region:    "US",
+			category:  "exempt",
+			unitPrice: "10",
+			quantity:  1,
+			expected: Line{Region: "US", Category: "exempt", Inclusive: false,
+				Net: money.MustParseAmount("10"), Gross: money.MustParseAmount("10")},
+		},
+		{
+			name:        "unknown region",
+			region:      "JP",
+			unitPrice:   "10",
+			quantity:    1,
+			expectedErr: ErrUnknownRegion,
+		},
+		{
+			name:        "unknown category",
+			category:    "luxury",
+			unitPrice:   "10",
+			quantity:    1,
+			expectedErr: ErrUnknownCategory,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			line, err := c.Compute(tt.region, tt.category, money.MustParseAmount(tt.unitPrice), tt.quantity)
+			if tt.expectedErr != nil {
+				assert.Equal(t, tt.expectedErr, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, *line)
+		})
+	}
+}
+
+func TestNewCalculator_Validation(t *testing.T) {
+	standard := map[string]money.Rate{"standard": money.MustParseRate("0.1")}
+
+	_, err := NewCalculator("CN", map[string]Region{"US": {Rates: standard}})
+	assert.Error(t, err, "default region must be configured")
+
+	_, err = NewCalculator("CN", map[string]Region{"CN": {Rates: map[string]money.Rate{"reduced": 0}}})
+	assert.Error(t, err, "standard category is required")
+
+	_, err = NewCalculator("CN", map[string]Region{
+		"CN": {Rates: map[string]money.Rate{"standard": 0, "reduced": 0}},
+		"US": {Rates: standard},
+	})
+	assert.Error(t, err, "regions must define the same categories")
+}
+
+func TestCalculator_HasCategory(t *testing.T) {
+	c := newTestCalculator(t)
+
+	assert.Equal(t, []string{"exempt", "reduced", "standard"}, c.Categories())
+	assert.True(t, c.HasCategory(""))
+	assert.True(t, c.HasCategory("Reduced"))
+	assert.False(t, c.HasCategory("luxury"))
+}
+
+func TestNoTax(t *testing.T) {
+	c := NoTax("CN")
+
+	line, err := c.Compute("", "", money.MustParseAmount("19.98"), 1)
+	require.NoError(t, err)
+	assert.Equal(t, money.MustParseAmount("19.98"), line.Gross)
+	assert.Equal(t, money.MustParseAmount("19.98"), line.Net)
+	assert.Equal(t, money.Amount(0), line.Tax)
+}
@@ -0,0 +1,64 @@
+// Package testutil 提供各包测试共用的辅助函数
+package testutil
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/glebarez/sqlite"
+	"github.com/stretchr/testify/require"
+	"github.com/ylh990835774/blockchain-shop-demo/internal/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// Models 测试数据库自动迁移的全部模型，新增模型时在此登记
+var Models = []interface{}{
+	&model.User{},
+	&model.Product{},
+	&model.ProductPrice{},
+	&model.ProductSKU{},
+	&model.ProductImage{},
+	&model.Order{},
+	&model.OrderTaxLine{},
+	&model.Transaction{},
+	&model.IdempotencyKey{},
+	&model.StockReservation{},
+	&model.Payment{},
+	&model.Shipment{},
+	&model.ShipmentEvent{},
+	&model.ReturnRequest{},
+	&model.Invoice{},
+	&model.InvoiceLine{},
+	&model.InvoiceSequence{},
+	&model.Coupon{},
+	&model.CouponProduct{},
+	&model.CouponRedemption{},
+	&model.Category{},
+	&model.ProductCategory{},
+	&model.ProductPriceHistory{},
+	&model.PriceCommitment{},
+	&model.PriceCommitmentEntry{},
+	&model.InventoryMovement{},
+	&model.LowStockAlert{},
+}
+
+// NewDB 创建基于SQLite文件的测试数据库并迁移全部模型，支持多连接并发访问。测试结束时关闭连接
+func NewDB(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	dsn := filepath.Join(t.TempDir(), "test.db") +
+		"?_pragma=busy_timeout(10000)&_pragma=journal_mode(WAL)&_txlock=immediate"
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{
+		TranslateError: true,
+		Logger:         logger.Default.LogMode(logger.Silent),
+	})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(Models...))
+
+	sqlDB, err := db.DB()
+	require.NoError(t, err)
+	t.Cleanup(func() { sqlDB.Close() })
+
+	return db
+}
@@ -0,0 +1,121 @@
+// Package webhook 向外部系统推送事件通知
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/ylh990835774/blockchain-shop-demo/pkg/logger"
+)
+
+const (
+	// SignatureHeader 请求体签名请求头，值为请求体的 HMAC-SHA256 十六进制值
+	SignatureHeader = "X-Signature"
+	// EventHeader 事件类型请求头
+	EventHeader = "X-Event"
+)
+
+// Event 推送的事件
+type Event struct {
+	Type      string      `json:"type"`
+	CreatedAt time.Time   `json:"created_at"`
+	Data      interface{} `json:"data"`
+}
+
+// Notifier 事件通知接口
+type Notifier interface {
+	// Notify 推送事件，返回错误时由调用方决定是否重试
+	Notify(ctx context.Context, event *Event) error
+}
+
+// HTTPNotifier 以JSON请求体POST到指定地址，响应2xx视为送达
+type HTTPNotifier struct {
+	url    string
+	secret []byte
+	client *http.Client
+}
+
+// 确保HTTPNotifier实现了Notifier接口
+var _ Notifier = (*HTTPNotifier)(nil)
+
+// NewHTTPNotifier 创建HTTP事件通知，secret为空时不签名
+func NewHTTPNotifier(endpoint, secret string, timeout time.Duration) (*HTTPNotifier, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("invalid webhook url %q: %w", endpoint, err)
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("invalid webhook url %q", endpoint)
+	}
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+	return &HTTPNotifier{
+		url:    endpoint,
+		secret: []byte(secret),
+		client: &http.Client{Timeout: timeout},
+	}, nil
+}
+
+func (n *HTTPNotifier) Notify(ctx context.Context, event *Event) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(EventHeader, event.Type)
+	if len(n.secret) > 0 {
+		req.Header.Set(SignatureHeader, Sign(n.secret, body))
+	}
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook: unexpected status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// Sign 计算请求体签名，接收方用相同的密钥计算并比较以校验来源
+func Sign(secret, payload []byte) string {
+	h := hmac.New(sha256.New, secret)
+	h.Write(payload)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// LogNotifier 只将事件写入日志，未配置推送地址时使用
+type LogNotifier struct{}
+
+// 确保LogNotifier实现了Notifier接口
+var _ Notifier = LogNotifier{}
+
+func (LogNotifier) Notify(ctx context.Context, event *Event) error {
+	data, err := json.Marshal(event.Data)
+	if err != nil {
+		return err
+	}
+	logger.Warn("事件通知",
+		logger.String("type", event.Type),
+		logger.String("data", string(data)),
+	)
+	return nil
+}
@@ -0,0 +1,68 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHTTPNotifier(t *testing.T) {
+	var (
+		body      []byte
+		signature string
+		eventType string
+		status    = http.StatusOK
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		body, _ = io.ReadAll(r.Body)
+		signature = r.Header.Get(SignatureHeader)
+		eventType = r.Header.Get(EventHeader)
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	notifier, err := NewHTTPNotifier(server.URL, "secret", time.Second)
+	require.NoError(t, err)
+
+	event := &Event{
+		Type:      "inventory.low_stock",
+		CreatedAt: time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC),
+		Data:      map[string]interface{}{"sku_code": "TEA-S", "stock": 2},
+	}
+	require.NoError(t, notifier.Notify(context.Background(), event))
+	assert.Equal(t, "inventory.low_stock", eventType)
+	assert.Equal(t, Sign([]byte("secret"), body), signature)
+
+	var got map[string]interface{}
+	require.NoError(t, json.Unmarshal(body, &got))
+	assert.Equal(t, "inventory.low_stock", got["type"])
+	assert.Equal(t, "2024-05-01T10:00:00Z", got["created_at"])
+	assert.Equal(t, map[string]interface{}{"sku_code": "TEA-S", "stock": float64(2)}, got["data"])
+
+	// 非2xx响应视为未送达
+	status = http.StatusInternalServerError
+	assert.EqualError(t, notifier.Notify(context.Background(), event), "webhook: unexpected status 500")
+
+	// 未配置密钥时不签名
+	status = http.StatusNoContent
+	notifier, err = NewHTTPNotifier(server.URL, "", time.Second)
+	require.NoError(t, err)
+	require.NoError(t, notifier.Notify(context.Background(), event))
+	assert.Empty(t, signature)
+}
+
+func TestNewHTTPNotifier_InvalidURL(t *testing.T) {
+	for _, endpoint := range []string{"", "localhost:8080/hook", "ftp://example.com/hook", "http://"} {
+		_, err := NewHTTPNotifier(endpoint, "", 0)
+		assert.Error(t, err, endpoint)
+	}
+}
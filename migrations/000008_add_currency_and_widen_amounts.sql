@@ -0,0 +1,42 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE products
+    MODIFY COLUMN price DECIMAL(19, 2) NOT NULL,
+    ADD COLUMN currency CHAR(3) NOT NULL DEFAULT 'CNY' AFTER price;
+
+-- +goose StatementEnd
+-- +goose StatementBegin
+ALTER TABLE orders
+    MODIFY COLUMN total_price DECIMAL(19, 2) NOT NULL,
+    ADD COLUMN currency CHAR(3) NOT NULL DEFAULT 'CNY' AFTER total_price;
+
+-- +goose StatementEnd
+-- +goose StatementBegin
+ALTER TABLE payments MODIFY COLUMN amount DECIMAL(19, 2) NOT NULL;
+
+-- +goose StatementEnd
+-- +goose StatementBegin
+-- 统一交易金额为两位小数的十进制字符串
+UPDATE transactions
+SET value = CAST(CAST(value AS DECIMAL(19, 2)) AS CHAR)
+WHERE value REGEXP '^-?[0-9]+(\\.[0-9]+)?$'
+    AND value <> CAST(CAST(value AS DECIMAL(19, 2)) AS CHAR);
+
+-- +goose StatementEnd
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE payments MODIFY COLUMN amount DECIMAL(10, 2) NOT NULL;
+
+-- +goose StatementEnd
+-- +goose StatementBegin
+ALTER TABLE orders
+    DROP COLUMN currency,
+    MODIFY COLUMN total_price DECIMAL(10, 2) NOT NULL;
+
+-- +goose StatementEnd
+-- +goose StatementBegin
+ALTER TABLE products
+    DROP COLUMN currency,
+    MODIFY COLUMN price DECIMAL(10, 2) NOT NULL;
+
+-- +goose StatementEnd
@@ -0,0 +1,41 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS product_prices (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    product_id BIGINT NOT NULL,
+    currency CHAR(3) NOT NULL,
+    price DECIMAL(19, 2) NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    UNIQUE KEY uk_product_prices_product_currency (product_id, currency)
+) ENGINE = InnoDB DEFAULT CHARSET = utf8mb4 COLLATE = utf8mb4_unicode_ci;
+
+-- +goose StatementEnd
+-- +goose StatementBegin
+ALTER TABLE users ADD COLUMN currency VARCHAR(3) NOT NULL DEFAULT '' AFTER address;
+
+-- +goose StatementEnd
+-- +goose StatementBegin
+ALTER TABLE orders ADD COLUMN exchange_rate DECIMAL(18, 8) NOT NULL DEFAULT 1 AFTER currency;
+
+-- +goose StatementEnd
+-- +goose StatementBegin
+ALTER TABLE transactions ADD COLUMN currency CHAR(3) NOT NULL DEFAULT 'CNY' AFTER value;
+
+-- +goose StatementEnd
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE transactions DROP COLUMN currency;
+
+-- +goose StatementEnd
+-- +goose StatementBegin
+ALTER TABLE orders DROP COLUMN exchange_rate;
+
+-- +goose StatementEnd
+-- +goose StatementBegin
+ALTER TABLE users DROP COLUMN currency;
+
+-- +goose StatementEnd
+-- +goose StatementBegin
+DROP TABLE IF EXISTS product_prices;
+-- +goose StatementEnd
@@ -0,0 +1,74 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS shipments (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    order_id BIGINT NOT NULL,
+    carrier VARCHAR(64) NOT NULL,
+    tracking_number VARCHAR(128) NOT NULL,
+    status ENUM(
+        'shipped',
+        'in_transit',
+        'out_for_delivery',
+        'exception',
+        'delivered'
+    ) NOT NULL DEFAULT 'shipped',
+    shipped_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    delivered_at TIMESTAMP NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    UNIQUE KEY uk_shipments_order_id (order_id),
+    KEY idx_shipments_carrier_tracking_number (carrier, tracking_number)
+) ENGINE = InnoDB DEFAULT CHARSET = utf8mb4 COLLATE = utf8mb4_unicode_ci;
+
+-- +goose StatementEnd
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS shipment_events (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    shipment_id BIGINT NOT NULL,
+    status ENUM(
+        'shipped',
+        'in_transit',
+        'out_for_delivery',
+        'exception',
+        'delivered'
+    ) NOT NULL,
+    location VARCHAR(255),
+    description VARCHAR(512),
+    occurred_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    KEY idx_shipment_events_shipment_id (shipment_id, occurred_at)
+) ENGINE = InnoDB DEFAULT CHARSET = utf8mb4 COLLATE = utf8mb4_unicode_ci;
+
+-- +goose StatementEnd
+-- +goose StatementBegin
+ALTER TABLE orders ADD COLUMN shipping_address TEXT AFTER status;
+
+-- +goose StatementEnd
+-- +goose StatementBegin
+ALTER TABLE users ADD COLUMN role ENUM('customer', 'merchant', 'admin') NOT NULL DEFAULT 'customer' AFTER currency;
+
+-- +goose StatementEnd
+-- +goose StatementBegin
+-- 历史订单使用用户当前地址作为收货地址快照
+UPDATE orders o
+    JOIN users u ON u.id = o.user_id
+SET o.shipping_address = u.address
+WHERE o.shipping_address IS NULL;
+
+-- +goose StatementEnd
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE users DROP COLUMN role;
+
+-- +goose StatementEnd
+-- +goose StatementBegin
+ALTER TABLE orders DROP COLUMN shipping_address;
+
+-- +goose StatementEnd
+-- +goose StatementBegin
+DROP TABLE IF EXISTS shipment_events;
+
+-- +goose StatementEnd
+-- +goose StatementBegin
+DROP TABLE IF EXISTS shipments;
+-- +goose StatementEnd
@@ -0,0 +1,55 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS return_requests (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    order_id BIGINT NOT NULL,
+    user_id BIGINT NOT NULL,
+    quantity INT NOT NULL,
+    reason VARCHAR(512) NOT NULL,
+    status ENUM(
+        'requested',
+        'approved',
+        'rejected',
+        'received',
+        'refunded'
+    ) NOT NULL DEFAULT 'requested',
+    merchant_note VARCHAR(512),
+    refund_amount DECIMAL(19, 2) NOT NULL,
+    currency CHAR(3) NOT NULL,
+    refund_ref VARCHAR(128),
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    KEY idx_return_requests_order_id (order_id),
+    KEY idx_return_requests_status (status)
+) ENGINE = InnoDB DEFAULT CHARSET = utf8mb4 COLLATE = utf8mb4_unicode_ci;
+
+-- +goose StatementEnd
+-- +goose StatementBegin
+ALTER TABLE orders MODIFY COLUMN status ENUM(
+    'pending',
+    'paid',
+    'shipped',
+    'complete',
+    'cancelled',
+    'refunded'
+) NOT NULL DEFAULT 'pending';
+
+-- +goose StatementEnd
+-- +goose Down
+-- +goose StatementBegin
+UPDATE orders SET status = 'complete' WHERE status = 'refunded';
+
+-- +goose StatementEnd
+-- +goose StatementBegin
+ALTER TABLE orders MODIFY COLUMN status ENUM(
+    'pending',
+    'paid',
+    'shipped',
+    'complete',
+    'cancelled'
+) NOT NULL DEFAULT 'pending';
+
+-- +goose StatementEnd
+-- +goose StatementBegin
+DROP TABLE IF EXISTS return_requests;
+-- +goose StatementEnd
@@ -0,0 +1,62 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS invoice_sequences (
+    merchant_code VARCHAR(32) PRIMARY KEY,
+    last_number BIGINT NOT NULL DEFAULT 0,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP
+) ENGINE = InnoDB DEFAULT CHARSET = utf8mb4 COLLATE = utf8mb4_unicode_ci;
+
+-- +goose StatementEnd
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS invoices (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    number VARCHAR(64) NOT NULL,
+    merchant_code VARCHAR(32) NOT NULL,
+    sequence BIGINT NOT NULL,
+    order_id BIGINT NOT NULL,
+    user_id BIGINT NOT NULL,
+    seller_name VARCHAR(255) NOT NULL,
+    seller_tax_id VARCHAR(64),
+    seller_address VARCHAR(255),
+    buyer_name VARCHAR(255) NOT NULL,
+    buyer_address TEXT,
+    currency CHAR(3) NOT NULL,
+    subtotal DECIMAL(19, 2) NOT NULL,
+    tax_total DECIMAL(19, 2) NOT NULL,
+    total DECIMAL(19, 2) NOT NULL,
+    tx_hash VARCHAR(66),
+    issued_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE KEY uk_invoices_number (number),
+    UNIQUE KEY uk_invoices_merchant_sequence (merchant_code, sequence),
+    UNIQUE KEY uk_invoices_order_id (order_id)
+) ENGINE = InnoDB DEFAULT CHARSET = utf8mb4 COLLATE = utf8mb4_unicode_ci;
+
+-- +goose StatementEnd
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS invoice_lines (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    invoice_id BIGINT NOT NULL,
+    description VARCHAR(255) NOT NULL,
+    quantity INT NOT NULL,
+    unit_price DECIMAL(19, 2) NOT NULL,
+    net_amount DECIMAL(19, 2) NOT NULL,
+    tax_rate DECIMAL(18, 8) NOT NULL,
+    tax_amount DECIMAL(19, 2) NOT NULL,
+    amount DECIMAL(19, 2) NOT NULL,
+    KEY idx_invoice_lines_invoice_id (invoice_id)
+) ENGINE = InnoDB DEFAULT CHARSET = utf8mb4 COLLATE = utf8mb4_unicode_ci;
+
+-- +goose StatementEnd
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS invoice_lines;
+
+-- +goose StatementEnd
+-- +goose StatementBegin
+DROP TABLE IF EXISTS invoices;
+
+-- +goose StatementEnd
+-- +goose StatementBegin
+DROP TABLE IF EXISTS invoice_sequences;
+-- +goose StatementEnd
@@ -0,0 +1,52 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE products
+    ADD COLUMN tax_category VARCHAR(32) NOT NULL DEFAULT 'standard' AFTER currency;
+
+-- +goose StatementEnd
+-- +goose StatementBegin
+ALTER TABLE orders
+    ADD COLUMN subtotal DECIMAL(19, 2) NOT NULL DEFAULT 0 AFTER quantity,
+    ADD COLUMN tax_total DECIMAL(19, 2) NOT NULL DEFAULT 0 AFTER subtotal,
+    ADD COLUMN tax_region VARCHAR(8) AFTER shipping_address;
+
+-- +goose StatementEnd
+-- +goose StatementBegin
+-- 历史订单未计税，不含税金额即订单金额
+UPDATE orders SET subtotal = total_price;
+
+-- +goose StatementEnd
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS order_tax_lines (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    order_id BIGINT NOT NULL,
+    product_id BIGINT NOT NULL,
+    quantity INT NOT NULL,
+    unit_price DECIMAL(19, 2) NOT NULL,
+    category VARCHAR(32) NOT NULL,
+    region VARCHAR(8) NOT NULL,
+    rate DECIMAL(18, 8) NOT NULL,
+    inclusive BOOLEAN NOT NULL,
+    net_amount DECIMAL(19, 2) NOT NULL,
+    tax_amount DECIMAL(19, 2) NOT NULL,
+    gross_amount DECIMAL(19, 2) NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    KEY idx_order_tax_lines_order_id (order_id)
+) ENGINE = InnoDB DEFAULT CHARSET = utf8mb4 COLLATE = utf8mb4_unicode_ci;
+
+-- +goose StatementEnd
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS order_tax_lines;
+
+-- +goose StatementEnd
+-- +goose StatementBegin
+ALTER TABLE orders
+    DROP COLUMN tax_region,
+    DROP COLUMN tax_total,
+    DROP COLUMN subtotal;
+
+-- +goose StatementEnd
+-- +goose StatementBegin
+ALTER TABLE products DROP COLUMN tax_category;
+-- +goose StatementEnd
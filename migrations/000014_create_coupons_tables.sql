@@ -0,0 +1,96 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS coupons (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    code VARCHAR(32) NOT NULL,
+    type VARCHAR(16) NOT NULL,
+    percent_off DECIMAL(18, 8) NOT NULL DEFAULT 0,
+    amount_off DECIMAL(19, 2) NOT NULL DEFAULT 0,
+    currency CHAR(3),
+    min_amount DECIMAL(19, 2) NOT NULL DEFAULT 0,
+    usage_limit INT NOT NULL DEFAULT 0,
+    per_user_limit INT NOT NULL DEFAULT 0,
+    used_count INT NOT NULL DEFAULT 0,
+    starts_at TIMESTAMP NULL,
+    ends_at TIMESTAMP NULL,
+    disabled BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    UNIQUE KEY uk_coupons_code (code)
+) ENGINE = InnoDB DEFAULT CHARSET = utf8mb4 COLLATE = utf8mb4_unicode_ci;
+
+-- +goose StatementEnd
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS coupon_products (
+    coupon_id BIGINT NOT NULL,
+    product_id BIGINT NOT NULL,
+    PRIMARY KEY (coupon_id, product_id)
+) ENGINE = InnoDB DEFAULT CHARSET = utf8mb4 COLLATE = utf8mb4_unicode_ci;
+
+-- +goose StatementEnd
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS coupon_redemptions (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    coupon_id BIGINT NOT NULL,
+    user_id BIGINT NOT NULL,
+    order_id BIGINT NOT NULL,
+    discount DECIMAL(19, 2) NOT NULL,
+    currency CHAR(3) NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE KEY uk_coupon_redemptions_order_id (order_id),
+    KEY idx_coupon_redemptions_coupon_user (coupon_id, user_id)
+) ENGINE = InnoDB DEFAULT CHARSET = utf8mb4 COLLATE = utf8mb4_unicode_ci;
+
+-- +goose StatementEnd
+-- +goose StatementBegin
+ALTER TABLE orders
+    ADD COLUMN discount DECIMAL(19, 2) NOT NULL DEFAULT 0 AFTER quantity,
+    ADD COLUMN coupon_code VARCHAR(32) AFTER tax_region;
+
+-- +goose StatementEnd
+-- +goose StatementBegin
+ALTER TABLE order_tax_lines ADD COLUMN discount DECIMAL(19, 2) NOT NULL DEFAULT 0 AFTER unit_price;
+
+-- +goose StatementEnd
+-- +goose StatementBegin
+ALTER TABLE invoice_lines ADD COLUMN discount DECIMAL(19, 2) NOT NULL DEFAULT 0 AFTER unit_price;
+
+-- +goose StatementEnd
+-- +goose StatementBegin
+ALTER TABLE transactions
+    ADD COLUMN discount VARCHAR(78) AFTER currency,
+    ADD COLUMN coupon_code VARCHAR(32) AFTER discount;
+
+-- +goose StatementEnd
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE transactions
+    DROP COLUMN coupon_code,
+    DROP COLUMN discount;
+
+-- +goose StatementEnd
+-- +goose StatementBegin
+ALTER TABLE invoice_lines DROP COLUMN discount;
+
+-- +goose StatementEnd
+-- +goose StatementBegin
+ALTER TABLE order_tax_lines DROP COLUMN discount;
+
+-- +goose StatementEnd
+-- +goose StatementBegin
+ALTER TABLE orders
+    DROP COLUMN coupon_code,
+    DROP COLUMN discount;
+
+-- +goose StatementEnd
+-- +goose StatementBegin
+DROP TABLE IF EXISTS coupon_redemptions;
+
+-- +goose StatementEnd
+-- +goose StatementBegin
+DROP TABLE IF EXISTS coupon_products;
+
+-- +goose StatementEnd
+-- +goose StatementBegin
+DROP TABLE IF EXISTS coupons;
+-- +goose StatementEnd
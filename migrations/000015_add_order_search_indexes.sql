@@ -0,0 +1,19 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE orders
+    ADD KEY idx_orders_user_created (user_id, created_at),
+    ADD KEY idx_orders_status_created (status, created_at),
+    ADD KEY idx_orders_product_id (product_id),
+    ADD KEY idx_orders_created_at (created_at),
+    ADD KEY idx_orders_total_price (total_price);
+
+-- +goose StatementEnd
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE orders
+    DROP KEY idx_orders_total_price,
+    DROP KEY idx_orders_created_at,
+    DROP KEY idx_orders_product_id,
+    DROP KEY idx_orders_status_created,
+    DROP KEY idx_orders_user_created;
+-- +goose StatementEnd
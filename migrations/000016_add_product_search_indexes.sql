@@ -0,0 +1,19 @@
+-- +goose Up
+-- +goose StatementBegin
+-- ngram分词支持中文商品名称和描述的全文搜索
+ALTER TABLE products
+    ADD FULLTEXT INDEX ft_products_name_description (name, description) WITH PARSER ngram;
+
+-- +goose StatementEnd
+-- +goose StatementBegin
+ALTER TABLE products ADD KEY idx_products_price (price);
+
+-- +goose StatementEnd
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE products DROP KEY idx_products_price;
+
+-- +goose StatementEnd
+-- +goose StatementBegin
+ALTER TABLE products DROP INDEX ft_products_name_description;
+-- +goose StatementEnd
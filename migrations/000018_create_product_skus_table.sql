@@ -0,0 +1,61 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS product_skus (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    product_id BIGINT NOT NULL,
+    code VARCHAR(64) NOT NULL,
+    options JSON,
+    price DECIMAL(19, 2),
+    stock INT NOT NULL,
+    is_default BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    UNIQUE KEY idx_product_skus_code (code),
+    KEY idx_product_skus_product_id (product_id)
+) ENGINE = InnoDB DEFAULT CHARSET = utf8mb4 COLLATE = utf8mb4_unicode_ci;
+
+-- +goose StatementEnd
+-- +goose StatementBegin
+-- 已有商品各迁移为一个默认SKU，承接商品的全部库存
+INSERT INTO product_skus (product_id, code, options, stock, is_default, created_at, updated_at)
+SELECT id, CONCAT('P', id), JSON_OBJECT(), stock, TRUE, created_at, updated_at FROM products;
+
+-- +goose StatementEnd
+-- +goose StatementBegin
+ALTER TABLE orders
+    ADD COLUMN sku_id BIGINT NOT NULL DEFAULT 0 AFTER product_id,
+    ADD KEY idx_orders_sku_id (sku_id);
+
+-- +goose StatementEnd
+-- +goose StatementBegin
+ALTER TABLE stock_reservations
+    ADD COLUMN sku_id BIGINT NOT NULL DEFAULT 0 AFTER product_id;
+
+-- +goose StatementEnd
+-- +goose StatementBegin
+-- 历史订单和库存预留关联到商品的默认SKU，取消或退货时归还到该SKU
+UPDATE orders o
+    JOIN product_skus s ON s.product_id = o.product_id AND s.is_default
+SET o.sku_id = s.id;
+
+-- +goose StatementEnd
+-- +goose StatementBegin
+UPDATE stock_reservations r
+    JOIN product_skus s ON s.product_id = r.product_id AND s.is_default
+SET r.sku_id = s.id;
+
+-- +goose StatementEnd
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE stock_reservations DROP COLUMN sku_id;
+
+-- +goose StatementEnd
+-- +goose StatementBegin
+ALTER TABLE orders
+    DROP KEY idx_orders_sku_id,
+    DROP COLUMN sku_id;
+
+-- +goose StatementEnd
+-- +goose StatementBegin
+DROP TABLE IF EXISTS product_skus;
+-- +goose StatementEnd
@@ -0,0 +1,54 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE products
+    ADD COLUMN archived_at TIMESTAMP NULL,
+    ADD COLUMN deleted_at DATETIME(3) NULL,
+    ADD KEY idx_products_archived_at (archived_at),
+    ADD KEY idx_products_deleted_at (deleted_at);
+
+-- +goose StatementEnd
+-- +goose StatementBegin
+ALTER TABLE orders
+    ADD COLUMN product_name VARCHAR(255) NOT NULL DEFAULT '' AFTER sku_id,
+    ADD COLUMN sku_code VARCHAR(64) NOT NULL DEFAULT '' AFTER product_name,
+    ADD COLUMN unit_price DECIMAL(19, 2) NOT NULL DEFAULT 0 AFTER quantity;
+
+-- +goose StatementEnd
+-- +goose StatementBegin
+-- 历史订单按商品的当前信息补全快照，已被删除的商品无法补全，保留为空
+UPDATE orders o
+    JOIN products p ON p.id = o.product_id
+SET o.product_name = p.name;
+
+-- +goose StatementEnd
+-- +goose StatementBegin
+UPDATE orders o
+    JOIN product_skus s ON s.id = o.sku_id
+SET o.sku_code = s.code;
+
+-- +goose StatementEnd
+-- +goose StatementBegin
+-- 单价优先取税费明细中的标价，没有明细的订单按优惠前的商品金额折算
+UPDATE orders o
+SET o.unit_price = COALESCE(
+    (SELECT MAX(t.unit_price) FROM order_tax_lines t WHERE t.order_id = o.id),
+    ROUND((o.subtotal + o.discount) / o.quantity, 2)
+)
+WHERE o.quantity > 0;
+
+-- +goose StatementEnd
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE orders
+    DROP COLUMN unit_price,
+    DROP COLUMN sku_code,
+    DROP COLUMN product_name;
+
+-- +goose StatementEnd
+-- +goose StatementBegin
+ALTER TABLE products
+    DROP KEY idx_products_deleted_at,
+    DROP KEY idx_products_archived_at,
+    DROP COLUMN deleted_at,
+    DROP COLUMN archived_at;
+-- +goose StatementEnd
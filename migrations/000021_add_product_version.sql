@@ -0,0 +1,9 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE products ADD COLUMN version BIGINT NOT NULL DEFAULT 1 AFTER archived_at;
+
+-- +goose StatementEnd
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE products DROP COLUMN version;
+-- +goose StatementEnd
@@ -0,0 +1,69 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS product_price_history (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    product_id BIGINT NOT NULL,
+    sku_id BIGINT,
+    currency CHAR(3) NOT NULL,
+    old_price DECIMAL(19, 2),
+    new_price DECIMAL(19, 2),
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    KEY idx_product_price_history_product_created (product_id, created_at)
+) ENGINE = InnoDB DEFAULT CHARSET = utf8mb4 COLLATE = utf8mb4_unicode_ci;
+
+-- +goose StatementEnd
+-- +goose StatementBegin
+-- 已有商品以当前价格作为初始记录，此前的变更时间未知，记录时间为迁移时间
+INSERT INTO product_price_history (product_id, currency, new_price)
+SELECT id, currency, price FROM products;
+
+-- +goose StatementEnd
+-- +goose StatementBegin
+INSERT INTO product_price_history (product_id, currency, new_price)
+SELECT product_id, currency, price FROM product_prices;
+
+-- +goose StatementEnd
+-- +goose StatementBegin
+INSERT INTO product_price_history (product_id, sku_id, currency, new_price)
+SELECT s.product_id, s.id, p.currency, s.price
+FROM product_skus s
+    JOIN products p ON p.id = s.product_id
+WHERE s.price IS NOT NULL;
+
+-- +goose StatementEnd
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS price_commitments (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    merkle_root CHAR(64) NOT NULL,
+    entry_count INT NOT NULL,
+    tx_hash VARCHAR(64) NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    KEY idx_price_commitments_created_at (created_at)
+) ENGINE = InnoDB DEFAULT CHARSET = utf8mb4 COLLATE = utf8mb4_unicode_ci;
+
+-- +goose StatementEnd
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS price_commitment_entries (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    commitment_id BIGINT NOT NULL,
+    position INT NOT NULL,
+    product_id BIGINT NOT NULL,
+    sku_code VARCHAR(64) NOT NULL,
+    currency CHAR(3) NOT NULL,
+    price DECIMAL(19, 2) NOT NULL,
+    UNIQUE KEY uk_price_commitment_entries_sku_currency (commitment_id, sku_code, currency)
+) ENGINE = InnoDB DEFAULT CHARSET = utf8mb4 COLLATE = utf8mb4_unicode_ci;
+
+-- +goose StatementEnd
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS price_commitment_entries;
+
+-- +goose StatementEnd
+-- +goose StatementBegin
+DROP TABLE IF EXISTS price_commitments;
+
+-- +goose StatementEnd
+-- +goose StatementBegin
+DROP TABLE IF EXISTS product_price_history;
+-- +goose StatementEnd
@@ -0,0 +1,62 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS inventory_movements (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    product_id BIGINT NOT NULL,
+    sku_id BIGINT NOT NULL,
+    type VARCHAR(20) NOT NULL,
+    quantity INT NOT NULL,
+    actor_id BIGINT,
+    order_id BIGINT,
+    reason VARCHAR(255) NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    KEY idx_inventory_movements_product_created (product_id, created_at),
+    KEY idx_inventory_movements_sku_created (sku_id, created_at),
+    KEY idx_inventory_movements_order_id (order_id)
+) ENGINE = InnoDB DEFAULT CHARSET = utf8mb4 COLLATE = utf8mb4_unicode_ci;
+
+-- +goose StatementEnd
+-- +goose StatementBegin
+-- 已有SKU的当前库存记为一条期初调整，使库存等于流水数量之和
+INSERT INTO inventory_movements (product_id, sku_id, type, quantity, reason)
+SELECT product_id, id, 'adjustment', stock, '期初库存'
+FROM product_skus
+WHERE stock <> 0;
+
+-- +goose StatementEnd
+-- +goose StatementBegin
+ALTER TABLE product_skus
+    ADD COLUMN low_stock_threshold INT NULL AFTER is_default;
+
+-- +goose StatementEnd
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS low_stock_alerts (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    product_id BIGINT NOT NULL,
+    sku_id BIGINT NOT NULL,
+    sku_code VARCHAR(64) NOT NULL,
+    stock INT NOT NULL,
+    threshold INT NOT NULL,
+    resolved_at TIMESTAMP NULL,
+    delivered_at TIMESTAMP NULL,
+    attempts INT NOT NULL DEFAULT 0,
+    last_error VARCHAR(512) NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    KEY idx_low_stock_alerts_sku_id (sku_id),
+    KEY idx_low_stock_alerts_created_at (created_at)
+) ENGINE = InnoDB DEFAULT CHARSET = utf8mb4 COLLATE = utf8mb4_unicode_ci;
+
+-- +goose StatementEnd
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS low_stock_alerts;
+
+-- +goose StatementEnd
+-- +goose StatementBegin
+ALTER TABLE product_skus
+    DROP COLUMN low_stock_threshold;
+
+-- +goose StatementEnd
+-- +goose StatementBegin
+DROP TABLE IF EXISTS inventory_movements;
+-- +goose StatementEnd
@@ -0,0 +1,13 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE transactions
+    ADD COLUMN refunded BOOLEAN NOT NULL DEFAULT FALSE AFTER order_id,
+    ADD COLUMN refund_tx_hash VARCHAR(66) AFTER refunded;
+
+-- +goose StatementEnd
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE transactions
+    DROP COLUMN refund_tx_hash,
+    DROP COLUMN refunded;
+-- +goose StatementEnd
@@ -0,0 +1,16 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE payments
+    ADD COLUMN refunded_amount DECIMAL(10, 2) NOT NULL DEFAULT 0 AFTER status;
+
+-- +goose StatementEnd
+-- +goose StatementBegin
+-- 已全额退款的支付记入退款金额
+UPDATE payments SET refunded_amount = amount WHERE status = 'refunded';
+
+-- +goose StatementEnd
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE payments
+    DROP COLUMN refunded_amount;
+-- +goose StatementEnd
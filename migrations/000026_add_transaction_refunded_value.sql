@@ -0,0 +1,16 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE transactions
+    ADD COLUMN refunded_value DECIMAL(19, 2) NOT NULL DEFAULT 0 AFTER refunded;
+
+-- +goose StatementEnd
+-- +goose StatementBegin
+-- 已整笔退回的转账记入退回金额
+UPDATE transactions SET refunded_value = CAST(value AS DECIMAL(19, 2)) WHERE refunded = TRUE;
+
+-- +goose StatementEnd
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE transactions
+    DROP COLUMN refunded_value;
+-- +goose StatementEnd
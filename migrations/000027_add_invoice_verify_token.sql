@@ -0,0 +1,16 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE invoices
+    ADD COLUMN verify_token CHAR(32) NOT NULL DEFAULT '' AFTER number;
+
+-- +goose StatementEnd
+-- +goose StatementBegin
+-- 为已开具的发票生成随机核验令牌，旧的核验地址不再可用
+UPDATE invoices SET verify_token = LOWER(HEX(RANDOM_BYTES(16)));
+
+-- +goose StatementEnd
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE invoices
+    DROP COLUMN verify_token;
+-- +goose StatementEnd
@@ -0,0 +1,43 @@
+// Package cursor 提供键集分页使用的不透明游标
+package cursor
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Cursor 记录上一页最后一条记录的排序键，下一页从其后开始查询
+type Cursor struct {
+	// Sort 生成游标时的排序方式，游标不能用于其他排序
+	Sort string `json:"s,omitempty"`
+	// Value 排序字段的值，按ID或高度排序时为空
+	Value string `json:"v,omitempty"`
+	// ID 记录ID或区块高度，排序字段的值相同时用于区分先后
+	ID int64 `json:"id"`
+}
+
+// Encode 将游标编码为URL安全的字符串
+func (c *Cursor) Encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// Decode 解析游标字符串，并校验游标的排序方式与sort一致
+func Decode(s, sort string) (*Cursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	var c Cursor
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, ErrInvalidCursor
+	}
+	if c.Sort != sort || c.ID < 0 {
+		return nil, ErrInvalidCursor
+	}
+	return &c, nil
+}
@@ -0,0 +1,34 @@
+package cursor
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCursor(t *testing.T) {
+	c := &Cursor{Sort: "total:asc", Value: "99.90", ID: 42}
+	encoded := c.Encode()
+
+	decoded, err := Decode(encoded, "total:asc")
+	require.NoError(t, err)
+	assert.Equal(t, c, decoded)
+
+	tests := []struct {
+		name  string
+		value string
+		sort  string
+	}{
+		{name: "other sort", value: encoded, sort: "created_at:desc"},
+		{name: "not base64", value: "!!!", sort: "total:asc"},
+		{name: "not json", value: "bm90LWpzb24", sort: "total:asc"},
+		{name: "negative id", value: (&Cursor{ID: -1}).Encode(), sort: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Decode(tt.value, tt.sort)
+			assert.Equal(t, ErrInvalidCursor, err)
+		})
+	}
+}
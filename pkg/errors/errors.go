@@ -9,7 +9,32 @@ var (
 	ErrInvalidToken      = errors.New("无效的令牌")
 	ErrUnauthorized      = errors.New("未授权的访问")
 	ErrInsufficientStock = errors.New("库存不足")
+	ErrProductArchived   = errors.New("商品已下架")
 	ErrForbidden         = errors.New("禁止访问")
 	ErrBadRequest        = errors.New("请求参数错误")
 	ErrNoFieldsToUpdate  = errors.New("至少需要更新一个字段")
+
+	ErrPreconditionFailed = errors.New("记录已被修改，请重新获取后再提交")
+
+	ErrInvalidOrderStatus = errors.New("当前订单状态不允许该操作")
+	ErrReservationExpired = errors.New("订单支付已超时，库存预留已释放")
+
+	ErrInvalidSignature      = errors.New("签名校验失败")
+	ErrPaymentAmountMismatch = errors.New("支付金额与订单金额不一致")
+	ErrInsufficientBalance   = errors.New("账户余额不足")
+
+	ErrUnsupportedCurrency = errors.New("不支持的币种")
+
+	ErrCategoryNotEmpty = errors.New("分类下还有子分类")
+
+	ErrUnsupportedMediaType = errors.New("不支持的图片格式")
+	ErrPayloadTooLarge      = errors.New("上传的文件过大")
+	ErrUnsupportedFormat    = errors.New("不支持的文件格式")
+
+	ErrCouponInvalid       = errors.New("优惠券不存在或不在有效期内")
+	ErrCouponNotApplicable = errors.New("订单不满足优惠券的使用条件")
+	ErrCouponUsageExceeded = errors.New("优惠券使用次数已达上限")
+
+	ErrIdempotencyKeyReused     = errors.New("幂等键已被用于不同的请求")
+	ErrIdempotencyKeyInProgress = errors.New("相同幂等键的请求正在处理中")
 )
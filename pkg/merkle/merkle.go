@@ -0,0 +1,105 @@
+// Package merkle 提供SHA-256默克尔树的根哈希计算和包含证明。
+// 树的构造与RFC 6962一致：叶子哈希为 SHA256(0x00 || 数据)，内部节点哈希为 SHA256(0x01 || 左 || 右)，
+// 包含n个叶子的树分为前k个叶子和其余叶子两棵子树，k为小于n的最大的2的幂
+package merkle
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+)
+
+var ErrIndexOutOfRange = errors.New("leaf index out of range")
+
+const (
+	leafPrefix = 0x00
+	nodePrefix = 0x01
+)
+
+// ProofStep 包含证明中的一个兄弟节点
+type ProofStep struct {
+	Hash []byte
+	Left bool // 兄弟节点位于左侧
+}
+
+// LeafHash 计算叶子数据的哈希
+func LeafHash(data []byte) []byte {
+	h := sha256.New()
+	h.Write([]byte{leafPrefix})
+	h.Write(data)
+	return h.Sum(nil)
+}
+
+func nodeHash(left, right []byte) []byte {
+	h := sha256.New()
+	h.Write([]byte{nodePrefix})
+	h.Write(left)
+	h.Write(right)
+	return h.Sum(nil)
+}
+
+// Root 计算叶子数据组成的树的根哈希，没有叶子时返回空数据的SHA-256
+func Root(leaves [][]byte) []byte {
+	if len(leaves) == 0 {
+		sum := sha256.Sum256(nil)
+		return sum[:]
+	}
+	hashes := make([][]byte, len(leaves))
+	for i, leaf := range leaves {
+		hashes[i] = LeafHash(leaf)
+	}
+	return root(hashes)
+}
+
+func root(hashes [][]byte) []byte {
+	if len(hashes) == 1 {
+		return hashes[0]
+	}
+	k := split(len(hashes))
+	return nodeHash(root(hashes[:k]), root(hashes[k:]))
+}
+
+// Proof 生成第index个叶子的包含证明，兄弟节点按从叶子到根的顺序排列
+func Proof(leaves [][]byte, index int) ([]ProofStep, error) {
+	if index < 0 || index >= len(leaves) {
+		return nil, ErrIndexOutOfRange
+	}
+	hashes := make([][]byte, len(leaves))
+	for i, leaf := range leaves {
+		hashes[i] = LeafHash(leaf)
+	}
+	return proof(hashes, index), nil
+}
+
+func proof(hashes [][]byte, index int) []ProofStep {
+	if len(hashes) == 1 {
+		return nil
+	}
+	k := split(len(hashes))
+	if index < k {
+		return append(proof(hashes[:k], index), ProofStep{Hash: root(hashes[k:])})
+	}
+	return append(proof(hashes[k:], index-k), ProofStep{Hash: root(hashes[:k]), Left: true})
+}
+
+// Verify 校验叶子数据与包含证明能否得到根哈希
+func Verify(leaf []byte, steps []ProofStep, rootHash []byte) bool {
+	hash := LeafHash(leaf)
+	for _, step := range steps {
+		if step.Left {
+			hash = nodeHash(step.Hash, hash)
+		} else {
+			hash = nodeHash(hash, step.Hash)
+		}
+	}
+	return bytes.Equal(hash, rootHash)
+}
+
+// split 返回小于n的最大的2的幂，n大于1
+func split(n int) int {
+	k := 1
+	for k*2 < n {
+		k *= 2
+	}
+	return k
+}
@@ -0,0 +1,53 @@
+package merkle
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRoot(t *testing.T) {
+	// RFC 6962 2.1节定义的哈希
+	a, b, c := []byte("a"), []byte("b"), []byte("c")
+	ab := nodeHash(LeafHash(a), LeafHash(b))
+
+	empty := sha256.Sum256(nil)
+	assert.Equal(t, empty[:], Root(nil))
+	assert.Equal(t, LeafHash(a), Root([][]byte{a}))
+	assert.Equal(t, ab, Root([][]byte{a, b}))
+	assert.Equal(t, nodeHash(ab, LeafHash(c)), Root([][]byte{a, b, c}))
+
+	// 叶子与内部节点使用不同的前缀，两个叶子的根不等于以其哈希拼接为数据的叶子
+	assert.NotEqual(t, Root([][]byte{a, b}), Root([][]byte{append(LeafHash(a), LeafHash(b)...)}))
+	assert.Equal(t, "022a6979e6dab7aa5ae4c3e5e45f7e977112a7e63593820dbec1ec738a24f93c", hex.EncodeToString(LeafHash(a)))
+}
+
+func TestProof(t *testing.T) {
+	for n := 1; n <= 9; n++ {
+		leaves := make([][]byte, n)
+		for i := range leaves {
+			leaves[i] = []byte(fmt.Sprintf("leaf-%d", i))
+		}
+		rootHash := Root(leaves)
+
+		for i := range leaves {
+			steps, err := Proof(leaves, i)
+			require.NoError(t, err)
+			assert.True(t, Verify(leaves[i], steps, rootHash), "n=%d index=%d", n, i)
+			assert.False(t, Verify([]byte("forged"), steps, rootHash), "n=%d index=%d", n, i)
+			if n > 1 {
+				other := leaves[(i+1)%n]
+				assert.False(t, Verify(other, steps, rootHash), "n=%d index=%d", n, i)
+			}
+		}
+	}
+
+	_, err := Proof([][]byte{[]byte("a")}, 1)
+	assert.Equal(t, ErrIndexOutOfRange, err)
+	_, err = Proof(nil, 0)
+	assert.Equal(t, ErrIndexOutOfRange, err)
+}
@@ -0,0 +1,193 @@
+// Package money 提供定点金额类型，避免使用浮点数表示金额产生的舍入误差
+package money
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// DefaultCurrency 未指定币种时使用的币种
+const DefaultCurrency = "CNY"
+
+// scale 每个货币单位包含的最小单位数量，金额保留两位小数
+const scale = 100
+
+var (
+	ErrInvalidAmount    = errors.New("invalid amount")
+	ErrCurrencyMismatch = errors.New("currency mismatch")
+)
+
+var amountPattern = regexp.MustCompile(`^-?[0-9]+(\.[0-9]{1,2})?$`)
+
+// Amount 以最小货币单位（分）存储的定点金额
+type Amount int64
+
+// FromMinor 由最小货币单位的数量创建金额
+func FromMinor(minor int64) Amount {
+	return Amount(minor)
+}
+
+// ParseAmount 解析十进制金额，最多两位小数
+func ParseAmount(s string) (Amount, error) {
+	if !amountPattern.MatchString(s) {
+		return 0, ErrInvalidAmount
+	}
+
+	negative := strings.HasPrefix(s, "-")
+	whole, frac, _ := strings.Cut(strings.TrimPrefix(s, "-"), ".")
+	for len(frac) < 2 {
+		frac += "0"
+	}
+
+	minor, err := strconv.ParseInt(whole+frac, 10, 64)
+	if err != nil {
+		return 0, ErrInvalidAmount
+	}
+	if negative {
+		minor = -minor
+	}
+	return Amount(minor), nil
+}
+
+// MustParseAmount 解析金额，失败时panic，用于常量和测试
+func MustParseAmount(s string) Amount {
+	a, err := ParseAmount(s)
+	if err != nil {
+		panic(fmt.Sprintf("money: invalid amount %q", s))
+	}
+	return a
+}
+
+// Minor 返回最小货币单位的数量
+func (a Amount) Minor() int64 {
+	return int64(a)
+}
+
+// Mul 金额乘以数量
+func (a Amount) Mul(n int) Amount {
+	return a * Amount(n)
+}
+
+// IsPositive 金额是否大于零
+func (a Amount) IsPositive() bool {
+	return a > 0
+}
+
+// String 返回保留两位小数的十进制表示
+func (a Amount) String() string {
+	minor := int64(a)
+	sign := ""
+	if minor < 0 {
+		sign = "-"
+		minor = -minor
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, minor/scale, minor%scale)
+}
+
+// MarshalJSON 序列化为十进制字符串，避免客户端按浮点数解析
+func (a Amount) MarshalJSON() ([]byte, error) {
+	return []byte(`"` + a.String() + `"`), nil
+}
+
+// UnmarshalJSON 同时支持字符串和数字形式的金额
+func (a *Amount) UnmarshalJSON(data []byte) error {
+	text := string(bytes.Trim(data, `"`))
+	parsed, err := ParseAmount(text)
+	if err != nil {
+		return err
+	}
+	*a = parsed
+	return nil
+}
+
+// Scan 实现sql.Scanner接口，支持DECIMAL列的各种驱动返回类型
+func (a *Amount) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		*a = 0
+		return nil
+	case []byte:
+		return a.scanText(string(v))
+	case string:
+		return a.scanText(v)
+	case int64:
+		*a = Amount(v * scale)
+		return nil
+	case float64:
+		*a = Amount(math.Round(v * scale))
+		return nil
+	}
+	return fmt.Errorf("money: cannot scan %T into Amount", value)
+}
+
+func (a *Amount) scanText(s string) error {
+	// 数据库可能返回多于两位的小数，如DECIMAL(19,4)或SUM结果
+	if whole, frac, ok := strings.Cut(s, "."); ok && len(frac) > 2 {
+		if strings.Trim(frac[2:], "0") != "" {
+			return fmt.Errorf("money: %q has more than two decimal places", s)
+		}
+		s = whole + "." + frac[:2]
+	}
+	parsed, err := ParseAmount(s)
+	if err != nil {
+		return err
+	}
+	*a = parsed
+	return nil
+}
+
+// Value 实现driver.Valuer接口
+func (a Amount) Value() (driver.Value, error) {
+	return a.String(), nil
+}
+
+// GormDBDataType 自动迁移时使用的列类型
+func (Amount) GormDBDataType(db *gorm.DB, field *schema.Field) string {
+	return "DECIMAL(19,2)"
+}
+
+// Money 带币种的金额
+type Money struct {
+	Amount   Amount `json:"amount"`
+	Currency string `json:"currency"`
+}
+
+// New 创建带币种的金额，币种为空时使用默认币种
+func New(amount Amount, currency string) Money {
+	if currency == "" {
+		currency = DefaultCurrency
+	}
+	return Money{Amount: amount, Currency: currency}
+}
+
+// Add 相加，币种不同时返回错误
+func (m Money) Add(other Money) (Money, error) {
+	if m.Currency != other.Currency {
+		return Money{}, ErrCurrencyMismatch
+	}
+	return Money{Amount: m.Amount + other.Amount, Currency: m.Currency}, nil
+}
+
+// Mul 乘以数量
+func (m Money) Mul(n int) Money {
+	return Money{Amount: m.Amount.Mul(n), Currency: m.Currency}
+}
+
+// Equal 金额和币种是否都相同
+func (m Money) Equal(other Money) bool {
+	return m.Amount == other.Amount && m.Currency == other.Currency
+}
+
+// String 返回如 "19.98 CNY" 的表示
+func (m Money) String() string {
+	return m.Amount.String() + " " + m.Currency
+}
@@ -0,0 +1,246 @@
+package money
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseAmount(t *testing.T) {
+	tests := []struct {
+		input     string
+		expected  Amount
+		expectErr bool
+	}{
+		{input: "0", expected: 0},
+		{input: "19.98", expected: 1998},
+		{input: "19.9", expected: 1990},
+		{input: "100", expected: 10000},
+		{input: "0.01", expected: 1},
+		{input: "-3.50", expected: -350},
+		{input: "007.10", expected: 710},
+		{input: "1.999", expectErr: true},
+		{input: "1.", expectErr: true},
+		{input: ".5", expectErr: true},
+		{input: "", expectErr: true},
+		{input: "1e3", expectErr: true},
+		{input: "99999999999999999999", expectErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := ParseAmount(tt.input)
+			if tt.expectErr {
+				assert.Equal(t, ErrInvalidAmount, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, got)
+		})
+	}
+}
+
+func TestAmount_String(t *testing.T) {
+	assert.Equal(t, "0.00", Amount(0).String())
+	assert.Equal(t, "0.05", Amount(5).String())
+	assert.Equal(t, "19.98", Amount(1998).String())
+	assert.Equal(t, "-0.50", Amount(-50).String())
+}
+
+func TestAmount_Mul(t *testing.T) {
+	// 0.1 * 3 使用浮点数计算会得到 0.30000000000000004
+	assert.Equal(t, "0.30", MustParseAmount("0.10").Mul(3).String())
+	assert.Equal(t, "59.94", MustParseAmount("19.98").Mul(3).String())
+}
+
+func TestAmount_JSON(t *testing.T) {
+	data, err := json.Marshal(struct {
+		Price Amount `json:"price"`
+	}{Price: 1998})
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"price":"19.98"}`, string(data))
+
+	tests := []struct {
+		name      string
+		input     string
+		expected  Amount
+		expectErr bool
+	}{
+		{name: "string", input: `{"price":"19.98"}`, expected: 1998},
+		{name: "number", input: `{"price":19.98}`, expected: 1998},
+		{name: "integer", input: `{"price":20}`, expected: 2000},
+		{name: "too many decimals", input: `{"price":19.999}`, expectErr: true},
+		{name: "not a number", input: `{"price":"abc"}`, expectErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var v struct {
+				Price Amount `json:"price"`
+			}
+			err := json.Unmarshal([]byte(tt.input), &v)
+			if tt.expectErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, v.Price)
+		})
+	}
+}
+
+func TestAmount_Scan(t *testing.T) {
+	tests := []struct {
+		name      string
+		value     interface{}
+		expected  Amount
+		expectErr bool
+	}{
+		{name: "bytes", value: []byte("19.98"), expected: 1998},
+		{name: "string", value: "19.90", expected: 1990},
+		{name: "trailing zeros", value: "19.9800", expected: 1998},
+		{name: "float", value: 0.1 + 0.2, expected: 30},
+		{name: "integer", value: int64(5), expected: 500},
+		{name: "nil", value: nil, expected: 0},
+		{name: "extra precision", value: "19.985", expectErr: true},
+		{name: "unsupported type", value: true, expectErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var a Amount
+			err := a.Scan(tt.value)
+			if tt.expectErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, a)
+		})
+	}
+}
+
+func TestMoney_Add(t *testing.T) {
+	sum, err := New(1998, "").Add(New(2, "CNY"))
+	require.NoError(t, err)
+	assert.Equal(t, "20.00 CNY", sum.String())
+
+	_, err = New(100, "CNY").Add(New(100, "USD"))
+	assert.Equal(t, ErrCurrencyMismatch, err)
+}
+
+func TestParseRate(t *testing.T) {
+	tests := []struct {
+		input     string
+		expected  string
+		expectErr bool
+	}{
+		{input: "1", expected: "1.00000000"},
+		{input: "0.138", expected: "0.13800000"},
+		{input: "7.24637681", expected: "7.24637681"},
+		{input: "0", expectErr: true},
+		{input: "-1", expectErr: true},
+		{input: "0.123456789", expectErr: true},
+		{input: "abc", expectErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := ParseRate(tt.input)
+			if tt.expectErr {
+				assert.Equal(t, ErrInvalidRate, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, got.String())
+		})
+	}
+}
+
+func TestRate_Convert(t *testing.T) {
+	tests := []struct {
+		name     string
+		rate     string
+		amount   string
+		expected string
+	}{
+		{name: "identity", rate: "1", amount: "19.98", expected: "19.98"},
+		{name: "round down", rate: "0.138", amount: "19.98", expected: "2.76"},
+		{name: "round half up", rate: "0.5", amount: "0.01", expected: "0.01"},
+		{name: "negative rounds away from zero", rate: "0.5", amount: "-0.01", expected: "-0.01"},
+		{name: "large amount", rate: "7.24637681", amount: "99999999999.99", expected: "724637680999.93"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := MustParseRate(tt.rate).Convert(MustParseAmount(tt.amount))
+			assert.Equal(t, tt.expected, got.String())
+		})
+	}
+}
+
+func TestRate_ConvertBack(t *testing.T) {
+	tests := []struct {
+		name     string
+		rate     string
+		amount   string
+		expected string
+	}{
+		{name: "identity", rate: "1", amount: "19.98", expected: "19.98"},
+		{name: "exact", rate: "1.13", amount: "113", expected: "100.00"},
+		{name: "rounded", rate: "1.13", amount: "10", expected: "8.85"},
+		{name: "smallest unit", rate: "1.06", amount: "0.01", expected: "0.01"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := MustParseRate(tt.rate).ConvertBack(MustParseAmount(tt.amount))
+			assert.Equal(t, tt.expected, got.String())
+		})
+	}
+}
+
+func TestRate_Div(t *testing.T) {
+	usd := MustParseRate("0.14")
+	eur := MustParseRate("0.125")
+
+	assert.Equal(t, "0.89285714", eur.Div(usd).String())
+	assert.Equal(t, RateOne, usd.Div(usd))
+}
+
+func TestRate_Scan(t *testing.T) {
+	var r Rate
+	assert.NoError(t, r.Scan([]byte("7.2463768100")))
+	assert.Equal(t, MustParseRate("7.24637681"), r)
+
+	// 数据库中的零值比率（如零税率）可以读取，但不能作为汇率解析
+	assert.NoError(t, r.Scan("0.00000000"))
+	assert.Equal(t, Rate(0), r)
+	_, err := ParseRate("0")
+	assert.Equal(t, ErrInvalidRate, err)
+
+	assert.Error(t, r.Scan("abc"))
+}
+
+func TestRate_JSON(t *testing.T) {
+	for _, r := range []Rate{0, MustParseRate("0.13")} {
+		data, err := json.Marshal(r)
+		assert.NoError(t, err)
+
+		var decoded Rate
+		assert.NoError(t, json.Unmarshal(data, &decoded))
+		assert.Equal(t, r, decoded)
+	}
+
+	var r Rate
+	assert.Error(t, json.Unmarshal([]byte(`"-0.1"`), &r))
+}
+
+func TestValidCurrency(t *testing.T) {
+	assert.True(t, ValidCurrency("CNY"))
+	assert.False(t, ValidCurrency("cny"))
+	assert.False(t, ValidCurrency("CN"))
+	assert.False(t, ValidCurrency(""))
+}
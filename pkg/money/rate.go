@@ -0,0 +1,163 @@
+package money
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// rateScale 汇率保留8位小数
+const rateScale = 100000000
+
+// RateOne 相同币种之间的汇率
+const RateOne Rate = rateScale
+
+var ErrInvalidRate = errors.New("invalid exchange rate")
+
+var (
+	ratePattern     = regexp.MustCompile(`^[0-9]+(\.[0-9]{1,8})?$`)
+	currencyPattern = regexp.MustCompile(`^[A-Z]{3}$`)
+)
+
+// ValidCurrency 是否为ISO 4217格式的币种代码
+func ValidCurrency(code string) bool {
+	return currencyPattern.MatchString(code)
+}
+
+// Rate 汇率，表示1单位源币种可兑换的目标币种数量，以1e-8为单位存储
+type Rate int64
+
+// ParseRate 解析十进制汇率，最多8位小数，必须大于零
+func ParseRate(s string) (Rate, error) {
+	r, err := parseRate(s)
+	if err != nil || r <= 0 {
+		return 0, ErrInvalidRate
+	}
+	return r, nil
+}
+
+// parseRate 解析非负的十进制汇率，数据库中零值的比率（如零税率）需要能正常读取
+func parseRate(s string) (Rate, error) {
+	if !ratePattern.MatchString(s) {
+		return 0, ErrInvalidRate
+	}
+
+	whole, frac, _ := strings.Cut(s, ".")
+	frac += strings.Repeat("0", 8-len(frac))
+	v, err := strconv.ParseInt(whole+frac, 10, 64)
+	if err != nil {
+		return 0, ErrInvalidRate
+	}
+	return Rate(v), nil
+}
+
+// MustParseRate 解析汇率，失败时panic，用于常量和测试
+func MustParseRate(s string) Rate {
+	r, err := ParseRate(s)
+	if err != nil {
+		panic(fmt.Sprintf("money: invalid rate %q", s))
+	}
+	return r
+}
+
+// String 返回保留8位小数的十进制表示
+func (r Rate) String() string {
+	return fmt.Sprintf("%d.%08d", int64(r)/rateScale, int64(r)%rateScale)
+}
+
+// Convert 按汇率换算金额，结果四舍五入到分
+func (r Rate) Convert(a Amount) Amount {
+	n := new(big.Int).Mul(big.NewInt(int64(a)), big.NewInt(int64(r)))
+	return Amount(roundDiv(n, big.NewInt(rateScale)).Int64())
+}
+
+// ConvertBack 按汇率反向换算金额，即金额除以汇率，结果四舍五入到分
+func (r Rate) ConvertBack(a Amount) Amount {
+	n := new(big.Int).Mul(big.NewInt(int64(a)), big.NewInt(rateScale))
+	return Amount(roundDiv(n, big.NewInt(int64(r))).Int64())
+}
+
+// Div 计算两个汇率之比，用于由基准汇率推导交叉汇率
+func (r Rate) Div(other Rate) Rate {
+	n := new(big.Int).Mul(big.NewInt(int64(r)), big.NewInt(rateScale))
+	return Rate(roundDiv(n, big.NewInt(int64(other))).Int64())
+}
+
+// roundDiv 整数除法，结果四舍五入（远离零）
+func roundDiv(n, d *big.Int) *big.Int {
+	q, m := new(big.Int).QuoRem(n, d, new(big.Int))
+	if new(big.Int).Mul(new(big.Int).Abs(m), big.NewInt(2)).Cmp(new(big.Int).Abs(d)) >= 0 {
+		if n.Sign()*d.Sign() < 0 {
+			q.Sub(q, big.NewInt(1))
+		} else {
+			q.Add(q, big.NewInt(1))
+		}
+	}
+	return q
+}
+
+// MarshalJSON 序列化为十进制字符串
+func (r Rate) MarshalJSON() ([]byte, error) {
+	return []byte(`"` + r.String() + `"`), nil
+}
+
+// UnmarshalJSON 同时支持字符串和数字形式的比率，与MarshalJSON对称，零值（如零税率）也能解析
+func (r *Rate) UnmarshalJSON(data []byte) error {
+	parsed, err := parseRate(string(bytes.Trim(data, `"`)))
+	if err != nil {
+		return err
+	}
+	*r = parsed
+	return nil
+}
+
+// Scan 实现sql.Scanner接口
+func (r *Rate) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		*r = 0
+		return nil
+	case []byte:
+		return r.scanText(string(v))
+	case string:
+		return r.scanText(v)
+	case int64:
+		*r = Rate(v * rateScale)
+		return nil
+	case float64:
+		*r = Rate(math.Round(v * rateScale))
+		return nil
+	}
+	return fmt.Errorf("money: cannot scan %T into Rate", value)
+}
+
+func (r *Rate) scanText(s string) error {
+	if whole, frac, ok := strings.Cut(s, "."); ok && len(frac) > 8 {
+		s = whole + "." + frac[:8]
+	}
+	parsed, err := parseRate(s)
+	if err != nil {
+		return err
+	}
+	*r = parsed
+	return nil
+}
+
+// Value 实现driver.Valuer接口
+func (r Rate) Value() (driver.Value, error) {
+	return r.String(), nil
+}
+
+// GormDBDataType 自动迁移时使用的列类型
+func (Rate) GormDBDataType(db *gorm.DB, field *schema.Field) string {
+	return "DECIMAL(18,8)"
+}